@@ -52,6 +52,13 @@ func checkFormatLicense(format string) error {
 var aiProvider string
 var interactive bool
 var helmValuesFile string
+var helmSet []string
+var helmBinary bool
+var kustomizeOverlay string
+var springProfiles []string
+var composeProfiles []string
+var composeOverrides []string
+var dotnetEnvironment string
 var demoName string
 
 var analyzeCmd = &cobra.Command{
@@ -70,11 +77,37 @@ Supported URL formats:
   - github.com/org/repo (scheme is added automatically)
 
 Supported file types:
-  - Spring: application.yml, application.properties
-  - Docker: docker-compose.yml
-  - Kubernetes: Deployment, Service, ConfigMap manifests
-  - Environment: .env files
+  - Spring: application.yml, application.properties, application-{profile}.*
+    (${VAR:default} resolved from .env and compose/k8s env; --spring-profile)
+  - Quarkus, Micronaut: the same files, detected from pom.xml/build.gradle
+    or their keys (%profile. prefixes select with --spring-profile)
+  - Docker: docker-compose.yml, compose.yaml (.env interpolation, env_file,
+    include, extends, networks; --compose-profile), merged with
+    docker-compose.override.yml or the --compose-override files
+  - Kubernetes: workloads (Deployment, StatefulSet, DaemonSet, Job, CronJob,
+    ReplicaSet, Pod, Argo Rollout, Knative Service), Service, ConfigMap
+  - Istio: ServiceEntry, VirtualService, DestinationRule
+  - Helm: charts are rendered in-process (--helm-values, --helm-set; --helm-binary uses helm)
+  - Kustomize: overlays are built in-process (--kustomize-overlay selects one)
+  - Environment: .env, .env.<environment>, *.env files (tagged with the
+    environment their name implies; ${VAR} references expanded)
   - Build: pom.xml, build.gradle (dependency inference)
+  - Node.js: package.json (client libraries), config/*.json, .npmrc
+  - Python: requirements*.txt, pyproject.toml, Pipfile (client libraries),
+    Django settings.py, Celery config modules
+  - Go: go.mod (client modules), *.go (literal DSNs, grpc.Dial targets)
+  - Terraform: *.tf (RDS, Cloud SQL, ElastiCache, MSK, security groups)
+  - .NET: appsettings.json, appsettings.{Environment}.json (ConnectionStrings,
+    Redis, RabbitMQ, HttpClient base addresses, Kestrel endpoints;
+    --dotnet-environment)
+  - Dockerfile: Dockerfile, *.Dockerfile, Containerfile (EXPOSE, ENV,
+    HEALTHCHECK URLs; final build stage)
+  - Proxies: nginx.conf and conf.d/sites-enabled includes (upstream,
+    proxy_pass, listen), haproxy.cfg (server, bind), Envoy bootstrap YAML
+    (static clusters and listeners), Traefik YAML/TOML (services,
+    entryPoints)
+  - Rails: config/database.yml, cable.yml, storage.yml, sidekiq.yml (ERB
+    ENV lookups resolved from .env files), Gemfile.lock (client gems)
 
 AI-powered analysis (--ai flag):
   --ai         Auto-detect: tries local Ollama first, then Gemini cloud
@@ -95,11 +128,35 @@ func init() {
 	analyzeCmd.Flags().StringVar(&aiProvider, "ai", "", "AI backend: 'local' (Ollama), 'cloud' (Gemini), or omit for auto-detect")
 	analyzeCmd.Flag("ai").NoOptDefVal = "auto"
 	analyzeCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Review dependencies interactively before generating output")
-	analyzeCmd.Flags().StringVar(&helmValuesFile, "helm-values", "", "Helm values file to use when rendering charts")
+	addWalkFlags(analyzeCmd)
 	analyzeCmd.Flags().StringVar(&demoName, "demo", "", "Analyze a bundled demo fixture instead of a path. Use 'list' to see available demos.")
 	rootCmd.AddCommand(analyzeCmd)
 }
 
+// addWalkFlags registers the flags that choose what a walk renders and
+// layers (Helm values, overlays, profiles, environments) on cmd. analyze,
+// snapshot and diff share them, so a baseline and the run compared
+// against it can select the same configuration.
+func addWalkFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&helmValuesFile, "helm-values", "", "Helm values file to use when rendering charts")
+	cmd.Flags().StringArrayVar(&helmSet, "helm-set", nil, "Helm value override (key=value, repeatable), as with helm --set")
+	cmd.Flags().BoolVar(&helmBinary, "helm-binary", false, "Render charts with the external helm binary instead of the built-in renderer")
+	cmd.Flags().StringVar(&kustomizeOverlay, "kustomize-overlay", "", "Kustomize overlay to render, by path or directory name (default: every top-level overlay)")
+	cmd.Flags().StringSliceVar(&springProfiles, "spring-profile", nil, "Active Spring profiles, Quarkus profiles or Micronaut environments (comma-separated or repeatable; default: the default config plus every profile found)")
+	cmd.Flags().StringSliceVar(&composeProfiles, "compose-profile", nil, "Active Compose profiles (comma-separated or repeatable; default: every service)")
+	cmd.Flags().StringSliceVar(&composeOverrides, "compose-override", nil, "Compose override files to layer on each base file, by environment (prod for docker-compose.prod.yml) or file name (default: docker-compose.override.yml)")
+	cmd.Flags().StringVar(&dotnetEnvironment, "dotnet-environment", "", "Active ASP.NET Core environment whose appsettings.{Environment}.json overlays appsettings.json (default: the base file plus every environment found)")
+}
+
+// printWalkWarnings reports the walk's non-fatal problems -- files that
+// could not be parsed, references left unresolved, overrides not applied --
+// on stderr, one per line.
+func printWalkWarnings(warnings []walker.WalkWarning) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", w.File, w.Err)
+	}
+}
+
 // isGitHubURL reports whether arg looks like a GitHub repository URL.
 // It uses proper URL parsing to prevent domain-spoofing attacks like
 // github.com.evil.com or evil.github.com.
@@ -169,7 +226,7 @@ func collectInputFiles(root string) []renderer.EvidenceBundleInputFile {
 			}
 			return nil
 		}
-		if !isSupportedInputFile(d.Name()) {
+		if !isSupportedInputFile(p) {
 			return nil
 		}
 		content, readErr := os.ReadFile(p)
@@ -200,15 +257,57 @@ func collectInputFiles(root string) []renderer.EvidenceBundleInputFile {
 // TODO(v0.7): replace this allow-list with "files the parsers actually
 // emitted dependencies or declarations from", tracked at parse time.
 // Until then, baselines are sensitive to incidental YAML changes.
-func isSupportedInputFile(name string) bool {
-	lower := strings.ToLower(name)
+func isSupportedInputFile(path string) bool {
+	lower := strings.ToLower(filepath.Base(path))
 	switch lower {
 	case "docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml",
 		"application.yml", "application.yaml", "application.properties",
-		"pom.xml", "build.gradle", "build.gradle.kts":
+		"pom.xml", "build.gradle", "build.gradle.kts",
+		"package.json", ".npmrc",
+		"pyproject.toml", "pipfile", "settings.py", "celeryconfig.py", "celery_config.py",
+		"go.mod":
+		return true
+	}
+	if strings.HasSuffix(lower, ".json") && filepath.Base(filepath.Dir(path)) == "config" {
+		return true
+	}
+	if strings.HasPrefix(lower, "application-") && strings.HasSuffix(lower, ".properties") {
+		return true
+	}
+	if strings.HasPrefix(lower, "requirements") && strings.HasSuffix(lower, ".txt") {
+		return true
+	}
+	if strings.HasSuffix(lower, ".py") && filepath.Base(filepath.Dir(path)) == "settings" {
+		return true
+	}
+	if strings.HasSuffix(lower, ".go") && !strings.HasSuffix(lower, "_test.go") {
 		return true
 	}
-	if strings.HasSuffix(lower, ".env") || lower == ".env" {
+	if strings.HasSuffix(lower, ".tf") {
+		return true
+	}
+	if strings.HasPrefix(lower, "appsettings.") && strings.HasSuffix(lower, ".json") {
+		return true
+	}
+	if lower == "gemfile.lock" {
+		return true
+	}
+	switch parent := filepath.Base(filepath.Dir(path)); {
+	case lower == "nginx.conf", parent == "sites-enabled":
+		return true
+	case strings.HasSuffix(lower, ".conf") && (parent == "conf.d" || parent == "http.d" || parent == "stream.d" || parent == "nginx"):
+		return true
+	case strings.HasSuffix(lower, ".cfg") && (strings.HasPrefix(lower, "haproxy") || parent == "haproxy"):
+		return true
+	}
+	if strings.HasSuffix(lower, ".toml") {
+		return true
+	}
+	if lower == "dockerfile" || lower == "containerfile" || strings.HasSuffix(lower, ".dockerfile") ||
+		strings.HasPrefix(lower, "dockerfile.") || strings.HasPrefix(lower, "containerfile.") {
+		return true
+	}
+	if strings.HasSuffix(lower, ".env") || strings.HasPrefix(lower, ".env.") {
 		return true
 	}
 	if strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml") {
@@ -328,7 +427,7 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 
 	registry := parser.DefaultRegistry()
 
-	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile}
+	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile, HelmSet: helmSet, HelmBinary: helmBinary, KustomizeOverlay: kustomizeOverlay, SpringProfiles: springProfiles, ComposeProfiles: composeProfiles, ComposeOverrides: composeOverrides, DotnetEnvironment: dotnetEnvironment}
 	ds, warnings, err := walker.Walk(path, registry, walkOpts)
 	if err != nil {
 		return fmt.Errorf("analysis failed: %w", err)
@@ -337,9 +436,7 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	if repoName != "" {
 		ds.RenameSource(ds.ServiceName, repoName)
 	}
-	if len(warnings) > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: %d file(s) could not be parsed (use --verbose for details)\n", len(warnings))
-	}
+	printWalkWarnings(warnings)
 
 	if aiProvider != "" {
 		aiDeps, aiErr := ai.Analyze(path, ds.Dependencies(), aiProvider)
@@ -352,7 +449,7 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if ds.Len() == 0 {
+	if ds.Len() == 0 && len(ds.Listeners()) == 0 {
 		if outputFormat == "json" {
 			// Route through the renderer so the parser_versions block + the
 			// documented {dependencies: []} shape are preserved on empty input.
@@ -376,6 +473,9 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 			for _, dep := range selected {
 				filtered.Add(dep)
 			}
+			for _, l := range ds.Listeners() {
+				filtered.AddListener(l)
+			}
 			ds = filtered
 		}
 	}
@@ -157,6 +157,9 @@ func collectAppConfigWorkloads(path string) []coverage.Workload {
 		add(dep.Source)
 		add(dep.Target)
 	}
+	for _, l := range ds.Listeners() {
+		add(l.Workload)
+	}
 	return out
 }
 
@@ -36,6 +36,7 @@ Use --exit-code to exit with code 1 if changes are detected (for CI/CD).`,
 
 func init() {
 	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with code 1 if changes detected")
+	addWalkFlags(diffCmd)
 	rootCmd.AddCommand(diffCmd)
 }
 
@@ -105,7 +106,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 
 	// Analyze the current directory.
 	registry := parser.DefaultRegistry()
-	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile}
+	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile, HelmSet: helmSet, HelmBinary: helmBinary, KustomizeOverlay: kustomizeOverlay, SpringProfiles: springProfiles, ComposeProfiles: composeProfiles, ComposeOverrides: composeOverrides, DotnetEnvironment: dotnetEnvironment}
 	current, warnings, err := walker.Walk(path, registry, walkOpts)
 	if err != nil {
 		return fmt.Errorf("analysis failed: %w", err)
@@ -113,9 +114,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	if repoName != "" {
 		current.RenameSource(current.ServiceName, repoName)
 	}
-	if len(warnings) > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: %d file(s) could not be parsed\n", len(warnings))
-	}
+	printWalkWarnings(warnings)
 
 	if aiProvider != "" {
 		aiDeps, aiErr := ai.Analyze(path, current.Dependencies(), aiProvider)
@@ -143,7 +142,7 @@ func runDiff(cmd *cobra.Command, args []string) error {
 
 	fmt.Fprint(out, renderer.Diff(diff))
 
-	if diffExitCode && (len(diff.Added) > 0 || len(diff.Removed) > 0) {
+	if diffExitCode && diff.HasChanges() {
 		cmd.SilenceErrors = true
 		return errChangesDetected
 	}
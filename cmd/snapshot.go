@@ -37,6 +37,7 @@ type snapshotFile struct {
 	Generated    string                    `json:"generated"`
 	Version      string                    `json:"version"`
 	Dependencies []model.NetworkDependency `json:"dependencies"`
+	Listeners    []model.Listener          `json:"listeners,omitempty"`
 }
 
 var snapshotCmd = &cobra.Command{
@@ -55,6 +56,7 @@ Use -o to write to a file (default: stdout).`,
 }
 
 func init() {
+	addWalkFlags(snapshotCmd)
 	rootCmd.AddCommand(snapshotCmd)
 }
 
@@ -70,14 +72,12 @@ func runSnapshot(cmd *cobra.Command, args []string) error {
 	}
 
 	registry := parser.DefaultRegistry()
-	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile}
+	walkOpts := walker.WalkOptions{HelmValuesFile: helmValuesFile, HelmSet: helmSet, HelmBinary: helmBinary, KustomizeOverlay: kustomizeOverlay, SpringProfiles: springProfiles, ComposeProfiles: composeProfiles, ComposeOverrides: composeOverrides, DotnetEnvironment: dotnetEnvironment}
 	ds, warnings, err := walker.Walk(path, registry, walkOpts)
 	if err != nil {
 		return fmt.Errorf("analysis failed: %w", err)
 	}
-	if len(warnings) > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: %d file(s) could not be parsed\n", len(warnings))
-	}
+	printWalkWarnings(warnings)
 
 	absPath, absErr := filepath.Abs(path)
 	if absErr != nil {
@@ -99,6 +99,7 @@ func runSnapshot(cmd *cobra.Command, args []string) error {
 		Generated:    time.Now().UTC().Format("2006-01-02"),
 		Version:      Version,
 		Dependencies: ds.Dependencies(),
+		Listeners:    ds.Listeners(),
 	}
 
 	out := cmd.OutOrStdout()
@@ -160,6 +160,61 @@ func TestDiffAcceptsSnapshotWithMetadata(t *testing.T) {
 	}
 }
 
+// TestDiffSnapshotKeepsSelfReferencingRules verifies that a security group
+// rule admitting its own group survives a snapshot round trip as a
+// dependency, instead of being read back as a legacy listener.
+func TestDiffSnapshotKeepsSelfReferencingRules(t *testing.T) {
+	resetLicenseState(t)
+
+	scratch := t.TempDir()
+	if err := os.WriteFile(filepath.Join(scratch, "main.tf"), []byte(`
+resource "aws_security_group" "cluster" {
+  name = "cluster"
+
+  ingress {
+    from_port = 7000
+    to_port   = 7000
+    protocol  = "tcp"
+    self      = true
+  }
+}
+`), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	snapshotFilePath := filepath.Join(t.TempDir(), "baseline.json")
+	outputFile = snapshotFilePath
+	cmd := rootCmd
+	cmd.SetOut(new(bytes.Buffer))
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"snapshot", scratch})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	outputFile = ""
+
+	raw, err := os.ReadFile(snapshotFilePath)
+	if err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if !bytes.Contains(raw, []byte(`"target": "cluster"`)) {
+		t.Fatalf("expected the snapshot to carry the cluster -> cluster rule, got:\n%s", raw)
+	}
+
+	buf := new(bytes.Buffer)
+	outputFormat = "summary"
+	diffExitCode = false
+	cmd.SetOut(buf)
+	cmd.SetErr(new(bytes.Buffer))
+	cmd.SetArgs([]string{"diff", snapshotFilePath, scratch})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No changes detected.") {
+		t.Errorf("expected no-change diff for a self-referencing rule, got:\n%s", buf.String())
+	}
+}
+
 // TestDiffWarnsOnVersionMismatch verifies that when the baseline was created
 // by a different segspec version than the running binary, a warning is
 // emitted to stderr (but the diff still runs).
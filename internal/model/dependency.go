@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 )
 
@@ -16,6 +17,34 @@ const (
 	Low    Confidence = "low"    // Inferred from build dependencies
 )
 
+// rank orders confidences so the strongest evidence can win a merge.
+// Unknown values rank below Low.
+func (c Confidence) rank() int {
+	switch c {
+	case High:
+		return 3
+	case Medium:
+		return 2
+	case Low:
+		return 1
+	}
+	return 0
+}
+
+// Provenance is one config declaration backing a dependency. The same edge
+// is routinely declared several times (a compose depends_on, an env URL, a
+// Spring datasource); each declaration keeps its own record so auditors can
+// see every source, not just the first one the walker happened to visit.
+type Provenance struct {
+	File        string     `json:"file"`
+	Line        int        `json:"line,omitempty"`
+	Column      int        `json:"column,omitempty"`
+	Evidence    string     `json:"evidence,omitempty"`
+	Parser      string     `json:"parser,omitempty"`
+	Confidence  Confidence `json:"confidence"`
+	Environment string     `json:"environment,omitempty"`
+}
+
 // NetworkDependency represents a discovered network connection requirement.
 //
 // Disabled, when non-empty, indicates that the workload this dep belongs to
@@ -26,50 +55,235 @@ const (
 // summary` still surface the directive so the suppression is visible. See
 // k8s upstream #112560 for the original use case ("disable the
 // networkpolicy temporarily ... without delete-or-edit-to-match-none").
+//
+// Line and Column are the 1-based position of EvidenceLine inside
+// SourceFile. Zero means the parser could not attribute the dependency to a
+// single line (e.g. Helm-rendered output or AI-discovered deps).
+//
+// SourceFile/Line/EvidenceLine/Parser describe the primary (strongest)
+// declaration. Provenance lists every declaration merged into this dep by
+// DependencySet.Add, strongest first; the primary is always among them.
+//
+// Environment names the deployment environment the declaring file belongs
+// to (.env.production -> "production"), or is empty when the file applies
+// to every environment. An edge declared for more than one environment
+// (or for all of them) has an empty Environment; its provenance records
+// keep each declaration's own.
+//
+// BuildTime marks a connection made while building or installing the
+// service (an npm registry) rather than by the running workload. Policy
+// renderers leave it out; reports still list it.
+//
+// EndPort, when above Port, makes the edge cover the whole range
+// Port-EndPort (a security-group rule's from_port/to_port).
+//
+// Aliases are the other spellings the configs used for Target (a
+// namespaced DNS name, a Service name, a ClusterIP) before
+// ResolveIdentities folded them into its canonical name.
 type NetworkDependency struct {
-	Source       string     `json:"source"`
-	Target       string     `json:"target"`
-	Port         int        `json:"port"`
-	Protocol     string     `json:"protocol"`
-	Description  string     `json:"description"`
-	Confidence   Confidence `json:"confidence"`
-	SourceFile   string     `json:"source_file"`
-	EvidenceLine string     `json:"evidence_line,omitempty"`
-	ServiceType  string     `json:"service_type,omitempty"`
-	Disabled     string     `json:"disabled,omitempty"`
+	Source       string       `json:"source"`
+	Target       string       `json:"target"`
+	Port         int          `json:"port"`
+	EndPort      int          `json:"end_port,omitempty"`
+	Protocol     string       `json:"protocol"`
+	Description  string       `json:"description"`
+	Confidence   Confidence   `json:"confidence"`
+	SourceFile   string       `json:"source_file"`
+	Line         int          `json:"line,omitempty"`
+	Column       int          `json:"column,omitempty"`
+	EvidenceLine string       `json:"evidence_line,omitempty"`
+	ServiceType  string       `json:"service_type,omitempty"`
+	Disabled     string       `json:"disabled,omitempty"`
+	Parser       string       `json:"parser,omitempty"`
+	Environment  string       `json:"environment,omitempty"`
+	BuildTime    bool         `json:"build_time,omitempty"`
+	Aliases      []string     `json:"aliases,omitempty"`
+	Provenance   []Provenance `json:"provenance,omitempty"`
 }
 
 // Key returns a unique identifier for deduplication.
 func (d NetworkDependency) Key() string {
-	return fmt.Sprintf("%s->%s:%d/%s", d.Source, d.Target, d.Port, d.Protocol)
+	return fmt.Sprintf("%s->%s:%s/%s", d.Source, d.Target, d.PortRange(), d.Protocol)
+}
+
+// PortRange returns the port as text: "5432", or "1024-65535" for a
+// range.
+func (d NetworkDependency) PortRange() string {
+	if d.EndPort > d.Port {
+		return fmt.Sprintf("%d-%d", d.Port, d.EndPort)
+	}
+	return strconv.Itoa(d.Port)
+}
+
+// Location returns the "file:line" anchor for the dependency's evidence,
+// or just the file when no line is known. Empty when SourceFile is empty.
+func (d NetworkDependency) Location() string {
+	if d.SourceFile == "" {
+		return ""
+	}
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d", d.SourceFile, d.Line)
+	}
+	return d.SourceFile
+}
+
+// Location returns the "file:line" anchor for this record, or just the
+// file when no line is known.
+func (p Provenance) Location() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return p.File
 }
 
-// DependencySet collects network dependencies for a service with deduplication.
+// primaryProvenance returns the record describing the dep's own
+// SourceFile/Line/EvidenceLine, or false when the dep carries no evidence.
+func (d NetworkDependency) primaryProvenance() (Provenance, bool) {
+	if d.SourceFile == "" && d.EvidenceLine == "" {
+		return Provenance{}, false
+	}
+	return Provenance{
+		File:        d.SourceFile,
+		Line:        d.Line,
+		Column:      d.Column,
+		Evidence:    d.EvidenceLine,
+		Parser:      d.Parser,
+		Confidence:  d.Confidence,
+		Environment: d.Environment,
+	}, true
+}
+
+// DependencySet collects network dependencies for a service with
+// deduplication, and the listeners of the workloads involved.
 type DependencySet struct {
-	ServiceName string
-	deps        []NetworkDependency
-	seen        map[string]bool
+	ServiceName   string
+	deps          []NetworkDependency
+	index         map[string]int // Key() -> position in deps
+	listeners     []Listener
+	listenerIndex map[string]int // Listener.Key() -> position in listeners
 }
 
 // NewDependencySet creates an empty set for the named service.
 func NewDependencySet(name string) *DependencySet {
 	return &DependencySet{
-		ServiceName: name,
-		deps:        make([]NetworkDependency, 0),
-		seen:        make(map[string]bool),
+		ServiceName:   name,
+		deps:          make([]NetworkDependency, 0),
+		index:         make(map[string]int),
+		listenerIndex: make(map[string]int),
 	}
 }
 
-// Add inserts a dependency, skipping duplicates by Key().
+// Add inserts a dependency. A dependency whose Key() is already present is
+// merged into the existing entry: its provenance records are appended and,
+// if it is more confident than what we had, its evidence becomes the
+// primary one (Confidence, Description, SourceFile, Line, EvidenceLine).
 func (ds *DependencySet) Add(dep NetworkDependency) {
 	key := dep.Key()
-	if ds.seen[key] {
+	if i, ok := ds.index[key]; ok {
+		ds.deps[i] = ds.deps[i].MergedWith(dep)
 		return
 	}
-	ds.seen[key] = true
+	dep.Provenance = mergeProvenance(nil, dep.records())
+	ds.index[key] = len(ds.deps)
 	ds.deps = append(ds.deps, dep)
 }
 
+// MergedWith returns d with incoming's evidence folded in, following the
+// same rules as DependencySet.Add. Parsers that de-duplicate within a single
+// file use it so repeated declarations are not lost before the set sees them.
+func (d NetworkDependency) MergedWith(incoming NetworkDependency) NetworkDependency {
+	existing := d
+	existing.Provenance = mergeProvenance(nil, d.records())
+	merged := existing
+	if incoming.Confidence.rank() > existing.Confidence.rank() {
+		merged.Description = incoming.Description
+		merged.Confidence = incoming.Confidence
+		merged.SourceFile = incoming.SourceFile
+		merged.Line = incoming.Line
+		merged.Column = incoming.Column
+		merged.EvidenceLine = incoming.EvidenceLine
+		merged.Parser = incoming.Parser
+		if incoming.ServiceType != "" {
+			merged.ServiceType = incoming.ServiceType
+		}
+	}
+	if merged.ServiceType == "" {
+		merged.ServiceType = incoming.ServiceType
+	}
+	if merged.Disabled == "" {
+		merged.Disabled = incoming.Disabled
+	}
+	if merged.Environment != incoming.Environment {
+		merged.Environment = ""
+	}
+	// Also needed at runtime: the running workload makes it too.
+	merged.BuildTime = existing.BuildTime && incoming.BuildTime
+	merged.Aliases = mergeAliases(existing.Aliases, incoming.Aliases)
+	merged.Provenance = mergeProvenance(existing.Provenance, incoming.records())
+	return merged
+}
+
+// records returns the dep's provenance list, or its primary evidence when
+// the list is empty.
+func (d NetworkDependency) records() []Provenance {
+	if len(d.Provenance) > 0 {
+		return d.Provenance
+	}
+	if p, ok := d.primaryProvenance(); ok {
+		return []Provenance{p}
+	}
+	return nil
+}
+
+// mergeProvenance returns base plus incoming, de-duplicated and sorted
+// strongest-first. Duplicate declarations keep the higher confidence. The
+// sort makes output independent of walk order.
+func mergeProvenance(base, incoming []Provenance) []Provenance {
+	if len(base) == 0 && len(incoming) == 0 {
+		return nil
+	}
+
+	type provKey struct {
+		file, evidence, parser string
+		line, column           int
+	}
+	out := make([]Provenance, 0, len(base)+len(incoming))
+	pos := make(map[provKey]int, len(base)+len(incoming))
+	for _, list := range [][]Provenance{base, incoming} {
+		for _, p := range list {
+			k := provKey{p.File, p.Evidence, p.Parser, p.Line, p.Column}
+			if i, ok := pos[k]; ok {
+				if p.Confidence.rank() > out[i].Confidence.rank() {
+					out[i].Confidence = p.Confidence
+				}
+				continue
+			}
+			pos[k] = len(out)
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Confidence.rank() != b.Confidence.rank() {
+			return a.Confidence.rank() > b.Confidence.rank()
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.Evidence != b.Evidence {
+			return a.Evidence < b.Evidence
+		}
+		return a.Parser < b.Parser
+	})
+	return out
+}
+
 // Dependencies returns all dependencies sorted by Key() for deterministic output.
 func (ds *DependencySet) Dependencies() []NetworkDependency {
 	sorted := make([]NetworkDependency, len(ds.deps))
@@ -80,11 +294,14 @@ func (ds *DependencySet) Dependencies() []NetworkDependency {
 	return sorted
 }
 
-// Merge adds all dependencies from another set into this one.
+// Merge adds all dependencies and listeners from another set into this one.
 func (ds *DependencySet) Merge(other *DependencySet) {
 	for _, dep := range other.deps {
 		ds.Add(dep)
 	}
+	for _, l := range other.listeners {
+		ds.AddListener(l)
+	}
 }
 
 // Sources returns a sorted, deduplicated list of all source service names.
@@ -138,24 +355,50 @@ func (ds *DependencySet) Len() int {
 	return len(ds.deps)
 }
 
-// RenameSource replaces all occurrences of oldName in dep Source fields
-// (and the ServiceName) with newName. This rebuilds the dedup index since
-// Key() includes Source.
+// RenameSource replaces all occurrences of oldName in dep Source fields,
+// listener Workload fields and the ServiceName with newName. This rebuilds
+// the dedup indexes since the keys include them; entries that collide
+// after the rename are merged.
 func (ds *DependencySet) RenameSource(oldName, newName string) {
 	ds.ServiceName = newName
-	rebuilt := make([]NetworkDependency, 0, len(ds.deps))
-	ds.seen = make(map[string]bool)
-	for _, dep := range ds.deps {
+	ds.Rewrite(func(dep NetworkDependency) []NetworkDependency {
 		if dep.Source == oldName {
 			dep.Source = newName
 		}
-		key := dep.Key()
-		if !ds.seen[key] {
-			ds.seen[key] = true
-			rebuilt = append(rebuilt, dep)
+		return []NetworkDependency{dep}
+	}, func(l Listener) []Listener {
+		if l.Workload == oldName {
+			l.Workload = newName
+		}
+		return []Listener{l}
+	})
+}
+
+// Rewrite rebuilds the set, replacing each dependency with what dep
+// returns for it and each listener with what listener returns for it.
+// Entries that share a key afterwards are merged as Add merges them. A
+// nil func leaves that list unchanged.
+func (ds *DependencySet) Rewrite(dep func(NetworkDependency) []NetworkDependency, listener func(Listener) []Listener) {
+	if dep != nil {
+		old := ds.deps
+		ds.deps = make([]NetworkDependency, 0, len(old))
+		ds.index = make(map[string]int)
+		for _, d := range old {
+			for _, nd := range dep(d) {
+				ds.Add(nd)
+			}
+		}
+	}
+	if listener != nil {
+		old := ds.listeners
+		ds.listeners = nil
+		ds.listenerIndex = make(map[string]int)
+		for _, l := range old {
+			for _, nl := range listener(l) {
+				ds.AddListener(nl)
+			}
 		}
 	}
-	ds.deps = rebuilt
 }
 
 // dependencySetJSON is the JSON wire format for DependencySet, matching the
@@ -166,6 +409,7 @@ type dependencySetJSON struct {
 	Version      string              `json:"version"`
 	Summary      json.RawMessage     `json:"summary,omitempty"`
 	Dependencies []NetworkDependency `json:"dependencies"`
+	Listeners    []Listener          `json:"listeners,omitempty"`
 }
 
 // MarshalJSON produces the evidence JSON format.
@@ -175,20 +419,41 @@ func (ds *DependencySet) MarshalJSON() ([]byte, error) {
 		Generated:    time.Now().Format("2006-01-02"),
 		Version:      "0.6.0",
 		Dependencies: ds.Dependencies(),
+		Listeners:    ds.Listeners(),
 	})
 }
 
-// UnmarshalJSON reads the evidence JSON format and rebuilds the DependencySet.
+// UnmarshalJSON reads the evidence JSON format and rebuilds the
+// DependencySet. Files written before listeners existed (no "listeners"
+// key) recorded a workload's own ports as dependencies on itself; those
+// are read back as listeners so old baselines diff cleanly against new
+// output.
 func (ds *DependencySet) UnmarshalJSON(data []byte) error {
 	var raw dependencySetJSON
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
+	var probe struct {
+		Listeners *json.RawMessage `json:"listeners"`
+	}
+	_ = json.Unmarshal(data, &probe)
+	hasListeners := probe.Listeners != nil
 	ds.ServiceName = raw.Service
 	ds.deps = make([]NetworkDependency, 0)
-	ds.seen = make(map[string]bool)
+	ds.index = make(map[string]int)
+	ds.listeners = nil
+	ds.listenerIndex = make(map[string]int)
 	for _, dep := range raw.Dependencies {
+		if !hasListeners {
+			if l, ok := listenerFromSelfLoop(dep); ok {
+				ds.AddListener(l)
+				continue
+			}
+		}
 		ds.Add(dep)
 	}
+	for _, l := range raw.Listeners {
+		ds.AddListener(l)
+	}
 	return nil
 }
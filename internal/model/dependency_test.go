@@ -148,3 +148,107 @@ func TestDependenciesSorted(t *testing.T) {
 		t.Errorf("third dep target = %q, want zookeeper", deps[2].Target)
 	}
 }
+
+func TestDependencySetKeepsProvenance(t *testing.T) {
+	ds := NewDependencySet("app")
+
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: Medium, Description: "env", SourceFile: ".env", Line: 3,
+		EvidenceLine: "DATABASE_URL=postgresql://db:5432/app", Parser: "envfile"})
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: High, Description: "datasource", SourceFile: "application.yml", Line: 4,
+		EvidenceLine: "spring.datasource.url: jdbc:postgresql://db:5432/app", Parser: "spring"})
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: Medium, SourceFile: ".env", Line: 3,
+		EvidenceLine: "DATABASE_URL=postgresql://db:5432/app", Parser: "envfile"}) // exact repeat
+
+	if ds.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", ds.Len())
+	}
+	d := ds.Dependencies()[0]
+	if d.Confidence != High || d.SourceFile != "application.yml" || d.Line != 4 || d.Parser != "spring" {
+		t.Errorf("primary evidence = %s %s:%d (%s), want high application.yml:4 (spring)",
+			d.Confidence, d.SourceFile, d.Line, d.Parser)
+	}
+	if len(d.Provenance) != 2 {
+		t.Fatalf("provenance = %+v, want 2 records", d.Provenance)
+	}
+	if d.Provenance[0].File != "application.yml" || d.Provenance[1].File != ".env" {
+		t.Errorf("provenance not ordered strongest first: %+v", d.Provenance)
+	}
+}
+
+func TestDependencySetEnvironment(t *testing.T) {
+	ds := NewDependencySet("app")
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: Medium, SourceFile: ".env.production", Line: 1, Environment: "production"})
+	if got := ds.Dependencies()[0].Environment; got != "production" {
+		t.Fatalf("Environment = %q, want production", got)
+	}
+
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: Medium, SourceFile: ".env.staging", Line: 1, Environment: "staging"})
+	d := ds.Dependencies()[0]
+	if d.Environment != "" {
+		t.Errorf("Environment = %q, want empty for an edge declared in two environments", d.Environment)
+	}
+	envs := map[string]string{}
+	for _, p := range d.Provenance {
+		envs[p.File] = p.Environment
+	}
+	if envs[".env.production"] != "production" || envs[".env.staging"] != "staging" {
+		t.Errorf("provenance environments = %v", envs)
+	}
+}
+
+func TestDependencySetProvenanceRoundTrip(t *testing.T) {
+	ds := NewDependencySet("app")
+	ds.Add(NetworkDependency{Source: "app", Target: "redis", Port: 6379, Protocol: "TCP",
+		Confidence: Medium, SourceFile: ".env", Line: 1})
+	ds.Add(NetworkDependency{Source: "app", Target: "redis", Port: 6379, Protocol: "TCP",
+		Confidence: Low, SourceFile: "pom.xml", Line: 9})
+
+	data, err := ds.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back DependencySet
+	if err := back.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	got := back.Dependencies()
+	if len(got) != 1 || len(got[0].Provenance) != 2 {
+		t.Fatalf("round-tripped deps = %+v, want one dep with 2 provenance records", got)
+	}
+	if got[0].Provenance[1].File != "pom.xml" || got[0].Provenance[1].Line != 9 {
+		t.Errorf("second record = %+v, want pom.xml:9", got[0].Provenance[1])
+	}
+}
+
+func TestDependencySetRewriteFansOutAndMerges(t *testing.T) {
+	ds := NewDependencySet("app")
+	ds.Add(NetworkDependency{Source: "app", Target: "svc", Port: 80, Protocol: "TCP", Confidence: High, SourceFile: "a.yaml"})
+	ds.Add(NetworkDependency{Source: "app", Target: "pod-a", Port: 8080, Protocol: "TCP", Confidence: Medium, SourceFile: "b.env"})
+	ds.AddListener(Listener{Workload: "svc", Port: 80, Protocol: "TCP"})
+
+	ds.Rewrite(func(d NetworkDependency) []NetworkDependency {
+		if d.Target != "svc" {
+			return []NetworkDependency{d}
+		}
+		a, b := d, d
+		a.Target, a.Port = "pod-a", 8080
+		b.Target, b.Port = "pod-b", 8080
+		return []NetworkDependency{a, b}
+	}, nil)
+
+	got := ds.Dependencies()
+	if len(got) != 2 || got[0].Key() != "app->pod-a:8080/TCP" || got[1].Key() != "app->pod-b:8080/TCP" {
+		t.Fatalf("deps = %v", got)
+	}
+	if got[0].SourceFile != "a.yaml" || len(got[0].Provenance) != 2 {
+		t.Errorf("merged dep = %+v, want a.yaml primary and both records", got[0])
+	}
+	if len(ds.Listeners()) != 1 {
+		t.Errorf("nil listener func changed listeners: %v", ds.Listeners())
+	}
+}
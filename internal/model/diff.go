@@ -7,10 +7,23 @@ type DependencyDiff struct {
 	Added     []NetworkDependency
 	Removed   []NetworkDependency
 	Unchanged []NetworkDependency
+
+	// AddedListeners and RemovedListeners are the ports workloads started
+	// or stopped listening on, matched by Listener.Key().
+	AddedListeners   []Listener
+	RemovedListeners []Listener
+}
+
+// HasChanges reports whether any dependency or listener was added or
+// removed.
+func (d DependencyDiff) HasChanges() bool {
+	return len(d.Added)+len(d.Removed)+len(d.AddedListeners)+len(d.RemovedListeners) > 0
 }
 
 // DiffSets compares baseline and current dependency sets.
-// Dependencies are matched by Key() (source->target:port/protocol).
+// Dependencies are matched by Key() (source->target:port/protocol), with
+// any spelling either set records as an alias read as the name it stands
+// for, so renaming `redis.default` to `redis` in config is not a change.
 // Results are sorted by Key() for deterministic output.
 func DiffSets(baseline, current *DependencySet) DependencyDiff {
 	if baseline == nil {
@@ -20,14 +33,25 @@ func DiffSets(baseline, current *DependencySet) DependencyDiff {
 		current = NewDependencySet("")
 	}
 
+	aliases := aliasIndex(baseline, current)
+	canonicalKey := func(dep NetworkDependency) string {
+		if name, ok := aliases[dep.Source]; ok {
+			dep.Source = name
+		}
+		if name, ok := aliases[dep.Target]; ok {
+			dep.Target = name
+		}
+		return dep.Key()
+	}
+
 	baselineMap := make(map[string]NetworkDependency)
 	for _, dep := range baseline.Dependencies() {
-		baselineMap[dep.Key()] = dep
+		baselineMap[canonicalKey(dep)] = dep
 	}
 
 	currentMap := make(map[string]NetworkDependency)
 	for _, dep := range current.Dependencies() {
-		currentMap[dep.Key()] = dep
+		currentMap[canonicalKey(dep)] = dep
 	}
 
 	var diff DependencyDiff
@@ -55,5 +79,29 @@ func DiffSets(baseline, current *DependencySet) DependencyDiff {
 	sortDeps(diff.Removed)
 	sortDeps(diff.Unchanged)
 
+	diff.AddedListeners, diff.RemovedListeners = diffListeners(baseline.Listeners(), current.Listeners())
+
 	return diff
 }
+
+// diffListeners returns the listeners only in current and only in
+// baseline. Both inputs are sorted by Key(), and so are the results.
+func diffListeners(baseline, current []Listener) (added, removed []Listener) {
+	baselineKeys := make(map[string]bool, len(baseline))
+	for _, l := range baseline {
+		baselineKeys[l.Key()] = true
+	}
+	currentKeys := make(map[string]bool, len(current))
+	for _, l := range current {
+		currentKeys[l.Key()] = true
+		if !baselineKeys[l.Key()] {
+			added = append(added, l)
+		}
+	}
+	for _, l := range baseline {
+		if !currentKeys[l.Key()] {
+			removed = append(removed, l)
+		}
+	}
+	return added, removed
+}
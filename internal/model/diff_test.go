@@ -125,3 +125,49 @@ func TestDiffSetsEmpty(t *testing.T) {
 		t.Errorf("expected 0 unchanged, got %d", len(diff.Unchanged))
 	}
 }
+
+func TestDiffSetsListeners(t *testing.T) {
+	baseline := NewDependencySet("svc")
+	current := NewDependencySet("svc")
+
+	baseline.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "TCP"})
+	baseline.AddListener(Listener{Workload: "api", Port: 9090, Protocol: "TCP"})
+	current.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "TCP", Exposure: NodePort})
+	current.AddListener(Listener{Workload: "api", Port: 8443, Protocol: "TCP"})
+
+	diff := DiffSets(baseline, current)
+
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("listeners must not show up as dependency changes: %+v", diff)
+	}
+	if len(diff.AddedListeners) != 1 || diff.AddedListeners[0].Port != 8443 {
+		t.Errorf("added listeners = %+v, want api:8443", diff.AddedListeners)
+	}
+	if len(diff.RemovedListeners) != 1 || diff.RemovedListeners[0].Port != 9090 {
+		t.Errorf("removed listeners = %+v, want api:9090", diff.RemovedListeners)
+	}
+	if !diff.HasChanges() {
+		t.Error("HasChanges() = false with listener changes")
+	}
+	if DiffSets(current, current).HasChanges() {
+		t.Error("HasChanges() = true for identical sets")
+	}
+}
+
+func TestDiffSetsIgnoresRespelledTargets(t *testing.T) {
+	baseline := NewDependencySet("shop")
+	baseline.Add(NetworkDependency{Source: "app", Target: "redis.default", Port: 6379, Protocol: "TCP"})
+	baseline.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP"})
+
+	current := NewDependencySet("shop")
+	current.Add(NetworkDependency{Source: "app", Target: "redis", Port: 6379, Protocol: "TCP", Aliases: []string{"redis.default"}})
+	current.Add(NetworkDependency{Source: "app", Target: "db", Port: 5433, Protocol: "TCP"})
+
+	diff := DiffSets(baseline, current)
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0].Target != "redis" {
+		t.Errorf("unchanged = %v, want the respelled redis edge", diff.Unchanged)
+	}
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || diff.Added[0].Port != 5433 {
+		t.Errorf("added = %v, removed = %v, want only the db port change", diff.Added, diff.Removed)
+	}
+}
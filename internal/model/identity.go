@@ -0,0 +1,199 @@
+package model
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// Entity is one workload or backend and the names configs reach it by.
+// Name is the canonical name: the Kubernetes workload or compose service.
+// Namespace, when set, makes its Kubernetes DNS names (name.ns,
+// name.ns.svc, name.ns.svc.cluster.local) aliases too. Aliases are any
+// other names and addresses: a Service in front of it, a ClusterIP, a
+// container_name, a network alias or a static address.
+type Entity struct {
+	Name      string
+	Namespace string
+	Aliases   []string
+}
+
+// identities maps every spelling of an entity to its canonical name.
+// Spellings claimed by two entities are ambiguous and resolve to neither.
+type identities struct {
+	names      map[string]string
+	ambiguous  map[string]bool
+	namespaces map[string]bool
+}
+
+func newIdentities() *identities {
+	return &identities{
+		names:      make(map[string]string),
+		ambiguous:  make(map[string]bool),
+		namespaces: map[string]bool{"default": true},
+	}
+}
+
+// claim records spelling as a name of canonical. An entity's own name
+// beats an alias of another entity.
+func (x *identities) claim(spelling, canonical string) {
+	if spelling == "" || x.ambiguous[spelling] {
+		return
+	}
+	prev, ok := x.names[spelling]
+	switch {
+	case !ok || prev == canonical:
+		x.names[spelling] = canonical
+	case prev == spelling:
+		// spelling is an entity of its own.
+	case canonical == spelling:
+		x.names[spelling] = canonical
+	default:
+		delete(x.names, spelling)
+		x.ambiguous[spelling] = true
+	}
+}
+
+// implicit records name as an entity of its own when nothing claims it.
+func (x *identities) implicit(name string) {
+	if _, ok := x.names[name]; !ok {
+		x.claim(name, name)
+	}
+}
+
+func (x *identities) add(e Entity) {
+	x.claim(e.Name, e.Name)
+	if e.Namespace != "" {
+		x.namespaces[e.Namespace] = true
+	}
+	for _, a := range e.Aliases {
+		x.claim(a, e.Name)
+	}
+	if e.Namespace == "" {
+		return
+	}
+	for _, n := range append([]string{e.Name}, e.Aliases...) {
+		if isDNSLabel(n) {
+			for _, suffix := range []string{"", ".svc", ".svc.cluster.local"} {
+				x.claim(n+"."+e.Namespace+suffix, e.Name)
+			}
+		}
+	}
+}
+
+// canonical returns the canonical name for a spelling. A Kubernetes DNS
+// name of no known entity still loses its namespace and cluster suffix,
+// since policies select pods by name alone: redis.shop.svc.cluster.local
+// is redis. name.ns is only read as DNS when ns is a namespace some
+// entity lives in (or "default"), so api.example stays a hostname.
+func (x *identities) canonical(spelling string) string {
+	if name, ok := x.names[spelling]; ok {
+		return name
+	}
+	host, ns, definite := splitServiceDNS(spelling)
+	if host == "" || x.ambiguous[host] {
+		return spelling
+	}
+	if !definite && !x.namespaces[ns] {
+		return spelling
+	}
+	if name, ok := x.names[host]; ok {
+		return name
+	}
+	if definite {
+		return host
+	}
+	return spelling
+}
+
+// splitServiceDNS splits name.ns, name.ns.svc and
+// name.ns.svc.cluster.local. definite is true for the forms ending in
+// .svc, which can't be anything but Kubernetes DNS.
+func splitServiceDNS(spelling string) (name, namespace string, definite bool) {
+	trimmed := strings.TrimSuffix(spelling, ".cluster.local")
+	parts := strings.Split(trimmed, ".")
+	switch {
+	case len(parts) == 3 && parts[2] == "svc":
+		return parts[0], parts[1], true
+	case len(parts) == 2 && trimmed == spelling && net.ParseIP(spelling) == nil:
+		return parts[0], parts[1], false
+	}
+	return "", "", false
+}
+
+// isDNSLabel reports whether s is a single DNS label (no dots, not an
+// address).
+func isDNSLabel(s string) bool {
+	return s != "" && !strings.ContainsAny(s, ".:")
+}
+
+// ResolveIdentities folds the different spellings of one backend into a
+// single canonical name. Entities come from the configs that declare
+// workloads (Kubernetes workloads and Services, compose services); every
+// dependency source and listener workload in the set counts as an entity
+// too, unless an entity claims it as an alias. Targets, sources and
+// listener workloads are rewritten to the canonical name, and each
+// dependency keeps the target spellings it was declared with in Aliases.
+// Entries that collide afterwards are merged.
+func (ds *DependencySet) ResolveIdentities(entities []Entity) {
+	x := newIdentities()
+	for _, e := range entities {
+		x.add(e)
+	}
+	// Names the set already uses are entities of their own unless a
+	// declared entity claims them as an alias.
+	for _, d := range ds.deps {
+		x.implicit(d.Source)
+	}
+	for _, l := range ds.listeners {
+		x.implicit(l.Workload)
+	}
+
+	ds.Rewrite(func(d NetworkDependency) []NetworkDependency {
+		d.Source = x.canonical(d.Source)
+		if target := x.canonical(d.Target); target != d.Target {
+			d.Aliases = mergeAliases(d.Aliases, []string{d.Target})
+			d.Target = target
+		}
+		return []NetworkDependency{d}
+	}, func(l Listener) []Listener {
+		l.Workload = x.canonical(l.Workload)
+		return []Listener{l}
+	})
+}
+
+// aliasIndex maps every alias recorded in the sets to its target, so
+// sets resolved at different times (or a baseline written before
+// identities were resolved) compare by canonical name.
+func aliasIndex(sets ...*DependencySet) map[string]string {
+	index := make(map[string]string)
+	for _, ds := range sets {
+		for _, d := range ds.deps {
+			for _, a := range d.Aliases {
+				if a != d.Target {
+					index[a] = d.Target
+				}
+			}
+		}
+	}
+	return index
+}
+
+// mergeAliases unions two alias lists, sorted.
+func mergeAliases(base, incoming []string) []string {
+	if len(incoming) == 0 {
+		return base
+	}
+	seen := make(map[string]bool, len(base)+len(incoming))
+	var out []string
+	for _, list := range [][]string{base, incoming} {
+		for _, a := range list {
+			if !seen[a] {
+				seen[a] = true
+				out = append(out, a)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveIdentitiesFoldsSpellings(t *testing.T) {
+	ds := NewDependencySet("shop")
+	for i, target := range []string{"redis", "redis.default", "redis.default.svc.cluster.local", "redis-master", "10.0.0.4"} {
+		ds.Add(NetworkDependency{Source: "app", Target: target, Port: 6379, Protocol: "TCP",
+			Confidence: High, SourceFile: "app.yaml", Line: i + 1})
+	}
+	ds.Add(NetworkDependency{Source: "app", Target: "cache.shop.svc", Port: 11211, Protocol: "TCP"})
+	ds.Add(NetworkDependency{Source: "app", Target: "api.example", Port: 443, Protocol: "TCP"})
+	ds.AddListener(Listener{Workload: "redis-master", Port: 6379, Protocol: "TCP"})
+
+	ds.ResolveIdentities([]Entity{
+		{Name: "redis", Namespace: "default", Aliases: []string{"redis-master", "10.0.0.4"}},
+	})
+
+	deps := ds.Dependencies()
+	if len(deps) != 3 {
+		t.Fatalf("deps = %v, want redis, cache and api.example", deps)
+	}
+	redis := deps[2]
+	if redis.Key() != "app->redis:6379/TCP" {
+		t.Fatalf("deps[2] = %s", redis.Key())
+	}
+	want := []string{"10.0.0.4", "redis-master", "redis.default", "redis.default.svc.cluster.local"}
+	if !reflect.DeepEqual(redis.Aliases, want) {
+		t.Errorf("aliases = %v, want %v", redis.Aliases, want)
+	}
+	if len(redis.Provenance) != 5 {
+		t.Errorf("provenance = %d records, want one per spelling", len(redis.Provenance))
+	}
+	// Kubernetes DNS of an unknown entity still loses its suffix; a
+	// two-label hostname outside any known namespace is left alone.
+	if deps[1].Target != "cache" || deps[0].Target != "api.example" {
+		t.Errorf("targets = %s, %s", deps[0].Target, deps[1].Target)
+	}
+	if ls := ds.Listeners(); len(ls) != 1 || ls[0].Workload != "redis" {
+		t.Errorf("listeners = %v, want redis-master folded into redis", ls)
+	}
+}
+
+func TestResolveIdentitiesAmbiguousAlias(t *testing.T) {
+	ds := NewDependencySet("shop")
+	ds.Add(NetworkDependency{Source: "app", Target: "db", Port: 5432, Protocol: "TCP"})
+	ds.Add(NetworkDependency{Source: "app", Target: "primary", Port: 5432, Protocol: "TCP"})
+
+	ds.ResolveIdentities([]Entity{
+		{Name: "orders-db", Aliases: []string{"db"}},
+		{Name: "users-db", Aliases: []string{"db"}},
+		{Name: "primary", Aliases: []string{"ignored"}},
+		{Name: "replica", Aliases: []string{"primary"}},
+	})
+
+	deps := ds.Dependencies()
+	if deps[0].Target != "db" || deps[1].Target != "primary" {
+		t.Errorf("targets = %s, %s; an alias of two entities, or an entity's own name, must not be folded", deps[0].Target, deps[1].Target)
+	}
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Exposure says who can reach a listener from outside its workload.
+type Exposure string
+
+const (
+	// ClusterIP listeners are reachable by other workloads on the same
+	// cluster or compose network: a ClusterIP Service, compose `expose:`.
+	ClusterIP Exposure = "ClusterIP"
+	// NodePort listeners are also reachable on every node's address.
+	NodePort Exposure = "NodePort"
+	// LoadBalancer listeners are reachable through an external load
+	// balancer.
+	LoadBalancer Exposure = "LoadBalancer"
+	// HostPort listeners are published on the host's address: compose
+	// `ports:`, a container's `hostPort`.
+	HostPort Exposure = "HostPort"
+)
+
+// rank orders exposures from narrowest to widest so a merge keeps the
+// widest one declared. Unknown (empty) ranks lowest.
+func (e Exposure) rank() int {
+	switch e {
+	case ClusterIP:
+		return 1
+	case HostPort:
+		return 2
+	case NodePort:
+		return 3
+	case LoadBalancer:
+		return 4
+	}
+	return 0
+}
+
+// External reports whether the listener accepts traffic from outside the
+// cluster or compose network.
+func (e Exposure) External() bool {
+	return e.rank() > ClusterIP.rank()
+}
+
+// Listener is a port a workload accepts connections on: a container
+// port, a Service port, a compose `ports:`/`expose:` entry or an app's
+// own server port. Listeners are kept apart from NetworkDependency edges
+// so that "db listens on 5432" is not mistaken for "db connects to
+// itself"; renderers turn them into ingress rules.
+//
+// Name is the port's name (`http`, `grpc`) when the config gives one.
+// Exposure is empty when the config doesn't say how far the port is
+// published (a bare containerPort, or a port inferred from an image).
+// The evidence fields have the same meaning as on NetworkDependency.
+type Listener struct {
+	Workload     string       `json:"workload"`
+	Port         int          `json:"port"`
+	Protocol     string       `json:"protocol"`
+	Name         string       `json:"name,omitempty"`
+	Exposure     Exposure     `json:"exposure,omitempty"`
+	Description  string       `json:"description"`
+	Confidence   Confidence   `json:"confidence"`
+	SourceFile   string       `json:"source_file"`
+	Line         int          `json:"line,omitempty"`
+	Column       int          `json:"column,omitempty"`
+	EvidenceLine string       `json:"evidence_line,omitempty"`
+	ServiceType  string       `json:"service_type,omitempty"`
+	Disabled     string       `json:"disabled,omitempty"`
+	Parser       string       `json:"parser,omitempty"`
+	Environment  string       `json:"environment,omitempty"`
+	Provenance   []Provenance `json:"provenance,omitempty"`
+}
+
+// Key returns a unique identifier for deduplication.
+func (l Listener) Key() string {
+	return fmt.Sprintf("%s:%d/%s", l.Workload, l.Port, l.Protocol)
+}
+
+// Location returns the "file:line" anchor for the listener's evidence,
+// or just the file when no line is known. Empty when SourceFile is empty.
+func (l Listener) Location() string {
+	return NetworkDependency{SourceFile: l.SourceFile, Line: l.Line}.Location()
+}
+
+// records returns the listener's provenance list, or its primary
+// evidence when the list is empty.
+func (l Listener) records() []Provenance {
+	if len(l.Provenance) > 0 {
+		return l.Provenance
+	}
+	if l.SourceFile == "" && l.EvidenceLine == "" {
+		return nil
+	}
+	return []Provenance{{
+		File:        l.SourceFile,
+		Line:        l.Line,
+		Column:      l.Column,
+		Evidence:    l.EvidenceLine,
+		Parser:      l.Parser,
+		Confidence:  l.Confidence,
+		Environment: l.Environment,
+	}}
+}
+
+// MergedWith returns l with incoming's evidence folded in, like
+// NetworkDependency.MergedWith. The widest exposure and the first port
+// name declared are kept.
+func (l Listener) MergedWith(incoming Listener) Listener {
+	existing := l
+	existing.Provenance = mergeProvenance(nil, l.records())
+	merged := existing
+	if incoming.Confidence.rank() > existing.Confidence.rank() {
+		merged.Description = incoming.Description
+		merged.Confidence = incoming.Confidence
+		merged.SourceFile = incoming.SourceFile
+		merged.Line = incoming.Line
+		merged.Column = incoming.Column
+		merged.EvidenceLine = incoming.EvidenceLine
+		merged.Parser = incoming.Parser
+	}
+	if incoming.Exposure.rank() > merged.Exposure.rank() {
+		merged.Exposure = incoming.Exposure
+	}
+	if merged.Name == "" {
+		merged.Name = incoming.Name
+	}
+	if merged.ServiceType == "" {
+		merged.ServiceType = incoming.ServiceType
+	}
+	if merged.Disabled == "" {
+		merged.Disabled = incoming.Disabled
+	}
+	if merged.Environment != incoming.Environment {
+		merged.Environment = ""
+	}
+	merged.Provenance = mergeProvenance(existing.Provenance, incoming.records())
+	return merged
+}
+
+// AddListener inserts a listener, merging it into an existing one with the
+// same Key().
+func (ds *DependencySet) AddListener(l Listener) {
+	if ds.listenerIndex == nil {
+		ds.listenerIndex = make(map[string]int)
+	}
+	key := l.Key()
+	if i, ok := ds.listenerIndex[key]; ok {
+		ds.listeners[i] = ds.listeners[i].MergedWith(l)
+		return
+	}
+	l.Provenance = mergeProvenance(nil, l.records())
+	ds.listenerIndex[key] = len(ds.listeners)
+	ds.listeners = append(ds.listeners, l)
+}
+
+// Listeners returns all listeners sorted by Key() for deterministic output.
+func (ds *DependencySet) Listeners() []Listener {
+	sorted := make([]Listener, len(ds.listeners))
+	copy(sorted, ds.listeners)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key() < sorted[j].Key()
+	})
+	return sorted
+}
+
+// ListenersFor returns the listeners of the named workload, sorted by
+// Key().
+func (ds *DependencySet) ListenersFor(workload string) []Listener {
+	var result []Listener
+	for _, l := range ds.Listeners() {
+		if l.Workload == workload {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+// legacySelfLoopRe matches the descriptions the pre-listener parsers gave
+// a workload's own ports: k8s container and Service ports, compose
+// `ports:` and Spring's server.port.
+var legacySelfLoopRe = regexp.MustCompile(`^(container port \d+|service port \d+( -> targetPort \d+)?|exposed port|server listening port)$`)
+
+// listenerFromSelfLoop converts a dependency of the pre-listener format,
+// where a workload's own port was recorded as an edge to itself (or, for
+// Spring's server.port, to "self"), into the listener it described. Real
+// self-edges, like a security group that admits itself, are left alone.
+func listenerFromSelfLoop(d NetworkDependency) (Listener, bool) {
+	if d.Port <= 0 || (d.Source != d.Target && d.Target != "self") || !legacySelfLoopRe.MatchString(d.Description) {
+		return Listener{}, false
+	}
+	return Listener{
+		Workload:     d.Source,
+		Port:         d.Port,
+		Protocol:     d.Protocol,
+		Description:  d.Description,
+		Confidence:   d.Confidence,
+		SourceFile:   d.SourceFile,
+		Line:         d.Line,
+		Column:       d.Column,
+		EvidenceLine: d.EvidenceLine,
+		ServiceType:  d.ServiceType,
+		Disabled:     d.Disabled,
+		Parser:       d.Parser,
+		Environment:  d.Environment,
+		Provenance:   d.Provenance,
+	}, true
+}
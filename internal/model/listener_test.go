@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestListenerKey(t *testing.T) {
+	l := Listener{Workload: "api", Port: 8080, Protocol: "TCP"}
+	if got := l.Key(); got != "api:8080/TCP" {
+		t.Errorf("Key() = %q, want api:8080/TCP", got)
+	}
+}
+
+func TestDependencySetAddListenerMerges(t *testing.T) {
+	ds := NewDependencySet("app")
+	ds.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "TCP", Name: "http",
+		Confidence: High, SourceFile: "deploy.yaml", Line: 12})
+	ds.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "TCP", Exposure: LoadBalancer,
+		Confidence: High, SourceFile: "service.yaml", Line: 8})
+	ds.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "TCP", Exposure: ClusterIP,
+		Confidence: High, SourceFile: "service-internal.yaml", Line: 8})
+	ds.AddListener(Listener{Workload: "api", Port: 8080, Protocol: "UDP", Confidence: High})
+
+	got := ds.ListenersFor("api")
+	if len(got) != 2 {
+		t.Fatalf("ListenersFor(api) = %+v, want TCP and UDP", got)
+	}
+	l := got[0]
+	if l.Name != "http" || l.Exposure != LoadBalancer {
+		t.Errorf("merged listener = %+v, want name http and the widest exposure", l)
+	}
+	if len(l.Provenance) != 3 {
+		t.Errorf("provenance = %+v, want 3 records", l.Provenance)
+	}
+	if ds.Len() != 0 {
+		t.Errorf("listeners must not be counted as dependencies, Len() = %d", ds.Len())
+	}
+}
+
+func TestDependencySetRenameSourceRenamesListeners(t *testing.T) {
+	ds := NewDependencySet("old")
+	ds.AddListener(Listener{Workload: "old", Port: 8080, Protocol: "TCP"})
+	ds.AddListener(Listener{Workload: "db", Port: 5432, Protocol: "TCP"})
+	ds.RenameSource("old", "new")
+
+	if len(ds.ListenersFor("new")) != 1 || len(ds.ListenersFor("old")) != 0 {
+		t.Errorf("listeners = %+v, want old renamed to new", ds.Listeners())
+	}
+	if len(ds.ListenersFor("db")) != 1 {
+		t.Error("other workloads' listeners must be kept")
+	}
+}
+
+func TestDependencySetJSONListeners(t *testing.T) {
+	ds := NewDependencySet("app")
+	ds.Add(NetworkDependency{Source: "api", Target: "db", Port: 5432, Protocol: "TCP", Confidence: High})
+	ds.AddListener(Listener{Workload: "api", Port: 443, Protocol: "TCP", Name: "https",
+		Exposure: NodePort, Confidence: High, SourceFile: "svc.yaml", Line: 9})
+
+	data, err := json.Marshal(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back DependencySet
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Len() != 1 {
+		t.Errorf("dependencies = %+v, want 1", back.Dependencies())
+	}
+	got := back.Listeners()
+	if len(got) != 1 || got[0].Name != "https" || got[0].Exposure != NodePort || got[0].Line != 9 {
+		t.Errorf("round-tripped listeners = %+v", got)
+	}
+}
+
+func TestDependencySetJSONConvertsLegacySelfLoops(t *testing.T) {
+	legacy := `{"service":"app","dependencies":[
+		{"source":"api","target":"api","port":8080,"protocol":"TCP","description":"container port 8080","confidence":"high","source_file":"deploy.yaml","line":12},
+		{"source":"app","target":"self","port":9090,"protocol":"TCP","description":"server listening port","confidence":"high","source_file":"application.yml"},
+		{"source":"api","target":"db","port":5432,"protocol":"TCP","description":"DATABASE_URL","confidence":"high","source_file":"deploy.yaml"}
+	]}`
+	var ds DependencySet
+	if err := json.Unmarshal([]byte(legacy), &ds); err != nil {
+		t.Fatal(err)
+	}
+	if ds.Len() != 1 || ds.Dependencies()[0].Target != "db" {
+		t.Errorf("dependencies = %+v, want only api -> db", ds.Dependencies())
+	}
+	got := ds.Listeners()
+	if len(got) != 2 || got[0].Key() != "api:8080/TCP" || got[1].Key() != "app:9090/TCP" {
+		t.Errorf("listeners = %+v, want api:8080 and app:9090", got)
+	}
+	if got[0].Line != 12 || got[0].Description != "container port 8080" {
+		t.Errorf("converted listener lost its evidence: %+v", got[0])
+	}
+}
+
+func TestDependencySetJSONKeepsRealSelfEdges(t *testing.T) {
+	// A security group admitting itself is a real edge, in any format.
+	for _, data := range []string{
+		`{"service":"app","dependencies":[
+			{"source":"cluster","target":"cluster","port":7000,"protocol":"TCP","description":"security group cluster ingress","confidence":"high","source_file":"main.tf"}
+		]}`,
+		`{"service":"app","dependencies":[
+			{"source":"api","target":"api","port":8080,"protocol":"TCP","description":"container port 8080","confidence":"high","source_file":"deploy.yaml"}
+		],"listeners":[
+			{"workload":"api","port":9090,"protocol":"TCP","confidence":"high","source_file":"deploy.yaml"}
+		]}`,
+	} {
+		var ds DependencySet
+		if err := json.Unmarshal([]byte(data), &ds); err != nil {
+			t.Fatal(err)
+		}
+		deps := ds.Dependencies()
+		if len(deps) != 1 || deps[0].Source != deps[0].Target {
+			t.Errorf("dependencies = %+v, want the self-edge kept", deps)
+		}
+		for _, l := range ds.Listeners() {
+			if l.Port != 9090 {
+				t.Errorf("self-edge converted to listener %s", l.Key())
+			}
+		}
+	}
+}
@@ -29,10 +29,11 @@ func TestComposeDisableFull(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("parse: %v", err)
 	}
+	deps := res.Dependencies
 
 	// Every dep where Source=="web" should carry Disabled=="full".
 	var webDeps, dbDeps int
@@ -78,10 +79,11 @@ spec:
           value: "postgresql://db.prod.svc.cluster.local:5432/orders"
 `
 	path := writeTempFile(t, "deployment.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("parse: %v", err)
 	}
+	deps := res.Dependencies
 
 	if len(deps) == 0 {
 		t.Fatal("expected deps")
@@ -113,10 +115,11 @@ spring:
 		t.Fatal(err)
 	}
 
-	deps, err := parseSpringYAML(path)
+	res, err := parseSpringYAML(path)
 	if err != nil {
 		t.Fatalf("parse: %v", err)
 	}
+	deps := res.Dependencies
 
 	if len(deps) == 0 {
 		t.Fatal("expected deps")
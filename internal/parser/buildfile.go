@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"os"
@@ -11,9 +12,9 @@ import (
 )
 
 func init() {
-	defaultRegistry.Register("pom.xml", parsePomXML)
-	defaultRegistry.Register("build.gradle", parseBuildGradle)
-	defaultRegistry.Register("build.gradle.kts", parseBuildGradle)
+	defaultRegistry.RegisterFormat("buildfile", "pom.xml", parsePomXML)
+	defaultRegistry.RegisterFormat("buildfile", "build.gradle", parseBuildGradle)
+	defaultRegistry.RegisterFormat("buildfile", "build.gradle.kts", parseBuildGradle)
 }
 
 // infraLib maps an artifactId pattern to an inferred infrastructure dependency.
@@ -41,6 +42,7 @@ var knownLibs = []infraLib{
 	{"amqp-client", "rabbitmq", 5672, "RabbitMQ", "broker"},
 	{"elasticsearch-rest-high-level-client", "elasticsearch", 9200, "Elasticsearch", "search"},
 	{"spring-data-elasticsearch", "elasticsearch", 9200, "Elasticsearch (Spring Data)", "search"},
+	{"java-dogstatsd-client", "statsd", 8125, "StatsD (DogStatsD client)", "metrics"},
 }
 
 // --- pom.xml parser ---
@@ -72,6 +74,7 @@ func parsePomXML(path string) ([]model.NetworkDependency, error) {
 	}
 
 	disable := ScanFileDisable(data)
+	lines := pomArtifactLines(data)
 
 	var deps []model.NetworkDependency
 	for _, d := range project.Dependencies.Dependency {
@@ -81,10 +84,11 @@ func parsePomXML(path string) ([]model.NetworkDependency, error) {
 					Source:       "",
 					Target:       lib.target,
 					Port:         lib.port,
-					Protocol:     "TCP",
+					Protocol:     inferProtocol("", lib.port),
 					Description:  fmt.Sprintf("build dependency: %s:%s -> %s", d.GroupID, d.ArtifactID, lib.description),
 					Confidence:   model.Low,
 					SourceFile:   path,
+					Line:         lines[d.ArtifactID],
 					EvidenceLine: fmt.Sprintf("%s:%s", d.GroupID, d.ArtifactID),
 					ServiceType:  lib.serviceType,
 				})
@@ -102,6 +106,42 @@ func parsePomXML(path string) ([]model.NetworkDependency, error) {
 	return deps, nil
 }
 
+// pomArtifactLines maps each <dependency><artifactId> value to the line it
+// is declared on. encoding/xml's struct decoding discards positions, so we
+// take a second, token-level pass over the same bytes. First declaration
+// wins when an artifact appears more than once.
+func pomArtifactLines(data []byte) map[string]int {
+	lines := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var stack []string
+	artifactLine := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return lines
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "artifactId" && len(stack) > 0 && stack[len(stack)-1] == "dependency" {
+				artifactLine, _ = dec.InputPos()
+			}
+			stack = append(stack, t.Name.Local)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			artifactLine = 0
+		case xml.CharData:
+			if artifactLine > 0 {
+				name := strings.TrimSpace(string(t))
+				if _, ok := lines[name]; !ok && name != "" {
+					lines[name] = artifactLine
+				}
+			}
+		}
+	}
+}
+
 // --- build.gradle parser ---
 
 // Matches lines like: implementation 'group:artifact:version'
@@ -120,7 +160,7 @@ func parseBuildGradle(path string) ([]model.NetworkDependency, error) {
 	var deps []model.NetworkDependency
 	lines := strings.Split(string(data), "\n")
 
-	for _, line := range lines {
+	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		matches := gradleDepRe.FindStringSubmatch(trimmed)
 		if len(matches) < 2 {
@@ -140,10 +180,11 @@ func parseBuildGradle(path string) ([]model.NetworkDependency, error) {
 					Source:       "",
 					Target:       lib.target,
 					Port:         lib.port,
-					Protocol:     "TCP",
+					Protocol:     inferProtocol("", lib.port),
 					Description:  fmt.Sprintf("build dependency: %s:%s -> %s", group, artifact, lib.description),
 					Confidence:   model.Low,
 					SourceFile:   path,
+					Line:         i + 1,
 					EvidenceLine: depStr,
 					ServiceType:  lib.serviceType,
 				})
@@ -2,19 +2,22 @@ package parser
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/dormstern/segspec/internal/model"
-	"gopkg.in/yaml.v3"
 )
 
 func init() {
-	defaultRegistry.Register("docker-compose.yml", parseCompose)
-	defaultRegistry.Register("docker-compose.yaml", parseCompose)
-	defaultRegistry.Register("compose.yml", parseCompose)
-	defaultRegistry.Register("compose.yaml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "docker-compose.yml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "docker-compose.yaml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "compose.yml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "compose.yaml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "docker-compose.*.yml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "docker-compose.*.yaml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "compose.*.yml", parseCompose)
+	defaultRegistry.RegisterResults("compose", "compose.*.yaml", parseCompose)
 }
 
 // wellKnownImages maps image name prefixes to their default port and description.
@@ -37,105 +40,172 @@ var wellKnownImages = map[string]struct {
 	"zookeeper":     {2181, "ZooKeeper"},
 	"minio":         {9000, "MinIO"},
 	"vault":         {8200, "Vault"},
+	"statsd":        {8125, "StatsD"},
+	"coredns":       {53, "CoreDNS"},
 }
 
-// composeFile represents a docker-compose YAML structure.
-type composeFile struct {
-	Services map[string]composeService `yaml:"services"`
+// ComposeOptions selects what of a compose project to analyze.
+type ComposeOptions struct {
+	// Profiles are the active compose profiles: services gated on other
+	// profiles are left out. Empty means every service, whatever its
+	// profiles.
+	Profiles []string
 }
 
-type composeService struct {
-	Image       string      `yaml:"image"`
-	Ports       []string    `yaml:"ports"`
-	DependsOn   interface{} `yaml:"depends_on"`
-	Environment interface{} `yaml:"environment"`
+func parseCompose(path string) (Result, error) {
+	return ParseCompose([]string{path}, ComposeOptions{})
 }
 
-func parseCompose(path string) ([]model.NetworkDependency, error) {
-	data, err := os.ReadFile(path)
+// ParseCompose parses a stack of compose files (see ComposeStacks), with
+// their includes, extends and env_files, merged as `docker compose -f
+// ... -f ...` merges them. Only services that share a network are
+// assumed to reach each other. Each service's `ports` and `expose`
+// entries are returned as its listeners.
+func ParseCompose(paths []string, opts ComposeOptions) (Result, error) {
+	project, err := loadComposeProject(paths...)
 	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
+		return Result{}, err
 	}
 
-	var cf composeFile
-	if err := yaml.Unmarshal(data, &cf); err != nil {
-		return nil, fmt.Errorf("parsing compose %s: %w", path, err)
+	active := func(svc *composeService) bool {
+		if len(opts.Profiles) == 0 || len(svc.profiles) == 0 {
+			return true
+		}
+		for _, p := range svc.profiles {
+			if containsString(opts.Profiles, p) {
+				return true
+			}
+		}
+		return false
 	}
 
-	// Per-service `# segspec:disable=...` directives are scanned from the raw
-	// bytes because the YAML AST round-trip drops comments.
-	disableMap := ScanComposeDisable(data)
+	// Hostnames other services reach a service by: its name, its
+	// container_name and its network aliases.
+	hosts := make(map[string]string)
+	for _, name := range project.names() {
+		svc := project.services[name]
+		hosts[name] = name
+		if svc.containerName != "" {
+			hosts[svc.containerName] = name
+		}
+		for _, aliases := range svc.networks {
+			for _, a := range aliases {
+				hosts[a] = name
+			}
+		}
+	}
 
 	var deps []model.NetworkDependency
+	var listeners []model.Listener
+	for _, serviceName := range project.names() {
+		svc := project.services[serviceName]
+		if !active(svc) {
+			continue
+		}
+		// reaches reports whether svc can reach a target by name: services
+		// on a disjoint network can't; hosts outside the project may.
+		reaches := func(target string) bool {
+			other, ok := project.services[target]
+			return !ok || svc.reachable(other)
+		}
 
-	for serviceName, svc := range cf.Services {
-		// Ports: exposed ports for this service
-		for _, p := range svc.Ports {
-			containerPort := parseContainerPort(p)
-			if containerPort > 0 {
-				deps = append(deps, model.NetworkDependency{
-					Source:       serviceName,
-					Target:       serviceName,
-					Port:         containerPort,
-					Protocol:     "TCP",
-					Description:  "exposed port",
-					Confidence:   model.High,
-					SourceFile:   path,
-					EvidenceLine: fmt.Sprintf("ports: %s", p),
-				})
-			}
+		// Ports: published on the host, or exposed to the network only.
+		for _, p := range svc.ports {
+			listeners = append(listeners, composeListener(svc, p, "exposed port", model.HostPort))
+		}
+		for _, p := range svc.expose {
+			listeners = append(listeners, composeListener(svc, p, "exposed port (internal)", model.ClusterIP))
 		}
 
-		// DependsOn: service dependencies
-		depNames := parseDependsOn(svc.DependsOn)
-		for _, depName := range depNames {
-			dep := model.NetworkDependency{
-				Source:       serviceName,
-				Target:       depName,
-				Protocol:     "TCP",
-				Description:  "depends_on",
-				Confidence:   model.Medium,
-				SourceFile:   path,
-				EvidenceLine: fmt.Sprintf("depends_on: %s", depName),
-			}
-			// Try to infer port from the dependent service's image
-			if depSvc, ok := cf.Services[depName]; ok {
-				if port, desc := inferFromImage(depSvc.Image); port > 0 {
-					dep.Port = port
-					dep.Description = desc
-					dep.Confidence = model.High
-					dep.ServiceType = serviceTypeFromDesc(desc)
+		// depends_on and links: service dependencies
+		links := make(map[string]string) // alias -> service
+		for _, edge := range []struct {
+			refs        []composeValue
+			description string
+			field       string
+		}{
+			{svc.dependsOn, "depends_on", "depends_on"},
+			{svc.links, "links", "links"},
+			{svc.externalLinks, "external_links", "external_links"},
+		} {
+			for _, ref := range edge.refs {
+				depName, alias, _ := strings.Cut(ref.value, ":")
+				if alias != "" {
+					links[alias] = depName
+				}
+				if !reaches(depName) {
+					continue
+				}
+				dep := model.NetworkDependency{
+					Source:       serviceName,
+					Target:       depName,
+					Protocol:     "TCP",
+					Description:  edge.description,
+					Confidence:   model.Medium,
+					SourceFile:   ref.file,
+					Line:         ref.line,
+					Column:       ref.col,
+					EvidenceLine: fmt.Sprintf("%s: %s", edge.field, ref.value),
 				}
+				// Try to infer port from the dependent service's image
+				if depSvc, ok := project.services[depName]; ok {
+					if port, desc := inferFromImage(depSvc.image.value); port > 0 {
+						dep.Port = port
+						dep.Protocol = inferProtocol("", port)
+						dep.Description = desc
+						dep.Confidence = model.High
+						dep.ServiceType = serviceTypeFromDesc(desc)
+					}
+				}
+				deps = append(deps, dep)
 			}
-			deps = append(deps, dep)
 		}
 
 		// Image: infer well-known service ports
-		if port, desc := inferFromImage(svc.Image); port > 0 {
-			deps = append(deps, model.NetworkDependency{
-				Source:       serviceName,
-				Target:       serviceName,
+		if port, desc := inferFromImage(svc.image.value); port > 0 {
+			listeners = append(listeners, model.Listener{
+				Workload:     serviceName,
 				Port:         port,
-				Protocol:     "TCP",
+				Protocol:     inferProtocol("", port),
 				Description:  desc + " (inferred from image)",
 				Confidence:   model.Low,
-				SourceFile:   path,
-				EvidenceLine: fmt.Sprintf("image: %s", svc.Image),
+				SourceFile:   svc.image.file,
+				Line:         svc.image.line,
+				Column:       svc.image.col,
+				EvidenceLine: fmt.Sprintf("image: %s", svc.image.value),
 				ServiceType:  serviceTypeFromDesc(desc),
 			})
 		}
 
-		// Environment: scan for URLs/connection strings
-		envVars := parseEnvironment(svc.Environment)
-		for key, val := range envVars {
-			if d, ok := extractFromValue(val, path); ok {
-				d.Source = serviceName
-				d.EvidenceLine = fmt.Sprintf("%s=%s", key, val)
-				if d.Confidence == model.High {
-					d.Confidence = model.Medium
-				}
-				deps = append(deps, d)
+		// Environment: scan for URLs/connection strings. A hostname that
+		// is a link alias, container_name or network alias names its
+		// service.
+		keys := make([]string, 0, len(svc.environment))
+		for key := range svc.environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			env := svc.environment[key]
+			d, ok := extractFromValue(env.value, env.file)
+			if !ok {
+				continue
+			}
+			if name, ok := links[d.Target]; ok {
+				d.Target = name
+			} else if name, ok := hosts[d.Target]; ok {
+				d.Target = name
+			}
+			if !reaches(d.Target) {
+				continue
+			}
+			d.Source = serviceName
+			d.EvidenceLine = model.RedactSecrets(env.evidence)
+			d.Line, d.Column = env.line, env.col
+			if d.Confidence == model.High {
+				d.Confidence = model.Medium
 			}
+			deps = append(deps, d)
 		}
 	}
 
@@ -144,15 +214,94 @@ func parseCompose(path string) ([]model.NetworkDependency, error) {
 	// Source so that a disabled `web` doesn't taint the inferred port for
 	// a downstream `db` that the `depends_on` edge points at — `db` is its
 	// own workload with its own (possibly absent) directive.
-	if len(disableMap) > 0 {
-		for i := range deps {
-			if d, ok := disableMap[deps[i].Source]; ok {
-				deps[i].Disabled = d
-			}
+	for i := range deps {
+		if svc, ok := project.services[deps[i].Source]; ok && svc.disable != "" {
+			deps[i].Disabled = svc.disable
+		}
+		deps[i].Parser = "compose"
+	}
+	for i := range listeners {
+		if svc, ok := project.services[listeners[i].Workload]; ok && svc.disable != "" {
+			listeners[i].Disabled = svc.disable
+		}
+		listeners[i].Parser = "compose"
+	}
+
+	// Entities: each active service, by its name, container_name,
+	// network aliases and static addresses.
+	var entities []model.Entity
+	for _, name := range project.names() {
+		svc := project.services[name]
+		if !active(svc) {
+			continue
+		}
+		e := model.Entity{Name: name}
+		if svc.containerName != "" {
+			e.Aliases = append(e.Aliases, svc.containerName)
+		}
+		nets := make([]string, 0, len(svc.networks))
+		for n := range svc.networks {
+			nets = append(nets, n)
+		}
+		sort.Strings(nets)
+		for _, n := range nets {
+			e.Aliases = append(e.Aliases, svc.networks[n]...)
+		}
+		e.Aliases = append(e.Aliases, svc.addresses...)
+		entities = append(entities, e)
+	}
+
+	return Result{Dependencies: deps, Listeners: listeners, Entities: entities}, nil
+}
+
+// composeListener is a service's own port from `ports` or `expose`.
+func composeListener(svc *composeService, p composePort, description string, exposure model.Exposure) model.Listener {
+	return model.Listener{
+		Workload:     svc.name,
+		Port:         p.port,
+		Protocol:     p.protocol,
+		Exposure:     exposure,
+		Description:  description,
+		Confidence:   model.High,
+		SourceFile:   p.pos.file,
+		Line:         p.pos.line,
+		Column:       p.pos.col,
+		EvidenceLine: p.evidence,
+	}
+}
+
+// dependsOnPos locates a depends_on entry in either its list form
+// (`- db`) or its map form (`db: {condition: ...}`).
+func dependsOnPos(ix yamlIndex, service, dep string) (int, int) {
+	if line, col := ix.at("services", service, "depends_on", dep); line > 0 {
+		return line, col
+	}
+	for i := 0; ; i++ {
+		pos, ok := ix[yamlPath("services", service, "depends_on", i)]
+		if !ok {
+			return 0, 0
+		}
+		if pos.value == dep {
+			return pos.line, pos.col
 		}
 	}
+}
 
-	return deps, nil
+// environmentPos locates an environment entry in either its map form
+// (`KEY: value`) or its list form (`- KEY=value`).
+func environmentPos(ix yamlIndex, service, key string) (int, int) {
+	if line, col := ix.at("services", service, "environment", key); line > 0 {
+		return line, col
+	}
+	for i := 0; ; i++ {
+		pos, ok := ix[yamlPath("services", service, "environment", i)]
+		if !ok {
+			return 0, 0
+		}
+		if strings.HasPrefix(pos.value, key+"=") {
+			return pos.line, pos.col
+		}
+	}
 }
 
 // parseContainerPort extracts the container port from a port mapping string.
@@ -196,6 +345,7 @@ func parseDependsOn(v interface{}) []string {
 		for name := range val {
 			names = append(names, name)
 		}
+		sort.Strings(names)
 		return names
 	}
 	return nil
@@ -262,8 +412,10 @@ func parseEnvironment(v interface{}) map[string]string {
 		}
 	case map[string]interface{}:
 		for k, vv := range val {
-			if s, ok := vv.(string); ok {
-				result[k] = s
+			switch vv.(type) {
+			case nil, map[string]interface{}, []interface{}:
+			default:
+				result[k] = fmt.Sprint(vv)
 			}
 		}
 	}
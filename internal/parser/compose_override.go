@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeBaseNames are the files `docker compose` loads by default, in the
+// order it looks for them.
+var composeBaseNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// composeOverrideName returns the environment name of an override file
+// (docker-compose.prod.yml -> "prod", compose.override.yaml ->
+// "override"), or "" for anything else.
+func composeOverrideName(path string) string {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	if ext != ".yml" && ext != ".yaml" {
+		return ""
+	}
+	stem := strings.TrimSuffix(base, ext)
+	for _, prefix := range []string{"docker-compose.", "compose."} {
+		if name, ok := strings.CutPrefix(stem, prefix); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// ComposeStacks groups compose files into the stacks `docker compose`
+// would load with -f, one per directory: the directory's base file
+// followed by its overrides. With no overrides named, a stack is the base
+// plus its `override` file, which compose applies on its own; other
+// environment files (docker-compose.prod.yml) are left out. Naming
+// overrides (by environment name or file name) replaces that default, in
+// the order given. Override files without a base in their directory are
+// loaded on their own. Files another compose file pulls in with
+// `include:` belong to their includer's project and get no stack of their
+// own. unmatched lists the named overrides no directory has. skipped
+// lists, when no overrides are named, the environment files the default
+// leaves out, so the caller can say how to include them.
+func ComposeStacks(paths []string, overrides []string) (stacks [][]string, unmatched, skipped []string) {
+	included := composeIncluded(paths)
+	byDir := make(map[string][]string)
+	for _, p := range paths {
+		if included[filepath.Clean(p)] {
+			continue
+		}
+		dir := filepath.Dir(filepath.Clean(p))
+		byDir[dir] = append(byDir[dir], p)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	selected := overrides
+	if len(selected) == 0 {
+		selected = []string{"override"}
+	}
+	matched := make(map[string]bool)
+	for _, dir := range dirs {
+		files := byDir[dir]
+		sort.Strings(files)
+
+		var bases []string
+		for _, name := range composeBaseNames {
+			for _, f := range files {
+				if filepath.Base(f) == name {
+					bases = append(bases, f)
+				}
+			}
+		}
+		var layers []string
+		for _, want := range selected {
+			for _, f := range files {
+				if composeOverrideName(f) == want || filepath.Base(f) == want {
+					layers = append(layers, f)
+					matched[want] = true
+				}
+			}
+		}
+		if len(overrides) == 0 {
+			for _, f := range files {
+				if composeOverrideName(f) != "" && !containsString(layers, f) {
+					skipped = append(skipped, f)
+				}
+			}
+		}
+
+		if len(bases) == 0 {
+			for _, f := range layers {
+				stacks = append(stacks, []string{f})
+			}
+			continue
+		}
+		stacks = append(stacks, append([]string{bases[0]}, layers...))
+		// A second base file in one directory (compose.yaml next to a
+		// legacy docker-compose.yml) is a project of its own.
+		for _, b := range bases[1:] {
+			stacks = append(stacks, []string{b})
+		}
+	}
+	for _, want := range overrides {
+		if !matched[want] {
+			unmatched = append(unmatched, want)
+		}
+	}
+	return stacks, unmatched, skipped
+}
+
+// composeIncluded returns the cleaned paths of every file reachable
+// through `include:` from the given compose files. Files that can't be
+// read or parsed include nothing; loading the stack reports the error.
+func composeIncluded(paths []string) map[string]bool {
+	included := make(map[string]bool)
+	queue := append([]string{}, paths...)
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		path := filepath.Clean(queue[0])
+		queue = queue[1:]
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var raw map[string]interface{}
+		if yaml.Unmarshal(data, &raw) != nil {
+			continue
+		}
+		for _, inc := range composeIncludePaths(raw) {
+			p := filepath.Clean(filepath.Join(filepath.Dir(path), inc))
+			included[p] = true
+			queue = append(queue, p)
+		}
+	}
+	return included
+}
+
+// merge layers an override project on p as `docker compose -f` does: new
+// services are added, and a service in both gets mergeComposeService.
+func (p *composeProject) merge(over *composeProject) {
+	for name, svc := range over.services {
+		if base, ok := p.services[name]; ok {
+			p.services[name] = mergeComposeService(base, svc)
+		} else {
+			p.services[name] = svc
+		}
+	}
+}
+
+// mergeComposeService merges an override service into its base. Scalars
+// set in the override win; ports, expose, depends_on, links and static
+// addresses are unioned, environment and networks merged by key. Every
+// value keeps the file it came from, so a redeclared port or variable
+// cites the override.
+func mergeComposeService(base, over *composeService) *composeService {
+	out := *base
+	if over.image.value != "" {
+		out.image = over.image
+	}
+	if over.containerName != "" {
+		out.containerName = over.containerName
+	}
+	if over.build != "" {
+		out.build = over.build
+	}
+	if over.networkMode != "" {
+		out.networkMode = over.networkMode
+	}
+	if over.profiles != nil {
+		out.profiles = over.profiles
+	}
+	if over.disable != "" {
+		out.disable = over.disable
+	}
+	out.envFiles = append(append([]string{}, base.envFiles...), over.envFiles...)
+	out.ports = mergeComposePorts(base.ports, over.ports)
+	out.expose = mergeComposePorts(base.expose, over.expose)
+	out.dependsOn = mergeComposeValues(base.dependsOn, over.dependsOn)
+	out.links = mergeComposeValues(base.links, over.links)
+	out.externalLinks = mergeComposeValues(base.externalLinks, over.externalLinks)
+
+	out.environment = make(map[string]envSetting, len(base.environment)+len(over.environment))
+	for k, v := range base.environment {
+		out.environment[k] = v
+	}
+	for k, v := range over.environment {
+		out.environment[k] = v
+	}
+	out.addresses = append([]string{}, base.addresses...)
+	for _, a := range over.addresses {
+		if !containsString(out.addresses, a) {
+			out.addresses = append(out.addresses, a)
+		}
+	}
+	if over.networks != nil {
+		out.networks = make(map[string][]string, len(base.networks)+len(over.networks))
+		for k, v := range base.networks {
+			out.networks[k] = v
+		}
+		for k, v := range over.networks {
+			out.networks[k] = v
+		}
+	}
+	return &out
+}
+
+// mergeComposePorts unions ports by number and protocol; the later
+// declaration replaces the earlier one.
+func mergeComposePorts(base, over []composePort) []composePort {
+	out := append([]composePort{}, base...)
+	for _, p := range over {
+		replaced := false
+		for i := range out {
+			if out[i].port == p.port && out[i].protocol == p.protocol {
+				out[i], replaced = p, true
+			}
+		}
+		if !replaced {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergeComposeValues unions values; the later declaration replaces the
+// earlier one.
+func mergeComposeValues(base, over []composeValue) []composeValue {
+	out := append([]composeValue{}, base...)
+	for _, v := range over {
+		replaced := false
+		for i := range out {
+			if out[i].value == v.value {
+				out[i], replaced = v, true
+			}
+		}
+		if !replaced {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,526 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeProject is a compose file as `docker compose` sees it: variables
+// interpolated from the project's .env, `include:`d files and `extends`
+// base services pulled in, and env_file entries merged into environment.
+type composeProject struct {
+	services map[string]*composeService
+}
+
+// composeService is one service of a project. Every value keeps the file
+// and position it came from, which for included, extended or env_file
+// values is not the file that was analyzed.
+type composeService struct {
+	name          string
+	file          string // file declaring the service
+	disable       string
+	image         composeValue
+	containerName string
+	build         string // build context directory, if local
+	ports         []composePort
+	expose        []composePort
+	dependsOn     []composeValue
+	links         []composeValue // "service" or "service:alias"
+	externalLinks []composeValue // "container" or "container:alias"
+	environment   map[string]envSetting
+	envFiles      []string            // env_file paths read into environment
+	networks      map[string][]string // network -> aliases; nil: the default network
+	addresses     []string            // static ipv4_address/ipv6_address on its networks
+	networkMode   string
+	profiles      []string
+}
+
+// composeValue is a scalar and where it was declared.
+type composeValue struct {
+	value     string
+	file      string
+	line, col int
+}
+
+// composePort is a `ports` or `expose` entry.
+type composePort struct {
+	port     int
+	protocol string
+	evidence string
+	pos      composeValue
+}
+
+// loadComposeProject reads a compose file and everything it includes or
+// extends, then layers each override file on it in order.
+func loadComposeProject(paths ...string) (*composeProject, error) {
+	var project *composeProject
+	for _, path := range paths {
+		p, err := loadComposeFile(path, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		if project == nil {
+			project = p
+		} else {
+			project.merge(p)
+		}
+	}
+	if project == nil {
+		project = &composeProject{services: make(map[string]*composeService)}
+	}
+	return project, nil
+}
+
+func loadComposeFile(path string, loading map[string]bool) (*composeProject, error) {
+	clean := filepath.Clean(path)
+	if loading[clean] {
+		return nil, fmt.Errorf("compose %s: include/extends cycle", path)
+	}
+	loading[clean] = true
+	defer delete(loading, clean)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing compose %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	interpolateComposeNode(&root, composeVars(dir))
+
+	var raw map[string]interface{}
+	if err := root.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("parsing compose %s: %w", path, err)
+	}
+	ix := indexYAML(&root)
+
+	// Per-service `# segspec:disable=...` directives are scanned from the raw
+	// bytes because the YAML AST round-trip drops comments.
+	disableMap := ScanComposeDisable(data)
+
+	project := &composeProject{services: make(map[string]*composeService)}
+	defs, _ := raw["services"].(map[string]interface{})
+	extends := make(map[string]interface{})
+	for name, def := range defs {
+		m, _ := def.(map[string]interface{})
+		svc := readComposeService(name, m, ix, path)
+		svc.disable = disableMap[name]
+		project.services[name] = svc
+		if ext, ok := m["extends"]; ok {
+			extends[name] = ext
+		}
+	}
+
+	// extends: the base may itself extend another service, in this file
+	// or another one.
+	resolved := make(map[string]bool)
+	var extend func(name string, depth int) error
+	extend = func(name string, depth int) error {
+		ext, ok := extends[name]
+		if !ok || resolved[name] {
+			return nil
+		}
+		if depth > 10 {
+			return fmt.Errorf("compose %s: extends chain too deep at %s", path, name)
+		}
+		baseName, _ := ext.(string)
+		baseFile := ""
+		if m, ok := ext.(map[string]interface{}); ok {
+			baseName, _ = m["service"].(string)
+			baseFile, _ = m["file"].(string)
+		}
+		var base *composeService
+		if baseFile == "" {
+			if err := extend(baseName, depth+1); err != nil {
+				return err
+			}
+			base = project.services[baseName]
+		} else {
+			other, err := loadComposeFile(filepath.Join(dir, baseFile), loading)
+			if err != nil {
+				return err
+			}
+			base = other.services[baseName]
+		}
+		if base == nil {
+			return fmt.Errorf("compose %s: service %s extends unknown service %q", path, name, baseName)
+		}
+		project.services[name] = extendComposeService(base, project.services[name])
+		resolved[name] = true
+		return nil
+	}
+	for name := range extends {
+		if err := extend(name, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// include: services of the included projects join this one. Compose
+	// rejects a name defined twice; the including file wins here.
+	for _, s := range composeIncludePaths(raw) {
+		included, err := loadComposeFile(filepath.Join(dir, s), loading)
+		if err != nil {
+			return nil, err
+		}
+		for name, svc := range included.services {
+			if _, ok := project.services[name]; !ok {
+				project.services[name] = svc
+			}
+		}
+	}
+	return project, nil
+}
+
+// composeIncludePaths returns the paths a compose file's top-level
+// `include:` names, relative to its directory, in both the short (string)
+// and long (path: string or list) syntax.
+func composeIncludePaths(raw map[string]interface{}) []string {
+	var out []string
+	for _, inc := range toSlice(raw["include"]) {
+		var paths []interface{}
+		switch v := inc.(type) {
+		case string:
+			paths = []interface{}{v}
+		case map[string]interface{}:
+			paths = toSlice(v["path"])
+			if s, ok := v["path"].(string); ok {
+				paths = []interface{}{s}
+			}
+		}
+		for _, p := range paths {
+			if s, ok := p.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// ComposeEnvFiles returns the env_file paths the services of a compose stack
+// (see ComposeStacks) read, with those of included and extended services,
+// sorted. Their variables are the services' environment, so the files are
+// analyzed through compose rather than on their own.
+func ComposeEnvFiles(stack []string) ([]string, error) {
+	project, err := loadComposeProject(stack...)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, svc := range project.services {
+		for _, f := range svc.envFiles {
+			if f = filepath.Clean(f); !seen[f] {
+				seen[f] = true
+				files = append(files, f)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// names returns the project's service names, sorted.
+func (p *composeProject) names() []string {
+	names := make([]string, 0, len(p.services))
+	for name := range p.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readComposeService reads one service definition of the file at path.
+func readComposeService(name string, m map[string]interface{}, ix yamlIndex, path string) *composeService {
+	dir := filepath.Dir(path)
+	at := func(parts ...interface{}) composeValue {
+		line, col := ix.at(append([]interface{}{"services", name}, parts...)...)
+		return composeValue{file: path, line: line, col: col}
+	}
+	svc := &composeService{name: name, file: path, environment: make(map[string]envSetting)}
+
+	if image, ok := m["image"].(string); ok {
+		svc.image = at("image")
+		svc.image.value = image
+	}
+	svc.containerName, _ = m["container_name"].(string)
+	context, _ := m["build"].(string)
+	if b, ok := m["build"].(map[string]interface{}); ok {
+		context, _ = b["context"].(string)
+	}
+	if context != "" && !strings.Contains(context, "://") && !strings.HasPrefix(context, "git@") {
+		svc.build = filepath.Clean(filepath.Join(dir, context))
+	}
+
+	for i, p := range toSlice(m["ports"]) {
+		if port, ok := readComposePort(p, true); ok {
+			port.pos = at("ports", i)
+			svc.ports = append(svc.ports, port)
+		}
+	}
+	for i, p := range toSlice(m["expose"]) {
+		if port, ok := readComposePort(p, false); ok {
+			port.pos = at("expose", i)
+			svc.expose = append(svc.expose, port)
+		}
+	}
+
+	for _, dep := range parseDependsOn(m["depends_on"]) {
+		line, col := dependsOnPos(ix, name, dep)
+		svc.dependsOn = append(svc.dependsOn, composeValue{value: dep, file: path, line: line, col: col})
+	}
+	for field, dst := range map[string]*[]composeValue{"links": &svc.links, "external_links": &svc.externalLinks} {
+		for i, l := range toSlice(m[field]) {
+			if s, ok := l.(string); ok {
+				v := at(field, i)
+				v.value = s
+				*dst = append(*dst, v)
+			}
+		}
+	}
+
+	// env_file first: environment overrides it.
+	envFiles := toSlice(m["env_file"])
+	if s, ok := m["env_file"].(string); ok {
+		envFiles = []interface{}{s}
+	}
+	for _, f := range envFiles {
+		file, _ := f.(string)
+		if fm, ok := f.(map[string]interface{}); ok {
+			file, _ = fm["path"].(string)
+		}
+		if file == "" {
+			continue
+		}
+		svc.envFiles = append(svc.envFiles, filepath.Join(dir, file))
+		for k, v := range readDotenv(filepath.Join(dir, file)) {
+			svc.environment[k] = v
+		}
+	}
+	for k, v := range parseEnvironment(m["environment"]) {
+		line, col := environmentPos(ix, name, k)
+		svc.environment[k] = envSetting{value: v, file: path, line: line, col: col, evidence: k + "=" + v, parser: "compose"}
+	}
+
+	switch nets := m["networks"].(type) {
+	case []interface{}:
+		svc.networks = make(map[string][]string)
+		for _, n := range nets {
+			if s, ok := n.(string); ok {
+				svc.networks[s] = nil
+			}
+		}
+	case map[string]interface{}:
+		svc.networks = make(map[string][]string)
+		for n, cfg := range nets {
+			var aliases []string
+			if c, ok := cfg.(map[string]interface{}); ok {
+				for _, a := range toSlice(c["aliases"]) {
+					if s, ok := a.(string); ok {
+						aliases = append(aliases, s)
+					}
+				}
+				for _, key := range []string{"ipv4_address", "ipv6_address"} {
+					if ip, ok := c[key].(string); ok && ip != "" {
+						svc.addresses = append(svc.addresses, ip)
+					}
+				}
+			}
+			svc.networks[n] = aliases
+		}
+	}
+	svc.networkMode, _ = m["network_mode"].(string)
+	for _, p := range toSlice(m["profiles"]) {
+		if s, ok := p.(string); ok {
+			svc.profiles = append(svc.profiles, s)
+		}
+	}
+	return svc
+}
+
+// readComposePort reads a short-syntax ("8080:80/udp", 80) or long-syntax
+// ({target: 80, protocol: udp}) port. published says whether the short
+// form may carry a host part, as `ports` does and `expose` doesn't.
+func readComposePort(v interface{}, published bool) (composePort, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		port := toInt(m["target"])
+		protocol, _ := m["protocol"].(string)
+		evidence := fmt.Sprintf("ports: target=%d", port)
+		if pub := m["published"]; pub != nil {
+			evidence += fmt.Sprintf(" published=%v", pub)
+		}
+		if protocol != "" {
+			evidence += " protocol=" + protocol
+		}
+		return composePort{port: port, protocol: specProtocol(protocol), evidence: evidence}, port > 0
+	}
+	s := fmt.Sprint(v)
+	field := "expose"
+	if published {
+		field = "ports"
+	}
+	_, protocol, _ := strings.Cut(s, "/")
+	port := parseContainerPort(s)
+	return composePort{port: port, protocol: specProtocol(protocol), evidence: fmt.Sprintf("%s: %s", field, s)}, port > 0
+}
+
+// extendComposeService applies `extends`: svc's own values win, ports
+// and expose are appended to the base's and environment is merged by key.
+// depends_on and links are never inherited.
+func extendComposeService(base, svc *composeService) *composeService {
+	out := *svc
+	if out.image.value == "" {
+		out.image = base.image
+	}
+	if out.build == "" {
+		out.build = base.build
+	}
+	out.ports = append(append([]composePort{}, base.ports...), svc.ports...)
+	out.expose = append(append([]composePort{}, base.expose...), svc.expose...)
+	out.envFiles = append(append([]string{}, base.envFiles...), svc.envFiles...)
+	out.environment = make(map[string]envSetting, len(base.environment)+len(svc.environment))
+	for k, v := range base.environment {
+		out.environment[k] = v
+	}
+	for k, v := range svc.environment {
+		out.environment[k] = v
+	}
+	if out.networks == nil {
+		out.networks = base.networks
+	}
+	if out.networkMode == "" {
+		out.networkMode = base.networkMode
+	}
+	if out.profiles == nil {
+		out.profiles = base.profiles
+	}
+	return &out
+}
+
+// composeVars returns the variables compose interpolates a project with:
+// the project directory's .env. The shell environment is deliberately
+// ignored so that results don't depend on who runs segspec.
+func composeVars(dir string) map[string]string {
+	vars := make(map[string]string)
+	for k, v := range readDotenv(filepath.Join(dir, ".env")) {
+		vars[k] = v.value
+	}
+	return vars
+}
+
+// interpolateComposeNode interpolates every scalar value (not key) in n.
+func interpolateComposeNode(n *yaml.Node, vars map[string]string) {
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			interpolateComposeNode(c, vars)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			interpolateComposeNode(n.Content[i], vars)
+		}
+	case yaml.ScalarNode:
+		if strings.Contains(n.Value, "$") {
+			n.Value = interpolateCompose(n.Value, vars)
+		}
+	}
+}
+
+// interpolateCompose expands $VAR, ${VAR}, ${VAR:-default},
+// ${VAR-default}, ${VAR:+alt}, ${VAR+alt}, ${VAR:?err} and ${VAR?err} as
+// compose does; $$ is a literal $. Defaults may nest. An unset variable
+// expands to "", as compose does after warning.
+func interpolateCompose(s string, vars map[string]string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := placeholderEnd(s, i)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			b.WriteString(composeSubstitute(s[i+2:end], vars))
+			i = end
+		case next == '_' || isASCIILetter(next):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || isASCIILetter(s[j]) || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			b.WriteString(vars[s[i+1:j]])
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String()
+}
+
+// composeSubstitute evaluates the inside of one ${...}.
+func composeSubstitute(expr string, vars map[string]string) string {
+	end := strings.IndexAny(expr, ":-+?")
+	if end < 0 {
+		return vars[expr]
+	}
+	name, op := expr[:end], expr[end:]
+	val, set := vars[name]
+	nonEmpty := set && val != ""
+	for _, form := range []struct {
+		op      string
+		useWord bool
+	}{
+		{":-", !nonEmpty}, {"-", !set},
+		{":+", nonEmpty}, {"+", set},
+		{":?", false}, {"?", false},
+	} {
+		if word, ok := strings.CutPrefix(op, form.op); ok {
+			if form.useWord {
+				return interpolateCompose(word, vars)
+			}
+			if strings.Contains(form.op, "+") {
+				return ""
+			}
+			return val
+		}
+	}
+	return val
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// reachable reports whether a and b share a network. A service without
+// `networks` is on the project's default network; one with a
+// network_mode (host, service:x, container:x) is assumed reachable.
+func (a *composeService) reachable(b *composeService) bool {
+	if a.networkMode != "" || b.networkMode != "" {
+		return true
+	}
+	an, bn := a.networks, b.networks
+	if an == nil {
+		an = map[string][]string{"default": nil}
+	}
+	if bn == nil {
+		bn = map[string][]string{"default": nil}
+	}
+	for n := range an {
+		if _, ok := bn[n]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/dormstern/segspec/internal/model"
@@ -20,17 +22,21 @@ func TestParseCompose_Ports(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
-	if findDepWithSource(deps, "web", "web", 80) == nil {
-		t.Error("expected exposed port 80 for web service")
+	if l := findListener(res.Listeners, "web", 80); l == nil || l.Exposure != model.HostPort {
+		t.Errorf("expected published port 80 for web service, got %+v", l)
 	}
-	if findDepWithSource(deps, "web", "web", 443) == nil {
+	if findListener(res.Listeners, "web", 443) == nil {
 		t.Error("expected exposed port 443 for web service")
 	}
+	if len(deps) != 0 {
+		t.Errorf("own ports should not be dependencies, got %+v", deps)
+	}
 }
 
 func TestParseCompose_DependsOnList(t *testing.T) {
@@ -49,10 +55,11 @@ func TestParseCompose_DependsOnList(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	dbDep := findDepWithSource(deps, "app", "db", 5432)
 	if dbDep == nil {
@@ -82,10 +89,11 @@ func TestParseCompose_DependsOnMap(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	if findDepWithSource(deps, "app", "db", 3306) == nil {
 		t.Error("expected app -> db:3306 dependency (MySQL inferred from image)")
@@ -131,10 +139,11 @@ func TestParseCompose_EnvironmentMap(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	if findDep(deps, "db-server", 5432) == nil {
 		t.Error("expected dependency on db-server:5432 from environment")
@@ -156,10 +165,11 @@ func TestParseCompose_EnvironmentList(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	if findDep(deps, "redis-node", 6379) == nil {
 		t.Error("expected dependency on redis-node:6379 from env list")
@@ -202,10 +212,11 @@ func TestParseCompose_FullStack(t *testing.T) {
 	path := filepath.Join(dir, "compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	// Should have dependencies from multiple sources
 	if len(deps) == 0 {
@@ -239,10 +250,11 @@ func TestParseCompose_EmptyFile(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	if len(deps) != 0 {
 		t.Errorf("expected 0 deps for empty services, got %d", len(deps))
 	}
@@ -280,10 +292,11 @@ func TestParseCompose_SourceFile(t *testing.T) {
 	path := filepath.Join(dir, "docker-compose.yml")
 	os.WriteFile(path, []byte(content), 0644)
 
-	deps, err := parseCompose(path)
+	res, err := parseCompose(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	for _, d := range deps {
 		if d.SourceFile != path {
 			t.Errorf("SourceFile = %q, want %q", d.SourceFile, path)
@@ -300,3 +313,379 @@ func findDepWithSource(deps []model.NetworkDependency, source, target string, po
 	}
 	return nil
 }
+
+func TestParseCompose_InterpolationAndEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("DB_IMAGE=postgres:16\nAPI_PORT=9000\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "api.env"), []byte("# api settings\nCACHE_URL=redis://cache:6379/0\n"), 0644)
+	path := filepath.Join(dir, "docker-compose.yml")
+	os.WriteFile(path, []byte(`services:
+  api:
+    image: example/api
+    ports:
+      - "${API_PORT:-8080}:${API_PORT:-8080}"
+    env_file: api.env
+    environment:
+      DATABASE_URL: postgres://db:${DB_PORT:-5432}/app
+  db:
+    image: ${DB_IMAGE}
+  cache:
+    image: redis:7
+`), 0644)
+
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	if findListener(res.Listeners, "api", 9000) == nil {
+		t.Errorf("expected port interpolated from .env, got %+v", deps)
+	}
+	if findListener(res.Listeners, "db", 5432) == nil {
+		t.Errorf("expected image interpolated from .env to infer PostgreSQL, got %+v", deps)
+	}
+	if db := findDepWithSource(deps, "api", "db", 5432); db == nil || db.EvidenceLine != "DATABASE_URL=postgres://db:5432/app" {
+		t.Errorf("expected api -> db:5432 with interpolated evidence, got %+v", db)
+	}
+	cache := findDepWithSource(deps, "api", "cache", 6379)
+	if cache == nil {
+		t.Fatalf("expected api -> cache:6379 from env_file, got %+v", deps)
+	}
+	if filepath.Base(cache.SourceFile) != "api.env" || cache.Line != 2 {
+		t.Errorf("env_file dep should cite api.env:2, got %s:%d", cache.SourceFile, cache.Line)
+	}
+}
+
+func TestInterpolateCompose(t *testing.T) {
+	vars := map[string]string{"SET": "v", "EMPTY": ""}
+	tests := map[string]string{
+		"${SET}":              "v",
+		"$SET/x":              "v/x",
+		"${UNSET:-d}":         "d",
+		"${EMPTY:-d}":         "d",
+		"${EMPTY-d}":          "",
+		"${UNSET-d}":          "d",
+		"${SET:+alt}":         "alt",
+		"${EMPTY:+alt}":       "",
+		"${SET:?required}":    "v",
+		"${UNSET:-${SET}}":    "v",
+		"$$SET":               "$SET",
+		"${UNSET}host:${SET}": "host:v",
+	}
+	for in, want := range tests {
+		if got := interpolateCompose(in, vars); got != want {
+			t.Errorf("interpolateCompose(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseCompose_Networks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	os.WriteFile(path, []byte(`services:
+  web:
+    image: example/web
+    networks: [frontend]
+    depends_on: [api, db]
+    environment:
+      DB_URL: postgres://db:5432/app
+  api:
+    image: example/api
+    networks:
+      frontend:
+      backend:
+        aliases: [api.internal]
+    depends_on: [db]
+  db:
+    image: postgres:16
+    networks: [backend]
+  worker:
+    image: example/worker
+    environment:
+      API: http://api.internal:8080
+`), 0644)
+
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	if findDepWithSource(deps, "web", "db", 5432) != nil {
+		t.Error("web and db share no network; web -> db must not be emitted")
+	}
+	if findDepWithSource(deps, "web", "api", 0) == nil {
+		t.Error("expected web -> api over the frontend network")
+	}
+	if findDepWithSource(deps, "api", "db", 5432) == nil {
+		t.Error("expected api -> db over the backend network")
+	}
+	if findDepWithSource(deps, "worker", "api", 8080) != nil {
+		t.Error("worker is on the default network only; the api alias must not be reachable")
+	}
+}
+
+func TestParseCompose_Entities(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	os.WriteFile(path, []byte(`services:
+  cache:
+    image: redis:7
+    container_name: shop-redis
+    networks:
+      backend:
+        aliases: [redis]
+        ipv4_address: 172.20.0.5
+  api:
+    image: example/api
+    networks: [backend]
+networks:
+  backend:
+`), 0644)
+
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []model.Entity{
+		{Name: "api"},
+		{Name: "cache", Aliases: []string{"shop-redis", "redis", "172.20.0.5"}},
+	}
+	if !reflect.DeepEqual(res.Entities, want) {
+		t.Errorf("entities = %+v, want %+v", res.Entities, want)
+	}
+}
+
+func TestParseCompose_ExtendsIncludeProfiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "infra"), 0755)
+	os.WriteFile(filepath.Join(dir, "common.yml"), []byte(`services:
+  base:
+    image: example/base
+    expose:
+      - "9100"
+    environment:
+      METRICS_HOST: statsd:8125
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "infra", "compose.yaml"), []byte(`services:
+  queue:
+    image: rabbitmq:3
+`), 0644)
+	path := filepath.Join(dir, "docker-compose.yml")
+	os.WriteFile(path, []byte(`include:
+  - infra/compose.yaml
+services:
+  app:
+    extends:
+      file: common.yml
+      service: base
+    image: example/app
+    links:
+      - "queue:mq"
+    environment:
+      AMQP_URL: amqp://mq:5672
+  statsd:
+    image: example/statsd
+    profiles: [metrics]
+    ports:
+      - target: 8125
+        published: 8125
+        protocol: udp
+`), 0644)
+
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	exposed := findListener(res.Listeners, "app", 9100)
+	if exposed == nil || filepath.Base(exposed.SourceFile) != "common.yml" || exposed.Exposure != model.ClusterIP {
+		t.Errorf("expected extended expose port citing common.yml, got %+v", exposed)
+	}
+	if findDepWithSource(deps, "app", "statsd", 8125) == nil {
+		t.Error("expected environment inherited through extends")
+	}
+	queue := findListener(res.Listeners, "queue", 5672)
+	if queue == nil || filepath.Base(queue.SourceFile) != "compose.yaml" {
+		t.Errorf("expected included queue service citing infra/compose.yaml, got %+v", queue)
+	}
+	if findDepWithSource(deps, "app", "queue", 5672) == nil {
+		t.Errorf("expected link alias mq to resolve to queue, got %+v", deps)
+	}
+	udp := findListener(res.Listeners, "statsd", 8125)
+	if udp == nil || udp.Protocol != "UDP" {
+		t.Errorf("expected long-syntax UDP port, got %+v", udp)
+	}
+
+	// Only services without profiles, or in an active one, are analyzed.
+	res, err = ParseCompose([]string{path}, ComposeOptions{Profiles: []string{"debug"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps = res.Dependencies
+	if findListener(res.Listeners, "statsd", 8125) != nil {
+		t.Error("statsd is in the inactive metrics profile")
+	}
+	if findListener(res.Listeners, "app", 9100) == nil {
+		t.Error("app has no profiles and is always active")
+	}
+}
+
+func TestComposeStacks(t *testing.T) {
+	files := []string{
+		"/app/docker-compose.yml",
+		"/app/docker-compose.override.yml",
+		"/app/docker-compose.prod.yml",
+		"/app/docker-compose.staging.yaml",
+		"/tools/compose.prod.yaml",
+		"/db/compose.yaml",
+	}
+
+	stacks, unmatched, skipped := ComposeStacks(files, nil)
+	want := [][]string{
+		{"/app/docker-compose.yml", "/app/docker-compose.override.yml"},
+		{"/db/compose.yaml"},
+	}
+	if fmt.Sprint(stacks) != fmt.Sprint(want) || len(unmatched) != 0 {
+		t.Errorf("default stacks = %v (unmatched %v), want %v", stacks, unmatched, want)
+	}
+	// Environment files the default leaves out are reported.
+	if want := "[/app/docker-compose.prod.yml /app/docker-compose.staging.yaml /tools/compose.prod.yaml]"; fmt.Sprint(skipped) != want {
+		t.Errorf("skipped = %v, want %s", skipped, want)
+	}
+
+	stacks, unmatched, skipped = ComposeStacks(files, []string{"staging", "docker-compose.prod.yml", "qa"})
+	want = [][]string{
+		{"/app/docker-compose.yml", "/app/docker-compose.staging.yaml", "/app/docker-compose.prod.yml"},
+		{"/db/compose.yaml"},
+	}
+	if fmt.Sprint(stacks) != fmt.Sprint(want) {
+		t.Errorf("named stacks = %v, want %v", stacks, want)
+	}
+	if fmt.Sprint(unmatched) != "[qa]" {
+		t.Errorf("unmatched = %v, want [qa]", unmatched)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("skipped = %v, want none once overrides are named", skipped)
+	}
+
+	stacks, _, _ = ComposeStacks(files, []string{"prod"})
+	if len(stacks) != 3 || fmt.Sprint(stacks[2]) != "[/tools/compose.prod.yaml]" {
+		t.Errorf("an override without a base should load on its own, got %v", stacks)
+	}
+}
+
+func TestComposeStacksSkipsIncluded(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "metrics"), 0755)
+	root := filepath.Join(dir, "compose.yaml")
+	os.WriteFile(root, []byte("include:\n  - path: metrics/compose.yml\nservices:\n  web:\n    image: example/web\n"), 0644)
+	included := filepath.Join(dir, "metrics", "compose.yml")
+	os.WriteFile(included, []byte("services:\n  metrics:\n    image: example/metrics\n"), 0644)
+	other := filepath.Join(dir, "db", "compose.yaml")
+
+	stacks, _, _ := ComposeStacks([]string{root, included, other}, nil)
+	want := [][]string{{root}, {other}}
+	if fmt.Sprint(stacks) != fmt.Sprint(want) {
+		t.Errorf("stacks = %v, want the included file only through its includer: %v", stacks, want)
+	}
+}
+
+func TestParseCompose_OverrideMerge(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "docker-compose.yml")
+	os.WriteFile(base, []byte(`services:
+  api:
+    image: example/api
+    ports:
+      - "8080:8080"
+    environment:
+      DATABASE_URL: postgres://db-dev:5432/app
+      CACHE_URL: redis://cache:6379
+  cache:
+    image: redis:7
+`), 0644)
+	prod := filepath.Join(dir, "docker-compose.prod.yml")
+	os.WriteFile(prod, []byte(`services:
+  api:
+    ports:
+      - "8443:8443"
+    environment:
+      DATABASE_URL: postgres://db-prod:5432/app
+`), 0644)
+
+	res, err := ParseCompose([]string{base, prod}, ComposeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	if findDepWithSource(deps, "api", "db-dev", 5432) != nil {
+		t.Error("overridden DATABASE_URL still reported")
+	}
+	db := findDepWithSource(deps, "api", "db-prod", 5432)
+	if db == nil || db.SourceFile != prod || db.Line != 6 {
+		t.Errorf("expected api -> db-prod:5432 citing docker-compose.prod.yml:6, got %+v", db)
+	}
+	cache := findDepWithSource(deps, "api", "cache", 6379)
+	if cache == nil || cache.SourceFile != base {
+		t.Errorf("expected untouched CACHE_URL citing the base file, got %+v", cache)
+	}
+	if p := findListener(res.Listeners, "api", 8080); p == nil || p.SourceFile != base {
+		t.Errorf("expected base port 8080 kept, got %+v", p)
+	}
+	if p := findListener(res.Listeners, "api", 8443); p == nil || p.SourceFile != prod {
+		t.Errorf("expected override port 8443 appended, got %+v", p)
+	}
+}
+
+func TestParseCompose_Protocols(t *testing.T) {
+	path := writeTempFile(t, "docker-compose.yml", `services:
+  app:
+    image: example/app
+    depends_on: [statsd, dns]
+    ports:
+      - "8080:8080"
+      - "4433:4433/udp"
+    environment:
+      METRICS_ADDR: statsd:8125
+  statsd:
+    image: statsd/statsd
+  dns:
+    image: coredns/coredns:1.11
+`)
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := res.Dependencies
+	for _, want := range []struct {
+		workload string
+		port     int
+		protocol string
+	}{
+		{"app", 8080, "TCP"},
+		{"app", 4433, "UDP"},
+		{"statsd", 8125, "UDP"},
+	} {
+		if l := findListener(res.Listeners, want.workload, want.port); l == nil || l.Protocol != want.protocol {
+			t.Errorf("missing listener %s:%d/%s, got %+v", want.workload, want.port, want.protocol, l)
+		}
+	}
+	for _, want := range []struct {
+		source, target string
+		port           int
+		protocol       string
+	}{
+		{"app", "statsd", 8125, "UDP"},
+		{"app", "dns", 53, "UDP"},
+	} {
+		found := false
+		for _, d := range deps {
+			if d.Source == want.source && d.Target == want.target && d.Port == want.port && d.Protocol == want.protocol {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing %s -> %s:%d/%s in %+v", want.source, want.target, want.port, want.protocol, deps)
+		}
+	}
+}
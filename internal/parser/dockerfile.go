@@ -0,0 +1,394 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	for _, pattern := range []string{"Dockerfile", "Dockerfile.*", "*.Dockerfile", "*.dockerfile", "Containerfile", "Containerfile.*"} {
+		defaultRegistry.RegisterResults("dockerfile", pattern, parseDockerfile)
+	}
+}
+
+// dockerInstruction is one logical Dockerfile instruction: continuation
+// lines joined, comments inside it dropped.
+type dockerInstruction struct {
+	cmd  string // upper-cased instruction keyword
+	args string
+	line int    // 1-based line the instruction starts on
+	text string // the instruction's first line, for evidence
+}
+
+// dockerStage is what a build stage has declared by a given point:
+// ARGs and ENVs in scope, EXPOSEd ports and the HEALTHCHECK. A stage
+// built FROM an earlier one starts from a copy of it.
+type dockerStage struct {
+	name        string
+	vars        map[string]string // ARG and ENV values, for expansion
+	env         []dockerEnv
+	expose      []dockerInstruction
+	healthcheck *dockerInstruction
+}
+
+type dockerEnv struct {
+	key, value string
+	expanded   bool
+	inst       dockerInstruction
+}
+
+func (s *dockerStage) hasEnv(key string) bool {
+	for _, e := range s.env {
+		if e.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *dockerStage) clone(name string) *dockerStage {
+	out := &dockerStage{
+		name:        name,
+		vars:        make(map[string]string, len(s.vars)),
+		env:         append([]dockerEnv{}, s.env...),
+		expose:      append([]dockerInstruction{}, s.expose...),
+		healthcheck: s.healthcheck,
+	}
+	for k, v := range s.vars {
+		out.vars[k] = v
+	}
+	return out
+}
+
+// parseDockerfile extracts what an image declares about the network from
+// its final build stage: EXPOSEd ports become listeners, connection
+// strings in ENV become dependencies (medium confidence, as the runtime
+// environment may override them), and the HEALTHCHECK command's URL is a
+// listener when it probes the container itself, a dependency otherwise.
+// Earlier stages only matter when the final stage is built FROM one of
+// them. ARG defaults (global ones re-declared in the stage, as docker
+// requires) and earlier ENVs are expanded.
+func parseDockerfile(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	insts, disable := readDockerInstructions(string(data))
+
+	global := make(map[string]string) // ARGs before the first FROM
+	var stages []*dockerStage
+	var cur *dockerStage
+	for _, inst := range insts {
+		if inst.cmd == "FROM" {
+			cur = dockerFrom(inst, global, stages)
+			stages = append(stages, cur)
+			continue
+		}
+		if cur == nil {
+			if inst.cmd == "ARG" {
+				for _, kv := range dockerAssignments(inst.args) {
+					global[kv[0]] = interpolateCompose(kv[1], global)
+				}
+			}
+			continue
+		}
+		switch inst.cmd {
+		case "ARG":
+			for _, kv := range dockerAssignments(inst.args) {
+				if cur.hasEnv(kv[0]) {
+					continue // an ENV of the same name wins
+				}
+				if kv[1] == "" {
+					if v, ok := global[kv[0]]; ok {
+						cur.vars[kv[0]] = v
+					}
+					continue
+				}
+				cur.vars[kv[0]] = interpolateCompose(kv[1], cur.vars)
+			}
+		case "ENV":
+			for _, kv := range dockerAssignments(inst.args) {
+				value := interpolateCompose(kv[1], cur.vars)
+				cur.vars[kv[0]] = value
+				cur.env = append(cur.env, dockerEnv{key: kv[0], value: value, expanded: value != kv[1], inst: inst})
+			}
+		case "EXPOSE":
+			inst.args = interpolateCompose(inst.args, cur.vars)
+			cur.expose = append(cur.expose, inst)
+		case "HEALTHCHECK":
+			if strings.EqualFold(strings.TrimSpace(inst.args), "NONE") {
+				cur.healthcheck = nil
+				continue
+			}
+			inst.args = interpolateCompose(inst.args, cur.vars)
+			cur.healthcheck = &inst
+		}
+	}
+	if len(stages) == 0 {
+		return Result{}, nil
+	}
+	final := stages[len(stages)-1]
+
+	var res Result
+	for _, inst := range final.expose {
+		res.Listeners = append(res.Listeners, dockerExpose(inst, path)...)
+	}
+	for _, e := range final.env {
+		if e.value == "" {
+			continue
+		}
+		d, ok := extractFromValue(e.value, path)
+		if !ok {
+			continue
+		}
+		if desc := matchWellKnownEnv(e.key); desc != "" {
+			d.Description = desc
+		}
+		d.Confidence = model.Medium
+		d.EvidenceLine = e.inst.text
+		if e.expanded {
+			d.EvidenceLine += fmt.Sprintf(" (resolved: %s=%s)", e.key, e.value)
+		}
+		d.Line = e.inst.line
+		d.ServiceType = serviceTypeFromEnvKey(e.key)
+		res.Dependencies = append(res.Dependencies, d)
+	}
+	if hc := final.healthcheck; hc != nil {
+		deps, listeners := dockerHealthcheck(*hc, path)
+		res.Dependencies = append(res.Dependencies, deps...)
+		res.Listeners = append(res.Listeners, listeners...)
+	}
+
+	if disable != "" {
+		for i := range res.Dependencies {
+			res.Dependencies[i].Disabled = disable
+		}
+		for i := range res.Listeners {
+			res.Listeners[i].Disabled = disable
+		}
+	}
+	return res, nil
+}
+
+// dockerFrom starts the stage a FROM instruction opens: a copy of an
+// earlier stage when it names one, else a fresh stage.
+func dockerFrom(inst dockerInstruction, global map[string]string, stages []*dockerStage) *dockerStage {
+	fields := strings.Fields(inst.args)
+	var image, name string
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case strings.HasPrefix(fields[i], "--"):
+			continue // --platform=...
+		case image == "":
+			image = interpolateCompose(fields[i], global)
+		case strings.EqualFold(fields[i], "AS") && i+1 < len(fields):
+			name = fields[i+1]
+			i++
+		}
+	}
+	for i := len(stages) - 1; i >= 0; i-- {
+		if stages[i].name != "" && strings.EqualFold(stages[i].name, image) {
+			return stages[i].clone(name)
+		}
+	}
+	return &dockerStage{name: name, vars: make(map[string]string)}
+}
+
+// maxExposePortRange caps how many ports an EXPOSE range is expanded to;
+// wider ranges (a passive FTP pool) are skipped.
+const maxExposePortRange = 16
+
+// dockerExpose returns the listeners of an EXPOSE instruction: `8080`,
+// `53/udp`, and small ranges like `8000-8002/tcp`.
+func dockerExpose(inst dockerInstruction, path string) []model.Listener {
+	var listeners []model.Listener
+	for _, spec := range strings.Fields(inst.args) {
+		ports, proto, _ := strings.Cut(spec, "/")
+		lo, hi, isRange := strings.Cut(ports, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first || last-first >= maxExposePortRange {
+				continue
+			}
+		}
+		for port := first; port <= last; port++ {
+			if port < 1 || port > 65535 {
+				continue
+			}
+			listeners = append(listeners, model.Listener{
+				Port:         port,
+				Protocol:     specProtocol(proto),
+				Description:  fmt.Sprintf("EXPOSE %d", port),
+				Confidence:   model.High,
+				SourceFile:   path,
+				Line:         inst.line,
+				EvidenceLine: inst.text,
+			})
+		}
+	}
+	return listeners
+}
+
+// dockerURLRe finds URLs in a HEALTHCHECK command.
+var dockerURLRe = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"|;&)]+`)
+
+// dockerHealthcheck reads the URL a HEALTHCHECK command probes. A probe
+// of the container itself (localhost, 127.0.0.1, 0.0.0.0) says the image
+// listens on that port; any other host is a dependency.
+func dockerHealthcheck(inst dockerInstruction, path string) ([]model.NetworkDependency, []model.Listener) {
+	var deps []model.NetworkDependency
+	var listeners []model.Listener
+	for _, raw := range dockerURLRe.FindAllString(inst.args, -1) {
+		d, ok := extractFromValue(raw, path)
+		if !ok || d.Port == 0 {
+			continue
+		}
+		d.Line = inst.line
+		d.EvidenceLine = inst.text
+		if localHosts[strings.ToLower(d.Target)] {
+			listeners = append(listeners, model.Listener{
+				Port:         d.Port,
+				Protocol:     d.Protocol,
+				Description:  fmt.Sprintf("HEALTHCHECK probes port %d", d.Port),
+				Confidence:   model.Medium,
+				SourceFile:   path,
+				Line:         inst.line,
+				EvidenceLine: inst.text,
+			})
+			continue
+		}
+		d.Description = "HEALTHCHECK " + d.Description
+		d.Confidence = model.Medium
+		deps = append(deps, d)
+	}
+	return deps, listeners
+}
+
+// localHosts are the names a container reaches itself by.
+var localHosts = map[string]bool{
+	"localhost": true, "127.0.0.1": true, "0.0.0.0": true, "::1": true, "[::1]": true,
+}
+
+// readDockerInstructions splits a Dockerfile into instructions. Lines
+// ending in the escape character (\, or ` after a `# escape=` parser
+// directive) continue on the next line; comment lines, including those
+// inside a continuation, are skipped. disable is the first
+// segspec:disable directive on a comment line.
+func readDockerInstructions(content string) (insts []dockerInstruction, disable string) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	escape := `\`
+	directives := true
+	var cur *dockerInstruction
+	var body strings.Builder
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		cmd, args, _ := strings.Cut(strings.TrimSpace(body.String()), " ")
+		cur.cmd, cur.args = strings.ToUpper(cmd), strings.TrimSpace(args)
+		insts = append(insts, *cur)
+		cur = nil
+		body.Reset()
+	}
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "#") {
+			if directives && cur == nil {
+				key, val, ok := strings.Cut(strings.TrimSpace(line[1:]), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(key), "escape") {
+					if v := strings.TrimSpace(val); v == "`" || v == `\` {
+						escape = v
+					}
+					continue
+				}
+			}
+			if disable == "" {
+				disable = ParseDisableDirective(line)
+			}
+			continue
+		}
+		directives = false
+		if line == "" && cur == nil {
+			continue
+		}
+		if cur == nil {
+			cur = &dockerInstruction{line: i + 1, text: line}
+		}
+		if cont, ok := strings.CutSuffix(line, escape); ok {
+			body.WriteString(cont + " ")
+			continue
+		}
+		body.WriteString(line)
+		flush()
+	}
+	flush()
+	return insts, disable
+}
+
+// dockerAssignments reads the NAME=value pairs of an ARG or ENV (values
+// may be quoted), or the legacy `ENV NAME value` form. An ARG without a
+// default yields an empty value.
+func dockerAssignments(args string) [][2]string {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return nil
+	}
+	first := strings.Fields(args)[0]
+	if !strings.Contains(first, "=") {
+		name, value, _ := strings.Cut(args, " ")
+		return [][2]string{{name, stripQuotes(strings.TrimSpace(value))}}
+	}
+	var out [][2]string
+	for _, tok := range dockerWords(args) {
+		name, value, _ := strings.Cut(tok, "=")
+		if name != "" {
+			out = append(out, [2]string{name, value})
+		}
+	}
+	return out
+}
+
+// dockerWords splits on unquoted whitespace, removing the quotes and
+// backslash escapes.
+func dockerWords(s string) []string {
+	var words []string
+	var b strings.Builder
+	var quote byte
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && quote != '\'' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+			inWord = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+			inWord = true
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			b.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, b.String())
+	}
+	return words
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func TestParseDockerfileFinalStage(t *testing.T) {
+	path := writeTempFile(t, "Dockerfile", `# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.22
+ARG APP_PORT=8080
+
+FROM golang:${GO_VERSION} AS build
+ENV GOPROXY=http://proxy.internal:3128
+EXPOSE 9999
+RUN go build -o /app .
+
+FROM gcr.io/distroless/base AS runtime
+ARG APP_PORT
+ARG DB_HOST=postgres
+ENV DATABASE_URL=postgres://${DB_HOST}:5432/app \
+    CACHE_ADDR="redis:6379"
+EXPOSE ${APP_PORT} 53/udp 9100-9102
+HEALTHCHECK --interval=30s CMD wget -qO- http://localhost:${APP_PORT}/healthz || exit 1
+COPY --from=build /app /app
+`)
+	res, err := parseDockerfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the final stage counts: the build stage's proxy and port are gone.
+	for _, d := range res.Dependencies {
+		if d.Target == "proxy.internal" {
+			t.Error("build-stage ENV leaked into the image")
+		}
+	}
+	if findListener(res.Listeners, "", 9999) != nil {
+		t.Error("build-stage EXPOSE leaked into the image")
+	}
+
+	// Global ARG re-declared in the stage expands in EXPOSE.
+	l := findListener(res.Listeners, "", 8080)
+	if l == nil || l.Protocol != "TCP" || l.Confidence != model.High || l.Line != 15 {
+		t.Errorf("EXPOSE ${APP_PORT} listener = %+v, want TCP 8080 at line 15", l)
+	}
+	if l := findListener(res.Listeners, "", 53); l == nil || l.Protocol != "UDP" {
+		t.Errorf("EXPOSE 53/udp listener = %+v", l)
+	}
+	for _, port := range []int{9100, 9101, 9102} {
+		if findListener(res.Listeners, "", port) == nil {
+			t.Errorf("EXPOSE range missing port %d", port)
+		}
+	}
+
+	// ENV across a continuation, with a stage ARG default interpolated.
+	db := findDep(res.Dependencies, "postgres", 5432)
+	if db == nil || db.Line != 13 || db.Confidence != model.Medium || db.ServiceType != "database" {
+		t.Fatalf("DATABASE_URL dep = %+v, want postgres:5432 at line 13", db)
+	}
+	if !strings.Contains(db.EvidenceLine, "(resolved: DATABASE_URL=postgres://postgres:5432/app)") {
+		t.Errorf("evidence = %q, want the resolved value", db.EvidenceLine)
+	}
+	if d := findDep(res.Dependencies, "redis", 6379); d == nil || d.Line != 13 {
+		t.Errorf("CACHE_ADDR dep = %+v", d)
+	}
+
+	// A HEALTHCHECK on localhost is the image's own port, not an edge.
+	if hc := findListener(res.Listeners, "", 8080); hc == nil || len(res.Dependencies) != 2 {
+		t.Errorf("healthcheck produced deps: %v", res.Dependencies)
+	}
+}
+
+func TestParseDockerfileInheritsFromStage(t *testing.T) {
+	path := writeTempFile(t, "api.Dockerfile", `FROM node:20 AS base
+ENV API_URL=http://users:3000
+EXPOSE 3000
+
+FROM base AS prod
+HEALTHCHECK CMD curl -f http://status.internal:8081/ping
+# segspec:disable=egress
+`)
+	res, err := parseDockerfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := findDep(res.Dependencies, "users", 3000); d == nil || d.Disabled != "egress" {
+		t.Errorf("inherited ENV dep = %+v, want users:3000 (disabled: egress)", d)
+	}
+	if findListener(res.Listeners, "", 3000) == nil {
+		t.Error("inherited EXPOSE missing")
+	}
+	if d := findDep(res.Dependencies, "status.internal", 8081); d == nil || d.Line != 6 {
+		t.Errorf("remote healthcheck dep = %+v", d)
+	}
+}
+
+func TestDockerfileRegistered(t *testing.T) {
+	for _, name := range []string{"Dockerfile", "Dockerfile.prod", "api.Dockerfile", "Containerfile"} {
+		if !DefaultRegistry().MatchesFormat("svc/"+name, "dockerfile") {
+			t.Errorf("%s not matched", name)
+		}
+	}
+}
@@ -0,0 +1,792 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterResults("dotnet", "appsettings.json", parseAppSettingsFile)
+	defaultRegistry.RegisterResults("dotnet", "appsettings.*.json", parseAppSettingsFile)
+}
+
+// .NET configuration is read as flat, case-insensitive keys
+// (ConnectionStrings:Default, Redis:Configuration, ...) like Spring
+// properties. An app is the appsettings*.json files of one directory:
+// appsettings.json is the base, and appsettings.{Environment}.json
+// overlays it key by key when that environment is active.
+
+// DotnetOptions selects the ASP.NET Core environment to analyze.
+type DotnetOptions struct {
+	// Environment is the active environment, as ASPNETCORE_ENVIRONMENT
+	// names it. Empty means every environment: the base file and each
+	// overlay layered on it are resolved on their own and the union is
+	// reported.
+	Environment string
+}
+
+// dotnetSetting is one configuration value as written, and where.
+type dotnetSetting struct {
+	key         string // colon-separated, as .NET names it
+	value       string
+	file        string
+	line        int
+	environment string // environment of the overlay that sets it
+}
+
+// evidence renders the setting as written, secrets redacted.
+func (s dotnetSetting) evidence() string {
+	return model.RedactSecrets(s.key + ": " + redactConnectionString(s.value))
+}
+
+// dotnetFile is one appsettings file.
+type dotnetFile struct {
+	path        string
+	environment string // "" for appsettings.json
+	settings    []dotnetSetting
+	disable     string
+}
+
+// dotnetEnvironment returns the environment of an
+// appsettings.{Environment}.json file name, or "".
+func dotnetEnvironment(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), ".json")
+	env, _ := strings.CutPrefix(base, "appsettings.")
+	if env == base {
+		return ""
+	}
+	return env
+}
+
+// loadDotnetFile reads an appsettings file. Comments and trailing commas,
+// which the .NET JSON provider accepts, are blanked out first.
+func loadDotnetFile(path string) (dotnetFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dotnetFile{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+	entries, err := flattenJSON(stripJSONComments(data))
+	if err != nil {
+		return dotnetFile{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	f := dotnetFile{path: path, environment: dotnetEnvironment(path), disable: ScanFileDisable(data)}
+	for _, e := range entries {
+		var value string
+		switch v := e.value.(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		}
+		f.settings = append(f.settings, dotnetSetting{
+			key:         dotnetKey(e.path),
+			value:       value,
+			file:        path,
+			line:        e.line,
+			environment: strings.ToLower(f.environment),
+		})
+	}
+	return f, nil
+}
+
+// dotnetKey converts a flattened JSON path (a.b[0]) to a .NET
+// configuration key (a:b:0).
+func dotnetKey(path string) string {
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	return strings.ReplaceAll(path, ".", ":")
+}
+
+// dotnetView is the effective configuration for one environment: the
+// overlay's settings replace the base's key by key (keys compare
+// case-insensitively, as in .NET), in base file order.
+type dotnetView []dotnetSetting
+
+func layerDotnet(files ...dotnetFile) dotnetView {
+	var view dotnetView
+	index := make(map[string]int)
+	for _, f := range files {
+		for _, s := range f.settings {
+			k := strings.ToLower(s.key)
+			if i, ok := index[k]; ok {
+				view[i] = s
+				continue
+			}
+			index[k] = len(view)
+			view = append(view, s)
+		}
+	}
+	return view
+}
+
+// get returns the setting at key (case-insensitive).
+func (v dotnetView) get(key string) (dotnetSetting, bool) {
+	for _, s := range v {
+		if strings.EqualFold(s.key, key) {
+			return s, true
+		}
+	}
+	return dotnetSetting{}, false
+}
+
+// ParseAppSettings parses the appsettings files of one .NET app (one
+// directory). With opts.Environment set, only appsettings.json and the
+// overlay of that environment are read; otherwise the base and each
+// overlay layered on it are resolved and reported together. Findings from
+// an overlay carry its environment, lower-cased like the env file tags.
+func ParseAppSettings(paths []string, opts DotnetOptions) (Result, error) {
+	var base []dotnetFile
+	var overlays []dotnetFile
+	for _, p := range paths {
+		f, err := loadDotnetFile(p)
+		if err != nil {
+			return Result{}, err
+		}
+		switch {
+		case f.environment == "":
+			base = append(base, f)
+		case opts.Environment == "" || strings.EqualFold(f.environment, opts.Environment):
+			overlays = append(overlays, f)
+		}
+	}
+	sort.Slice(overlays, func(i, j int) bool { return overlays[i].environment < overlays[j].environment })
+
+	var views []dotnetView
+	if opts.Environment == "" || len(overlays) == 0 {
+		views = append(views, layerDotnet(base...))
+	}
+	for _, o := range overlays {
+		views = append(views, layerDotnet(append(append([]dotnetFile{}, base...), o)...))
+	}
+
+	var res Result
+	for _, view := range views {
+		res.Dependencies = mergeUnique(res.Dependencies, dotnetViewDeps(view))
+		res.Listeners = mergeUniqueListeners(res.Listeners, dotnetViewListeners(view))
+	}
+
+	disabled := make(map[string]string)
+	for _, f := range append(base, overlays...) {
+		disabled[f.path] = f.disable
+	}
+	for i := range res.Dependencies {
+		res.Dependencies[i].Disabled = disabled[res.Dependencies[i].SourceFile]
+		res.Dependencies[i].Parser = "dotnet"
+	}
+	for i := range res.Listeners {
+		res.Listeners[i].Disabled = disabled[res.Listeners[i].SourceFile]
+		res.Listeners[i].Parser = "dotnet"
+	}
+	return res, nil
+}
+
+// parseAppSettingsFile parses one appsettings file, layered on the
+// appsettings.json beside it when it is an overlay. The walker parses
+// each directory's files together through ParseAppSettings instead.
+func parseAppSettingsFile(path string) (Result, error) {
+	paths := []string{path}
+	if env := dotnetEnvironment(path); env != "" {
+		if base := filepath.Join(filepath.Dir(path), "appsettings.json"); fileExists(base) {
+			paths = append([]string{base}, path)
+		}
+		return ParseAppSettings(paths, DotnetOptions{Environment: env})
+	}
+	return ParseAppSettings(paths, DotnetOptions{})
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// dotnetSections are configuration sections named for a client whose
+// settings don't say what they connect to: any key below a section whose
+// name contains the word is read with that client's grammar and default
+// port.
+var dotnetSections = []struct {
+	word        string
+	defaultPort int
+	description string
+	serviceType string
+}{
+	{"redis", 6379, "Redis", "cache"},
+	{"rabbitmq", 5672, "RabbitMQ", "broker"},
+}
+
+// dotnetEndpointKeys name a section's connection string or endpoint list.
+var dotnetEndpointKeys = map[string]bool{
+	"configuration": true, "connectionstring": true, "connection": true,
+	"endpoints": true, "uri": true, "url": true, "hostnames": true, "hosts": true,
+}
+
+// dotnetHostKeys name a section's host; the port comes from a sibling
+// Port key or the client's default.
+var dotnetHostKeys = map[string]bool{
+	"host": true, "hostname": true, "server": true, "address": true,
+}
+
+// dotnetViewDeps extracts dependencies from one view: ConnectionStrings
+// entries, Redis and RabbitMQ sections, and any other setting holding a
+// connection URL, such as an HttpClient BaseAddress.
+func dotnetViewDeps(view dotnetView) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	add := func(s dotnetSetting, d model.NetworkDependency) {
+		d.SourceFile = s.file
+		d.Line = s.line
+		d.EvidenceLine = s.evidence()
+		d.Environment = s.environment
+		d.Description = model.RedactSecrets(d.Description)
+		deps = append(deps, d)
+	}
+
+	handled := make(map[string]bool)
+	for _, s := range view {
+		parts := strings.Split(s.key, ":")
+		if len(parts) < 2 || !strings.EqualFold(parts[0], "ConnectionStrings") || s.value == "" {
+			continue
+		}
+		handled[strings.ToLower(s.key)] = true
+		name := strings.Join(parts[1:], ":")
+		for _, d := range dotnetConnectionString(name, s.value) {
+			add(s, d)
+		}
+	}
+
+	for _, sec := range dotnetSections {
+		hosts := make(map[string]dotnetSetting) // parent key -> host setting
+		var parents []string
+		for _, s := range view {
+			k := strings.ToLower(s.key)
+			if handled[k] || s.value == "" || !dotnetInSection(k, sec.word) {
+				continue
+			}
+			parent, last := dotnetKeyShape(k)
+			switch {
+			case dotnetHostKeys[last]:
+				handled[k] = true
+				if _, ok := hosts[parent]; !ok {
+					parents = append(parents, parent)
+				}
+				hosts[parent] = s
+			case dotnetEndpointKeys[last]:
+				handled[k] = true
+				var eps []endpoint
+				if sec.word == "redis" && !strings.Contains(s.value, "://") {
+					eps = redisEndpoints(s.value)
+				} else {
+					eps = springEndpoints(s.value, sec.defaultPort)
+				}
+				for _, ep := range eps {
+					add(s, dotnetEndpointDep(ep, fmt.Sprintf("%s (%s)", sec.description, s.key), sec.serviceType))
+				}
+			}
+		}
+		for _, parent := range parents {
+			s := hosts[parent]
+			port := sec.defaultPort
+			if p, ok := view.get(s.key[:strings.LastIndex(s.key, ":")] + ":Port"); ok {
+				if n, err := strconv.Atoi(p.value); err == nil && n > 0 {
+					port = n
+				}
+			}
+			for _, h := range strings.Split(s.value, ",") {
+				if host, p, scheme, ok := endpointFromValue(h, port); ok {
+					add(s, dotnetEndpointDep(endpoint{host, p, scheme}, fmt.Sprintf("%s (%s)", sec.description, s.key), sec.serviceType))
+				}
+			}
+		}
+	}
+
+	for _, s := range view {
+		if handled[strings.ToLower(s.key)] || !strings.Contains(s.value, "://") || dotnetListenerKey(s.key) {
+			continue
+		}
+		for _, d := range configValueDeps(s.value, s.key, s.file) {
+			if d.ServiceType == "" {
+				d.ServiceType = schemeServiceTypes[urlScheme(s.value)]
+			}
+			if strings.HasPrefix(s.value, "http") {
+				d.ServiceType = "http"
+				d.Description = fmt.Sprintf("HTTP service (%s)", s.key)
+			}
+			d.Confidence = model.Medium
+			add(s, d)
+		}
+	}
+	return deps
+}
+
+// dotnetKeyShape splits a key into its parent and last segment, array
+// indexes dropped (Redis:EndPoints:0 -> Redis, EndPoints).
+func dotnetKeyShape(key string) (parent, last string) {
+	var parts []string
+	for _, p := range strings.Split(key, ":") {
+		if _, err := strconv.Atoi(p); err != nil {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) == 0 {
+		return "", ""
+	}
+	return strings.Join(parts[:len(parts)-1], ":"), parts[len(parts)-1]
+}
+
+// dotnetInSection reports whether a lower-cased key lies below a section
+// whose name contains word (Redis, RedisCache, MassTransit:RabbitMq).
+func dotnetInSection(key, word string) bool {
+	parts := strings.Split(key, ":")
+	for _, p := range parts[:len(parts)-1] {
+		if strings.Contains(p, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func dotnetEndpointDep(ep endpoint, description, serviceType string) model.NetworkDependency {
+	return model.NetworkDependency{
+		Target:      ep.host,
+		Port:        ep.port,
+		Protocol:    inferProtocol(ep.scheme, ep.port),
+		Description: description,
+		Confidence:  model.High,
+		ServiceType: serviceType,
+	}
+}
+
+// dotnetListenerKey reports whether key configures the app's own
+// endpoints rather than a peer: Urls or a Kestrel endpoint's Url.
+func dotnetListenerKey(key string) bool {
+	parts := strings.Split(strings.ToLower(key), ":")
+	if len(parts) == 1 {
+		return parts[0] == "urls"
+	}
+	return len(parts) == 4 && parts[0] == "kestrel" && parts[1] == "endpoints" && parts[3] == "url"
+}
+
+// dotnetViewListeners reads the app's own ports from Urls
+// (semicolon-separated) and Kestrel:Endpoints:<name>:Url.
+func dotnetViewListeners(view dotnetView) []model.Listener {
+	var listeners []model.Listener
+	for _, s := range view {
+		if !dotnetListenerKey(s.key) {
+			continue
+		}
+		name := ""
+		if parts := strings.Split(s.key, ":"); len(parts) == 4 {
+			name = strings.ToLower(parts[2])
+		}
+		for _, u := range strings.Split(s.value, ";") {
+			port, ok := listenURLPort(strings.TrimSpace(u))
+			if !ok {
+				continue
+			}
+			description := fmt.Sprintf("%s: %s", s.key, strings.TrimSpace(u))
+			listeners = append(listeners, model.Listener{
+				Port:         port,
+				Protocol:     "TCP",
+				Name:         name,
+				Description:  description,
+				Confidence:   model.High,
+				SourceFile:   s.file,
+				Line:         s.line,
+				EvidenceLine: s.evidence(),
+				Environment:  s.environment,
+			})
+		}
+	}
+	return listeners
+}
+
+// listenURLPort returns the port of a Kestrel listen URL
+// (http://*:5000, https://+:443, http://[::]:8080, http://localhost).
+// The host is a bind address, so wildcards are fine.
+func listenURLPort(u string) (int, bool) {
+	scheme, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		return 0, false
+	}
+	hostport, _, _ := strings.Cut(rest, "/")
+	if _, p, err := net.SplitHostPort(hostport); err == nil {
+		n, err := strconv.Atoi(p)
+		return n, err == nil && n > 0 && n <= 65535
+	}
+	switch strings.ToLower(scheme) {
+	case "http":
+		return 80, hostport != ""
+	case "https":
+		return 443, hostport != ""
+	}
+	return 0, false
+}
+
+// Connection-string grammars.
+
+// dbDefaultPorts names the database a port belongs to, for connection
+// strings that state one.
+var dbDefaultPorts = map[int]string{
+	1433: "SQL Server",
+	1521: "Oracle",
+	3306: "MySQL",
+	5432: "PostgreSQL",
+}
+
+// sqlServerOnlyKeys appear in SqlClient connection strings and no other
+// ADO.NET provider's.
+var sqlServerOnlyKeys = []string{
+	"initial catalog", "integrated security", "trusted_connection",
+	"multipleactiveresultsets", "trustservercertificate", "applicationintent",
+}
+
+// dotnetConnectionString extracts the endpoints of a ConnectionStrings
+// entry: a URL (mongodb://, amqp://, postgres://), a StackExchange.Redis
+// string (host:port,password=...,ssl=true), an Azure endpoint string
+// (Endpoint=sb://...), or an ADO.NET key=value string read with the
+// grammar of its provider: SqlClient (Server=tcp:host,1433 or
+// host\instance), Npgsql (Host=h1,h2:5433;Port=5432) or MySqlConnector
+// (Server=host;Port=3306). The provider is taken from the entry's name
+// (Postgres, MySql, Redis...), then from provider-specific keys, then
+// from the port; a bare Server= with nothing else to go on is SQL Server,
+// ADO.NET's default, at medium confidence.
+func dotnetConnectionString(name, value string) []model.NetworkDependency {
+	lname := strings.ToLower(name)
+	label := fmt.Sprintf(" (ConnectionStrings:%s)", name)
+	if strings.Contains(value, "://") && !strings.Contains(value, ";") {
+		var deps []model.NetworkDependency
+		for _, ep := range springEndpoints(value, 0) {
+			d := dotnetEndpointDep(ep, dotnetSchemeDescription(ep.scheme)+label, schemeServiceTypes[ep.scheme])
+			if d.ServiceType == "" {
+				d.ServiceType = "database"
+			}
+			deps = append(deps, d)
+		}
+		return deps
+	}
+	kv := connectionStringPairs(value)
+	if len(kv) == 0 || strings.Contains(lname, "redis") {
+		var deps []model.NetworkDependency
+		for _, ep := range redisEndpoints(value) {
+			deps = append(deps, dotnetEndpointDep(ep, "Redis"+label, "cache"))
+		}
+		return deps
+	}
+
+	for _, k := range []string{"endpoint", "accountendpoint"} {
+		scheme, rest, ok := strings.Cut(kv[k], "://")
+		if !ok {
+			continue
+		}
+		host, port, _, ok := endpointFromValue("https://"+rest, 0)
+		if !ok {
+			return nil
+		}
+		if strings.EqualFold(scheme, "sb") {
+			return []model.NetworkDependency{dotnetEndpointDep(endpoint{host: host, port: 5671}, "Azure Service Bus"+label, "broker")}
+		}
+		return []model.NetworkDependency{dotnetEndpointDep(endpoint{host: host, port: port}, "HTTP service"+label, "http")}
+	}
+
+	server, hostKey := "", ""
+	for _, k := range []string{"host", "server", "data source", "datasource", "address", "addr", "network address"} {
+		if v := kv[k]; v != "" {
+			server, hostKey = v, k
+			break
+		}
+	}
+	if server == "" {
+		return nil
+	}
+	port, _ := strconv.Atoi(kv["port"])
+
+	provider := ""
+	switch {
+	case strings.Contains(lname, "postgres") || strings.Contains(lname, "npgsql") || strings.Contains(lname, "pgsql"):
+		provider = "PostgreSQL"
+	case strings.Contains(lname, "mysql") || strings.Contains(lname, "maria"):
+		provider = "MySQL"
+	case strings.Contains(lname, "oracle"):
+		provider = "Oracle"
+	case strings.Contains(lname, "sqlserver") || strings.Contains(lname, "mssql"):
+		provider = "SQL Server"
+	case hostKey == "host":
+		provider = "PostgreSQL"
+	case strings.ContainsAny(server, `,\`) || strings.HasPrefix(strings.ToLower(server), "tcp:"):
+		provider = "SQL Server"
+	}
+	if provider == "" {
+		for _, k := range sqlServerOnlyKeys {
+			if _, ok := kv[k]; ok {
+				provider = "SQL Server"
+				break
+			}
+		}
+	}
+	if provider == "" && port != 0 {
+		provider = dbDefaultPorts[port]
+	}
+	confidence := model.High
+	if provider == "" {
+		if _, p, ok := strings.Cut(server, ":"); ok && p != "" {
+			// host:port/service, as Oracle's EZConnect writes it.
+			provider = "database"
+		} else {
+			provider, confidence = "SQL Server", model.Medium
+		}
+	}
+
+	var eps []endpoint
+	switch provider {
+	case "SQL Server":
+		ep, instance, ok := sqlServerEndpoint(server, port)
+		if ok {
+			eps = append(eps, ep)
+		}
+		if instance {
+			confidence = model.Medium
+		}
+	default:
+		defaultPort := port
+		if defaultPort == 0 {
+			for p, name := range dbDefaultPorts {
+				if name == provider {
+					defaultPort = p
+				}
+			}
+		}
+		for _, h := range strings.Split(server, ",") {
+			h, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(h), "//"), "/")
+			if host, p, _, ok := endpointFromValue(h, defaultPort); ok {
+				eps = append(eps, endpoint{host: host, port: p})
+			}
+		}
+	}
+
+	var deps []model.NetworkDependency
+	for _, ep := range eps {
+		description := provider
+		if description == "database" {
+			if name := dbDefaultPorts[ep.port]; name != "" {
+				description = name
+			}
+		}
+		d := dotnetEndpointDep(ep, description+label, "database")
+		d.Confidence = confidence
+		deps = append(deps, d)
+	}
+	return deps
+}
+
+// dotnetSchemeDescription names the service behind a connection URL
+// scheme.
+func dotnetSchemeDescription(scheme string) string {
+	switch scheme {
+	case "mongodb", "mongodb+srv":
+		return "MongoDB"
+	case "amqp", "amqps":
+		return "RabbitMQ"
+	case "postgres", "postgresql":
+		return "PostgreSQL"
+	case "redis", "rediss":
+		return "Redis"
+	case "mysql":
+		return "MySQL"
+	case "http", "https":
+		return "HTTP service"
+	}
+	return scheme
+}
+
+// sqlServerEndpoint reads a SqlClient Server value: an optional tcp:
+// prefix, then host, host,port or host\instance. A named instance without
+// a port listens wherever SQL Browser says, so 1433 is only a guess and
+// instance is true. Shared-memory and named-pipe servers ((local), .,
+// (localdb)\..., np:, lpc:) are not network endpoints.
+func sqlServerEndpoint(server string, port int) (ep endpoint, instance, ok bool) {
+	lower := strings.ToLower(server)
+	if strings.HasPrefix(lower, "np:") || strings.HasPrefix(lower, "lpc:") {
+		return endpoint{}, false, false
+	}
+	if strings.HasPrefix(lower, "tcp:") {
+		server = server[len("tcp:"):]
+	}
+	host, p, hasPort := strings.Cut(server, ",")
+	host, _, instance = strings.Cut(host, `\`)
+	host = strings.TrimSpace(host)
+	switch strings.ToLower(host) {
+	case "", ".", "(local)", "(localdb)":
+		return endpoint{}, false, false
+	}
+	if hasPort {
+		port, _ = strconv.Atoi(strings.TrimSpace(p))
+	}
+	if port == 0 {
+		port = 1433
+	} else {
+		instance = false
+	}
+	return endpoint{host: host, port: port}, instance, port <= 65535
+}
+
+// redisEndpoints reads a StackExchange.Redis configuration string:
+// comma-separated endpoints (host, host:port, [::1]:6379) mixed with
+// key=value options. Endpoints without a port take 6379, 6380 with
+// ssl=true, or 26379 when serviceName= makes them Sentinels.
+func redisEndpoints(value string) []endpoint {
+	defaultPort := 6379
+	var hosts []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		key, val, isOption := strings.Cut(item, "=")
+		switch {
+		case item == "":
+		case isOption:
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "ssl":
+				if strings.EqualFold(strings.TrimSpace(val), "true") && defaultPort == 6379 {
+					defaultPort = 6380
+				}
+			case "servicename":
+				defaultPort = 26379
+			}
+		default:
+			hosts = append(hosts, item)
+		}
+	}
+	var eps []endpoint
+	for _, h := range hosts {
+		if host, port, err := net.SplitHostPort(h); err == nil {
+			if n, err := strconv.Atoi(port); err == nil && n > 0 && n <= 65535 {
+				eps = append(eps, endpoint{host: host, port: n, scheme: "redis"})
+			}
+			continue
+		}
+		if hostnameRe.MatchString(h) {
+			eps = append(eps, endpoint{host: h, port: defaultPort, scheme: "redis"})
+		}
+	}
+	return eps
+}
+
+// connectionStringPairs splits an ADO.NET connection string into its
+// keys (lower-cased, inner whitespace collapsed) and values. Values may
+// be quoted with ' or " to hold a ;. A string with no key=value pair
+// yields nil.
+func connectionStringPairs(s string) map[string]string {
+	kv := make(map[string]string)
+	for _, part := range splitConnectionString(s) {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.Join(strings.Fields(key), " "))
+		if key == "" {
+			continue
+		}
+		kv[key] = stripQuotes(strings.TrimSpace(val))
+	}
+	if len(kv) == 0 {
+		return nil
+	}
+	// A StackExchange.Redis string (host:port,password=x) has = signs too,
+	// but its first item is an endpoint, not a key.
+	first, _, _ := strings.Cut(s, ",")
+	if !strings.Contains(first, "=") {
+		return nil
+	}
+	return kv
+}
+
+// splitConnectionString splits on ; outside quotes.
+func splitConnectionString(s string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';':
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// connectionStringSecretKeys are connection-string keys whose values are
+// credentials.
+var connectionStringSecretKeys = regexp.MustCompile(`(?i)(^|[;,]\s*)((?:password|pwd|sharedaccesskey|accountkey|accesskey|sharedaccesssignature)\s*=)([^;,]*)`)
+
+// redactConnectionString blanks credential values in a connection
+// string, leaving the rest of it readable.
+func redactConnectionString(s string) string {
+	return connectionStringSecretKeys.ReplaceAllString(s, "${1}${2}[REDACTED]")
+}
+
+// stripJSONComments blanks // and /* */ comments and trailing commas
+// outside strings, keeping every newline so lines still match the file.
+func stripJSONComments(data []byte) []byte {
+	out := bytes.Clone(data)
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	// lastComma is the offset of a comma not yet followed by a value.
+	lastComma := -1
+	for i := 0; i < len(out); i++ {
+		switch c := out[i]; {
+		case c == '"':
+			lastComma = -1
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := bytes.IndexByte(out[i:], '\n')
+			if end < 0 {
+				end = len(out) - i
+			}
+			blank(i, i+end)
+			i += end - 1
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			stop := len(out)
+			if end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			blank(i, stop)
+			i = stop - 1
+		case c == ',':
+			lastComma = i
+		case c == '}' || c == ']':
+			if lastComma >= 0 {
+				out[lastComma] = ' '
+			}
+			lastComma = -1
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		default:
+			lastComma = -1
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func TestParseAppSettingsConnectionStrings(t *testing.T) {
+	path := writeTempFile(t, "appsettings.json", `{
+  // The .NET JSON provider accepts comments and trailing commas.
+  "ConnectionStrings": {
+    "Default": "Server=tcp:sql.internal,1433;Database=app;User Id=sa;Password=hunter2;",
+    "Reporting": "Host=pg-primary:5433,pg-replica;Port=5432;Database=rep;Username=rep",
+    "Orders": "Server=orders-db;Port=3306;Uid=orders;Pwd=s3cret",
+    "Legacy": "Data Source=legacy-sql\\REPORTS;Initial Catalog=x;Integrated Security=true",
+    "LocalDb": "Server=(localdb)\\mssqllocaldb;Database=dev",
+    "Cache": "redis-cache,password=abc,ssl=true,abortConnect=false",
+    "Events": "mongodb://mongo-1:27017,mongo-2:27018/events",
+  },
+  "Redis": { "Configuration": "redis-a,redis-b:6390" },
+  "RabbitMQ": { "HostName": "rabbit", "Port": 5673 },
+  "Services": { "Catalog": { "BaseAddress": "http://catalog-api:8080/" } },
+  "Kestrel": { "Endpoints": { "Grpc": { "Url": "http://0.0.0.0:5001" } } },
+  "Urls": "http://+:80;https://+:443"
+}`)
+	res, err := parseAppSettingsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target      string
+		port        int
+		confidence  model.Confidence
+		serviceType string
+		line        int
+	}{
+		{"sql.internal", 1433, model.High, "database", 4},
+		{"pg-primary", 5433, model.High, "database", 5},
+		{"pg-replica", 5432, model.High, "database", 5},
+		{"orders-db", 3306, model.High, "database", 6},
+		{"legacy-sql", 1433, model.Medium, "database", 7}, // named instance
+		{"redis-cache", 6380, model.High, "cache", 9},     // ssl=true
+		{"mongo-1", 27017, model.High, "database", 10},
+		{"mongo-2", 27018, model.High, "database", 10},
+		{"redis-a", 6379, model.High, "cache", 12},
+		{"redis-b", 6390, model.High, "cache", 12},
+		{"rabbit", 5673, model.High, "broker", 13},
+		{"catalog-api", 8080, model.Medium, "http", 14},
+	}
+	for _, tt := range tests {
+		d := findDep(res.Dependencies, tt.target, tt.port)
+		if d == nil {
+			t.Errorf("missing %s:%d in %v", tt.target, tt.port, res.Dependencies)
+			continue
+		}
+		if d.Confidence != tt.confidence || d.ServiceType != tt.serviceType || d.Line != tt.line || d.Parser != "dotnet" {
+			t.Errorf("%s:%d = %s/%s line %d parser %q, want %s/%s line %d", tt.target, tt.port, d.Confidence, d.ServiceType, d.Line, d.Parser, tt.confidence, tt.serviceType, tt.line)
+		}
+	}
+	if len(res.Dependencies) != len(tests) {
+		t.Errorf("got %d deps, want %d: %v", len(res.Dependencies), len(tests), res.Dependencies)
+	}
+
+	for _, d := range res.Dependencies {
+		if strings.Contains(d.EvidenceLine, "hunter2") || strings.Contains(d.EvidenceLine, "s3cret") {
+			t.Errorf("credential in evidence: %q", d.EvidenceLine)
+		}
+	}
+	if d := findDep(res.Dependencies, "orders-db", 3306); d != nil && d.EvidenceLine != "ConnectionStrings:Orders: Server=orders-db;Port=3306;Uid=orders;Pwd=[REDACTED]" {
+		t.Errorf("evidence = %q", d.EvidenceLine)
+	}
+
+	for _, port := range []int{5001, 80, 443} {
+		if findListener(res.Listeners, "", port) == nil {
+			t.Errorf("missing listener on %d: %v", port, res.Listeners)
+		}
+	}
+	if l := findListener(res.Listeners, "", 5001); l != nil && l.Name != "grpc" {
+		t.Errorf("Kestrel endpoint name = %q, want grpc", l.Name)
+	}
+}
+
+func TestParseAppSettingsEnvironments(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "appsettings.json")
+	prod := filepath.Join(dir, "appsettings.Production.json")
+	os.WriteFile(base, []byte(`{
+  "ConnectionStrings": { "Default": "Host=db-dev;Database=app" },
+  "RabbitMQ": { "Host": "rabbit-dev", "Port": 5673 }
+}`), 0644)
+	os.WriteFile(prod, []byte(`{
+  "connectionStrings": { "default": "Host=db-prod;Database=app" },
+  "RabbitMQ": { "Host": "rabbit-prod" }
+}`), 0644)
+
+	targets := func(opts DotnetOptions) map[string]string {
+		res, err := ParseAppSettings([]string{base, prod}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[string]string)
+		for _, d := range res.Dependencies {
+			out[d.Key()] = d.Environment
+		}
+		return out
+	}
+
+	// Every environment: the base and the layered overlay, the overlay's
+	// findings tagged with its environment.
+	all := targets(DotnetOptions{})
+	want := map[string]string{
+		"->db-dev:5432/TCP":      "",
+		"->rabbit-dev:5673/TCP":  "",
+		"->db-prod:5432/TCP":     "production",
+		"->rabbit-prod:5673/TCP": "production", // port from the base file
+	}
+	if len(all) != len(want) {
+		t.Errorf("all environments = %v, want %v", all, want)
+	}
+	for k, env := range want {
+		if got, ok := all[k]; !ok || got != env {
+			t.Errorf("%s: environment %q (present %v), want %q", k, got, ok, env)
+		}
+	}
+
+	// One environment: overridden keys are gone.
+	one := targets(DotnetOptions{Environment: "production"})
+	if _, ok := one["->db-dev:5432/TCP"]; ok || len(one) != 2 {
+		t.Errorf("production = %v, want only the production endpoints", one)
+	}
+
+	// An environment without an overlay runs on the base file.
+	staging := targets(DotnetOptions{Environment: "Staging"})
+	if _, ok := staging["->db-dev:5432/TCP"]; !ok || len(staging) != 2 {
+		t.Errorf("staging = %v, want the base endpoints", staging)
+	}
+}
+
+func TestAppSettingsRegistered(t *testing.T) {
+	r := DefaultRegistry()
+	for _, name := range []string{"appsettings.json", "appsettings.Development.json"} {
+		if !r.MatchesFormat(filepath.Join("src", "Api", name), "dotnet") {
+			t.Errorf("%s not matched by the dotnet parser", name)
+		}
+	}
+}
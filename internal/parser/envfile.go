@@ -1,16 +1,20 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/dormstern/segspec/internal/model"
 )
 
 func init() {
-	defaultRegistry.Register(".env", parseEnvFile)
+	// .env, its variants (.env.production) and named env files (app.env,
+	// config/staging.env); "?*" keeps .env itself from matching twice.
+	defaultRegistry.RegisterFormat("envfile", ".env", parseEnvFile)
+	defaultRegistry.RegisterFormat("envfile", ".env.*", parseEnvFile)
+	defaultRegistry.RegisterFormat("envfile", "?*.env", parseEnvFile)
 }
 
 // wellKnownEnvVars maps env var name patterns to descriptions.
@@ -44,20 +48,86 @@ var wellKnownEnvVars = map[string]string{
 	"VAULT_ADDR":         "Vault",
 }
 
-func parseEnvFile(path string) ([]model.NetworkDependency, error) {
-	f, err := os.Open(path)
+// envEnvironments are the environment names recognised in a <name>.env
+// file name (config/production.env, app-staging.env).
+var envEnvironments = map[string]bool{
+	"production": true, "prod": true, "staging": true, "stage": true,
+	"preprod": true, "uat": true, "qa": true, "test": true, "testing": true,
+	"development": true, "dev": true, "local": true, "ci": true,
+	"preview": true, "demo": true, "sandbox": true,
+}
+
+// envTemplates name files that document variables rather than set them
+// (.env.example); they are skipped.
+var envTemplates = map[string]bool{
+	"example": true, "sample": true, "template": true, "tmpl": true, "dist": true,
+}
+
+// envFileEnvironment infers the environment an env file configures from
+// its name: .env.production and .env.production.local -> "production",
+// config/staging.env and app-prod.env -> "staging", "prod". A plain .env,
+// or a <name>.env without an environment word (app.env), applies to every
+// environment and yields "".
+func envFileEnvironment(path string) string {
+	base := filepath.Base(path)
+	if rest, ok := strings.CutPrefix(base, ".env."); ok {
+		name, _, _ := strings.Cut(rest, ".")
+		return strings.ToLower(name)
+	}
+	parts := strings.FieldsFunc(strings.TrimSuffix(base, ".env"), func(r rune) bool {
+		return r == '.' || r == '-' || r == '_'
+	})
+	for i := len(parts) - 1; i >= 0; i-- {
+		if p := strings.ToLower(parts[i]); envEnvironments[p] || envTemplates[p] {
+			return p
+		}
+	}
+	return ""
+}
+
+// envBaseVars returns the variables an env file variant can reference from
+// the .env next to it, which tools like Vite, Next.js and dotenv-flow load
+// first. A plain .env has no base.
+func envBaseVars(path string) map[string]string {
+	if filepath.Base(path) == ".env" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), ".env"))
 	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
+		return nil
+	}
+	entries, _ := readEnvEntries(data, nil)
+	vars := make(map[string]string, len(entries))
+	for _, e := range entries {
+		vars[e.key] = e.value
 	}
-	defer f.Close()
+	return vars
+}
 
-	var deps []model.NetworkDependency
-	seen := make(map[string]bool)
-	disable := ""
+// envEntry is one KEY=value assignment of an env file.
+type envEntry struct {
+	key      string
+	value    string // unquoted, references expanded
+	expanded bool   // value differs from what is written because of expansion
+	line     int
+	evidence string // the assignment's first line
+}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// readEnvEntries parses env file data the way docker compose and the
+// dotenv libraries do: KEY=value lines with an optional `export` prefix,
+// # comments (also after an unquoted value), and single- or double-quoted
+// values that may span lines. Unquoted and double-quoted values expand
+// $VAR and ${VAR:-default} references against vars and the entries above
+// them; single-quoted values are literal. disable is the first
+// segspec:disable directive on a comment line.
+func readEnvEntries(data []byte, vars map[string]string) (entries []envEntry, disable string) {
+	scope := make(map[string]string, len(vars))
+	for k, v := range vars {
+		scope[k] = v
+	}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
 		if line == "" {
 			continue
 		}
@@ -68,26 +138,126 @@ func parseEnvFile(path string) ([]model.NetworkDependency, error) {
 			}
 			continue
 		}
+		stmt := line
+		if rest, ok := strings.CutPrefix(stmt, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			stmt = rest
+		}
+		key, val, ok := strings.Cut(stmt, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		e := envEntry{key: key, line: i + 1, evidence: line}
+		val = strings.TrimSpace(val)
+
+		expand := true
+		switch {
+		case val != "" && (val[0] == '"' || val[0] == '\''):
+			quote := val[0]
+			body, end := val[1:], closingQuote(val[1:], quote)
+			// A quoted value runs on until its closing quote, possibly
+			// several lines down; without one it is just this line.
+			for j := i + 1; end < 0 && j < len(lines); j++ {
+				body += "\n" + strings.TrimSuffix(lines[j], "\r")
+				if end = closingQuote(body, quote); end >= 0 {
+					i = j
+				}
+			}
+			if end < 0 {
+				body, end = val[1:], len(val)-1
+			}
+			e.value = body[:end]
+			if quote == '\'' {
+				expand = false
+			} else {
+				e.value = unescapeEnvValue(e.value)
+			}
+		default:
+			if j := strings.Index(val, " #"); j >= 0 {
+				val = strings.TrimSpace(val[:j])
+			}
+			e.value = val
+		}
+		if expand && strings.Contains(e.value, "$") {
+			resolved := interpolateCompose(e.value, scope)
+			e.expanded = resolved != strings.ReplaceAll(e.value, "$$", "$")
+			e.value = resolved
+		}
+		scope[key] = e.value
+		entries = append(entries, e)
+	}
+	return entries, disable
+}
+
+// closingQuote returns the index in s of the quote ending a value, skipping
+// backslash escapes inside double quotes, or -1.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
 
-		idx := strings.Index(line, "=")
-		if idx < 0 {
+// unescapeEnvValue interprets the escapes of a double-quoted value. An
+// escaped dollar becomes $$ so expansion leaves it literal.
+func unescapeEnvValue(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
 			continue
 		}
-		key := strings.TrimSpace(line[:idx])
-		val := strings.TrimSpace(line[idx+1:])
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case '$':
+			b.WriteString("$$")
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
 
-		// Strip surrounding quotes
-		val = stripQuotes(val)
+// parseEnvFile extracts dependencies from the connection strings and
+// host:port values of an env file (.env, .env.production, app.env,
+// config/*.env). Values may reference other entries and, in a variant,
+// the .env beside it. Dependencies are tagged with the environment the
+// file name implies; template files (.env.example) are skipped.
+func parseEnvFile(path string) ([]model.NetworkDependency, error) {
+	environment := envFileEnvironment(path)
+	if envTemplates[environment] {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	entries, disable := readEnvEntries(data, envBaseVars(path))
 
-		if val == "" {
+	var deps []model.NetworkDependency
+	seen := make(map[string]int)
+	for _, e := range entries {
+		if e.value == "" {
 			continue
 		}
 
 		// Check if key is well-known
-		desc := matchWellKnownEnv(key)
+		desc := matchWellKnownEnv(e.key)
 
 		// Try to extract host:port from value
-		d, ok := extractFromValue(val, path)
+		d, ok := extractFromValue(e.value, path)
 		if !ok {
 			continue
 		}
@@ -99,19 +269,22 @@ func parseEnvFile(path string) ([]model.NetworkDependency, error) {
 
 		// Env vars are medium confidence
 		d.Confidence = model.Medium
-		d.EvidenceLine = line
-		d.ServiceType = serviceTypeFromEnvKey(key)
+		d.EvidenceLine = e.evidence
+		if e.expanded {
+			d.EvidenceLine += " (resolved: " + e.value + ")"
+		}
+		d.Line = e.line
+		d.ServiceType = serviceTypeFromEnvKey(e.key)
+		d.Environment = environment
 
-		dedup := fmt.Sprintf("%s:%d", d.Target, d.Port)
-		if seen[dedup] {
+		dedup := fmt.Sprintf("%s:%d/%s", d.Target, d.Port, d.Protocol)
+		if i, ok := seen[dedup]; ok {
+			deps[i] = deps[i].MergedWith(d)
 			continue
 		}
-		seen[dedup] = true
+		seen[dedup] = len(deps)
 		deps = append(deps, d)
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning %s: %w", path, err)
-	}
 
 	if disable != "" {
 		for i := range deps {
@@ -274,3 +274,146 @@ func TestParseEnvFile_BareHostPort(t *testing.T) {
 		t.Fatal("expected dependency on redis-bare:6379 from bare host:port")
 	}
 }
+
+func TestEnvFileEnvironment(t *testing.T) {
+	tests := map[string]string{
+		".env":                        "",
+		".env.production":             "production",
+		".env.staging":                "staging",
+		".env.production.local":       "production",
+		"app.env":                     "",
+		"config/staging.env":          "staging",
+		"deploy/app-prod.env":         "prod",
+		".env.example":                "example",
+		"/srv/app/config/service.env": "",
+	}
+	for path, want := range tests {
+		if got := envFileEnvironment(path); got != want {
+			t.Errorf("envFileEnvironment(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestParseEnvFile_Variants(t *testing.T) {
+	for _, name := range []string{".env.production", ".env.staging", "app.env", "config/prod.env"} {
+		if len(DefaultRegistry().Match(name)) != 1 {
+			t.Errorf("%s: want exactly one parser", name)
+		}
+	}
+	if len(DefaultRegistry().Match(".env")) != 1 {
+		t.Error(".env: want exactly one parser")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("DB_HOST=db-dev\nDB_PORT=5432\n"), 0644)
+	prod := filepath.Join(dir, ".env.production")
+	os.WriteFile(prod, []byte("DB_HOST=db-prod\nDATABASE_URL=postgres://${DB_HOST}:${DB_PORT}/app\n"), 0644)
+
+	deps, err := parseEnvFile(prod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := findDep(deps, "db-prod", 5432)
+	if d == nil {
+		t.Fatalf("expected db-prod:5432 resolved across .env and .env.production, got %+v", deps)
+	}
+	if d.Environment != "production" {
+		t.Errorf("Environment = %q, want production", d.Environment)
+	}
+	if want := "DATABASE_URL=postgres://${DB_HOST}:${DB_PORT}/app (resolved: postgres://db-prod:5432/app)"; d.EvidenceLine != want {
+		t.Errorf("EvidenceLine = %q, want %q", d.EvidenceLine, want)
+	}
+	if d.Line != 2 {
+		t.Errorf("Line = %d, want 2", d.Line)
+	}
+
+	example := filepath.Join(dir, ".env.example")
+	os.WriteFile(example, []byte("DATABASE_URL=postgres://db.example.com:5432/app\n"), 0644)
+	if deps, _ := parseEnvFile(example); len(deps) != 0 {
+		t.Errorf(".env.example: want no deps, got %+v", deps)
+	}
+}
+
+func TestParseEnvFile_ExportAndMultiline(t *testing.T) {
+	dir := t.TempDir()
+	content := `export REDIS_URL=redis://cache:6379 # primary cache
+CERT="-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----"
+AMQP_URL='amqp://mq:5672/${VHOST}'
+export	KAFKA_BROKERS="kafka:9092"
+`
+	path := filepath.Join(dir, ".env")
+	os.WriteFile(path, []byte(content), 0644)
+
+	entries, _ := readEnvEntries([]byte(content), nil)
+	got := map[string]envEntry{}
+	for _, e := range entries {
+		got[e.key] = e
+	}
+	if got["REDIS_URL"].value != "redis://cache:6379" {
+		t.Errorf("REDIS_URL = %q", got["REDIS_URL"].value)
+	}
+	if want := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"; got["CERT"].value != want {
+		t.Errorf("CERT = %q, want %q", got["CERT"].value, want)
+	}
+	if got["AMQP_URL"].value != "amqp://mq:5672/${VHOST}" || got["AMQP_URL"].line != 5 {
+		t.Errorf("AMQP_URL = %+v, want literal single-quoted value on line 5", got["AMQP_URL"])
+	}
+
+	deps, err := parseEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		host string
+		port int
+		line int
+	}{{"cache", 6379, 1}, {"mq", 5672, 5}, {"kafka", 9092, 6}} {
+		d := findDep(deps, want.host, want.port)
+		if d == nil {
+			t.Errorf("missing %s:%d in %+v", want.host, want.port, deps)
+			continue
+		}
+		if d.Line != want.line {
+			t.Errorf("%s line = %d, want %d", want.host, d.Line, want.line)
+		}
+	}
+}
+
+func TestParseEnvFile_Protocols(t *testing.T) {
+	dir := t.TempDir()
+	content := `STATSD_HOST=statsd:8125
+DNS_SERVER=resolver:53
+GELF_URL=udp://graylog:12201
+GELF_TCP_URL=tcp://graylog:12202
+REDIS_URL=redis://cache:6379
+`
+	path := filepath.Join(dir, ".env")
+	os.WriteFile(path, []byte(content), 0644)
+
+	deps, err := parseEnvFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		host     string
+		port     int
+		protocol string
+	}{
+		{"statsd", 8125, "UDP"},
+		{"resolver", 53, "UDP"},
+		{"graylog", 12201, "UDP"},
+		{"graylog", 12202, "TCP"},
+		{"cache", 6379, "TCP"},
+	} {
+		d := findDep(deps, want.host, want.port)
+		if d == nil {
+			t.Errorf("missing %s:%d", want.host, want.port)
+			continue
+		}
+		if d.Protocol != want.protocol {
+			t.Errorf("%s:%d protocol = %s, want %s", want.host, want.port, d.Protocol, want.protocol)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	// Envoy bootstrap files have no fixed name (envoy.yaml,
+	// front-envoy.yaml, config.yaml), so every YAML file is checked for
+	// static_resources, the way the k8s parser checks for a kind.
+	defaultRegistry.RegisterResults("envoy", "*.yaml", parseEnvoy)
+	defaultRegistry.RegisterResults("envoy", "*.yml", parseEnvoy)
+}
+
+// parseEnvoy reads an Envoy bootstrap config: the endpoints of each
+// static cluster (load_assignment, or the v2 `hosts` list) become
+// dependencies, and the listeners' and admin interface's socket
+// addresses listeners. Clusters that HTTP routes send to are tagged as
+// HTTP; the rest (tcp_proxy, xDS) are plain TCP or UDP. Files that
+// aren't Envoy bootstrap configs, or aren't valid YAML, are ignored.
+func parseEnvoy(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return Result{}, nil
+	}
+	root := yamlPairs(doc.Content[0])
+	static := yamlPairs(yamlGet(root, "static_resources"))
+	if static == nil {
+		return Result{}, nil
+	}
+	lines := sourceLines(data)
+	listeners := yamlGet(static, "listeners")
+	routed := make(map[string]bool)
+	envoyRouteClusters(listeners, false, routed)
+
+	var res Result
+	for _, l := range yamlItems(listeners) {
+		pairs := yamlPairs(l)
+		name := yamlString(yamlGet(pairs, "name"))
+		addresses := []*yaml.Node{yamlGet(pairs, "address")}
+		for _, extra := range yamlItems(yamlGet(pairs, "additional_addresses")) {
+			addresses = append(addresses, yamlGet(yamlPairs(extra), "address"))
+		}
+		for _, a := range addresses {
+			if sa, ok := envoySocketAddress(a); ok && !loopbackHost(sa.host) {
+				res.Listeners = append(res.Listeners, proxyListener(path, sa.line, lineAt(lines, sa.line), sa.port, sa.protocol, "Envoy listener "+name))
+			}
+		}
+	}
+	if sa, ok := envoySocketAddress(yamlGet(yamlPairs(yamlGet(root, "admin")), "address")); ok && !loopbackHost(sa.host) {
+		res.Listeners = append(res.Listeners, proxyListener(path, sa.line, lineAt(lines, sa.line), sa.port, sa.protocol, "Envoy admin interface"))
+	}
+
+	for _, c := range yamlItems(yamlGet(static, "clusters")) {
+		pairs := yamlPairs(c)
+		name := yamlString(yamlGet(pairs, "name"))
+		serviceType := ""
+		if routed[name] {
+			serviceType = "http"
+		}
+		var addresses []*yaml.Node
+		for _, group := range yamlItems(yamlGet(yamlPairs(yamlGet(pairs, "load_assignment")), "endpoints")) {
+			for _, lb := range yamlItems(yamlGet(yamlPairs(group), "lb_endpoints")) {
+				addresses = append(addresses, yamlGet(yamlPairs(yamlGet(yamlPairs(lb), "endpoint")), "address"))
+			}
+		}
+		// v2 configs list hosts as bare addresses.
+		addresses = append(addresses, yamlItems(yamlGet(pairs, "hosts"))...)
+		for _, a := range addresses {
+			sa, ok := envoySocketAddress(a)
+			if !ok || sa.host == "" || loopbackHost(sa.host) {
+				continue
+			}
+			res.Dependencies = append(res.Dependencies, proxyDep(path, sa.line, lineAt(lines, sa.line),
+				sa.host, sa.port, sa.protocol, "Envoy cluster "+name, serviceType))
+		}
+	}
+	return withDisable(res, data), nil
+}
+
+// envoySocket is a socket_address, with the line of its address.
+type envoySocket struct {
+	host     string
+	port     int
+	protocol string
+	line     int
+}
+
+// envoySocketAddress reads an Address's socket_address. Pipes and named
+// ports resolved by a resolver have no port to report.
+func envoySocketAddress(address *yaml.Node) (envoySocket, bool) {
+	sa := yamlPairs(yamlGet(yamlPairs(address), "socket_address"))
+	if sa == nil {
+		return envoySocket{}, false
+	}
+	hostNode := yamlGet(sa, "address")
+	port, err := strconv.Atoi(yamlString(yamlGet(sa, "port_value")))
+	if err != nil || port < 1 || port > 65535 {
+		return envoySocket{}, false
+	}
+	s := envoySocket{host: yamlString(hostNode), port: port, protocol: "TCP"}
+	if strings.EqualFold(yamlString(yamlGet(sa, "protocol")), "UDP") {
+		s.protocol = "UDP"
+	}
+	if s.host == "0.0.0.0" || s.host == "::" {
+		s.host = ""
+	}
+	if hostNode != nil {
+		s.line = hostNode.Line
+	} else if n := yamlGet(sa, "port_value"); n != nil {
+		s.line = n.Line
+	}
+	return s, true
+}
+
+// envoyRouteClusters records the clusters HTTP routes send to: `cluster`
+// and weighted_clusters names anywhere under a route_config.
+func envoyRouteClusters(n *yaml.Node, inRoute bool, out map[string]bool) {
+	n = yamlDeref(n)
+	if n == nil {
+		return
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			envoyRouteClusters(item, inRoute, out)
+		}
+	case yaml.MappingNode:
+		for _, p := range yamlPairs(n) {
+			switch {
+			case inRoute && p.key == "cluster" && p.value.Kind == yaml.ScalarNode:
+				out[p.value.Value] = true
+			case inRoute && p.key == "weighted_clusters":
+				for _, c := range yamlItems(yamlGet(yamlPairs(p.value), "clusters")) {
+					out[yamlString(yamlGet(yamlPairs(c), "name"))] = true
+				}
+			default:
+				envoyRouteClusters(p.value, inRoute || p.key == "route_config", out)
+			}
+		}
+	}
+}
+
+// yamlItems returns the items of a sequence node, or nil.
+func yamlItems(n *yaml.Node) []*yaml.Node {
+	n = yamlDeref(n)
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// yamlString returns a scalar node's value, or "".
+func yamlString(n *yaml.Node) string {
+	n = yamlDeref(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
@@ -0,0 +1,87 @@
+package parser
+
+import "testing"
+
+func TestParseEnvoy(t *testing.T) {
+	path := writeTempFile(t, "front-envoy.yaml", `admin:
+  address:
+    socket_address: { address: 0.0.0.0, port_value: 9901 }
+static_resources:
+  listeners:
+  - name: ingress
+    address:
+      socket_address:
+        address: 0.0.0.0
+        port_value: 10000
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.http_connection_manager
+        typed_config:
+          route_config:
+            virtual_hosts:
+            - name: all
+              domains: ["*"]
+              routes:
+              - match: { prefix: "/" }
+                route: { cluster: service_a }
+  - name: statsd
+    address:
+      socket_address: { address: 0.0.0.0, port_value: 8125, protocol: UDP }
+  clusters:
+  - name: service_a
+    type: STRICT_DNS
+    load_assignment:
+      cluster_name: service_a
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: service-a
+                port_value: 8000
+        - endpoint:
+            address:
+              socket_address: { address: 127.0.0.1, port_value: 8001 }
+  - name: redis_cluster
+    hosts:
+    - socket_address: { address: redis, port_value: 6379 }
+`)
+	res, err := parseEnvoy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Dependencies) != 2 {
+		t.Fatalf("got %d deps, want 2: %v", len(res.Dependencies), res.Dependencies)
+	}
+	if d := findDep(res.Dependencies, "service-a", 8000); d == nil || d.ServiceType != "http" || d.Line != 35 || d.Description != "Envoy cluster service_a" {
+		t.Errorf("service-a = %+v", d)
+	}
+	// A v2 hosts entry, not routed by HTTP.
+	if d := findDep(res.Dependencies, "redis", 6379); d == nil || d.ServiceType != "" {
+		t.Errorf("redis = %+v", d)
+	}
+
+	for _, tt := range []struct {
+		port     int
+		protocol string
+	}{{10000, "TCP"}, {8125, "UDP"}, {9901, "TCP"}} {
+		if l := findListener(res.Listeners, "", tt.port); l == nil || l.Protocol != tt.protocol {
+			t.Errorf("listener %d = %+v, want %s", tt.port, l, tt.protocol)
+		}
+	}
+}
+
+func TestParseEnvoyIgnoresOtherYAML(t *testing.T) {
+	path := writeTempFile(t, "envoy-deployment.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: envoy
+`)
+	res, err := parseEnvoy(path)
+	if err != nil || len(res.Dependencies)+len(res.Listeners) != 0 {
+		t.Errorf("manifest = %+v, %v; want nothing", res, err)
+	}
+	if _, err := parseEnvoy(writeTempFile(t, "broken.yaml", "a: [")); err != nil {
+		t.Errorf("invalid YAML: %v, want it ignored", err)
+	}
+}
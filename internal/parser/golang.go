@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterFormat("go", "go.mod", parseGoMod)
+	defaultRegistry.RegisterFormat("go", "*.go", parseGoSource)
+}
+
+// knownGoModules maps Go module paths to the infrastructure their clients
+// talk to, like knownLibs does for Maven/Gradle. A module matches itself
+// and its major-version and package subpaths (github.com/jackc/pgx/v5).
+// gRPC names no fixed peer, so google.golang.org/grpc maps to a generic
+// grpc target on the conventional 50051; the source pass adds the real
+// targets from grpc.Dial/NewClient calls.
+var knownGoModules = []infraLib{
+	{"github.com/lib/pq", "postgresql", 5432, "PostgreSQL (lib/pq)", "database"},
+	{"github.com/jackc/pgx", "postgresql", 5432, "PostgreSQL (pgx)", "database"},
+	{"gorm.io/driver/postgres", "postgresql", 5432, "PostgreSQL (GORM)", "database"},
+	{"github.com/go-sql-driver/mysql", "mysql", 3306, "MySQL (go-sql-driver)", "database"},
+	{"gorm.io/driver/mysql", "mysql", 3306, "MySQL (GORM)", "database"},
+	{"github.com/redis/go-redis", "redis", 6379, "Redis (go-redis)", "cache"},
+	{"github.com/go-redis/redis", "redis", 6379, "Redis (go-redis)", "cache"},
+	{"github.com/gomodule/redigo", "redis", 6379, "Redis (redigo)", "cache"},
+	{"github.com/IBM/sarama", "kafka", 9092, "Kafka (sarama)", "broker"},
+	{"github.com/Shopify/sarama", "kafka", 9092, "Kafka (sarama)", "broker"},
+	{"github.com/segmentio/kafka-go", "kafka", 9092, "Kafka (kafka-go)", "broker"},
+	{"github.com/confluentinc/confluent-kafka-go", "kafka", 9092, "Kafka (confluent-kafka-go)", "broker"},
+	{"github.com/twmb/franz-go", "kafka", 9092, "Kafka (franz-go)", "broker"},
+	{"go.mongodb.org/mongo-driver", "mongodb", 27017, "MongoDB (mongo-driver)", "database"},
+	{"github.com/nats-io/nats.go", "nats", 4222, "NATS", "broker"},
+	{"github.com/rabbitmq/amqp091-go", "rabbitmq", 5672, "RabbitMQ (amqp091-go)", "broker"},
+	{"github.com/streadway/amqp", "rabbitmq", 5672, "RabbitMQ (streadway/amqp)", "broker"},
+	{"github.com/elastic/go-elasticsearch", "elasticsearch", 9200, "Elasticsearch", "search"},
+	{"github.com/bradfitz/gomemcache", "memcached", 11211, "Memcached (gomemcache)", "cache"},
+	{"github.com/DataDog/datadog-go", "statsd", 8125, "StatsD (datadog-go)", "metrics"},
+	{"github.com/cactus/go-statsd-client", "statsd", 8125, "StatsD (go-statsd-client)", "metrics"},
+	{"google.golang.org/grpc", "grpc", 50051, "gRPC (grpc-go)", "grpc"},
+}
+
+// parseGoMod infers Low-confidence infrastructure deps from the modules a
+// Go service requires. `// indirect` requirements are only pulled in by
+// other modules and are skipped.
+func parseGoMod(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []model.NetworkDependency
+	inRequire := false
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		code, comment, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+
+		switch {
+		case len(fields) == 0:
+			continue
+		case inRequire && fields[0] == ")":
+			inRequire = false
+			continue
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inRequire = true
+			continue
+		case fields[0] == "require":
+			fields = fields[1:]
+		case !inRequire:
+			continue
+		}
+		if len(fields) == 0 || strings.TrimSpace(comment) == "indirect" {
+			continue
+		}
+
+		mod := fields[0]
+		for _, lib := range knownGoModules {
+			if mod != lib.artifactID && !strings.HasPrefix(mod, lib.artifactID+"/") {
+				continue
+			}
+			deps = append(deps, model.NetworkDependency{
+				Target:       lib.target,
+				Port:         lib.port,
+				Protocol:     inferProtocol("", lib.port),
+				Description:  fmt.Sprintf("module dependency: %s -> %s", mod, lib.description),
+				Confidence:   model.Low,
+				SourceFile:   path,
+				Line:         lineNo,
+				EvidenceLine: line,
+				ServiceType:  lib.serviceType,
+			})
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", path, err)
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
@@ -0,0 +1,336 @@
+package parser
+
+import (
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+// parseGoSource parses a Go file with go/ast (nothing is compiled or run)
+// and extracts the endpoints written into it as string literals:
+//
+//   - grpc.Dial/DialContext/NewClient targets (High);
+//   - arguments of calls into known client packages and of net.Dial and
+//     sql.Open, and Addr/Addrs/Brokers/... fields of their option structs,
+//     e.g. redis.NewClient(&redis.Options{Addr: "cache:6379"}) (High);
+//   - any other literal holding a connection URL (postgres://, redis://,
+//     amqp://, mongodb://, nats://...), a go-sql-driver "tcp(host:port)"
+//     DSN or a lib/pq "host=... port=..." DSN (Medium).
+//
+// _test.go files are skipped: what tests dial is not what the service does.
+func parseGoSource(path string) ([]model.NetworkDependency, error) {
+	if strings.HasSuffix(path, "_test.go") {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, path, data, goparser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	lines := strings.Split(string(data), "\n")
+	clients := goClientImports(file)
+
+	var deps []model.NetworkDependency
+	done := make(map[*ast.BasicLit]bool)
+	add := func(lit *ast.BasicLit, ep endpoint, role, serviceType string, confidence model.Confidence) {
+		pos := fset.Position(lit.Pos())
+		if serviceType == "" {
+			serviceType = schemeServiceTypes[ep.scheme]
+		}
+		deps = append(deps, model.NetworkDependency{
+			Target:       ep.host,
+			Port:         ep.port,
+			Protocol:     inferProtocol(ep.scheme, ep.port),
+			Description:  fmt.Sprintf("%s: %s:%d", role, ep.host, ep.port),
+			Confidence:   confidence,
+			SourceFile:   path,
+			Line:         pos.Line,
+			Column:       pos.Column,
+			EvidenceLine: model.RedactSecrets(strings.TrimSpace(lines[pos.Line-1])),
+			ServiceType:  serviceType,
+		})
+		done[lit] = true
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			pkg, fn := selectorName(n.Fun)
+			serviceType, ok := clients[pkg]
+			if !ok {
+				return true
+			}
+			role := pkg + "." + fn
+			switch {
+			case serviceType == "grpc":
+				if fn != "Dial" && fn != "DialContext" && fn != "NewClient" {
+					return true
+				}
+				arg := 0
+				if fn == "DialContext" {
+					arg = 1
+				}
+				if arg < len(n.Args) {
+					if lit, val, ok := stringLit(n.Args[arg]); ok {
+						if ep, ok := grpcTarget(val); ok {
+							add(lit, ep, role, "grpc", model.High)
+						}
+					}
+				}
+				return true
+			case pkg == "net" && fn != "Dial" && fn != "DialTimeout":
+				return true
+			case pkg == "sql" && fn != "Open":
+				return true
+			}
+			// net.Dial("udp", "statsd:8125") names its transport.
+			network := ""
+			if pkg == "net" && len(n.Args) > 0 {
+				if _, val, ok := stringLit(n.Args[0]); ok && (strings.HasPrefix(val, "udp") || strings.HasPrefix(val, "tcp")) {
+					network = val[:3]
+				}
+			}
+			for _, arg := range n.Args {
+				for _, lit := range stringLits(arg) {
+					val, _ := strconv.Unquote(lit.Value)
+					for _, ep := range goEndpoints(val, true) {
+						if network != "" {
+							ep.scheme = network
+						}
+						add(lit, ep, role, serviceType, model.High)
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			pkg, typ := selectorName(n.Type)
+			serviceType, ok := clients[pkg]
+			if !ok {
+				return true
+			}
+			for _, elt := range n.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.Ident)
+				if !ok || !goAddrFields[key.Name] {
+					continue
+				}
+				for _, lit := range stringLits(kv.Value) {
+					val, _ := strconv.Unquote(lit.Value)
+					for _, ep := range goEndpoints(val, true) {
+						add(lit, ep, fmt.Sprintf("%s.%s.%s", pkg, typ, key.Name), serviceType, model.High)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	// Then every other literal that is a connection string on its own.
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || done[lit] {
+			return true
+		}
+		val, _ := strconv.Unquote(lit.Value)
+		for _, ep := range goEndpoints(val, false) {
+			add(lit, ep, "connection string", "", model.Medium)
+		}
+		return true
+	})
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
+
+// goAddrFields are option-struct fields that hold peer addresses.
+var goAddrFields = map[string]bool{
+	"Addr": true, "Addrs": true, "Address": true, "Addresses": true,
+	"Brokers": true, "Servers": true, "Hosts": true, "Endpoints": true,
+	"URL": true, "URI": true, "DSN": true,
+}
+
+// goClientImports maps the local name of each imported client package to
+// the ServiceType of its peer: knownGoModules, gRPC, database/sql and net.
+func goClientImports(file *ast.File) map[string]string {
+	clients := make(map[string]string)
+	for _, imp := range file.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		serviceType, ok := "", false
+		switch p {
+		case "google.golang.org/grpc":
+			serviceType, ok = "grpc", true
+		case "database/sql":
+			serviceType, ok = "database", true
+		case "net":
+			ok = true
+		default:
+			for _, lib := range knownGoModules {
+				if p == lib.artifactID || strings.HasPrefix(p, lib.artifactID+"/") {
+					serviceType, ok = lib.serviceType, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		name := goPackageName(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name != "_" && name != "." {
+			clients[name] = serviceType
+		}
+	}
+	return clients
+}
+
+var goMajorVersionRe = regexp.MustCompile(`/v[0-9]+$`)
+
+// goPackageName guesses a package's name from its import path the way
+// goimports does: the last element without a major-version suffix or a
+// "go-" / "-go" / ".go" decoration (github.com/redis/go-redis/v9 -> redis).
+func goPackageName(importPath string) string {
+	name := path.Base(goMajorVersionRe.ReplaceAllString(importPath, ""))
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return name
+}
+
+// selectorName splits pkg.Name; anything else yields empty strings.
+func selectorName(expr ast.Expr) (string, string) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", ""
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	return x.Name, sel.Sel.Name
+}
+
+// stringLit returns expr as a string literal and its value.
+func stringLit(expr ast.Expr) (*ast.BasicLit, string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, "", false
+	}
+	val, err := strconv.Unquote(lit.Value)
+	return lit, val, err == nil
+}
+
+// stringLits returns expr if it is a string literal, or the string literal
+// elements of a slice literal ([]string{"kafka-1:9092", "kafka-2:9092"}).
+func stringLits(expr ast.Expr) []*ast.BasicLit {
+	if lit, _, ok := stringLit(expr); ok {
+		return []*ast.BasicLit{lit}
+	}
+	var lits []*ast.BasicLit
+	if cl, ok := expr.(*ast.CompositeLit); ok {
+		for _, elt := range cl.Elts {
+			if lit, _, ok := stringLit(elt); ok {
+				lits = append(lits, lit)
+			}
+		}
+	}
+	return lits
+}
+
+// endpoint is a parsed network address.
+type endpoint struct {
+	host   string
+	port   int
+	scheme string
+}
+
+var (
+	mysqlDSNRe = regexp.MustCompile(`@tcp\(([^():]+)(?::(\d+))?\)`)
+	pqHostRe   = regexp.MustCompile(`(?:^|\s)host=([^\s'"]+)`)
+	pqPortRe   = regexp.MustCompile(`(?:^|\s)port=(\d+)`)
+)
+
+// goEndpoints parses a string literal as connection URL(s), a go-sql-driver
+// or lib/pq DSN, or (in a client call, where any address is meaningful)
+// an http(s) URL or bare host:port. Multi-host URLs
+// (mongodb://a:27017,b:27017/db) yield one endpoint per host.
+func goEndpoints(val string, inClientCall bool) []endpoint {
+	val = strings.TrimSpace(val)
+	if scheme, rest, ok := strings.Cut(val, "://"); ok {
+		scheme = strings.ToLower(scheme)
+		if schemeServiceTypes[scheme] == "" && schemeProtocols[scheme] == "" && !(inClientCall && (scheme == "http" || scheme == "https")) {
+			return nil
+		}
+		hosts, _, _ := strings.Cut(rest, "/")
+		hosts, _, _ = strings.Cut(hosts, "?")
+		if i := strings.LastIndex(hosts, "@"); i >= 0 {
+			hosts = hosts[i+1:]
+		}
+		var eps []endpoint
+		for _, h := range strings.Split(hosts, ",") {
+			if host, port, _, ok := endpointFromValue(scheme+"://"+h, 0); ok {
+				eps = append(eps, endpoint{host, port, scheme})
+			}
+		}
+		return eps
+	}
+	if m := mysqlDSNRe.FindStringSubmatch(val); m != nil {
+		port := 3306
+		if m[2] != "" {
+			port, _ = strconv.Atoi(m[2])
+		}
+		return []endpoint{{m[1], port, "mysql"}}
+	}
+	if m := pqHostRe.FindStringSubmatch(val); m != nil && strings.Contains(val, "=") &&
+		(strings.Contains(val, "dbname=") || strings.Contains(val, "user=") || strings.Contains(val, "sslmode=")) {
+		port := 5432
+		if pm := pqPortRe.FindStringSubmatch(val); pm != nil {
+			port, _ = strconv.Atoi(pm[1])
+		}
+		return []endpoint{{m[1], port, "postgres"}}
+	}
+	if inClientCall {
+		if host, port, _, ok := endpointFromValue(val, 0); ok {
+			return []endpoint{{host, port, ""}}
+		}
+	}
+	return nil
+}
+
+// grpcTarget parses a gRPC dial target: host:port, or dns:/// and
+// passthrough:/// forms of it. Other resolvers (xds, unix) name no
+// host:port and yield nothing.
+func grpcTarget(val string) (endpoint, bool) {
+	switch {
+	case strings.HasPrefix(val, "dns://"):
+		// dns:[//authority]/host:port
+		_, val, _ = strings.Cut(strings.TrimPrefix(val, "dns://"), "/")
+	case strings.HasPrefix(val, "passthrough:///"):
+		val = strings.TrimPrefix(val, "passthrough:///")
+	case strings.Contains(val, ":/"):
+		return endpoint{}, false
+	}
+	host, port, _, ok := endpointFromValue(val, 0)
+	return endpoint{host, port, ""}, ok
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterResults("haproxy", "haproxy*.cfg", parseHAProxy)
+	defaultRegistry.RegisterResults("haproxy", "haproxy/*.cfg", func(path string) (Result, error) {
+		if strings.HasPrefix(strings.ToLower(filepath.Base(path)), "haproxy") {
+			return Result{}, nil // matched by haproxy*.cfg already
+		}
+		return parseHAProxy(path)
+	})
+}
+
+// haproxySections are the keywords that open a section; every other
+// line belongs to the section above it.
+var haproxySections = map[string]bool{
+	"global": true, "defaults": true, "frontend": true, "backend": true, "listen": true,
+	"resolvers": true, "peers": true, "userlist": true, "mailers": true, "program": true,
+	"http-errors": true, "ring": true, "cache": true, "fcgi-app": true, "crt-store": true,
+}
+
+// haproxyLine is one config line split into words.
+type haproxyLine struct {
+	fields []string
+	line   int
+	text   string
+}
+
+// haproxySection is a section and its lines. mode is the section's
+// `mode`, or the one of the defaults section before it.
+type haproxySection struct {
+	kind  string
+	name  string
+	mode  string
+	lines []haproxyLine
+}
+
+// parseHAProxy reads haproxy.cfg: the `server` (and `server-template`)
+// lines of backend and listen sections become dependencies, the `bind`
+// addresses of frontend and listen sections listeners, and `nameserver`
+// lines of resolvers sections DNS dependencies. Sections in `mode http`
+// are tagged as HTTP.
+func parseHAProxy(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	sections, err := readHAProxySections(data)
+	if err != nil {
+		return Result{}, fmt.Errorf("scanning %s: %w", path, err)
+	}
+	var res Result
+	for _, s := range sections {
+		serviceType := ""
+		if s.mode == "http" {
+			serviceType = "http"
+		}
+		for _, l := range s.lines {
+			switch {
+			case (l.fields[0] == "server" || l.fields[0] == "server-template") && (s.kind == "backend" || s.kind == "listen"):
+				addr := 2
+				if l.fields[0] == "server-template" {
+					addr = 3
+				}
+				if len(l.fields) <= addr {
+					continue
+				}
+				address, protocol, ok := haproxyAddress(l.fields[addr])
+				if !ok {
+					continue
+				}
+				host, port, ok := proxyAddress(address, 0)
+				if !ok || host == "" || loopbackHost(host) {
+					continue
+				}
+				res.Dependencies = append(res.Dependencies, proxyDep(path, l.line, l.text, host, port, protocol,
+					fmt.Sprintf("HAProxy %s %s (server %s)", s.kind, s.name, l.fields[1]), serviceType))
+			case l.fields[0] == "bind" && (s.kind == "frontend" || s.kind == "listen") && len(l.fields) > 1:
+				for _, spec := range strings.Split(l.fields[1], ",") {
+					res.Listeners = append(res.Listeners, haproxyBind(path, l, s, spec)...)
+				}
+			case l.fields[0] == "nameserver" && s.kind == "resolvers" && len(l.fields) > 2:
+				// Nameservers are queried over UDP unless given as tcp@.
+				address, protocol, ok := haproxyAddress(l.fields[2])
+				if !ok {
+					continue
+				}
+				if !strings.HasPrefix(l.fields[2], "tcp") {
+					protocol = "UDP"
+				}
+				host, port, ok := proxyAddress(address, 53)
+				if !ok || host == "" || loopbackHost(host) {
+					continue
+				}
+				res.Dependencies = append(res.Dependencies, proxyDep(path, l.line, l.text, host, port, protocol,
+					fmt.Sprintf("HAProxy resolvers %s (nameserver %s)", s.name, l.fields[1]), ""))
+			}
+		}
+	}
+	return withDisable(res, data), nil
+}
+
+// haproxyBind returns the listeners of one bind address: `*:80`, `:443`,
+// `:::8080`, `quic4@:443` (UDP) or a small range `:8000-8002`.
+func haproxyBind(path string, l haproxyLine, s haproxySection, spec string) []model.Listener {
+	address, protocol, ok := haproxyAddress(spec)
+	if !ok {
+		return nil
+	}
+	first, last := address, ""
+	if i := strings.LastIndex(address, ":"); i >= 0 {
+		if lo, hi, isRange := strings.Cut(address[i+1:], "-"); isRange {
+			first, last = address[:i+1]+lo, hi
+		}
+	}
+	host, port, ok := proxyAddress(first, 0)
+	if !ok || loopbackHost(host) {
+		return nil
+	}
+	end := port
+	if last != "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < port || n-port >= maxExposePortRange {
+			return nil
+		}
+		end = n
+	}
+	var listeners []model.Listener
+	for p := port; p <= end; p++ {
+		listeners = append(listeners, proxyListener(path, l.line, l.text, p, protocol, fmt.Sprintf("HAProxy %s %s", s.kind, s.name)))
+	}
+	return listeners
+}
+
+// haproxyAddress strips the address family prefix HAProxy allows
+// (ipv4@, tcp6@, quic4@, udp@) and returns the transport it implies.
+// Unix, abstract and inherited sockets aren't network addresses.
+func haproxyAddress(addr string) (string, string, bool) {
+	prefix, rest, ok := strings.Cut(addr, "@")
+	if !ok {
+		return addr, "TCP", !strings.HasPrefix(addr, "/")
+	}
+	switch strings.ToLower(prefix) {
+	case "ipv4", "ipv6", "tcp", "tcp4", "tcp6":
+		return rest, "TCP", true
+	case "udp", "udp4", "udp6", "quic4", "quic6":
+		return rest, "UDP", true
+	}
+	return "", "", false
+}
+
+// readHAProxySections splits a config into its sections, resolving each
+// one's mode.
+func readHAProxySections(data []byte) ([]haproxySection, error) {
+	var sections []haproxySection
+	defaultMode := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t') {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if haproxySections[fields[0]] {
+			s := haproxySection{kind: fields[0], mode: defaultMode}
+			if len(fields) > 1 {
+				s.name = fields[1]
+			}
+			sections = append(sections, s)
+			continue
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		s := &sections[len(sections)-1]
+		if fields[0] == "mode" && len(fields) > 1 {
+			s.mode = fields[1]
+			if s.kind == "defaults" {
+				defaultMode = fields[1]
+			}
+		}
+		s.lines = append(s.lines, haproxyLine{fields: fields, line: lineNo, text: scanner.Text()})
+	}
+	return sections, scanner.Err()
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseHAProxy(t *testing.T) {
+	path := writeTempFile(t, "haproxy.cfg", `global
+    log stdout format raw local0
+defaults
+    mode http
+frontend http-in
+    bind *:80
+    bind :443,:8443 ssl crt /etc/ssl/site.pem
+    bind quic4@:443 ssl crt /etc/ssl/site.pem
+    bind 127.0.0.1:8404
+    default_backend web
+backend web
+    server web1 web-1:8080 check
+    server web2 10.0.1.12:8080 check   # second node
+    server local 127.0.0.1:9000
+    server sock unix@/run/app.sock
+    server-template api 3 api.internal:8081 check
+listen mysql
+    bind :3306-3307
+    server db1 mysql-primary:3306 check
+    mode tcp
+resolvers dns
+    nameserver ns1 10.0.0.10:53
+`)
+	res, err := parseHAProxy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target, protocol, serviceType string
+		port, line                    int
+	}{
+		{"web-1", "TCP", "http", 8080, 12},
+		{"10.0.1.12", "TCP", "http", 8080, 13},
+		{"api.internal", "TCP", "http", 8081, 16},
+		{"mysql-primary", "TCP", "", 3306, 19}, // mode tcp, set after the server line
+		{"10.0.0.10", "UDP", "", 53, 22},
+	} {
+		d := findDep(res.Dependencies, tt.target, tt.port)
+		if d == nil {
+			t.Errorf("missing %s:%d in %v", tt.target, tt.port, res.Dependencies)
+			continue
+		}
+		if d.Protocol != tt.protocol || d.ServiceType != tt.serviceType || d.Line != tt.line {
+			t.Errorf("%s:%d = %s/%q line %d, want %s/%q line %d", tt.target, tt.port, d.Protocol, d.ServiceType, d.Line, tt.protocol, tt.serviceType, tt.line)
+		}
+	}
+	if len(res.Dependencies) != 5 {
+		t.Errorf("got %d deps, want 5: %v", len(res.Dependencies), res.Dependencies)
+	}
+	if d := findDep(res.Dependencies, "10.0.1.12", 8080); d != nil && d.EvidenceLine != "server web2 10.0.1.12:8080 check   # second node" {
+		t.Errorf("evidence = %q", d.EvidenceLine)
+	}
+
+	want := map[string]bool{"80/TCP": true, "443/TCP": true, "8443/TCP": true, "443/UDP": true, "3306/TCP": true, "3307/TCP": true}
+	for _, l := range res.Listeners {
+		key := fmt.Sprintf("%d/%s", l.Port, l.Protocol)
+		if !want[key] {
+			t.Errorf("unexpected listener %s: %+v", key, l)
+		}
+		delete(want, key)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing listeners %v in %v", want, res.Listeners)
+	}
+}
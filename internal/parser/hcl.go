@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hclBlock is one block from an HCL file, e.g. `resource "aws_db_instance"
+// "main" { ... }`. segspec only needs the block structure and the literal
+// attribute values, so this is a small line-oriented scanner rather than a
+// full HCL implementation: expressions are kept as raw text and only string,
+// number, bool and list-of-string literals are decoded.
+type hclBlock struct {
+	Type   string
+	Labels []string
+	Line   int
+	Header string // trimmed source line that opened the block
+	Attrs  map[string]hclAttr
+	Blocks []*hclBlock
+}
+
+// hclAttr is a `key = expr` attribute with its source position.
+type hclAttr struct {
+	Expr string // raw expression text, comments stripped
+	Line int
+	Text string // trimmed source line, used as evidence
+}
+
+var (
+	hclBlockOpen = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)((?:\s+(?:"[^"]*"|[A-Za-z_][A-Za-z0-9_-]*))*)\s*\{$`)
+	hclAttrLine  = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=\s*(.*)$`)
+	hclLabel     = regexp.MustCompile(`"([^"]*)"|([A-Za-z_][A-Za-z0-9_-]*)`)
+	hclHeredoc   = regexp.MustCompile(`<<-?([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+)
+
+// parseHCL scans HCL source into its top-level blocks. It never fails:
+// constructs it does not understand (one-line blocks, dynamic blocks with
+// unbalanced text) are skipped so a single odd file can't abort a walk.
+func parseHCL(data []byte) []*hclBlock {
+	lines := strings.Split(string(data), "\n")
+	root := &hclBlock{Attrs: map[string]hclAttr{}}
+	stack := []*hclBlock{root}
+	inComment := false
+
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		var line string
+		line, inComment = stripHCLComment(raw, inComment)
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cur := stack[len(stack)-1]
+
+		if line == "}" {
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		}
+		if m := hclBlockOpen.FindStringSubmatch(line); m != nil {
+			b := &hclBlock{
+				Type:   m[1],
+				Line:   i + 1,
+				Header: strings.TrimSpace(raw),
+				Attrs:  map[string]hclAttr{},
+			}
+			for _, lm := range hclLabel.FindAllStringSubmatch(m[2], -1) {
+				if lm[1] != "" || strings.HasPrefix(lm[0], `"`) {
+					b.Labels = append(b.Labels, lm[1])
+				} else {
+					b.Labels = append(b.Labels, lm[2])
+				}
+			}
+			cur.Blocks = append(cur.Blocks, b)
+			stack = append(stack, b)
+			continue
+		}
+		m := hclAttrLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		attr := hclAttr{Expr: strings.TrimSpace(m[2]), Line: i + 1, Text: strings.TrimSpace(raw)}
+
+		// Heredoc: swallow lines up to the terminator; the value is never
+		// a network endpoint we decode.
+		if hm := hclHeredoc.FindStringSubmatch(attr.Expr); hm != nil {
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != hm[1] {
+				i++
+			}
+			i++
+			cur.Attrs[m[1]] = attr
+			continue
+		}
+		// Multi-line list/map/call: keep reading until brackets balance.
+		for depth := hclDepth(attr.Expr); depth > 0 && i+1 < len(lines); {
+			i++
+			var next string
+			next, inComment = stripHCLComment(lines[i], inComment)
+			next = strings.TrimSpace(next)
+			attr.Expr += " " + next
+			depth += hclDepth(next)
+		}
+		cur.Attrs[m[1]] = attr
+	}
+	return root.Blocks
+}
+
+// stripHCLComment removes #, // and /* */ comments from one line, leaving
+// quoted strings intact. inComment carries an open /* across lines.
+func stripHCLComment(line string, inComment bool) (string, bool) {
+	var b strings.Builder
+	inStr := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if inComment {
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				inComment = false
+				i++
+			}
+			continue
+		}
+		if inStr {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(line) {
+				b.WriteByte(line[i+1])
+				i++
+			} else if c == '"' {
+				inStr = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inStr = true
+		case c == '#':
+			return b.String(), false
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return b.String(), false
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			inComment = true
+			i++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), inComment
+}
+
+// hclDepth returns the net bracket depth change of s, ignoring brackets
+// inside quoted strings.
+func hclDepth(s string) int {
+	depth := 0
+	inStr := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inStr {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inStr = true
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		}
+	}
+	return depth
+}
+
+// str returns the attribute as a string literal. Interpolations are left
+// in place; non-literal expressions (references, calls) return false.
+func (a hclAttr) str() (string, bool) {
+	e := a.Expr
+	if len(e) >= 2 && e[0] == '"' && e[len(e)-1] == '"' {
+		if s, err := strconv.Unquote(e); err == nil {
+			return s, true
+		}
+		return e[1 : len(e)-1], true
+	}
+	return "", false
+}
+
+// num returns the attribute as an integer literal (quoted or bare).
+func (a hclAttr) num() (int, bool) {
+	s := a.Expr
+	if v, ok := a.str(); ok {
+		s = v
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	return n, err == nil
+}
+
+// list returns the elements of a list expression. String elements are
+// unquoted; other elements (references) are returned as raw text.
+func (a hclAttr) list() []string {
+	e := strings.TrimSpace(a.Expr)
+	if !strings.HasPrefix(e, "[") || !strings.HasSuffix(e, "]") {
+		return nil
+	}
+	var out []string
+	for _, part := range splitHCLList(e[1 : len(e)-1]) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if s, ok := (hclAttr{Expr: part}).str(); ok {
+			part = s
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+// splitHCLList splits on top-level commas.
+func splitHCLList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	inStr := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inStr {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inStr = true
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// block returns the first nested block of the given type, or nil.
+func (b *hclBlock) block(typ string) *hclBlock {
+	for _, c := range b.Blocks {
+		if c.Type == typ {
+			return c
+		}
+	}
+	return nil
+}
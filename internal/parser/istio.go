@@ -0,0 +1,303 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+// Istio networking CRDs (networking.istio.io). ServiceEntry declares
+// mesh egress (and mesh-internal endpoints), VirtualService routes hosts to
+// destinations, DestinationRule sets per-port traffic policy. None of them
+// belong to a workload, so their deps carry no Source and the walker
+// attributes them to the analyzed service.
+
+// isIstioNetworking reports whether doc is a networking.istio.io resource.
+func isIstioNetworking(doc map[string]interface{}) bool {
+	apiVersion, _ := doc["apiVersion"].(string)
+	return strings.HasPrefix(apiVersion, "networking.istio.io/")
+}
+
+// parseIstio dispatches an Istio networking resource by kind.
+func parseIstio(doc map[string]interface{}, ix yamlIndex, path string) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	switch kind, _ := doc["kind"].(string); kind {
+	case "ServiceEntry":
+		deps = parseServiceEntry(doc, ix, path)
+	case "VirtualService":
+		deps = parseVirtualService(doc, ix, path)
+	case "DestinationRule":
+		deps = parseDestinationRule(doc, ix, path)
+	}
+	if disabled := metadataDisable(doc); disabled != "" {
+		for i := range deps {
+			deps[i].Disabled = disabled
+		}
+	}
+	return deps
+}
+
+// istioProtocol returns a port's protocol: the explicit `protocol` field,
+// else Istio's `<protocol>-<suffix>` port-naming convention, else TCP.
+func istioProtocol(port map[string]interface{}) string {
+	if p, _ := port["protocol"].(string); p != "" {
+		return strings.ToUpper(p)
+	}
+	name, _ := port["name"].(string)
+	prefix, _, _ := strings.Cut(strings.ToLower(name), "-")
+	switch prefix {
+	case "http", "http2", "https", "grpc", "tls", "tcp", "udp", "mongo", "mysql", "redis":
+		return strings.ToUpper(prefix)
+	}
+	return "TCP"
+}
+
+// istioTransport maps an Istio application protocol to the L4 protocol a
+// NetworkPolicy rule needs.
+func istioTransport(proto string) string {
+	if proto == "UDP" {
+		return "UDP"
+	}
+	return "TCP"
+}
+
+// istioServiceType maps an Istio application protocol to ServiceType.
+func istioServiceType(proto string) string {
+	switch proto {
+	case "HTTP", "HTTP2", "HTTPS", "GRPC-WEB":
+		return "http"
+	case "GRPC":
+		return "grpc"
+	case "TLS":
+		return "tls"
+	case "MONGO", "MYSQL":
+		return "database"
+	case "REDIS":
+		return "cache"
+	case "UDP":
+		return "udp"
+	}
+	return "tcp"
+}
+
+// parseServiceEntry emits one dep per host and port. With STATIC
+// resolution the listed endpoint addresses are the real peers, so each
+// endpoint gets a dep too, on its per-port override when one is set.
+func parseServiceEntry(doc map[string]interface{}, ix yamlIndex, path string) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	name := metadataName(doc)
+	spec, _ := navigateMap(doc, "spec")
+	location, _ := spec["location"].(string)
+	if location == "" {
+		location = "MESH_EXTERNAL"
+	}
+	resolution, _ := spec["resolution"].(string)
+	if resolution == "" {
+		resolution = "NONE"
+	}
+
+	ports := toSlice(spec["ports"])
+	for hi, h := range toSlice(spec["hosts"]) {
+		host, _ := h.(string)
+		if host == "" {
+			continue
+		}
+		line, col := ix.at("spec", "hosts", hi)
+		for _, p := range ports {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			port := toInt(pm["number"])
+			if port <= 0 {
+				continue
+			}
+			proto := istioProtocol(pm)
+			deps = append(deps, model.NetworkDependency{
+				Target:       host,
+				Port:         port,
+				Protocol:     istioTransport(proto),
+				Description:  fmt.Sprintf("ServiceEntry %s: %s %s:%d (%s, resolution %s)", name, proto, host, port, location, resolution),
+				Confidence:   model.High,
+				SourceFile:   path,
+				Line:         line,
+				Column:       col,
+				EvidenceLine: fmt.Sprintf("hosts: %s (port %d %s)", host, port, proto),
+				ServiceType:  istioServiceType(proto),
+			})
+		}
+	}
+
+	for ei, e := range toSlice(spec["endpoints"]) {
+		em, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		addr, _ := em["address"].(string)
+		if addr == "" || strings.HasPrefix(addr, "unix://") {
+			continue
+		}
+		overrides, _ := em["ports"].(map[string]interface{})
+		line, col := ix.at("spec", "endpoints", ei, "address")
+		for _, p := range ports {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			port := toInt(pm["number"])
+			portName, _ := pm["name"].(string)
+			if n := toInt(overrides[portName]); n > 0 {
+				port = n
+			} else if n := toInt(pm["targetPort"]); n > 0 {
+				port = n
+			}
+			if port <= 0 {
+				continue
+			}
+			proto := istioProtocol(pm)
+			deps = append(deps, model.NetworkDependency{
+				Target:       addr,
+				Port:         port,
+				Protocol:     istioTransport(proto),
+				Description:  fmt.Sprintf("ServiceEntry %s: %s endpoint %s:%d", name, proto, addr, port),
+				Confidence:   model.High,
+				SourceFile:   path,
+				Line:         line,
+				Column:       col,
+				EvidenceLine: fmt.Sprintf("address: %s", addr),
+				ServiceType:  istioServiceType(proto),
+			})
+		}
+	}
+	return deps
+}
+
+// parseVirtualService emits a dep for every route destination. The port
+// is the destination's, else the route's match port; destinations whose
+// port the resource doesn't state are skipped rather than emitted as
+// port-0 edges.
+func parseVirtualService(doc map[string]interface{}, ix yamlIndex, path string) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	name := metadataName(doc)
+	spec, _ := navigateMap(doc, "spec")
+
+	for _, section := range []string{"http", "tcp", "tls"} {
+		for ri, r := range toSlice(spec[section]) {
+			rm, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			matchPort := 0
+			for _, m := range toSlice(rm["match"]) {
+				if mm, ok := m.(map[string]interface{}); ok {
+					if p := toInt(mm["port"]); p > 0 {
+						matchPort = p
+						break
+					}
+				}
+			}
+			var dests []vsDestination
+			for di, d := range toSlice(rm["route"]) {
+				if dm, ok := d.(map[string]interface{}); ok {
+					if dest, ok := dm["destination"].(map[string]interface{}); ok {
+						dests = append(dests, vsDestination{dest, []interface{}{"spec", section, ri, "route", di, "destination", "host"}})
+					}
+				}
+			}
+			// http routes may also mirror traffic to a second destination.
+			if mirror, ok := rm["mirror"].(map[string]interface{}); ok {
+				dests = append(dests, vsDestination{mirror, []interface{}{"spec", section, ri, "mirror", "host"}})
+			}
+
+			for _, d := range dests {
+				host, _ := d.dest["host"].(string)
+				if host == "" {
+					continue
+				}
+				port := matchPort
+				if pm, ok := d.dest["port"].(map[string]interface{}); ok {
+					if n := toInt(pm["number"]); n > 0 {
+						port = n
+					}
+				}
+				if port <= 0 {
+					continue
+				}
+				line, col := ix.at(d.at...)
+				deps = append(deps, model.NetworkDependency{
+					Target:       host,
+					Port:         port,
+					Protocol:     "TCP",
+					Description:  fmt.Sprintf("VirtualService %s: %s route to %s:%d", name, section, host, port),
+					Confidence:   model.High,
+					SourceFile:   path,
+					Line:         line,
+					Column:       col,
+					EvidenceLine: fmt.Sprintf("destination: %s:%d", host, port),
+					ServiceType:  section,
+				})
+			}
+		}
+	}
+	return deps
+}
+
+// vsDestination is a VirtualService route destination and the index path
+// of its host, for evidence.
+type vsDestination struct {
+	dest map[string]interface{}
+	at   []interface{}
+}
+
+// parseDestinationRule emits a dep for each port-level traffic policy,
+// the only place a DestinationRule names a port. TLS origination
+// (SIMPLE/MUTUAL) marks the port as TLS.
+func parseDestinationRule(doc map[string]interface{}, ix yamlIndex, path string) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	name := metadataName(doc)
+	spec, _ := navigateMap(doc, "spec")
+	host, _ := spec["host"].(string)
+	if host == "" {
+		return nil
+	}
+	defaultTLS, _ := navigateMap(spec, "trafficPolicy", "tls")
+	for pi, p := range navigateSlice(spec, "trafficPolicy", "portLevelSettings") {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port := 0
+		if pn, ok := pm["port"].(map[string]interface{}); ok {
+			port = toInt(pn["number"])
+		}
+		if port <= 0 {
+			continue
+		}
+		tls := defaultTLS
+		if t, ok := pm["tls"].(map[string]interface{}); ok {
+			tls = t
+		}
+		mode, _ := tls["mode"].(string)
+		serviceType := "tcp"
+		desc := fmt.Sprintf("DestinationRule %s: traffic policy for %s:%d", name, host, port)
+		if mode == "SIMPLE" || mode == "MUTUAL" {
+			serviceType = "tls"
+			desc += fmt.Sprintf(" (TLS origination, %s)", mode)
+		}
+		line, col := ix.at("spec", "trafficPolicy", "portLevelSettings", pi, "port", "number")
+		deps = append(deps, model.NetworkDependency{
+			Target:       host,
+			Port:         port,
+			Protocol:     "TCP",
+			Description:  desc,
+			Confidence:   model.Medium,
+			SourceFile:   path,
+			Line:         line,
+			Column:       col,
+			EvidenceLine: fmt.Sprintf("host: %s port: %d", host, port),
+			ServiceType:  serviceType,
+		})
+	}
+	return deps
+}
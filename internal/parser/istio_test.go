@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func TestIstioServiceEntry(t *testing.T) {
+	manifest := `apiVersion: networking.istio.io/v1beta1
+kind: ServiceEntry
+metadata:
+  name: external-apis
+spec:
+  hosts:
+  - api.stripe.com
+  - hooks.slack.com
+  location: MESH_EXTERNAL
+  resolution: DNS
+  ports:
+  - number: 443
+    name: tls-egress
+    protocol: TLS
+  - number: 80
+    name: http-plain
+---
+apiVersion: networking.istio.io/v1beta1
+kind: ServiceEntry
+metadata:
+  name: statsd
+spec:
+  hosts:
+  - statsd.internal
+  resolution: STATIC
+  ports:
+  - number: 8125
+    name: udp-metrics
+  endpoints:
+  - address: 10.0.0.12
+    ports:
+      udp-metrics: 9125
+`
+	res, err := ParseK8sContent(manifest, "mesh.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "" && d.Target == "api.stripe.com" && d.Port == 443 &&
+			d.Protocol == "TCP" && d.ServiceType == "tls" && d.Line == 7
+	}, "TLS egress to api.stripe.com:443")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "hooks.slack.com" && d.Port == 80 && d.ServiceType == "http" && d.Line == 8
+	}, "protocol from the http- port-name prefix")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "statsd.internal" && d.Port == 8125 && d.Protocol == "UDP" && d.ServiceType == "udp"
+	}, "UDP ServiceEntry port")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "10.0.0.12" && d.Port == 9125 && d.Protocol == "UDP"
+	}, "STATIC endpoint with per-port override")
+	assertDepCount(t, deps, 6) // 2 hosts x 2 ports, the statsd host and its endpoint
+}
+
+func TestIstioVirtualServiceAndDestinationRule(t *testing.T) {
+	manifest := `apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews
+  http:
+  - route:
+    - destination:
+        host: reviews-v2
+        port:
+          number: 9080
+    mirror:
+      host: reviews-shadow
+      port:
+        number: 9080
+  - route:
+    - destination:
+        host: reviews-v1
+  tcp:
+  - match:
+    - port: 27017
+    route:
+    - destination:
+        host: mongo.backup.svc.cluster.local
+---
+apiVersion: networking.istio.io/v1beta1
+kind: DestinationRule
+metadata:
+  name: payments
+spec:
+  host: payments.example.com
+  trafficPolicy:
+    portLevelSettings:
+    - port:
+        number: 443
+      tls:
+        mode: SIMPLE
+`
+	res, err := ParseK8sContent(manifest, "routing.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "reviews-v2" && d.Port == 9080 && d.ServiceType == "http" && d.Line == 11
+	}, "http route destination")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "reviews-shadow" && d.Port == 9080
+	}, "mirror destination")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "mongo.backup.svc.cluster.local" && d.Port == 27017 && d.ServiceType == "tcp"
+	}, "tcp route falls back to the match port")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "payments.example.com" && d.Port == 443 && d.ServiceType == "tls" && d.Confidence == model.Medium
+	}, "DestinationRule TLS origination port")
+	for _, d := range deps {
+		if d.Target == "reviews-v1" {
+			t.Errorf("route without a port should be skipped, got %s", d.Key())
+		}
+	}
+}
@@ -14,8 +14,8 @@ import (
 )
 
 func init() {
-	defaultRegistry.Register("*.yaml", parseK8s)
-	defaultRegistry.Register("*.yml", parseK8s)
+	defaultRegistry.RegisterResults("k8s", "*.yaml", parseK8s)
+	defaultRegistry.RegisterResults("k8s", "*.yml", parseK8s)
 }
 
 // k8sMarker checks whether content looks like a Kubernetes manifest.
@@ -23,143 +23,214 @@ func k8sMarker(data []byte) bool {
 	return bytes.Contains(data, []byte("apiVersion:")) && bytes.Contains(data, []byte("kind:"))
 }
 
-func parseK8s(path string) ([]model.NetworkDependency, error) {
+func parseK8s(path string) (Result, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return Result{}, err
 	}
 
 	if !k8sMarker(data) {
-		return nil, nil
+		return Result{}, nil
 	}
 
 	return parseK8sBytes(data, path)
 }
 
 // ParseK8sContent parses K8s manifest YAML content (multi-document) and returns
-// discovered network dependencies. Used for parsing helm template output.
-// sourceLabel is used as the SourceFile in returned dependencies.
-func ParseK8sContent(content string, sourceLabel string) ([]model.NetworkDependency, error) {
+// discovered network dependencies and listeners. Used for parsing helm template output.
+// sourceLabel is used as the SourceFile in returned dependencies. Rendered
+// content doesn't go through the registry, so the result is stamped with
+// the k8s parser here.
+func ParseK8sContent(content string, sourceLabel string) (Result, error) {
 	data := []byte(content)
 
 	if !k8sMarker(data) {
-		return nil, nil
+		return Result{}, nil
 	}
 
-	return parseK8sBytes(data, sourceLabel)
+	res, err := parseK8sBytes(data, sourceLabel)
+	stampResult(&res, "k8s")
+	return res, err
 }
 
 // parseK8sBytes is the shared implementation for parsing K8s manifest bytes.
-func parseK8sBytes(data []byte, sourceLabel string) ([]model.NetworkDependency, error) {
-	var deps []model.NetworkDependency
+func parseK8sBytes(data []byte, sourceLabel string) (Result, error) {
+	var res Result
 
 	decoder := yaml.NewDecoder(bytes.NewReader(data))
 	for {
-		var doc map[string]interface{}
-		err := decoder.Decode(&doc)
-		if err != nil {
+		// Decode into a node first so every extractor can anchor its
+		// evidence to the real line; the map view is derived from it.
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
 			break // end of documents or parse error
 		}
-		if doc == nil {
+		var doc map[string]interface{}
+		if err := node.Decode(&doc); err != nil || doc == nil {
 			continue
 		}
+		ix := indexYAML(&node)
 
+		if podSpecPath(doc) != nil {
+			deps, listeners := parseWorkload(doc, ix, sourceLabel)
+			res.Dependencies = append(res.Dependencies, deps...)
+			res.Listeners = append(res.Listeners, listeners...)
+			continue
+		}
+		if isIstioNetworking(doc) {
+			res.Dependencies = append(res.Dependencies, parseIstio(doc, ix, sourceLabel)...)
+			continue
+		}
 		kind, _ := doc["kind"].(string)
 		switch kind {
-		case "Deployment", "StatefulSet":
-			deps = append(deps, parseWorkload(doc, sourceLabel)...)
 		case "Service":
-			deps = append(deps, parseService(doc, sourceLabel)...)
+			res.Listeners = append(res.Listeners, parseService(doc, ix, sourceLabel)...)
 		case "ConfigMap":
-			deps = append(deps, parseConfigMap(doc, sourceLabel)...)
+			res.Dependencies = append(res.Dependencies, parseConfigMap(doc, ix, sourceLabel)...)
+		}
+	}
+
+	return res, nil
+}
+
+// podSpecPath returns the path to a workload's pod spec, or nil when doc
+// isn't a workload. Every kind that runs pods is covered: the controllers
+// with a plain pod template, CronJob's job template, bare Pods, Argo
+// Rollouts and Knative Services (which share kind "Service" with the core
+// API and are told apart by apiVersion).
+func podSpecPath(doc map[string]interface{}) []string {
+	kind, _ := doc["kind"].(string)
+	apiVersion, _ := doc["apiVersion"].(string)
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		return []string{"spec", "template", "spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	case "Pod":
+		return []string{"spec"}
+	case "Rollout":
+		if strings.HasPrefix(apiVersion, "argoproj.io/") {
+			return []string{"spec", "template", "spec"}
+		}
+	case "Service":
+		if strings.HasPrefix(apiVersion, "serving.knative.dev/") {
+			return []string{"spec", "template", "spec"}
+		}
+	}
+	return nil
+}
+
+// podContainer is one container of a pod spec and its yamlIndex path.
+type podContainer struct {
+	spec map[string]interface{}
+	path []interface{}
+}
+
+// podContainers returns the init containers (native sidecars included)
+// and regular containers of a workload's pod spec.
+func podContainers(doc map[string]interface{}) []podContainer {
+	specPath := podSpecPath(doc)
+	if specPath == nil {
+		return nil
+	}
+	var out []podContainer
+	for _, field := range []string{"initContainers", "containers"} {
+		keys := append(append([]string{}, specPath...), field)
+		for ci, c := range navigateSlice(doc, keys...) {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := make([]interface{}, 0, len(keys)+1)
+			for _, k := range keys {
+				path = append(path, k)
+			}
+			out = append(out, podContainer{spec: container, path: append(path, ci)})
 		}
 	}
+	return out
+}
 
-	return deps, nil
+// workloadDisable returns the workload's `segspec.io/disable` directive,
+// read from its own metadata or, failing that, its pod template's.
+func workloadDisable(doc map[string]interface{}) string {
+	if d := metadataDisable(doc); d != "" {
+		return d
+	}
+	specPath := podSpecPath(doc)
+	if len(specPath) < 2 {
+		return "" // bare Pod: its metadata is the pod's
+	}
+	tmpl, ok := navigateMap(doc, specPath[:len(specPath)-1]...)
+	if !ok {
+		return ""
+	}
+	return metadataDisable(tmpl)
 }
 
-// parseWorkload extracts dependencies from Deployment or StatefulSet manifests.
-func parseWorkload(doc map[string]interface{}, path string) []model.NetworkDependency {
+// parseWorkload extracts dependencies and container port listeners from
+// any workload kind podSpecPath recognizes.
+func parseWorkload(doc map[string]interface{}, ix yamlIndex, path string) ([]model.NetworkDependency, []model.Listener) {
 	var deps []model.NetworkDependency
+	var listeners []model.Listener
 	workloadName := metadataName(doc)
-	disabled := metadataDisable(doc)
+	disabled := workloadDisable(doc)
 
-	containers := navigateSlice(doc, "spec", "template", "spec", "containers")
-	for _, c := range containers {
-		container, ok := c.(map[string]interface{})
-		if !ok {
-			continue
-		}
+	for _, pc := range podContainers(doc) {
+		cpath, container := pc.path, pc.spec
 
 		// Extract container ports (these are ports this workload exposes).
 		ports := toSlice(container["ports"])
-		for _, p := range ports {
+		for pi, p := range ports {
 			pm, ok := p.(map[string]interface{})
 			if !ok {
 				continue
 			}
 			port := toInt(pm["containerPort"])
+			proto, _ := pm["protocol"].(string)
+			name, _ := pm["name"].(string)
 			if port > 0 {
-				deps = append(deps, model.NetworkDependency{
-					Source:       workloadName,
-					Target:       workloadName,
+				line, col := ix.at(append(cpath, "ports", pi, "containerPort")...)
+				l := model.Listener{
+					Workload:     workloadName,
 					Port:         port,
-					Protocol:     "TCP",
+					Protocol:     specProtocol(proto),
+					Name:         name,
 					Description:  fmt.Sprintf("container port %d", port),
 					Confidence:   model.High,
 					SourceFile:   path,
+					Line:         line,
+					Column:       col,
 					EvidenceLine: fmt.Sprintf("containerPort: %d", port),
-				})
+				}
+				if toInt(pm["hostPort"]) > 0 {
+					l.Exposure = model.HostPort
+				}
+				listeners = append(listeners, l)
 			}
 		}
 
 		// Extract env vars and scan values for URLs/host:port/K8s DNS.
 		envVars := toSlice(container["env"])
-		for _, e := range envVars {
+		for ei, e := range envVars {
 			em, ok := e.(map[string]interface{})
 			if !ok {
 				continue
 			}
 			envName, _ := em["name"].(string)
 			value, _ := em["value"].(string)
+			epath := append(append([]interface{}{}, cpath...), "env", ei)
 
 			if value != "" {
-				deps = append(deps, extractDepsFromValue(value, workloadName, envName, model.High, path)...)
-			}
-
-			// Check for valueFrom referencing ConfigMaps or Secrets.
-			if vf, ok := em["valueFrom"].(map[string]interface{}); ok {
-				if cmRef, ok := vf["configMapKeyRef"].(map[string]interface{}); ok {
-					refName, _ := cmRef["name"].(string)
-					if refName != "" {
-						deps = append(deps, model.NetworkDependency{
-							Source:       workloadName,
-							Target:       refName,
-							Port:         0,
-							Protocol:     "TCP",
-							Description:  fmt.Sprintf("env %s references ConfigMap %s", envName, refName),
-							Confidence:   model.Medium,
-							SourceFile:   path,
-							EvidenceLine: fmt.Sprintf("valueFrom: configMapKeyRef/%s", refName),
-						})
-					}
-				}
-				if secRef, ok := vf["secretKeyRef"].(map[string]interface{}); ok {
-					refName, _ := secRef["name"].(string)
-					if refName != "" {
-						deps = append(deps, model.NetworkDependency{
-							Source:       workloadName,
-							Target:       refName,
-							Port:         0,
-							Protocol:     "TCP",
-							Description:  fmt.Sprintf("env %s references Secret %s", envName, refName),
-							Confidence:   model.Medium,
-							SourceFile:   path,
-							EvidenceLine: fmt.Sprintf("valueFrom: secretKeyRef/%s", refName),
-						})
-					}
+				found := extractDepsFromValue(value, workloadName, envName, model.High, path)
+				line, col := ix.at(append(epath, "value")...)
+				for i := range found {
+					found[i].Line, found[i].Column = line, col
 				}
+				deps = append(deps, found...)
 			}
+			// valueFrom ConfigMap/Secret references are resolved across
+			// documents by K8sRefIndex.
 		}
 	}
 
@@ -170,9 +241,12 @@ func parseWorkload(doc map[string]interface{}, path string) []model.NetworkDepen
 		for i := range deps {
 			deps[i].Disabled = disabled
 		}
+		for i := range listeners {
+			listeners[i].Disabled = disabled
+		}
 	}
 
-	return deps
+	return deps, listeners
 }
 
 // metadataDisable inspects a workload's metadata.labels and
@@ -201,42 +275,59 @@ func metadataDisable(doc map[string]interface{}) string {
 	return ""
 }
 
-// parseService extracts port information from a Service manifest.
-func parseService(doc map[string]interface{}, path string) []model.NetworkDependency {
-	var deps []model.NetworkDependency
+// parseService extracts the listeners a Service manifest declares, exposed
+// as its spec.type says (ClusterIP when unset). They are recorded on the
+// Service's own port; K8sRefIndex.LinkServices moves them onto the pod
+// port of the workloads the selector matches once the whole tree is read.
+func parseService(doc map[string]interface{}, ix yamlIndex, path string) []model.Listener {
+	var listeners []model.Listener
 	svcName := metadataName(doc)
+	exposure := model.ClusterIP
+	if spec, ok := navigateMap(doc, "spec"); ok {
+		if svcType, _ := spec["type"].(string); svcType == "NodePort" || svcType == "LoadBalancer" {
+			exposure = model.Exposure(svcType)
+		}
+	}
 
 	ports := navigateSlice(doc, "spec", "ports")
-	for _, p := range ports {
+	for pi, p := range ports {
 		pm, ok := p.(map[string]interface{})
 		if !ok {
 			continue
 		}
 		port := toInt(pm["port"])
 		targetPort := toInt(pm["targetPort"])
+		proto, _ := pm["protocol"].(string)
+		name, _ := pm["name"].(string)
 		if port > 0 {
 			desc := fmt.Sprintf("service port %d", port)
 			if targetPort > 0 && targetPort != port {
 				desc = fmt.Sprintf("service port %d -> targetPort %d", port, targetPort)
+			} else if named, _ := pm["targetPort"].(string); targetPort == 0 && named != "" {
+				desc = fmt.Sprintf("service port %d -> targetPort %s", port, named)
 			}
-			deps = append(deps, model.NetworkDependency{
-				Source:       svcName,
-				Target:       svcName,
+			line, col := ix.at("spec", "ports", pi, "port")
+			listeners = append(listeners, model.Listener{
+				Workload:     svcName,
 				Port:         port,
-				Protocol:     "TCP",
+				Protocol:     specProtocol(proto),
+				Name:         name,
+				Exposure:     exposure,
 				Description:  desc,
 				Confidence:   model.High,
 				SourceFile:   path,
+				Line:         line,
+				Column:       col,
 				EvidenceLine: fmt.Sprintf("port: %d", port),
 			})
 		}
 	}
 
-	return deps
+	return listeners
 }
 
 // parseConfigMap scans ConfigMap data values for URLs and host:port patterns.
-func parseConfigMap(doc map[string]interface{}, path string) []model.NetworkDependency {
+func parseConfigMap(doc map[string]interface{}, ix yamlIndex, path string) []model.NetworkDependency {
 	var deps []model.NetworkDependency
 	cmName := metadataName(doc)
 
@@ -251,8 +342,10 @@ func parseConfigMap(doc map[string]interface{}, path string) []model.NetworkDepe
 			continue
 		}
 		found := extractDepsFromValue(str, cmName, key, model.Medium, path)
+		line, col := ix.at("data", key)
 		for i := range found {
 			found[i].EvidenceLine = fmt.Sprintf("%s: %s", key, str)
+			found[i].Line, found[i].Column = line, col
 		}
 		deps = append(deps, found...)
 	}
@@ -341,7 +434,7 @@ func extractDepsFromValue(value, source, context string, confidence model.Confid
 	evidenceLine := fmt.Sprintf("%s=%s", context, value)
 
 	// Try parsing as URL first.
-	if u, err := url.Parse(value); err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "postgresql" || u.Scheme == "postgres" || u.Scheme == "redis" || u.Scheme == "amqp" || u.Scheme == "mongodb" || u.Scheme == "mysql" || u.Scheme == "kafka") {
+	if u, err := url.Parse(value); err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https" || u.Scheme == "postgresql" || u.Scheme == "postgres" || u.Scheme == "redis" || u.Scheme == "amqp" || u.Scheme == "mongodb" || u.Scheme == "mysql" || u.Scheme == "kafka" || schemeProtocols[u.Scheme] != "") {
 		host := u.Hostname()
 		port := 0
 		if u.Port() != "" {
@@ -351,7 +444,7 @@ func extractDepsFromValue(value, source, context string, confidence model.Confid
 			Source:       source,
 			Target:       host,
 			Port:         port,
-			Protocol:     "TCP",
+			Protocol:     inferProtocol(u.Scheme, port),
 			Description:  fmt.Sprintf("%s: URL %s", context, value),
 			Confidence:   confidence,
 			SourceFile:   path,
@@ -374,7 +467,7 @@ func extractDepsFromValue(value, source, context string, confidence model.Confid
 			Source:       source,
 			Target:       target,
 			Port:         port,
-			Protocol:     "TCP",
+			Protocol:     inferProtocol(urlScheme(value), port),
 			Description:  fmt.Sprintf("%s: K8s service DNS %s", context, value),
 			Confidence:   confidence,
 			SourceFile:   path,
@@ -396,7 +489,7 @@ func extractDepsFromValue(value, source, context string, confidence model.Confid
 				Source:       source,
 				Target:       host,
 				Port:         port,
-				Protocol:     "TCP",
+				Protocol:     inferProtocol(urlScheme(value), port),
 				Description:  fmt.Sprintf("%s: host:port %s:%d", context, host, port),
 				Confidence:   confidence,
 				SourceFile:   path,
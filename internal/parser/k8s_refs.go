@@ -0,0 +1,397 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// K8sRefIndex collects ConfigMap/Secret data and the workload env entries
+// that reference them (valueFrom.configMapKeyRef/secretKeyRef and
+// envFrom.configMapRef/secretRef), and the Services and pod templates a
+// selector links. A reference and its target usually live in different
+// files, so the walker feeds every manifest in the tree into one index
+// and calls Resolve and LinkServices once at the end.
+type K8sRefIndex struct {
+	sources   []k8sConfigSource
+	refs      []k8sEnvRef
+	services  []k8sService
+	workloads []k8sPodTemplate
+}
+
+// k8sConfigSource is one ConfigMap or Secret with its decoded entries.
+type k8sConfigSource struct {
+	kind      string // "ConfigMap" or "Secret"
+	name      string
+	namespace string
+	file      string
+	entries   map[string]k8sConfigEntry
+}
+
+type k8sConfigEntry struct {
+	value     string
+	line, col int
+}
+
+// k8sEnvRef is one env reference from a workload container. key is empty
+// for envFrom, which imports every key (with prefix prepended).
+type k8sEnvRef struct {
+	workload  string
+	namespace string
+	disabled  string
+	envName   string
+	prefix    string
+	kind      string
+	name      string
+	key       string
+	optional  bool
+	file      string
+	line, col int
+	evidence  string
+}
+
+// UnresolvedRef is a workload reference to a ConfigMap or Secret, or to a
+// key of one, that no indexed manifest provides. The value may come from
+// a manifest outside the tree or be created at deploy time, so its
+// dependencies are unknown.
+type UnresolvedRef struct {
+	File     string
+	Line     int
+	Workload string
+	Kind     string // "ConfigMap" or "Secret"
+	Name     string
+	Key      string // empty for envFrom
+}
+
+func (u UnresolvedRef) Error() string {
+	if u.Key == "" {
+		return fmt.Sprintf("line %d: %s imports %s %s, which is not in the scanned manifests", u.Line, u.Workload, u.Kind, u.Name)
+	}
+	return fmt.Sprintf("line %d: %s reads key %s of %s %s, which is not in the scanned manifests", u.Line, u.Workload, u.Key, u.Kind, u.Name)
+}
+
+// ConsumedEntry is a ConfigMap data entry that a workload reference
+// resolved: its dependencies belong to that workload.
+type ConsumedEntry struct {
+	Name string // the ConfigMap's name
+	File string
+	Line int
+}
+
+// NewK8sRefIndex returns an empty index.
+func NewK8sRefIndex() *K8sRefIndex {
+	return &K8sRefIndex{}
+}
+
+// Add indexes every document in a (multi-document) manifest. Content that
+// isn't Kubernetes YAML is ignored. sourceLabel is recorded as the file in
+// the evidence of resolved dependencies.
+func (x *K8sRefIndex) Add(data []byte, sourceLabel string) {
+	if !k8sMarker(data) {
+		return
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			break
+		}
+		var doc map[string]interface{}
+		if err := node.Decode(&doc); err != nil || doc == nil {
+			continue
+		}
+		ix := indexYAML(&node)
+		if podSpecPath(doc) != nil {
+			x.refs = append(x.refs, workloadEnvRefs(doc, ix, sourceLabel)...)
+			x.workloads = append(x.workloads, podTemplateOf(doc, sourceLabel))
+			continue
+		}
+		switch kind, _ := doc["kind"].(string); kind {
+		case "ConfigMap", "Secret":
+			x.sources = append(x.sources, configSource(kind, doc, ix, sourceLabel))
+		case "Service":
+			x.services = append(x.services, serviceOf(doc, sourceLabel))
+		}
+	}
+}
+
+// AddFile reads path and indexes it under path as the source label.
+func (x *K8sRefIndex) AddFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	x.Add(data, path)
+	return nil
+}
+
+// Resolve turns each indexed reference into the dependencies declared by
+// the value it points at. A resolved dependency belongs to the consuming
+// workload; its primary evidence is the reference in the workload and its
+// provenance chains through the ConfigMap/Secret entry that holds the
+// value. Secret values never appear in evidence or descriptions. Keys that
+// hold no network address yield nothing. References to ConfigMaps,
+// Secrets or keys that aren't in the index are returned as unresolved,
+// unless the reference is marked optional.
+func (x *K8sRefIndex) Resolve() ([]model.NetworkDependency, []UnresolvedRef) {
+	var deps []model.NetworkDependency
+	var unresolved []UnresolvedRef
+	for _, ref := range x.refs {
+		found := false
+		for _, src := range x.candidates(ref) {
+			keys := []string{ref.key}
+			if ref.key == "" {
+				keys = src.sortedKeys()
+			}
+			for _, key := range keys {
+				entry, ok := src.entries[key]
+				if !ok {
+					continue
+				}
+				found = true
+				deps = append(deps, resolveEnvRef(ref, src, key, entry)...)
+			}
+			if ref.key == "" {
+				found = true // an empty ConfigMap imports nothing
+			}
+		}
+		if !found && !ref.optional {
+			unresolved = append(unresolved, UnresolvedRef{
+				File: ref.file, Line: ref.line, Workload: ref.workload,
+				Kind: ref.kind, Name: ref.name, Key: ref.key,
+			})
+		}
+	}
+	return deps, unresolved
+}
+
+// Consumed returns the ConfigMap entries that workload references resolve
+// to. The k8s parser reads ConfigMap data on its own, as if the ConfigMap
+// were a workload; once an entry is resolved into its consumer, the
+// caller drops those deps.
+func (x *K8sRefIndex) Consumed() []ConsumedEntry {
+	var out []ConsumedEntry
+	seen := make(map[ConsumedEntry]bool)
+	for _, ref := range x.refs {
+		if ref.kind != "ConfigMap" {
+			continue
+		}
+		for _, src := range x.candidates(ref) {
+			keys := []string{ref.key}
+			if ref.key == "" {
+				keys = src.sortedKeys()
+			}
+			for _, key := range keys {
+				entry, ok := src.entries[key]
+				if !ok {
+					continue
+				}
+				e := ConsumedEntry{Name: src.name, File: src.file, Line: entry.line}
+				if !seen[e] {
+					seen[e] = true
+					out = append(out, e)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// candidates returns the ConfigMaps/Secrets a reference can resolve to:
+// same kind and name, in the workload's namespace (an unset namespace on
+// either side matches any). Sources from the workload's own file win, so
+// two overlays rendering identically-named ConfigMaps don't cross-resolve.
+func (x *K8sRefIndex) candidates(ref k8sEnvRef) []k8sConfigSource {
+	var same, other []k8sConfigSource
+	for _, src := range x.sources {
+		if src.kind != ref.kind || src.name != ref.name {
+			continue
+		}
+		if src.namespace != "" && ref.namespace != "" && src.namespace != ref.namespace {
+			continue
+		}
+		if src.file == ref.file {
+			same = append(same, src)
+		} else {
+			other = append(other, src)
+		}
+	}
+	if len(same) > 0 {
+		return same
+	}
+	return other
+}
+
+func resolveEnvRef(ref k8sEnvRef, src k8sConfigSource, key string, entry k8sConfigEntry) []model.NetworkDependency {
+	envName := ref.envName
+	confidence := model.High
+	if ref.key == "" {
+		// envFrom imports every key whether or not the app reads it.
+		envName = ref.prefix + key
+		confidence = model.Medium
+	}
+	found := extractDepsFromValue(entry.value, ref.workload, envName, confidence, ref.file)
+
+	secret := src.kind == "Secret"
+	entryEvidence := fmt.Sprintf("%s: %s", key, entry.value)
+	if secret {
+		entryEvidence = fmt.Sprintf("%s: [REDACTED]", key)
+	}
+	for i := range found {
+		d := &found[i]
+		if secret {
+			d.Description = fmt.Sprintf("%s: %s:%d (via Secret %s key %s)", envName, d.Target, d.Port, src.name, key)
+		} else {
+			d.Description = fmt.Sprintf("%s (via ConfigMap %s key %s)", d.Description, src.name, key)
+		}
+		d.Line, d.Column = ref.line, ref.col
+		d.EvidenceLine = ref.evidence
+		d.Disabled = ref.disabled
+		d.Parser = "k8s"
+		d.Provenance = []model.Provenance{
+			{File: ref.file, Line: ref.line, Column: ref.col, Evidence: ref.evidence, Parser: "k8s", Confidence: confidence},
+			{File: src.file, Line: entry.line, Column: entry.col, Evidence: entryEvidence, Parser: "k8s", Confidence: confidence},
+		}
+	}
+	return found
+}
+
+// workloadEnvRefs collects the ConfigMap/Secret references of every
+// container in a workload.
+func workloadEnvRefs(doc map[string]interface{}, ix yamlIndex, path string) []k8sEnvRef {
+	var refs []k8sEnvRef
+	base := k8sEnvRef{
+		workload:  metadataName(doc),
+		namespace: metadataNamespace(doc),
+		disabled:  workloadDisable(doc),
+		file:      path,
+	}
+
+	for _, pc := range podContainers(doc) {
+		cpath, container := pc.path, pc.spec
+
+		for ei, e := range toSlice(container["env"]) {
+			em, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			vf, ok := em["valueFrom"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			envName, _ := em["name"].(string)
+			for _, field := range []string{"configMapKeyRef", "secretKeyRef"} {
+				sel, ok := vf[field].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				ref := base
+				ref.envName, ref.kind = envName, "ConfigMap"
+				if field == "secretKeyRef" {
+					ref.kind = "Secret"
+				}
+				ref.name, _ = sel["name"].(string)
+				ref.key, _ = sel["key"].(string)
+				ref.optional, _ = sel["optional"].(bool)
+				if ref.name == "" || ref.key == "" {
+					continue
+				}
+				ref.line, ref.col = ix.at(append(cpath, "env", ei, "valueFrom", field)...)
+				ref.evidence = fmt.Sprintf("%s: %s %s/%s", envName, field, ref.name, ref.key)
+				refs = append(refs, ref)
+			}
+		}
+
+		for fi, f := range toSlice(container["envFrom"]) {
+			fm, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			prefix, _ := fm["prefix"].(string)
+			for _, field := range []string{"configMapRef", "secretRef"} {
+				sel, ok := fm[field].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				ref := base
+				ref.kind = "ConfigMap"
+				if field == "secretRef" {
+					ref.kind = "Secret"
+				}
+				ref.prefix = prefix
+				ref.name, _ = sel["name"].(string)
+				ref.optional, _ = sel["optional"].(bool)
+				if ref.name == "" {
+					continue
+				}
+				ref.line, ref.col = ix.at(append(cpath, "envFrom", fi, field)...)
+				ref.evidence = fmt.Sprintf("envFrom: %s %s", field, ref.name)
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// configSource reads a ConfigMap's data or a Secret's stringData and
+// base64-decoded data. Entries that aren't strings, or Secret data that
+// isn't valid base64, are skipped.
+func configSource(kind string, doc map[string]interface{}, ix yamlIndex, path string) k8sConfigSource {
+	src := k8sConfigSource{
+		kind:      kind,
+		name:      metadataName(doc),
+		namespace: metadataNamespace(doc),
+		file:      path,
+		entries:   make(map[string]k8sConfigEntry),
+	}
+	if data, ok := navigateMap(doc, "data"); ok {
+		for key, val := range data {
+			str, ok := val.(string)
+			if !ok {
+				continue
+			}
+			if kind == "Secret" {
+				decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(str))
+				if err != nil {
+					continue
+				}
+				str = string(decoded)
+			}
+			line, col := ix.at("data", key)
+			src.entries[key] = k8sConfigEntry{value: str, line: line, col: col}
+		}
+	}
+	if kind == "Secret" {
+		// stringData wins over data for the same key, as in the API server.
+		if data, ok := navigateMap(doc, "stringData"); ok {
+			for key, val := range data {
+				if str, ok := val.(string); ok {
+					line, col := ix.at("stringData", key)
+					src.entries[key] = k8sConfigEntry{value: str, line: line, col: col}
+				}
+			}
+		}
+	}
+	return src
+}
+
+func (s k8sConfigSource) sortedKeys() []string {
+	keys := make([]string, 0, len(s.entries))
+	for k := range s.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func metadataNamespace(doc map[string]interface{}) string {
+	meta, _ := doc["metadata"].(map[string]interface{})
+	ns, _ := meta["namespace"].(string)
+	return ns
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+const refWorkload = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: orders
+  namespace: shop
+spec:
+  template:
+    spec:
+      containers:
+      - name: orders
+        env:
+        - name: DATABASE_URL
+          valueFrom:
+            configMapKeyRef:
+              name: orders-config
+              key: db.url
+        - name: CACHE_ADDR
+          valueFrom:
+            secretKeyRef:
+              name: orders-secrets
+              key: cache
+        - name: MISSING
+          valueFrom:
+            configMapKeyRef:
+              name: not-in-tree
+              key: url
+        envFrom:
+        - configMapRef:
+            name: orders-config
+          prefix: CFG_
+`
+
+const refConfig = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: orders-config
+  namespace: shop
+data:
+  db.url: postgresql://orders-db:5432/orders
+  kafka: kafka-0:9092
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: orders-secrets
+  namespace: shop
+data:
+  cache: cmVkaXM6Ly86aHVudGVyMkByZWRpcy1tYWluOjYzNzk=
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: orders-config
+  namespace: other
+data:
+  kafka: wrong-namespace:9092
+`
+
+func TestK8sRefIndexResolvesAcrossFiles(t *testing.T) {
+	x := NewK8sRefIndex()
+	x.Add([]byte(refWorkload), "deploy.yaml")
+	x.Add([]byte(refConfig), "config.yaml")
+	deps, unresolved := x.Resolve()
+
+	// configMapKeyRef: owned by the workload, evidence chained through both files.
+	db := findK8sDep(t, deps, "orders-db", 5432)
+	if db.Source != "orders" || db.Confidence != model.High {
+		t.Errorf("db dep = %+v", db)
+	}
+	if db.Location() != "deploy.yaml:14" {
+		t.Errorf("db primary evidence at %s, want deploy.yaml:14", db.Location())
+	}
+	if len(db.Provenance) != 2 || db.Provenance[1].Location() != "config.yaml:7" {
+		t.Errorf("db provenance = %+v, want the workload ref and config.yaml:7", db.Provenance)
+	}
+
+	// secretKeyRef: base64 data decoded, but the value never surfaces.
+	cache := findK8sDep(t, deps, "redis-main", 6379)
+	for _, text := range []string{cache.Description, cache.EvidenceLine, cache.Provenance[1].Evidence} {
+		if strings.Contains(text, "hunter2") || strings.Contains(text, "redis://") {
+			t.Errorf("secret value leaked: %q", text)
+		}
+	}
+
+	// envFrom imports every key, prefixed, at medium confidence.
+	kafka := findK8sDep(t, deps, "kafka-0", 9092)
+	if kafka.Confidence != model.Medium || !strings.HasPrefix(kafka.Description, "CFG_kafka") {
+		t.Errorf("envFrom dep = %+v", kafka)
+	}
+
+	for _, d := range deps {
+		if d.Port == 0 || d.Target == "not-in-tree" || d.Target == "wrong-namespace" {
+			t.Errorf("unexpected dep %s", d.Key())
+		}
+	}
+
+	// The reference to a ConfigMap outside the tree is reported, not dropped.
+	if len(unresolved) != 1 || unresolved[0].Name != "not-in-tree" || unresolved[0].Key != "url" || unresolved[0].Line != 24 {
+		t.Errorf("unresolved = %+v, want not-in-tree/url at line 24", unresolved)
+	}
+	if got := x.Consumed(); len(got) != 2 || got[0] != (ConsumedEntry{Name: "orders-config", File: "config.yaml", Line: 7}) {
+		t.Errorf("consumed = %+v, want both orders-config entries in shop", got)
+	}
+}
+
+func findK8sDep(t *testing.T, deps []model.NetworkDependency, target string, port int) model.NetworkDependency {
+	t.Helper()
+	for _, d := range deps {
+		if d.Target == target && d.Port == port {
+			return d
+		}
+	}
+	t.Fatalf("missing dep on %s:%d in %v", target, port, deps)
+	return model.NetworkDependency{}
+}
@@ -0,0 +1,375 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+// k8sService is a Service, the pods it selects, the ports it forwards
+// and the cluster addresses it is reachable at.
+type k8sService struct {
+	name      string
+	namespace string
+	file      string
+	selector  map[string]string
+	ports     []k8sServicePort
+	addresses []string
+}
+
+// k8sServicePort is one Service port. target is the targetPort as
+// written: a number, the name of a container port, or "" for the same
+// number as port.
+type k8sServicePort struct {
+	port     int
+	protocol string
+	target   string
+}
+
+// k8sPodTemplate is a workload's pod labels and the container ports its
+// pods declare.
+type k8sPodTemplate struct {
+	name      string
+	namespace string
+	file      string
+	labels    map[string]string
+	ports     []k8sContainerPort
+}
+
+type k8sContainerPort struct {
+	name     string
+	port     int
+	protocol string
+}
+
+// serviceOf reads a core Service's selector, ports and addresses
+// (clusterIP(s), externalIPs, loadBalancerIP). Services without a
+// selector (ExternalName, manually managed Endpoints) back onto no
+// workload in the tree.
+func serviceOf(doc map[string]interface{}, path string) k8sService {
+	svc := k8sService{
+		name:      metadataName(doc),
+		namespace: metadataNamespace(doc),
+		file:      path,
+		selector:  stringMap(navigateMapValue(doc, "spec", "selector")),
+	}
+	spec, _ := navigateMap(doc, "spec")
+	addrs := append([]interface{}{spec["clusterIP"], spec["loadBalancerIP"]}, toSlice(spec["clusterIPs"])...)
+	for _, a := range append(addrs, toSlice(spec["externalIPs"])...) {
+		if ip, _ := a.(string); ip != "" && ip != "None" && !containsString(svc.addresses, ip) {
+			svc.addresses = append(svc.addresses, ip)
+		}
+	}
+	for _, p := range navigateSlice(doc, "spec", "ports") {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port := toInt(pm["port"])
+		if port <= 0 {
+			continue
+		}
+		proto, _ := pm["protocol"].(string)
+		var target string
+		switch t := pm["targetPort"].(type) {
+		case string:
+			target = strings.TrimSpace(t)
+		case nil:
+		default:
+			if n := toInt(t); n > 0 {
+				target = strconv.Itoa(n)
+			}
+		}
+		svc.ports = append(svc.ports, k8sServicePort{port: port, protocol: specProtocol(proto), target: target})
+	}
+	return svc
+}
+
+// podTemplateOf reads the pod labels and container ports of a workload.
+func podTemplateOf(doc map[string]interface{}, path string) k8sPodTemplate {
+	pt := k8sPodTemplate{
+		name:      metadataName(doc),
+		namespace: metadataNamespace(doc),
+		file:      path,
+	}
+	specPath := podSpecPath(doc)
+	if len(specPath) < 2 {
+		pt.labels = stringMap(navigateMapValue(doc, "metadata", "labels")) // bare Pod
+	} else {
+		tmpl := append(append([]string{}, specPath[:len(specPath)-1]...), "metadata", "labels")
+		pt.labels = stringMap(navigateMapValue(doc, tmpl...))
+	}
+	for _, pc := range podContainers(doc) {
+		for _, p := range toSlice(pc.spec["ports"]) {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := pm["name"].(string)
+			proto, _ := pm["protocol"].(string)
+			if port := toInt(pm["containerPort"]); port > 0 {
+				pt.ports = append(pt.ports, k8sContainerPort{name: name, port: port, protocol: specProtocol(proto)})
+			}
+		}
+	}
+	return pt
+}
+
+// selects reports whether the Service's selector matches the workload's
+// pod labels in a compatible namespace (an unset namespace on either side
+// matches any).
+func (s k8sService) selects(pt k8sPodTemplate) bool {
+	if len(s.selector) == 0 {
+		return false
+	}
+	if s.namespace != "" && pt.namespace != "" && s.namespace != pt.namespace {
+		return false
+	}
+	for k, v := range s.selector {
+		if pt.labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// podPort returns the container port a Service port forwards to on the
+// workload, and that port's name: a numeric targetPort as is, a named one
+// looked up among the workload's container ports. The name is empty when
+// the workload doesn't name the port. ok is false when a named targetPort
+// isn't declared.
+func (sp k8sServicePort) podPort(pt k8sPodTemplate) (port int, name string, ok bool) {
+	if sp.target == "" {
+		return sp.port, pt.portName(sp.port, sp.protocol), true
+	}
+	if n, err := strconv.Atoi(sp.target); err == nil {
+		return n, pt.portName(n, sp.protocol), n > 0
+	}
+	for _, cp := range pt.ports {
+		if cp.name == sp.target && cp.protocol == sp.protocol {
+			return cp.port, cp.name, true
+		}
+	}
+	return 0, "", false
+}
+
+// portName returns the name the workload gives a container port, or "".
+func (pt k8sPodTemplate) portName(port int, protocol string) string {
+	for _, cp := range pt.ports {
+		if cp.port == port && cp.protocol == protocol {
+			return cp.name
+		}
+	}
+	return ""
+}
+
+// backends returns the workloads a Service selects. Workloads from the
+// Service's own file win, so two overlays rendering the same names don't
+// cross-link.
+func (x *K8sRefIndex) backends(svc k8sService) []k8sPodTemplate {
+	var same, other []k8sPodTemplate
+	for _, pt := range x.workloads {
+		if !svc.selects(pt) {
+			continue
+		}
+		if pt.file == svc.file {
+			same = append(same, pt)
+		} else {
+			other = append(other, pt)
+		}
+	}
+	if len(same) > 0 {
+		return same
+	}
+	return other
+}
+
+// serviceLink is a Service port resolved to the pod port of one backing
+// workload.
+type serviceLink struct {
+	svc      k8sService
+	port     k8sServicePort
+	workload string
+	podPort  int
+	portName string // the container port's name, if it has one
+}
+
+// LinkServices moves what the set records against a Service onto the
+// workloads behind it, since NetworkPolicies select pods and match the
+// pod's port, not the Service's. Each Service port listener becomes a
+// listener of every workload the selector matches, on the container port
+// its targetPort names (by number or by port name), keeping the
+// Service's exposure. Dependencies on a Service port (addressed by the
+// Service's name, its <name>.<namespace>[.svc[.cluster.local]] DNS name
+// or one of its addresses) are retargeted the same way; they cite the
+// Service port in their provenance and keep the spelling they used as an
+// alias. Compose dependencies are left alone: compose resolves names
+// among its own services. Services with no backing workload in the tree,
+// or whose named targetPort a workload doesn't declare, stay as they
+// were.
+func (x *K8sRefIndex) LinkServices(ds *model.DependencySet) {
+	links := make(map[string][]serviceLink) // service name -> links
+	byAddress := make(map[string]string)    // ClusterIP etc. -> service name
+	for _, svc := range x.services {
+		for _, addr := range svc.addresses {
+			byAddress[addr] = svc.name
+		}
+		for _, pt := range x.backends(svc) {
+			for _, sp := range svc.ports {
+				if podPort, name, ok := sp.podPort(pt); ok {
+					links[svc.name] = append(links[svc.name], serviceLink{svc: svc, port: sp, workload: pt.name, podPort: podPort, portName: name})
+				}
+			}
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+
+	// The Service port listeners are the evidence for the link; they've
+	// already been positioned (Helm lines remapped, Kustomize dropped).
+	declared := make(map[string]model.Listener)
+	for _, l := range ds.Listeners() {
+		declared[l.Key()+"@"+l.SourceFile] = l
+	}
+	evidence := func(link serviceLink) (model.Listener, bool) {
+		l, ok := declared[fmt.Sprintf("%s:%d/%s@%s", link.svc.name, link.port.port, link.port.protocol, link.svc.file)]
+		return l, ok
+	}
+
+	ds.Rewrite(func(d model.NetworkDependency) []model.NetworkDependency {
+		if d.Parser == "compose" {
+			return []model.NetworkDependency{d}
+		}
+		name, ns := serviceHost(d.Target)
+		if svc, ok := byAddress[d.Target]; ok {
+			name, ns = svc, ""
+		}
+		var out []model.NetworkDependency
+		for _, link := range links[name] {
+			if ns != "" && link.svc.namespace != "" && ns != link.svc.namespace {
+				continue
+			}
+			if d.Port != 0 && (d.Port != link.port.port || d.Protocol != link.port.protocol) {
+				continue
+			}
+			nd := d
+			nd.Target = link.workload
+			nd.Aliases = append([]string{}, d.Aliases...)
+			if d.Target != link.workload && !containsString(nd.Aliases, d.Target) {
+				nd.Aliases = append(nd.Aliases, d.Target)
+				sort.Strings(nd.Aliases)
+			}
+			if d.Port != 0 {
+				nd.Port = link.podPort
+			}
+			nd.Description = fmt.Sprintf("%s (via Service %s)", d.Description, link.svc.name)
+			nd.Provenance = append([]model.Provenance{}, d.Provenance...)
+			if l, ok := evidence(link); ok {
+				nd.Provenance = append(nd.Provenance, model.Provenance{
+					File:       l.SourceFile,
+					Line:       l.Line,
+					Column:     l.Column,
+					Evidence:   l.EvidenceLine,
+					Parser:     "k8s",
+					Confidence: d.Confidence,
+				})
+			}
+			out = append(out, nd)
+		}
+		if len(out) == 0 {
+			return []model.NetworkDependency{d}
+		}
+		return out
+	}, func(l model.Listener) []model.Listener {
+		var out []model.Listener
+		for _, link := range links[l.Workload] {
+			if l.Port != link.port.port || l.Protocol != link.port.protocol || l.SourceFile != link.svc.file {
+				continue
+			}
+			nl := l
+			nl.Workload = link.workload
+			nl.Port = link.podPort
+			nl.Name = link.portName
+			nl.Description = fmt.Sprintf("Service %s port %d -> %s:%d", link.svc.name, link.port.port, link.workload, link.podPort)
+			if link.port.target != "" && link.port.target != strconv.Itoa(link.podPort) {
+				nl.Description = fmt.Sprintf("Service %s port %d -> %s:%s (%d)", link.svc.name, link.port.port, link.workload, link.port.target, link.podPort)
+			}
+			out = append(out, nl)
+		}
+		if len(out) == 0 {
+			return []model.Listener{l}
+		}
+		return out
+	})
+}
+
+// Entities returns the workloads in the index and the names they are
+// reached by: a Service that selects exactly one workload, and its
+// addresses, are aliases of that workload. A Service in front of several
+// workloads, or of none in the tree, is an entity of its own.
+func (x *K8sRefIndex) Entities() []model.Entity {
+	var entities []model.Entity
+	for _, pt := range x.workloads {
+		entities = append(entities, model.Entity{Name: pt.name, Namespace: pt.namespace})
+	}
+	for _, svc := range x.services {
+		if backends := x.backends(svc); len(backends) == 1 {
+			aliases := append([]string{svc.name}, svc.addresses...)
+			if svc.namespace != "" && svc.namespace != backends[0].namespace {
+				aliases = append(aliases, svc.name+"."+svc.namespace)
+			}
+			entities = append(entities, model.Entity{Name: backends[0].name, Namespace: backends[0].namespace, Aliases: aliases})
+			continue
+		}
+		entities = append(entities, model.Entity{Name: svc.name, Namespace: svc.namespace, Aliases: svc.addresses})
+	}
+	return entities
+}
+
+// serviceHost splits a target into a Service name and namespace when it
+// is spelled as Kubernetes DNS resolves one: name, name.namespace,
+// name.namespace.svc or name.namespace.svc.cluster.local.
+func serviceHost(target string) (name, namespace string) {
+	parts := strings.Split(strings.TrimSuffix(target, ".svc.cluster.local"), ".")
+	switch {
+	case len(parts) == 1:
+		return parts[0], ""
+	case len(parts) == 2:
+		return parts[0], parts[1]
+	case len(parts) == 3 && parts[2] == "svc":
+		return parts[0], parts[1]
+	}
+	return "", ""
+}
+
+// navigateMapValue returns the value at keys, or nil.
+func navigateMapValue(doc map[string]interface{}, keys ...string) interface{} {
+	if len(keys) == 0 {
+		return nil
+	}
+	m, ok := navigateMap(doc, keys[:len(keys)-1]...)
+	if !ok {
+		return nil
+	}
+	return m[keys[len(keys)-1]]
+}
+
+// stringMap returns a YAML mapping of scalars (labels, a selector) with
+// its values as strings.
+func stringMap(v interface{}) map[string]string {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, val := range m {
+		if val != nil {
+			out[k] = fmt.Sprint(val)
+		}
+	}
+	return out
+}
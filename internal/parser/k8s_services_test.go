@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+const servicedWorkloads = `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: redis-master
+  namespace: shop
+spec:
+  template:
+    metadata:
+      labels:
+        app: redis
+        role: master
+    spec:
+      containers:
+      - name: redis
+        ports:
+        - name: redis
+          containerPort: 6380
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+  namespace: shop
+spec:
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        env:
+        - name: CACHE_URL
+          value: redis://cache.shop.svc.cluster.local:6379
+        - name: CACHE_ADMIN
+          value: cache:9999
+`
+
+const services = `apiVersion: v1
+kind: Service
+metadata:
+  name: cache
+  namespace: shop
+spec:
+  selector:
+    app: redis
+    role: master
+  ports:
+  - port: 6379
+    targetPort: redis
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: www
+  namespace: shop
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+  ports:
+  - name: http
+    port: 80
+    targetPort: 8080
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: external-db
+spec:
+  type: ExternalName
+  externalName: db.example.com
+  ports:
+  - port: 5432
+`
+
+func TestK8sRefIndexLinkServices(t *testing.T) {
+	x := NewK8sRefIndex()
+	x.Add([]byte(servicedWorkloads), "workloads.yaml")
+	x.Add([]byte(services), "services.yaml")
+
+	ds := model.NewDependencySet("shop")
+	for _, doc := range []struct{ content, label string }{{servicedWorkloads, "workloads.yaml"}, {services, "services.yaml"}} {
+		res, err := ParseK8sContent(doc.content, doc.label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range res.Dependencies {
+			ds.Add(d)
+		}
+		for _, l := range res.Listeners {
+			ds.AddListener(l)
+		}
+	}
+	// compose resolves names among its own services, never through a
+	// Kubernetes Service.
+	ds.Add(model.NetworkDependency{Source: "worker", Target: "cache", Port: 6379, Protocol: "TCP", Parser: "compose"})
+
+	x.LinkServices(ds)
+
+	// The DNS name of the Service port becomes the pod's named port.
+	var linked *model.NetworkDependency
+	for _, d := range ds.Dependencies() {
+		if d.Source == "frontend" && d.Target == "redis-master" && d.Port == 6380 {
+			linked = &d
+		}
+		if d.Source == "frontend" && strings.HasPrefix(d.Target, "cache.shop") {
+			t.Errorf("dep still on the Service: %s", d.Key())
+		}
+	}
+	if linked == nil {
+		t.Fatalf("frontend -> cache:6379 not linked to redis-master:6380; got %v", ds.Dependencies())
+	}
+	if !strings.Contains(linked.Description, "via Service cache") {
+		t.Errorf("description = %q", linked.Description)
+	}
+	cited := false
+	for _, p := range linked.Provenance {
+		cited = cited || p.Location() == "services.yaml:11"
+	}
+	if len(linked.Provenance) != 2 || !cited {
+		t.Errorf("provenance = %+v, want the env value and services.yaml:11", linked.Provenance)
+	}
+
+	// A port the Service doesn't forward, and compose deps, stay put.
+	for _, key := range []string{"frontend->cache:9999/TCP", "worker->cache:6379/TCP"} {
+		found := false
+		for _, d := range ds.Dependencies() {
+			found = found || d.Key() == key
+		}
+		if !found {
+			t.Errorf("missing unlinked dep %s", key)
+		}
+	}
+
+	// Service listeners land on the pod port, with the Service's exposure.
+	ls := ds.Listeners()
+	if l := findListener(ls, "redis-master", 6380); l == nil || l.Exposure != model.ClusterIP || l.Name != "redis" {
+		t.Errorf("redis-master listener = %+v", l)
+	}
+	// The name is the container port's; frontend doesn't name 8080, and
+	// the Service's own port name (http) is not the pod's.
+	if l := findListener(ls, "frontend", 8080); l == nil || l.Exposure != model.LoadBalancer || l.Name != "" {
+		t.Errorf("frontend listener = %+v", l)
+	}
+	for _, l := range ls {
+		if l.Workload == "cache" || l.Workload == "www" {
+			t.Errorf("listener still on the Service: %s", l.Key())
+		}
+	}
+	// A Service without a selector has no workload to move to.
+	if findListener(ls, "external-db", 5432) == nil {
+		t.Error("selectorless Service listener dropped")
+	}
+}
+
+func TestK8sRefIndexLinkServicesSameName(t *testing.T) {
+	const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: redis-cart
+spec:
+  template:
+    metadata:
+      labels:
+        app: redis-cart
+    spec:
+      containers:
+      - name: redis
+        ports:
+        - name: redis
+          containerPort: 6379
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: redis-cart
+spec:
+  selector:
+    app: redis-cart
+  ports:
+  - name: tcp-redis
+    port: 6379
+    targetPort: 6379
+`
+	x := NewK8sRefIndex()
+	x.Add([]byte(manifests), "redis.yaml")
+	ds := model.NewDependencySet("shop")
+	ds.Add(model.NetworkDependency{Source: "cart", Target: "redis-cart", Port: 6379, Protocol: "TCP", Parser: "k8s"})
+	res, err := ParseK8sContent(manifests, "redis.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range res.Listeners {
+		ds.AddListener(l)
+	}
+
+	x.LinkServices(ds)
+
+	deps := ds.Dependencies()
+	if len(deps) != 1 || deps[0].Target != "redis-cart" || len(deps[0].Aliases) != 0 {
+		t.Errorf("deps = %+v, want redis-cart with no alias of itself", deps)
+	}
+	if l := findListener(ds.Listeners(), "redis-cart", 6379); l == nil || l.Name != "redis" {
+		t.Errorf("listener = %+v, want the container port name redis", l)
+	}
+}
+
+func TestK8sRefIndexEntities(t *testing.T) {
+	x := NewK8sRefIndex()
+	x.Add([]byte(servicedWorkloads), "workloads.yaml")
+	x.Add([]byte(services+`---
+apiVersion: v1
+kind: Service
+metadata:
+  name: cache-replicas
+  namespace: shop
+spec:
+  clusterIP: 10.0.0.4
+  selector:
+    app: redis
+  ports:
+  - port: 6379
+`), "services.yaml")
+
+	got := x.Entities()
+	want := []model.Entity{
+		{Name: "redis-master", Namespace: "shop"},
+		{Name: "frontend", Namespace: "shop"},
+		{Name: "redis-master", Namespace: "shop", Aliases: []string{"cache"}},
+		{Name: "frontend", Namespace: "shop", Aliases: []string{"www"}},
+		{Name: "external-db"},
+		{Name: "redis-master", Namespace: "shop", Aliases: []string{"cache-replicas", "10.0.0.4"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entities = %+v\nwant %+v", got, want)
+	}
+}
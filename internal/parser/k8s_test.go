@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -52,29 +53,29 @@ spec:
               key: password
 `
 	path := writeTempFile(t, "deployment.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	// Expect:
-	// 2 container ports (8080, 9090)
 	// 1 URL dep (postgresql://...)
 	// 1 host:port dep (redis-master:6379)
 	// 1 URL dep (http://payment-service:8080/api)
-	// 1 configMapKeyRef (order-config)
-	// 1 secretKeyRef (db-credentials)
-	// Total: 7
+	// configMapKeyRef/secretKeyRef are left to K8sRefIndex.
+	// Total: 3, plus 2 container port listeners (8080, 9090)
 
-	assertDepCount(t, deps, 7)
-
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 8080 && d.Target == "order-service" && d.Confidence == model.High
-	}, "container port 8080")
-
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 9090 && d.Target == "order-service" && d.Confidence == model.High
-	}, "container port 9090")
+	assertDepCount(t, deps, 3)
+	if len(res.Listeners) != 2 {
+		t.Fatalf("expected 2 listeners, got %+v", res.Listeners)
+	}
+	for _, port := range []int{8080, 9090} {
+		l := findListener(res.Listeners, "order-service", port)
+		if l == nil || l.Confidence != model.High || l.Exposure != "" {
+			t.Errorf("expected container port listener %d with no exposure, got %+v", port, l)
+		}
+	}
 
 	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
 		return d.Target == "db-primary.prod.svc.cluster.local" && d.Port == 5432 && d.Confidence == model.High
@@ -88,13 +89,6 @@ spec:
 		return d.Target == "payment-service" && d.Port == 8080 && d.Confidence == model.High
 	}, "payment-service URL dep")
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Target == "order-config" && d.Confidence == model.Medium
-	}, "configMapKeyRef dep")
-
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Target == "db-credentials" && d.Confidence == model.Medium
-	}, "secretKeyRef dep")
 }
 
 func TestK8sServicePorts(t *testing.T) {
@@ -113,20 +107,155 @@ spec:
     targetPort: 8443
 `
 	path := writeTempFile(t, "service.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	assertDepCount(t, deps, 2)
+	assertDepCount(t, res.Dependencies, 0)
+	if len(res.Listeners) != 2 {
+		t.Fatalf("expected 2 listeners, got %+v", res.Listeners)
+	}
+	if l := findListener(res.Listeners, "order-service", 80); l == nil || l.Confidence != model.High || l.Exposure != model.ClusterIP {
+		t.Errorf("expected ClusterIP service port 80, got %+v", l)
+	}
+	if l := findListener(res.Listeners, "order-service", 443); l == nil {
+		t.Error("expected service port 443")
+	}
+}
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 80 && d.Source == "order-service" && d.Confidence == model.High
-	}, "service port 80")
+func TestK8sListenerExposure(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: LoadBalancer
+  ports:
+  - name: https
+    port: 443
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: admin
+spec:
+  type: NodePort
+  ports:
+  - port: 9000
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  annotations:
+    segspec.io/disable: ingress
+spec:
+  template:
+    spec:
+      containers:
+      - name: agent
+        ports:
+        - name: metrics
+          containerPort: 9100
+          hostPort: 9100
+`
+	res, err := parseK8s(writeTempFile(t, "exposure.yaml", manifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []struct {
+		workload, name string
+		port           int
+		exposure       model.Exposure
+	}{
+		{"web", "https", 443, model.LoadBalancer},
+		{"admin", "", 9000, model.NodePort},
+		{"agent", "metrics", 9100, model.HostPort},
+	} {
+		l := findListener(res.Listeners, want.workload, want.port)
+		if l == nil || l.Name != want.name || l.Exposure != want.exposure {
+			t.Errorf("%s:%d: got %+v, want name %q exposure %s", want.workload, want.port, l, want.name, want.exposure)
+		}
+	}
+	if l := findListener(res.Listeners, "agent", 9100); l != nil && l.Disabled != "ingress" {
+		t.Errorf("agent listener Disabled = %q, want ingress", l.Disabled)
+	}
+}
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 443 && d.Source == "order-service"
-	}, "service port 443")
+func TestK8sProtocols(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Service
+metadata:
+  name: kube-dns
+spec:
+  ports:
+  - name: dns
+    port: 53
+    protocol: UDP
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: gateway
+spec:
+  template:
+    spec:
+      containers:
+      - name: gateway
+        ports:
+        - containerPort: 443
+        - containerPort: 443
+          protocol: UDP
+        - containerPort: 38412
+          protocol: SCTP
+        env:
+        - name: STATSD_ADDR
+          value: "statsd.monitoring.svc.cluster.local:8125"
+        - name: SYSLOG_URL
+          value: "udp://logs:1514"
+        - name: UPSTREAM
+          value: "quic://edge:443"
+`
+	path := writeTempFile(t, "manifests.yaml", manifest)
+	res, err := parseK8s(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	for _, want := range []struct {
+		workload, protocol string
+		port               int
+	}{
+		{"kube-dns", "UDP", 53},
+		{"kube-dns", "TCP", 53},
+		{"gateway", "TCP", 443},
+		{"gateway", "UDP", 443},
+		{"gateway", "SCTP", 38412},
+	} {
+		found := false
+		for _, l := range res.Listeners {
+			found = found || l.Workload == want.workload && l.Port == want.port && l.Protocol == want.protocol
+		}
+		if !found {
+			t.Errorf("missing listener %s:%d/%s in %+v", want.workload, want.port, want.protocol, res.Listeners)
+		}
+	}
+	for _, want := range []struct {
+		target, protocol string
+		port             int
+	}{
+		{"statsd.monitoring", "UDP", 8125},
+		{"logs", "UDP", 1514},
+		{"edge", "UDP", 443},
+	} {
+		assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+			return d.Target == want.target && d.Port == want.port && d.Protocol == want.protocol
+		}, fmt.Sprintf("%s:%d/%s", want.target, want.port, want.protocol))
+	}
 }
 
 func TestK8sMultiDocumentYAML(t *testing.T) {
@@ -164,24 +293,23 @@ data:
   cache_addr: "memcached:11211"
 `
 	path := writeTempFile(t, "multi-doc.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
-	// Service: 1 port
-	// Deployment: 1 container port + 1 URL env
+	// Deployment: 1 URL env
 	// ConfigMap: 1 URL + 1 host:port
-	// Total: 5
-	assertDepCount(t, deps, 5)
-
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Source == "frontend-svc" && d.Port == 80
-	}, "frontend service port")
+	// Total: 3, plus the service port and the container port as listeners
+	assertDepCount(t, deps, 3)
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Source == "frontend" && d.Port == 3000 && d.Target == "frontend"
-	}, "frontend container port")
+	if findListener(res.Listeners, "frontend-svc", 80) == nil {
+		t.Error("missing frontend service port listener")
+	}
+	if findListener(res.Listeners, "frontend", 3000) == nil {
+		t.Error("missing frontend container port listener")
+	}
 
 	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
 		return d.Source == "frontend" && d.Target == "api-gateway" && d.Port == 8080
@@ -208,10 +336,11 @@ server:
   port: 8080
 `
 	path := writeTempFile(t, "application.yml", content)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	if deps != nil {
 		t.Errorf("expected nil deps for non-K8s YAML, got %d deps", len(deps))
 	}
@@ -236,22 +365,150 @@ spec:
           value: "postgres-0.postgres.db.svc.cluster.local:5432"
 `
 	path := writeTempFile(t, "statefulset.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
-	assertDepCount(t, deps, 2)
+	assertDepCount(t, deps, 1)
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 5432 && d.Target == "postgres" && d.Confidence == model.High
-	}, "postgres container port")
+	if l := findListener(res.Listeners, "postgres", 5432); l == nil || l.Confidence != model.High {
+		t.Errorf("expected postgres container port listener, got %+v", l)
+	}
 
 	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
 		return d.Target == "postgres-0.postgres.db" && d.Port == 5432 && d.Confidence == model.High
 	}, "replication K8s DNS")
 }
 
+func TestK8sAllWorkloadKinds(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: log-agent
+spec:
+  template:
+    spec:
+      containers:
+      - name: agent
+        env:
+        - name: SINK
+          value: "loki:3100"
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: wait-for-db
+        env:
+        - name: DB
+          value: "postgres:5432"
+      containers:
+      - name: migrate
+        env:
+        - name: DB
+          value: "postgres:5432"
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        metadata:
+          annotations:
+            segspec.io/disable: egress
+        spec:
+          containers:
+          - name: report
+            env:
+            - name: WAREHOUSE
+              value: "clickhouse:9000"
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: legacy
+spec:
+  template:
+    spec:
+      containers:
+      - name: legacy
+        ports:
+        - containerPort: 7000
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: debug
+spec:
+  containers:
+  - name: debug
+    env:
+    - name: TARGET
+      value: "api:8080"
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: checkout
+spec:
+  template:
+    spec:
+      containers:
+      - name: checkout
+        ports:
+        - containerPort: 8443
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: thumbnailer
+spec:
+  template:
+    spec:
+      containers:
+      - name: thumbnailer
+        env:
+        - name: BUCKET
+          value: "minio:9000"
+`
+	res, err := ParseK8sContent(manifest, "workloads.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "log-agent" && d.Target == "loki" && d.Port == 3100
+	}, "DaemonSet env")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "migrate" && d.Target == "postgres" && d.Line == 25
+	}, "Job initContainer env")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "report" && d.Target == "clickhouse" && d.Port == 9000 && d.Disabled == "egress" && d.Line == 48
+	}, "CronJob env with pod-template disable annotation")
+	if findListener(res.Listeners, "legacy", 7000) == nil {
+		t.Error("missing ReplicaSet container port")
+	}
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "debug" && d.Target == "api" && d.Port == 8080
+	}, "bare Pod env")
+	if findListener(res.Listeners, "checkout", 8443) == nil {
+		t.Error("missing Argo Rollout container port")
+	}
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "thumbnailer" && d.Target == "minio" && d.Port == 9000
+	}, "Knative Service env")
+}
+
 func TestK8sConfigMap(t *testing.T) {
 	manifest := `apiVersion: v1
 kind: ConfigMap
@@ -263,10 +520,11 @@ data:
   plain_text: "no network info here"
 `
 	path := writeTempFile(t, "configmap.yaml", manifest)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	assertDepCount(t, deps, 2)
 
@@ -295,10 +553,11 @@ services:
       - "5432:5432"
 `
 	path := writeTempFile(t, "docker-compose.yml", content)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	if deps != nil {
 		t.Errorf("expected nil deps for docker-compose.yml, got %d deps", len(deps))
 	}
@@ -337,10 +596,11 @@ cache:
   port: 6379
 `
 	path := writeTempFile(t, "random-config.yml", nonK8sContent)
-	deps, err := parseK8s(path)
+	res, err := parseK8s(path)
 	if err != nil {
 		t.Fatalf("unexpected error for non-K8s YAML: %v", err)
 	}
+	deps := res.Dependencies
 	if deps != nil {
 		t.Errorf("expected nil deps for non-K8s YAML, got %d deps", len(deps))
 	}
@@ -376,55 +636,66 @@ spec:
     - port: 80
       targetPort: 8080
 `
-	deps, err := ParseK8sContent(content, "helm-app/Chart.yaml (helm template)")
+	res, err := ParseK8sContent(content, "helm-app/Chart.yaml (helm template)")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 
 	// Expect:
-	// 1 container port (8080)
 	// 1 host:port dep (redis-cache:6379)
-	// 1 service port (80)
-	// Total: 3
-	assertDepCount(t, deps, 3)
-
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Port == 8080 && d.Target == "myapp" && d.Confidence == model.High
-	}, "container port 8080")
+	// 2 listeners: container port (8080) and service port (80)
+	assertDepCount(t, deps, 1)
 
 	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
 		return d.Target == "redis-cache" && d.Port == 6379
 	}, "redis host:port dep")
 
-	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
-		return d.Source == "myapp-svc" && d.Port == 80
-	}, "service port 80")
+	if l := findListener(res.Listeners, "myapp", 8080); l == nil || l.Confidence != model.High {
+		t.Errorf("expected container port 8080 listener, got %+v", l)
+	}
+	if findListener(res.Listeners, "myapp-svc", 80) == nil {
+		t.Error("missing service port 80 listener")
+	}
 
 	// Verify sourceFile label is set correctly
 	for _, dep := range deps {
 		if dep.SourceFile != "helm-app/Chart.yaml (helm template)" {
 			t.Errorf("dep.SourceFile = %q, want %q", dep.SourceFile, "helm-app/Chart.yaml (helm template)")
 		}
+		if dep.Parser != "k8s" {
+			t.Errorf("dep.Parser = %q, want k8s", dep.Parser)
+		}
+	}
+	for _, l := range res.Listeners {
+		if l.SourceFile != "helm-app/Chart.yaml (helm template)" {
+			t.Errorf("listener.SourceFile = %q, want %q", l.SourceFile, "helm-app/Chart.yaml (helm template)")
+		}
+		if l.Parser != "k8s" {
+			t.Errorf("listener.Parser = %q, want k8s", l.Parser)
+		}
 	}
 }
 
 func TestParseK8sContentNonK8s(t *testing.T) {
 	content := `just some random text
 not yaml at all`
-	deps, err := ParseK8sContent(content, "test-source")
+	res, err := ParseK8sContent(content, "test-source")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	if deps != nil {
 		t.Errorf("expected nil deps for non-K8s content, got %d", len(deps))
 	}
 }
 
 func TestParseK8sContentEmpty(t *testing.T) {
-	deps, err := ParseK8sContent("", "test-source")
+	res, err := ParseK8sContent("", "test-source")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	deps := res.Dependencies
 	if deps != nil {
 		t.Errorf("expected nil deps for empty content, got %d", len(deps))
 	}
@@ -454,3 +725,12 @@ func assertHasDep(t *testing.T, deps []model.NetworkDependency, match func(model
 		t.Logf("  [%d] %s -> %s:%d (%s) %q", i, d.Source, d.Target, d.Port, d.Confidence, d.Description)
 	}
 }
+
+func findListener(listeners []model.Listener, workload string, port int) *model.Listener {
+	for i := range listeners {
+		if listeners[i].Workload == workload && listeners[i].Port == port {
+			return &listeners[i]
+		}
+	}
+	return nil
+}
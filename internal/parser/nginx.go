@@ -0,0 +1,456 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	// nginx.conf and the directories it conventionally includes.
+	for _, pattern := range []string{"nginx.conf", "conf.d/*.conf", "http.d/*.conf", "stream.d/*.conf", "sites-enabled/*"} {
+		defaultRegistry.RegisterResults("nginx", pattern, parseNginx)
+	}
+	// Configs kept in an nginx/ directory of the repo; nginx/nginx.conf
+	// already matches above.
+	defaultRegistry.RegisterResults("nginx", "nginx/*.conf", func(path string) (Result, error) {
+		if filepath.Base(path) == "nginx.conf" {
+			return Result{}, nil
+		}
+		return parseNginx(path)
+	})
+}
+
+// nginxIncludeDirs are the directories of included files; their parent
+// holds nginx.conf.
+var nginxIncludeDirs = []string{"conf.d", "http.d", "stream.d", "sites-enabled"}
+
+// nginxDirective is one directive, with its block if it has one.
+type nginxDirective struct {
+	name  string
+	args  []string
+	line  int
+	block []nginxDirective
+}
+
+// nginxPassDirectives hand a request to an upstream. proxy_pass in a
+// stream block takes host:port; in http it takes a URL.
+var nginxPassDirectives = map[string]bool{
+	"proxy_pass":     true,
+	"grpc_pass":      true,
+	"fastcgi_pass":   true,
+	"uwsgi_pass":     true,
+	"scgi_pass":      true,
+	"memcached_pass": true,
+}
+
+// parseNginx reads an nginx config: the servers of each `upstream`
+// block, the literal hosts of proxy_pass (and grpc_pass, fastcgi_pass,
+// ...), and `listen` ports. A *_pass naming an upstream block declared
+// anywhere in the config (nginx.conf or its conf.d/sites-enabled
+// includes) is reported at the upstream's servers instead.
+func parseNginx(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	directives, err := parseNginxConf(data)
+	if err != nil {
+		// conf.d holds other servers' configs too (Apache, supervisord);
+		// only files under an nginx* name have to parse.
+		if !nginxOwned(path) {
+			return Result{}, nil
+		}
+		return Result{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	directives = nginxInStream(path, directives)
+	n := nginxFile{path: path, lines: sourceLines(data), upstreams: make(map[string]bool), udpUpstreams: make(map[string]bool)}
+	for _, config := range append(nginxSiblingConfigs(path), directives) {
+		for name := range nginxUpstreams(config) {
+			n.upstreams[name] = true
+		}
+		for name := range nginxUDPUpstreams(config, false) {
+			n.udpUpstreams[name] = true
+		}
+	}
+	n.walk(directives, nginxContext{})
+	return withDisable(n.res, data), nil
+}
+
+// nginxOwned reports whether path is nginx.conf or sits under a
+// directory named for nginx (/etc/nginx/conf.d, deploy/nginx-proxy).
+func nginxOwned(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if strings.HasPrefix(strings.ToLower(part), "nginx") {
+			return true
+		}
+	}
+	return false
+}
+
+type nginxFile struct {
+	path         string
+	lines        []string
+	upstreams    map[string]bool
+	udpUpstreams map[string]bool // stream upstreams proxied from UDP listeners
+	res          Result
+}
+
+// nginxContext is where a directive sits: in a stream block or not, in
+// a stream server listening on UDP, and the server and location it
+// applies to, for descriptions.
+type nginxContext struct {
+	stream     bool
+	udp        bool
+	serverName string
+	location   string
+}
+
+func (n *nginxFile) walk(directives []nginxDirective, ctx nginxContext) {
+	for _, d := range directives {
+		switch {
+		case d.name == "stream":
+			n.walk(d.block, nginxContext{stream: true})
+		case d.name == "http":
+			n.walk(d.block, nginxContext{})
+		case d.name == "server" && d.block != nil:
+			inner := ctx
+			for _, c := range d.block {
+				if c.name == "server_name" && len(c.args) > 0 {
+					inner.serverName = c.args[0]
+				}
+				if ctx.stream && c.name == "listen" && containsString(c.args, "udp") {
+					inner.udp = true
+				}
+			}
+			n.walk(d.block, inner)
+		case d.name == "location" && len(d.args) > 0:
+			inner := ctx
+			inner.location = strings.Join(d.args, " ")
+			n.walk(d.block, inner)
+		case d.name == "upstream" && len(d.args) > 0:
+			n.upstream(d, ctx)
+		case d.name == "listen" && len(d.args) > 0:
+			n.listen(d, ctx)
+		case nginxPassDirectives[d.name] && len(d.args) > 0:
+			n.pass(d, ctx)
+		case d.block != nil:
+			n.walk(d.block, ctx)
+		}
+	}
+}
+
+// upstream reports the servers of an upstream block. http upstreams
+// default to port 80; stream upstreams must name one, and take UDP when
+// a UDP stream server proxies to them.
+func (n *nginxFile) upstream(d nginxDirective, ctx nginxContext) {
+	defaultPort, serviceType, protocol := 80, "http", "TCP"
+	if ctx.stream {
+		defaultPort, serviceType = 0, ""
+		if n.udpUpstreams[d.args[0]] {
+			protocol = "UDP"
+		}
+	}
+	for _, s := range d.block {
+		if s.name != "server" || len(s.args) == 0 || strings.HasPrefix(s.args[0], "unix:") {
+			continue
+		}
+		host, port, ok := proxyAddress(s.args[0], defaultPort)
+		if !ok || host == "" || loopbackHost(host) {
+			continue
+		}
+		n.res.Dependencies = append(n.res.Dependencies, proxyDep(n.path, s.line, lineAt(n.lines, s.line),
+			host, port, protocol, "nginx upstream "+d.args[0], serviceType))
+	}
+}
+
+// listen reports a listen port: `80`, `[::]:443 ssl`, `127.0.0.1:8080`,
+// `53 udp` in a stream server. HTTP/3 listeners (`443 quic`, or the
+// older `http3`) take UDP.
+func (n *nginxFile) listen(d nginxDirective, ctx nginxContext) {
+	if strings.HasPrefix(d.args[0], "unix:") {
+		return
+	}
+	host, port, ok := proxyAddress(d.args[0], 80)
+	if !ok || loopbackHost(host) {
+		return
+	}
+	protocol := "TCP"
+	for _, p := range []string{"udp", "quic", "http3"} {
+		if containsString(d.args[1:], p) {
+			protocol = "UDP"
+		}
+	}
+	description := "nginx listen"
+	if ctx.serverName != "" && ctx.serverName != "_" {
+		description += " (server " + ctx.serverName + ")"
+	}
+	n.res.Listeners = append(n.res.Listeners, proxyListener(n.path, d.line, lineAt(n.lines, d.line), port, protocol, description))
+}
+
+// pass reports the literal upstream of a *_pass directive. A target that
+// names an upstream block is reported there; one built from variables
+// can't be known.
+func (n *nginxFile) pass(d nginxDirective, ctx nginxContext) {
+	target := d.args[0]
+	scheme, rest, hasScheme := strings.Cut(target, "://")
+	if !hasScheme {
+		scheme, rest = "", target
+	}
+	scheme = strings.ToLower(scheme)
+	hostport, _, _ := strings.Cut(rest, "/")
+	if strings.HasPrefix(hostport, "unix:") || strings.Contains(hostport, "$") || n.upstreams[hostport] {
+		return
+	}
+	defaultPort := map[string]int{"http": 80, "https": 443, "grpc": 80, "grpcs": 443}[scheme]
+	host, port, ok := proxyAddress(hostport, defaultPort)
+	if !ok || host == "" || loopbackHost(host) {
+		return
+	}
+	protocol, serviceType := "TCP", ""
+	if ctx.udp {
+		protocol = "UDP"
+	}
+	if !ctx.stream && (d.name == "proxy_pass" || d.name == "grpc_pass") {
+		serviceType = "http"
+	}
+	description := "nginx " + d.name
+	if ctx.location != "" {
+		description += " (location " + ctx.location + ")"
+	}
+	n.res.Dependencies = append(n.res.Dependencies, proxyDep(n.path, d.line, lineAt(n.lines, d.line),
+		host, port, protocol, description, serviceType))
+}
+
+// nginxUpstreams returns the names of the upstream blocks declared at
+// the top level or in http/stream blocks.
+func nginxUpstreams(directives []nginxDirective) map[string]bool {
+	names := make(map[string]bool)
+	for _, d := range directives {
+		switch {
+		case d.name == "upstream" && len(d.args) > 0:
+			names[d.args[0]] = true
+		case d.name == "http" || d.name == "stream":
+			for name := range nginxUpstreams(d.block) {
+				names[name] = true
+			}
+		}
+	}
+	return names
+}
+
+// nginxUDPUpstreams returns the names of the upstream blocks that stream
+// servers listening on UDP proxy_pass to.
+func nginxUDPUpstreams(directives []nginxDirective, stream bool) map[string]bool {
+	names := make(map[string]bool)
+	for _, d := range directives {
+		switch {
+		case d.name == "stream":
+			for name := range nginxUDPUpstreams(d.block, true) {
+				names[name] = true
+			}
+		case stream && d.name == "server" && d.block != nil:
+			udp := false
+			for _, c := range d.block {
+				if c.name == "listen" && containsString(c.args, "udp") {
+					udp = true
+				}
+			}
+			for _, c := range d.block {
+				if udp && c.name == "proxy_pass" && len(c.args) > 0 {
+					names[c.args[0]] = true
+				}
+			}
+		}
+	}
+	return names
+}
+
+// nginxInStream wraps the directives of a stream.d file, which nginx.conf
+// includes inside its stream block, in that block.
+func nginxInStream(path string, directives []nginxDirective) []nginxDirective {
+	if filepath.Base(filepath.Dir(path)) != "stream.d" {
+		return directives
+	}
+	return []nginxDirective{{name: "stream", block: directives}}
+}
+
+// nginxSiblingConfigs parses the other files of the config path belongs
+// to: nginx.conf beside it (or above its include directory) and the
+// files of the include directories. Files that aren't nginx syntax are
+// left out.
+func nginxSiblingConfigs(path string) [][]nginxDirective {
+	root := filepath.Dir(path)
+	if containsString(nginxIncludeDirs, filepath.Base(root)) {
+		root = filepath.Dir(root)
+	}
+	files, _ := filepath.Glob(filepath.Join(root, "*.conf"))
+	for _, dir := range nginxIncludeDirs {
+		included, _ := filepath.Glob(filepath.Join(root, dir, "*"))
+		files = append(files, included...)
+	}
+	var configs [][]nginxDirective
+	for _, f := range files {
+		if filepath.Clean(f) == filepath.Clean(path) {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		directives, err := parseNginxConf(data)
+		if err != nil {
+			continue
+		}
+		configs = append(configs, nginxInStream(f, directives))
+	}
+	return configs
+}
+
+// nginxToken is a word or one of ; { } with the line it starts on.
+type nginxToken struct {
+	text  string
+	line  int
+	quote bool
+}
+
+// tokenizeNginx splits nginx config into tokens, dropping comments and
+// unquoting strings. ${var} inside a word stays part of it.
+func tokenizeNginx(data []byte) []nginxToken {
+	var tokens []nginxToken
+	s := string(data)
+	line := 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == ';' || c == '{' || c == '}':
+			tokens = append(tokens, nginxToken{text: string(c), line: line})
+			i++
+		case c == '"' || c == '\'':
+			start := line
+			var b strings.Builder
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				if s[i] == '\n' {
+					line++
+				}
+				b.WriteByte(s[i])
+			}
+			i++
+			tokens = append(tokens, nginxToken{text: b.String(), line: start, quote: true})
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\r\n;{}\"'", rune(s[i])) {
+				if s[i] == '$' && i+1 < len(s) && s[i+1] == '{' {
+					if end := strings.IndexByte(s[i:], '}'); end > 0 {
+						i += end
+					}
+				}
+				i++
+			}
+			tokens = append(tokens, nginxToken{text: s[start:i], line: line})
+		}
+	}
+	return tokens
+}
+
+// parseNginxConf parses nginx config into its directive tree.
+func parseNginxConf(data []byte) ([]nginxDirective, error) {
+	tokens := tokenizeNginx(data)
+	pos := 0
+	directives, err := parseNginxBlock(tokens, &pos, false)
+	if err != nil {
+		return nil, err
+	}
+	return directives, nil
+}
+
+func parseNginxBlock(tokens []nginxToken, pos *int, nested bool) ([]nginxDirective, error) {
+	var out []nginxDirective
+	for *pos < len(tokens) {
+		t := tokens[*pos]
+		*pos++
+		if !t.quote && t.text == "}" {
+			if !nested {
+				return nil, fmt.Errorf("line %d: unexpected }", t.line)
+			}
+			return out, nil
+		}
+		if !t.quote && (t.text == ";" || t.text == "{") {
+			return nil, fmt.Errorf("line %d: unexpected %s", t.line, t.text)
+		}
+		d := nginxDirective{name: t.text, line: t.line}
+		if strings.HasSuffix(d.name, "_by_lua_block") {
+			// Lua code, not directives: skip to the matching brace.
+			if err := skipNginxBlock(tokens, pos); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for {
+			if *pos >= len(tokens) {
+				return nil, fmt.Errorf("line %d: %s is not terminated by ;", t.line, t.text)
+			}
+			a := tokens[*pos]
+			*pos++
+			if !a.quote && a.text == ";" {
+				break
+			}
+			if !a.quote && a.text == "{" {
+				block, err := parseNginxBlock(tokens, pos, true)
+				if err != nil {
+					return nil, err
+				}
+				if block == nil {
+					block = []nginxDirective{}
+				}
+				d.block = block
+				break
+			}
+			if !a.quote && a.text == "}" {
+				return nil, fmt.Errorf("line %d: unexpected }", a.line)
+			}
+			d.args = append(d.args, a.text)
+		}
+		out = append(out, d)
+	}
+	if nested {
+		return nil, errors.New("unexpected end of file, expecting }")
+	}
+	return out, nil
+}
+
+// skipNginxBlock moves pos past the { ... } block starting at it.
+func skipNginxBlock(tokens []nginxToken, pos *int) error {
+	depth := 0
+	for ; *pos < len(tokens); *pos++ {
+		t := tokens[*pos]
+		if t.quote {
+			continue
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				*pos++
+				return nil
+			}
+		}
+	}
+	return errors.New("unexpected end of file, expecting }")
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNginx(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "conf.d"), 0755)
+	os.WriteFile(filepath.Join(dir, "nginx.conf"), []byte(`events {}
+http {
+    upstream api_backend {
+        server api-1:8080;
+        server api-2 backup;
+        server unix:/run/app.sock;
+    }
+    include conf.d/*.conf;
+}
+stream {
+    upstream pg { server db.internal:5432; }
+    server {
+        listen 5432;
+        proxy_pass pg;
+    }
+    server {
+        listen 53 udp;
+        proxy_pass 10.0.0.2:53;
+    }
+}
+`), 0644)
+	site := filepath.Join(dir, "conf.d", "site.conf")
+	os.WriteFile(site, []byte(`server {
+    listen 80;
+    listen [::]:443 ssl http2;
+    listen [::]:443 quic reuseport;
+    listen 127.0.0.1:8081;
+    server_name shop.example.com;
+    location /api/ { proxy_pass http://api_backend; }   # upstream in nginx.conf
+    location /auth { proxy_pass https://auth.internal/; }
+    location /grpc { grpc_pass grpc://orders:50051; }
+    location /app { proxy_pass http://127.0.0.1:3000; }
+    location ~ ^/u/(.*) { proxy_pass http://$upstream_host; }
+    location /lua { content_by_lua_block { ngx.say("}") } }
+}
+`), 0644)
+
+	main, err := parseNginx(filepath.Join(dir, "nginx.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target, protocol string
+		port, line       int
+	}{
+		{"api-1", "TCP", 8080, 4},
+		{"api-2", "TCP", 80, 5},
+		{"db.internal", "TCP", 5432, 11},
+		{"10.0.0.2", "UDP", 53, 18}, // proxied from a UDP listener
+	} {
+		d := findDep(main.Dependencies, tt.target, tt.port)
+		if d == nil {
+			t.Errorf("missing %s:%d in %v", tt.target, tt.port, main.Dependencies)
+			continue
+		}
+		if d.Protocol != tt.protocol || d.Line != tt.line {
+			t.Errorf("%s:%d = %s line %d, want %s line %d", tt.target, tt.port, d.Protocol, d.Line, tt.protocol, tt.line)
+		}
+	}
+	if len(main.Dependencies) != 4 {
+		t.Errorf("nginx.conf deps = %v, want 4", main.Dependencies)
+	}
+	if l := findListener(main.Listeners, "", 53); l == nil || l.Protocol != "UDP" {
+		t.Errorf("listener 53 = %+v, want UDP", l)
+	}
+
+	res, err := parseNginx(site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// api_backend is reported at its servers in nginx.conf; loopback and
+	// variable targets aren't network peers anyone can name.
+	if len(res.Dependencies) != 2 {
+		t.Errorf("site.conf deps = %v, want auth.internal and orders", res.Dependencies)
+	}
+	if d := findDep(res.Dependencies, "auth.internal", 443); d == nil || d.ServiceType != "http" || d.Description != "nginx proxy_pass (location /auth)" {
+		t.Errorf("auth.internal = %+v", d)
+	}
+	if findDep(res.Dependencies, "orders", 50051) == nil {
+		t.Errorf("missing grpc_pass target in %v", res.Dependencies)
+	}
+	if len(res.Listeners) != 3 || findListener(res.Listeners, "", 80) == nil || findListener(res.Listeners, "", 443) == nil {
+		t.Errorf("site.conf listeners = %v, want 80, 443 and 443/UDP", res.Listeners)
+	}
+	quic := false
+	for _, l := range res.Listeners {
+		quic = quic || (l.Port == 443 && l.Protocol == "UDP")
+	}
+	if !quic {
+		t.Errorf("site.conf listeners = %v, want the quic listener on 443/UDP", res.Listeners)
+	}
+}
+
+func TestParseNginxForeignConfD(t *testing.T) {
+	// Apache keeps its includes in conf.d too.
+	dir := filepath.Join(t.TempDir(), "httpd", "conf.d")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, "vhost.conf")
+	os.WriteFile(path, []byte("<VirtualHost *:80>\n  ProxyPass / http://app:8080/\n</VirtualHost>\n"), 0644)
+	res, err := parseNginx(path)
+	if err != nil || len(res.Dependencies) != 0 {
+		t.Errorf("Apache config = %v, %v; want nothing, no error", res.Dependencies, err)
+	}
+}
+
+func TestParseNginxUDPStreamUpstream(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "stream.d"), 0755)
+	path := filepath.Join(dir, "nginx.conf")
+	os.WriteFile(path, []byte(`stream {
+    upstream dns { server 10.0.0.53:53; }
+    upstream syslog { server logs.internal:514; }
+    upstream pg { server db.internal:5432; }
+    server {
+        listen 53 udp;
+        proxy_pass dns;
+    }
+    server {
+        listen 5432;
+        proxy_pass pg;
+    }
+    include stream.d/*.conf;
+}
+`), 0644)
+	// The server proxying to syslog sits in an included file.
+	os.WriteFile(filepath.Join(dir, "stream.d", "syslog.conf"), []byte("server {\n    listen 514 udp;\n    proxy_pass syslog;\n}\n"), 0644)
+
+	res, err := parseNginx(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target, protocol string
+		port             int
+	}{
+		{"10.0.0.53", "UDP", 53},
+		{"logs.internal", "UDP", 514},
+		{"db.internal", "TCP", 5432},
+	} {
+		if d := findDep(res.Dependencies, tt.target, tt.port); d == nil || d.Protocol != tt.protocol {
+			t.Errorf("%s:%d = %+v, want %s", tt.target, tt.port, d, tt.protocol)
+		}
+	}
+}
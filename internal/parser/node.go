@@ -0,0 +1,296 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterFormat("node", "package.json", parsePackageJSON)
+	defaultRegistry.RegisterFormat("node", "config/*.json", parseNodeConfig)
+	defaultRegistry.RegisterFormat("node", ".npmrc", parseNpmrc)
+}
+
+// knownNodeLibs maps npm client libraries to the infrastructure they talk
+// to, like knownLibs does for Maven/Gradle artifacts.
+var knownNodeLibs = []infraLib{
+	{"pg", "postgresql", 5432, "PostgreSQL (node-postgres)", "database"},
+	{"pg-promise", "postgresql", 5432, "PostgreSQL (pg-promise)", "database"},
+	{"mysql", "mysql", 3306, "MySQL", "database"},
+	{"mysql2", "mysql", 3306, "MySQL (mysql2)", "database"},
+	{"ioredis", "redis", 6379, "Redis (ioredis)", "cache"},
+	{"redis", "redis", 6379, "Redis (node-redis)", "cache"},
+	{"kafkajs", "kafka", 9092, "Kafka (KafkaJS)", "broker"},
+	{"node-rdkafka", "kafka", 9092, "Kafka (node-rdkafka)", "broker"},
+	{"amqplib", "rabbitmq", 5672, "RabbitMQ (amqplib)", "broker"},
+	{"mongodb", "mongodb", 27017, "MongoDB (driver)", "database"},
+	{"mongoose", "mongodb", 27017, "MongoDB (Mongoose)", "database"},
+	{"@elastic/elasticsearch", "elasticsearch", 9200, "Elasticsearch", "search"},
+	{"nats", "nats", 4222, "NATS", "broker"},
+	{"hot-shots", "statsd", 8125, "StatsD (hot-shots)", "metrics"},
+}
+
+// nodeRuntimeSections are the package.json sections installed in
+// production; devDependencies never run next to the service.
+var nodeRuntimeSections = []string{"dependencies", "optionalDependencies"}
+
+// parsePackageJSON infers Low-confidence infrastructure deps from the
+// client libraries a Node service depends on.
+func parsePackageJSON(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := flattenJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing package.json: %w", err)
+	}
+
+	var deps []model.NetworkDependency
+	for _, e := range entries {
+		section, pkg, ok := strings.Cut(e.path, ".")
+		if !ok || !containsString(nodeRuntimeSections, section) {
+			continue
+		}
+		for _, lib := range knownNodeLibs {
+			if pkg != lib.artifactID {
+				continue
+			}
+			version, _ := e.value.(string)
+			deps = append(deps, model.NetworkDependency{
+				Target:       lib.target,
+				Port:         lib.port,
+				Protocol:     inferProtocol("", lib.port),
+				Description:  fmt.Sprintf("package dependency: %s -> %s", pkg, lib.description),
+				Confidence:   model.Low,
+				SourceFile:   path,
+				Line:         e.line,
+				EvidenceLine: fmt.Sprintf("%q: %q", pkg, version),
+				ServiceType:  lib.serviceType,
+			})
+			break
+		}
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
+
+// nodeConfigKeyRe matches config keys that name a connection endpoint.
+var nodeConfigKeyRe = regexp.MustCompile(`(?i)(url|uri|host|hostname|addr|address|endpoint|server|servers|broker|brokers|dsn|connectionstring)$`)
+
+// parseNodeConfig reads node-config style files (config/default.json,
+// config/production.json, ...). Connection strings and host:port values
+// under endpoint-like keys become Medium-confidence deps, as do objects
+// that pair a `host` with a `port`. A URL under any other key (`$schema`,
+// `homepage`) is not a connection the service makes and is skipped.
+func parseNodeConfig(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := flattenJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var deps []model.NetworkDependency
+	seen := make(map[string]int)
+	add := func(d model.NetworkDependency, keyPath string) {
+		d.Confidence = model.Medium
+		if d.ServiceType == "" {
+			d.ServiceType = serviceTypeFromEnvKey(strings.ToUpper(strings.ReplaceAll(keyPath, ".", "_")) + "_")
+		}
+		dedup := fmt.Sprintf("%s:%d/%s", d.Target, d.Port, d.Protocol)
+		if i, ok := seen[dedup]; ok {
+			deps[i] = deps[i].MergedWith(d)
+			return
+		}
+		seen[dedup] = len(deps)
+		deps = append(deps, d)
+	}
+
+	// host/port pairs: group sibling leaves by their parent path.
+	type hostPort struct {
+		host string
+		port int
+		line int
+	}
+	pairs := make(map[string]*hostPort)
+	var pairOrder []string
+
+	for _, e := range entries {
+		parent, key := splitJSONPath(e.path)
+		if strings.EqualFold(key, "host") || strings.EqualFold(key, "port") {
+			hp, ok := pairs[parent]
+			if !ok {
+				hp = &hostPort{}
+				pairs[parent] = hp
+				pairOrder = append(pairOrder, parent)
+			}
+			if strings.EqualFold(key, "host") {
+				hp.host, _ = e.value.(string)
+				hp.line = e.line
+			} else {
+				hp.port = jsonInt(e.value)
+			}
+		}
+
+		val, ok := e.value.(string)
+		if !ok || val == "" {
+			continue
+		}
+		// Array elements (brokers[0]) are judged by their array's key.
+		if i := strings.IndexByte(key, '['); i >= 0 {
+			key = key[:i]
+		}
+		if !nodeConfigKeyRe.MatchString(key) {
+			continue
+		}
+		for _, d := range configValueDeps(val, key, path) {
+			d.Line = e.line
+			d.EvidenceLine = fmt.Sprintf("%s: %s", e.path, val)
+			add(d, e.path)
+		}
+	}
+
+	for _, parent := range pairOrder {
+		hp := pairs[parent]
+		if hp.host == "" || hp.port <= 0 || strings.Contains(hp.host, "://") {
+			continue
+		}
+		add(model.NetworkDependency{
+			Target:       hp.host,
+			Port:         hp.port,
+			Protocol:     inferProtocol("", hp.port),
+			Description:  fmt.Sprintf("%s: host %s port %d", parent, hp.host, hp.port),
+			SourceFile:   path,
+			Line:         hp.line,
+			EvidenceLine: fmt.Sprintf("%s: {host: %s, port: %d}", parent, hp.host, hp.port),
+		}, parent)
+	}
+
+	for i := range deps {
+		deps[i].Description = model.RedactSecrets(deps[i].Description)
+		deps[i].EvidenceLine = model.RedactSecrets(deps[i].EvidenceLine)
+	}
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
+
+// configValueDeps extracts deps from one config string: connection URLs
+// of any scheme extractDepsFromValue knows, then JDBC, http(s) and bare
+// host:port via extractFromValue.
+func configValueDeps(val, key, path string) []model.NetworkDependency {
+	if strings.Contains(val, "://") && !strings.HasPrefix(val, "http") {
+		if found := extractDepsFromValue(val, "", key, model.Medium, path); len(found) > 0 {
+			return found
+		}
+	}
+	if d, ok := extractFromValue(val, path); ok {
+		return []model.NetworkDependency{d}
+	}
+	return nil
+}
+
+// splitJSONPath splits a flattened path into its parent and last key.
+func splitJSONPath(p string) (string, string) {
+	if i := strings.LastIndex(p, "."); i >= 0 {
+		return p[:i], p[i+1:]
+	}
+	return "", p
+}
+
+func jsonInt(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case string:
+		return toInt(n)
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNpmrc reads registry and proxy settings from an .npmrc. These are
+// install-time connections (npm ci in a build stage), so they're Low
+// confidence and marked BuildTime, which keeps them out of policies. Auth
+// tokens and passwords are never read.
+func parseNpmrc(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []model.NetworkDependency
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, val = strings.TrimSpace(key), stripQuotes(strings.TrimSpace(val))
+
+		var desc string
+		switch {
+		case key == "registry":
+			desc = "npm registry"
+		case strings.HasSuffix(key, ":registry"):
+			desc = fmt.Sprintf("npm registry for %s", strings.TrimSuffix(key, ":registry"))
+		case key == "proxy", key == "https-proxy", key == "http-proxy":
+			desc = "npm " + key
+		default:
+			continue
+		}
+		d, ok := extractFromValue(val, path)
+		if !ok {
+			continue
+		}
+		d.Description = desc + " (install time)"
+		d.Confidence = model.Low
+		d.BuildTime = true
+		d.Line = lineNo
+		d.EvidenceLine = model.RedactSecrets(line)
+		deps = append(deps, d)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", path, err)
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlPos is the 1-based line/column of a YAML node plus its scalar value
+// (empty for mappings and sequences).
+type yamlPos struct {
+	line  int
+	col   int
+	value string
+}
+
+// yamlIndex maps a dotted key path to the position of the node declaring
+// it. Mapping entries are indexed at their key node (the line a reviewer
+// reads as the declaration); sequence items at the item node. Paths look
+// like `spec.template.spec.containers[0].ports[1].containerPort`, which is
+// also how Spring flattens nested keys, so `spring.datasource.url` resolves
+// whether the file nests the keys or writes them flat.
+type yamlIndex map[string]yamlPos
+
+// indexYAML walks a decoded document and records the position of every
+// key and sequence item. A DocumentNode wrapper is unwrapped transparently.
+func indexYAML(n *yaml.Node) yamlIndex {
+	ix := make(yamlIndex)
+	if n == nil {
+		return ix
+	}
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return ix
+		}
+		n = n.Content[0]
+	}
+	ix.walk(n, "")
+	return ix
+}
+
+func (ix yamlIndex) walk(n *yaml.Node, prefix string) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		var merged []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" && key.Tag == "!!merge" {
+				merged = append(merged, val)
+				continue
+			}
+			p := key.Value
+			if prefix != "" {
+				p = prefix + "." + key.Value
+			}
+			pos := yamlPos{line: key.Line, col: key.Column}
+			if val.Kind == yaml.ScalarNode {
+				pos.value = val.Value
+			}
+			ix[p] = pos
+			ix.walk(val, p)
+		}
+		// Merge keys (<<: *defaults) pull in the anchored mapping's
+		// entries at their own positions; keys the mapping sets itself win.
+		for _, m := range merged {
+			sources := []*yaml.Node{m}
+			if m.Kind == yaml.SequenceNode {
+				sources = m.Content
+			}
+			for _, src := range sources {
+				sub := make(yamlIndex)
+				sub.walk(src, prefix)
+				for p, pos := range sub {
+					if _, ok := ix[p]; !ok {
+						ix[p] = pos
+					}
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			p := fmt.Sprintf("%s[%d]", prefix, i)
+			pos := yamlPos{line: item.Line, col: item.Column}
+			if item.Kind == yaml.ScalarNode {
+				pos.value = item.Value
+			}
+			ix[p] = pos
+			ix.walk(item, p)
+		}
+	case yaml.AliasNode:
+		if n.Alias != nil {
+			ix.walk(n.Alias, prefix)
+		}
+	}
+}
+
+// at returns the line and column recorded for the path built from parts.
+// String parts are joined with "." and int parts become "[i]" suffixes.
+// Missing paths return (0, 0) so callers can assign unconditionally.
+func (ix yamlIndex) at(parts ...interface{}) (int, int) {
+	pos, ok := ix[yamlPath(parts...)]
+	if !ok {
+		return 0, 0
+	}
+	return pos.line, pos.col
+}
+
+// yamlPath renders path parts in the yamlIndex key format.
+func yamlPath(parts ...interface{}) string {
+	var b strings.Builder
+	for _, p := range parts {
+		switch v := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(v)
+		}
+	}
+	return b.String()
+}
+
+// lineOfOffset converts a byte offset in data into a 1-based line number.
+func lineOfOffset(data []byte, offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// jsonEntry is one scalar leaf of a JSON document: its path in yamlIndex
+// format, its decoded value (string, json.Number, bool or nil) and the
+// 1-based line it sits on.
+type jsonEntry struct {
+	path  string
+	value interface{}
+	line  int
+}
+
+// flattenJSON lists every scalar in a JSON document in document order.
+// encoding/json's struct decoding discards positions, so this walks the
+// token stream and converts decoder offsets to lines.
+func flattenJSON(data []byte) ([]jsonEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out []jsonEntry
+	var visit func(tok json.Token, prefix string) error
+	visit = func(tok json.Token, prefix string) error {
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			out = append(out, jsonEntry{path: prefix, value: tok, line: lineOfOffset(data, int(dec.InputOffset()))})
+			return nil
+		}
+		for i := 0; dec.More(); i++ {
+			p := fmt.Sprintf("%s[%d]", prefix, i)
+			if delim == '{' {
+				kt, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := kt.(string)
+				p = yamlPath(prefix, key)
+			}
+			vt, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := visit(vt, p); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token() // closing delimiter
+		return err
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if err := visit(tok, ""); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
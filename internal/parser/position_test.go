@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func TestIndexYAMLPaths(t *testing.T) {
+	src := `spring:
+  datasource:
+    url: jdbc:postgresql://db:5432/app
+items:
+  - first
+  - name: second
+`
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &n); err != nil {
+		t.Fatal(err)
+	}
+	ix := indexYAML(&n)
+
+	if line, col := ix.at("spring.datasource.url"); line != 3 || col != 5 {
+		t.Errorf("spring.datasource.url at %d:%d, want 3:5", line, col)
+	}
+	if line, _ := ix.at("items", 0); line != 5 {
+		t.Errorf("items[0] at line %d, want 5", line)
+	}
+	if line, _ := ix.at("items", 1, "name"); line != 6 {
+		t.Errorf("items[1].name at line %d, want 6", line)
+	}
+	if line, col := ix.at("missing"); line != 0 || col != 0 {
+		t.Errorf("missing path at %d:%d, want 0:0", line, col)
+	}
+}
+
+func TestIndexYAMLMergeKeys(t *testing.T) {
+	src := `x-defaults: &defaults
+  environment:
+    KAFKA: kafka:9092
+    REDIS: redis:6379
+services:
+  web:
+    <<: *defaults
+    image: example/web
+  cron:
+    environment:
+      REDIS: cache:6379
+    <<: *defaults
+`
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &n); err != nil {
+		t.Fatal(err)
+	}
+	ix := indexYAML(&n)
+
+	if line, _ := ix.at("services", "web", "environment", "KAFKA"); line != 3 {
+		t.Errorf("merged web KAFKA at line %d, want 3", line)
+	}
+	// The mapping's own keys win over merged ones, whatever the order.
+	if line, _ := ix.at("services", "cron", "environment"); line != 10 {
+		t.Errorf("cron environment at line %d, want 10", line)
+	}
+}
+
+func TestK8sDependencyLines(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        ports:
+        - containerPort: 8080
+        env:
+        - name: DB_URL
+          value: "postgresql://db:5432/app"
+`
+	path := writeTempFile(t, "web.yaml", manifest)
+	res, err := parseK8s(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	assertListenerLine(t, res.Listeners, "web", 80, 7)
+	assertListenerLine(t, res.Listeners, "web", 8080, 19)
+	assertLine(t, deps, "db", 5432, 22)
+}
+
+func TestComposeDependencyLines(t *testing.T) {
+	content := `services:
+  app:
+    image: myapp
+    ports:
+      - "8080:8080"
+    depends_on:
+      - db
+    environment:
+      - CACHE=redis:6379
+  db:
+    image: postgres:15
+`
+	path := writeTempFile(t, "docker-compose.yml", content)
+	res, err := parseCompose(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	assertListenerLine(t, res.Listeners, "app", 8080, 5)
+	assertLine(t, deps, "db", 5432, 7)
+	assertLine(t, deps, "redis", 6379, 9)
+}
+
+// Services, depends_on maps and environment are Go maps once decoded; the
+// line an edge cites must not depend on the order they are visited in.
+func TestComposeDependencyLinesDeterministic(t *testing.T) {
+	content := `services:
+  app:
+    image: myapp
+    depends_on:
+      db:
+        condition: service_healthy
+      cache:
+        condition: service_started
+    environment:
+      PRIMARY_URL: postgres://db:5432/app
+      REPLICA_URL: postgres://db:5432/app
+  db:
+    image: postgres:15
+  cache:
+    image: redis:7
+`
+	path := writeTempFile(t, "docker-compose.yml", content)
+	for i := 0; i < 20; i++ {
+		res, err := parseCompose(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Dependencies) < 2 || res.Dependencies[0].Target != "cache" || res.Dependencies[1].Target != "db" {
+			t.Fatalf("depends_on edges out of order: %v", res.Dependencies)
+		}
+		assertLine(t, res.Dependencies, "db", 5432, 5)
+		assertListenerLine(t, res.Listeners, "db", 5432, 13)
+	}
+}
+
+func TestSpringDependencyLines(t *testing.T) {
+	yml := `server:
+  port: 8080
+spring:
+  datasource:
+    url: jdbc:postgresql://pg:5432/app
+  kafka:
+    bootstrap-servers: broker:9092
+`
+	res, err := parseSpringYAML(writeTempFile(t, "application.yml", yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps := res.Dependencies
+	assertListenerLine(t, res.Listeners, "", 8080, 2)
+	assertLine(t, deps, "pg", 5432, 5)
+	assertLine(t, deps, "broker", 9092, 7)
+
+	props := `# comment
+server.port=9090
+
+spring.redis.host=cache
+`
+	res, err = parseSpringProperties(writeTempFile(t, "application.properties", props))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deps = res.Dependencies
+	assertListenerLine(t, res.Listeners, "", 9090, 2)
+	assertLine(t, deps, "cache", 6379, 4)
+}
+
+func TestEnvAndBuildFileLines(t *testing.T) {
+	env := `# services
+REDIS_URL=redis://cache:6379
+
+DATABASE_URL=postgresql://db:5432/app
+`
+	deps, err := parseEnvFile(writeTempFile(t, ".env", env))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLine(t, deps, "db", 5432, 4)
+
+	pom := `<project>
+  <dependencies>
+    <dependency>
+      <groupId>org.postgresql</groupId>
+      <artifactId>postgresql</artifactId>
+    </dependency>
+  </dependencies>
+</project>
+`
+	deps, err = parsePomXML(writeTempFile(t, "pom.xml", pom))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLine(t, deps, "postgresql", 5432, 5)
+
+	gradle := `dependencies {
+    implementation 'org.springframework.boot:spring-boot-starter-web:3.2.0'
+    implementation 'org.apache.kafka:kafka-clients:3.6.0'
+}
+`
+	deps, err = parseBuildGradle(writeTempFile(t, "build.gradle", gradle))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertLine(t, deps, "kafka", 9092, 3)
+}
+
+func assertLine(t *testing.T, deps []model.NetworkDependency, target string, port, want int) {
+	t.Helper()
+	for _, d := range deps {
+		if d.Target == target && d.Port == port {
+			if d.Line != want {
+				t.Errorf("%s:%d line = %d, want %d", target, port, d.Line, want)
+			}
+			return
+		}
+	}
+	t.Errorf("no dependency on %s:%d", target, port)
+}
+
+func assertListenerLine(t *testing.T, listeners []model.Listener, workload string, port, want int) {
+	t.Helper()
+	l := findListener(listeners, workload, port)
+	if l == nil {
+		t.Errorf("no listener %s:%d", workload, port)
+		return
+	}
+	if l.Line != want {
+		t.Errorf("listener %s:%d line = %d, want %d", workload, port, l.Line, want)
+	}
+}
@@ -0,0 +1,83 @@
+package parser
+
+import "strings"
+
+// schemeProtocols maps URL schemes whose traffic is not TCP to the
+// transport a NetworkPolicy has to allow. QUIC and HTTP/3 run over UDP.
+var schemeProtocols = map[string]string{
+	"udp":    "UDP",
+	"quic":   "UDP",
+	"h3":     "UDP",
+	"dns":    "UDP",
+	"syslog": "UDP",
+	"statsd": "UDP",
+	"coap":   "UDP",
+	"tftp":   "UDP",
+	"snmp":   "UDP",
+	"ntp":    "UDP",
+	"sctp":   "SCTP",
+}
+
+// wellKnownPortProtocols are the ports of services that run over UDP or
+// SCTP unless told otherwise. DNS also answers over TCP, but a client
+// that names port 53 is doing UDP lookups.
+var wellKnownPortProtocols = map[int]string{
+	53:    "UDP",  // DNS
+	67:    "UDP",  // DHCP
+	69:    "UDP",  // TFTP
+	123:   "UDP",  // NTP
+	161:   "UDP",  // SNMP
+	162:   "UDP",  // SNMP traps
+	514:   "UDP",  // syslog
+	1812:  "UDP",  // RADIUS
+	1813:  "UDP",  // RADIUS accounting
+	3478:  "UDP",  // STUN/TURN
+	4789:  "UDP",  // VXLAN
+	5353:  "UDP",  // mDNS
+	5683:  "UDP",  // CoAP
+	6831:  "UDP",  // Jaeger agent (compact thrift)
+	6832:  "UDP",  // Jaeger agent (binary thrift)
+	8125:  "UDP",  // StatsD
+	12201: "UDP",  // Graylog GELF
+	51820: "UDP",  // WireGuard
+	2905:  "SCTP", // M3UA
+	36412: "SCTP", // S1AP
+	38412: "SCTP", // NGAP
+}
+
+// inferProtocol returns the transport of a connection to port made
+// through a URL with the given scheme ("" when there is none): the
+// scheme's own, else the well-known service's on that port, else TCP.
+// Explicit port specs (k8s `protocol:`, compose `/udp`) are read where
+// they are declared and take precedence over this guess.
+func inferProtocol(scheme string, port int) string {
+	if p, ok := schemeProtocols[strings.ToLower(scheme)]; ok {
+		return p
+	}
+	if p, ok := wellKnownPortProtocols[port]; ok && scheme == "" {
+		return p
+	}
+	return "TCP"
+}
+
+// specProtocol normalizes a declared protocol (k8s `protocol: UDP`,
+// compose `/udp`, terraform `udp`) to TCP, UDP or SCTP; anything else,
+// empty included, is TCP, the default of every format we read.
+func specProtocol(p string) string {
+	switch strings.ToUpper(strings.TrimSpace(p)) {
+	case "UDP":
+		return "UDP"
+	case "SCTP":
+		return "SCTP"
+	}
+	return "TCP"
+}
+
+// urlScheme returns the lower-cased scheme of a scheme://... value, or "".
+func urlScheme(val string) string {
+	scheme, _, ok := strings.Cut(strings.TrimSpace(val), "://")
+	if !ok || strings.ContainsAny(scheme, " /=") {
+		return ""
+	}
+	return strings.ToLower(scheme)
+}
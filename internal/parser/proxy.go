@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+// Reverse proxies (nginx, HAProxy, Envoy, Traefik) route to upstreams
+// no application config names: a gateway's real dependencies are the
+// servers in its proxy config. Each upstream server becomes a
+// dependency of the proxy's workload and each listen/bind address a
+// listener. Upstreams on loopback (an nginx in front of the app in the
+// same container) and Unix sockets aren't network peers, and addresses
+// built from variables are skipped.
+
+// proxyAddress splits a listen, bind or server address into host and
+// port: "80", ":80", "*:80", "[::]:80", ":::80" (HAProxy), "10.0.0.1",
+// "api:8080". A wildcard host is returned as "", and an address without
+// a port gets defaultPort (0 for none).
+func proxyAddress(addr string, defaultPort int) (host string, port int, ok bool) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" || strings.ContainsAny(addr, "$%{") {
+		return "", 0, false
+	}
+	if n, err := strconv.Atoi(addr); err == nil {
+		return "", n, n > 0 && n <= 65535
+	}
+	var portPart string
+	switch {
+	case strings.HasPrefix(addr, "["):
+		end := strings.Index(addr, "]")
+		if end < 0 {
+			return "", 0, false
+		}
+		host, portPart = addr[1:end], strings.TrimPrefix(addr[end+1:], ":")
+	case strings.Count(addr, ":") > 1:
+		i := strings.LastIndex(addr, ":")
+		host, portPart = addr[:i], addr[i+1:]
+	default:
+		host, portPart, _ = strings.Cut(addr, ":")
+	}
+	port = defaultPort
+	if portPart != "" {
+		n, err := strconv.Atoi(portPart)
+		if err != nil {
+			return "", 0, false
+		}
+		port = n
+	}
+	if host == "*" || host == "0.0.0.0" || host == "::" {
+		host = ""
+	}
+	return host, port, port > 0 && port <= 65535
+}
+
+// loopbackHost reports whether host is only reachable from inside the
+// proxy's own workload.
+func loopbackHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "localhost" || host == "::1" || strings.HasPrefix(host, "127.")
+}
+
+// proxyDep is a proxy → upstream dependency declared at line.
+func proxyDep(path string, line int, evidence, host string, port int, protocol, description, serviceType string) model.NetworkDependency {
+	return model.NetworkDependency{
+		Target:       host,
+		Port:         port,
+		Protocol:     protocol,
+		Description:  description,
+		Confidence:   model.High,
+		SourceFile:   path,
+		Line:         line,
+		EvidenceLine: model.RedactSecrets(strings.TrimSpace(evidence)),
+		ServiceType:  serviceType,
+	}
+}
+
+// proxyListener is a port the proxy accepts connections on, declared at
+// line.
+func proxyListener(path string, line int, evidence string, port int, protocol, description string) model.Listener {
+	return model.Listener{
+		Port:         port,
+		Protocol:     protocol,
+		Description:  description,
+		Confidence:   model.High,
+		SourceFile:   path,
+		Line:         line,
+		EvidenceLine: model.RedactSecrets(strings.TrimSpace(evidence)),
+	}
+}
+
+// sourceLines splits file content into lines for evidence lookups by
+// 1-based line number.
+func sourceLines(data []byte) []string {
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+}
+
+// lineAt returns line n (1-based) of lines, or "".
+func lineAt(lines []string, n int) string {
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+// withDisable stamps a file's segspec:disable directive on a result.
+func withDisable(res Result, data []byte) Result {
+	disable := ScanFileDisable(data)
+	if disable == "" {
+		return res
+	}
+	for i := range res.Dependencies {
+		res.Dependencies[i].Disabled = disable
+	}
+	for i := range res.Listeners {
+		res.Listeners[i].Disabled = disable
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package parser
+
+import "testing"
+
+func TestProxyAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		host string
+		port int
+		ok   bool
+	}{
+		{"80", "", 80, true},
+		{":80", "", 80, true},
+		{"*:8080", "", 8080, true},
+		{"[::]:443", "", 443, true},
+		{":::80", "", 80, true},
+		{"0.0.0.0:9000", "", 9000, true},
+		{"10.0.0.1:5432", "10.0.0.1", 5432, true},
+		{"[fd00::1]:53", "fd00::1", 53, true},
+		{"api", "api", 80, true}, // default port
+		{"api:http", "", 0, false},
+		{"${BACKEND}:80", "", 0, false},
+		{"api:70000", "", 0, false},
+	}
+	for _, tt := range tests {
+		host, port, ok := proxyAddress(tt.addr, 80)
+		if ok != tt.ok || ok && (host != tt.host || port != tt.port) {
+			t.Errorf("proxyAddress(%q) = %q, %d, %v; want %q, %d, %v", tt.addr, host, port, ok, tt.host, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestProxyParsersRegistered(t *testing.T) {
+	r := DefaultRegistry()
+	for _, tt := range []struct{ path, format string }{
+		{"deploy/nginx.conf", "nginx"},
+		{"deploy/nginx/default.conf", "nginx"},
+		{"etc/nginx/conf.d/site.conf", "nginx"},
+		{"etc/nginx/sites-enabled/shop", "nginx"},
+		{"deploy/haproxy.cfg", "haproxy"},
+		{"deploy/front-envoy.yaml", "envoy"},
+		{"deploy/traefik.toml", "traefik"},
+		{"deploy/dynamic.yml", "traefik"},
+	} {
+		if !r.MatchesFormat(tt.path, tt.format) {
+			t.Errorf("%s not matched by the %s parser", tt.path, tt.format)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterFormat("python", "requirements*.txt", parseRequirementsTxt)
+	defaultRegistry.RegisterFormat("python", "pyproject.toml", parsePyproject)
+	defaultRegistry.RegisterFormat("python", "Pipfile", parsePipfile)
+}
+
+// knownPythonLibs maps PyPI client libraries (PEP 503 normalized names) to
+// the infrastructure they talk to, like knownLibs does for Maven/Gradle.
+var knownPythonLibs = []infraLib{
+	{"psycopg2", "postgresql", 5432, "PostgreSQL (psycopg2)", "database"},
+	{"psycopg2-binary", "postgresql", 5432, "PostgreSQL (psycopg2)", "database"},
+	{"psycopg", "postgresql", 5432, "PostgreSQL (psycopg 3)", "database"},
+	{"asyncpg", "postgresql", 5432, "PostgreSQL (asyncpg)", "database"},
+	{"mysqlclient", "mysql", 3306, "MySQL (mysqlclient)", "database"},
+	{"pymysql", "mysql", 3306, "MySQL (PyMySQL)", "database"},
+	{"mysql-connector-python", "mysql", 3306, "MySQL (Connector/Python)", "database"},
+	{"redis", "redis", 6379, "Redis (redis-py)", "cache"},
+	{"django-redis", "redis", 6379, "Redis (django-redis)", "cache"},
+	{"celery", "rabbitmq", 5672, "RabbitMQ (Celery default broker)", "broker"},
+	{"kombu", "rabbitmq", 5672, "RabbitMQ (Kombu)", "broker"},
+	{"pika", "rabbitmq", 5672, "RabbitMQ (Pika)", "broker"},
+	{"aio-pika", "rabbitmq", 5672, "RabbitMQ (aio-pika)", "broker"},
+	{"pymongo", "mongodb", 27017, "MongoDB (PyMongo)", "database"},
+	{"motor", "mongodb", 27017, "MongoDB (Motor)", "database"},
+	{"mongoengine", "mongodb", 27017, "MongoDB (MongoEngine)", "database"},
+	{"confluent-kafka", "kafka", 9092, "Kafka (confluent-kafka)", "broker"},
+	{"kafka-python", "kafka", 9092, "Kafka (kafka-python)", "broker"},
+	{"aiokafka", "kafka", 9092, "Kafka (aiokafka)", "broker"},
+	{"elasticsearch", "elasticsearch", 9200, "Elasticsearch", "search"},
+	{"nats-py", "nats", 4222, "NATS", "broker"},
+	{"pymemcache", "memcached", 11211, "Memcached (pymemcache)", "cache"},
+	{"pylibmc", "memcached", 11211, "Memcached (pylibmc)", "cache"},
+	{"python-memcached", "memcached", 11211, "Memcached (python-memcached)", "cache"},
+	{"statsd", "statsd", 8125, "StatsD (pystatsd)", "metrics"},
+}
+
+// pyRequirement is one declared package and where it was declared.
+type pyRequirement struct {
+	name     string // PEP 503 normalized
+	line     int
+	evidence string
+}
+
+// pyDevRequirementsRe matches requirements files for development, tests
+// and tooling (requirements-dev.txt, requirements_test.txt, ...), which
+// the deployed service never installs.
+var pyDevRequirementsRe = regexp.MustCompile(`(?i)^requirements[-_.](dev|develop|development|test|tests|testing|lint|docs|ci)([-_.].*)?\.txt$`)
+
+// parseRequirementsTxt reads a pip requirements file. Options (-r, -e,
+// --index-url ...) and VCS/URL requirements are skipped, as are dev and
+// test requirements files, like dev groups in pyproject.toml.
+func parseRequirementsTxt(path string) ([]model.NetworkDependency, error) {
+	if pyDevRequirementsRe.MatchString(filepath.Base(path)) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqs []pyRequirement
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || line[0] == '#' || line[0] == '-' {
+			continue
+		}
+		if name := pyRequirementName(line); name != "" {
+			reqs = append(reqs, pyRequirement{name: name, line: lineNo, evidence: line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", path, err)
+	}
+	return pythonLibDeps(reqs, data, path), nil
+}
+
+// parsePyproject reads PEP 621 `[project] dependencies` and Poetry's
+// `[tool.poetry.dependencies]`. Optional extras and dev groups are not
+// installed by default and are skipped.
+func parsePyproject(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reqs := tomlRequirements(data, []string{"project"}, []string{"tool.poetry.dependencies"})
+	return pythonLibDeps(reqs, data, path), nil
+}
+
+// parsePipfile reads a Pipfile's `[packages]`; `[dev-packages]` is skipped.
+func parsePipfile(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reqs := tomlRequirements(data, nil, []string{"packages"})
+	return pythonLibDeps(reqs, data, path), nil
+}
+
+// pythonLibDeps turns requirements naming a known client library into
+// Low-confidence inferred deps.
+func pythonLibDeps(reqs []pyRequirement, data []byte, path string) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	for _, r := range reqs {
+		for _, lib := range knownPythonLibs {
+			if r.name != lib.artifactID {
+				continue
+			}
+			deps = append(deps, model.NetworkDependency{
+				Target:       lib.target,
+				Port:         lib.port,
+				Protocol:     inferProtocol("", lib.port),
+				Description:  fmt.Sprintf("package dependency: %s -> %s", r.name, lib.description),
+				Confidence:   model.Low,
+				SourceFile:   path,
+				Line:         r.line,
+				EvidenceLine: r.evidence,
+				ServiceType:  lib.serviceType,
+			})
+			break
+		}
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps
+}
+
+var (
+	pyRequirementNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+	pyNameSeparatorRe   = regexp.MustCompile(`[-_.]+`)
+)
+
+// pyRequirementName returns the normalized package name of a PEP 508
+// requirement ("celery[redis]>=5.3; python_version>'3.8'" -> "celery"), or
+// "" for URL and path requirements.
+func pyRequirementName(spec string) string {
+	if strings.Contains(spec, "://") || strings.HasPrefix(spec, ".") || strings.HasPrefix(spec, "/") {
+		return ""
+	}
+	return normalizePyName(pyRequirementNameRe.FindString(spec))
+}
+
+func normalizePyName(name string) string {
+	return strings.ToLower(pyNameSeparatorRe.ReplaceAllString(name, "-"))
+}
+
+var tomlStringRe = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// tomlRequirements scans just enough TOML to list requirements: the PEP 508
+// strings of a `dependencies = [...]` array in one of arrayTables, and the
+// keys of keyTables (Poetry and Pipfile map package name to version).
+func tomlRequirements(data []byte, arrayTables, keyTables []string) []pyRequirement {
+	var reqs []pyRequirement
+	table := ""
+	inArray := false
+	addStrings := func(s string, lineNo int) {
+		for _, m := range tomlStringRe.FindAllStringSubmatch(s, -1) {
+			spec := m[1] + m[2]
+			if name := pyRequirementName(spec); name != "" {
+				reqs = append(reqs, pyRequirement{name: name, line: lineNo, evidence: fmt.Sprintf("%q", spec)})
+			}
+		}
+	}
+
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if inArray {
+			addStrings(line, lineNo)
+			if strings.Contains(line, "]") {
+				inArray = false
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			table = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = stripQuotes(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		switch {
+		case containsString(arrayTables, table) && key == "dependencies":
+			addStrings(val, lineNo)
+			inArray = strings.HasPrefix(val, "[") && !strings.Contains(val, "]")
+		case containsString(keyTables, table) && key != "python":
+			if name := normalizePyName(key); name != "" {
+				reqs = append(reqs, pyRequirement{name: name, line: lineNo, evidence: line})
+			}
+		}
+	}
+	return reqs
+}
+
+// stripTOMLComment drops a trailing # comment that isn't inside a string.
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
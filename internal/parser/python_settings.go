@@ -0,0 +1,724 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterFormat("python", "settings.py", parsePythonSettings)
+	defaultRegistry.RegisterFormat("python", "settings/*.py", parsePythonSettings)
+	defaultRegistry.RegisterFormat("python", "celeryconfig.py", parsePythonSettings)
+	defaultRegistry.RegisterFormat("python", "celery_config.py", parsePythonSettings)
+}
+
+// Django settings modules and Celery config modules are Python, but the
+// values that matter are almost always literals: a DATABASES dict, a CACHES
+// LOCATION, a broker URL. parsePythonSettings reads the module-level
+// assignments with a small tokenizer and literal parser and never executes
+// anything. Values read through os.environ.get / os.getenv / django-environ
+// / python-decouple resolve to their default, at Medium confidence since
+// the environment may override them; anything else that isn't a literal
+// (f-strings, concatenation, names) is ignored.
+
+// celerySettings maps Celery setting names, old uppercase and new
+// lowercase, to the role the URL plays.
+var celerySettings = map[string]string{
+	"CELERY_BROKER_URL":       "broker",
+	"CELERY_BROKER_READ_URL":  "broker",
+	"CELERY_BROKER_WRITE_URL": "broker",
+	"BROKER_URL":              "broker",
+	"broker_url":              "broker",
+	"broker_read_url":         "broker",
+	"broker_write_url":        "broker",
+	"CELERY_RESULT_BACKEND":   "result backend",
+	"result_backend":          "result backend",
+}
+
+// pySettingKeyRe matches other module-level settings that name an
+// endpoint (REDIS_URL, SENTRY_DSN, EMAIL_HOST, ...).
+var pySettingKeyRe = regexp.MustCompile(`(?i)_(url|uri|dsn|addr|host|server|servers|brokers)$`)
+
+// djangoEngines maps a Django database ENGINE (matched on its last dotted
+// component) to its default port; sqlite3 and dummy have no network peer.
+var djangoEngines = []struct {
+	engine string
+	port   int
+	desc   string
+}{
+	{"postgresql", 5432, "PostgreSQL"},
+	{"postgresql_psycopg2", 5432, "PostgreSQL"},
+	{"postgis", 5432, "PostgreSQL"},
+	{"mysql", 3306, "MySQL"},
+	{"oracle", 1521, "Oracle"},
+	{"mssql", 1433, "SQL Server"},
+}
+
+func parsePythonSettings(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+	assigns := readPyAssignments(data)
+
+	var deps []model.NetworkDependency
+	seen := make(map[string]int)
+	add := func(d model.NetworkDependency, ok bool) {
+		if !ok {
+			return
+		}
+		d.SourceFile = path
+		if d.Line > 0 && d.Line <= len(lines) {
+			d.EvidenceLine = model.RedactSecrets(strings.TrimSpace(lines[d.Line-1]))
+		}
+		dedup := fmt.Sprintf("%s:%d/%s", d.Target, d.Port, d.Protocol)
+		if i, ok := seen[dedup]; ok {
+			deps[i] = deps[i].MergedWith(d)
+			return
+		}
+		seen[dedup] = len(deps)
+		deps = append(deps, d)
+	}
+
+	values := make(map[string]*pyNode)
+	for _, a := range assigns {
+		values[a.name] = a.value
+	}
+
+	for _, a := range assigns {
+		switch {
+		case a.name == "DATABASES":
+			for _, alias := range a.value.keys {
+				db := a.value.dict[alias]
+				role := fmt.Sprintf("DATABASES[%q]", alias)
+				if db.isString() {
+					// dj_database_url.config(default=...) and env.db(...)
+					add(settingDep(db, db.str, role, "database", 0, model.High))
+					continue
+				}
+				add(djangoDatabaseDep(db, role))
+			}
+		case a.name == "CACHES":
+			for _, alias := range a.value.keys {
+				for _, d := range djangoCacheDeps(a.value.dict[alias], fmt.Sprintf("CACHES[%q]", alias)) {
+					add(d, true)
+				}
+			}
+		case celerySettings[a.name] != "":
+			if !a.value.isString() {
+				continue
+			}
+			serviceType := ""
+			if celerySettings[a.name] == "broker" {
+				serviceType = "broker"
+			}
+			// A broker URL may list failover brokers separated by ';'.
+			for _, u := range strings.Split(a.value.str, ";") {
+				add(settingDep(a.value, u, "Celery "+celerySettings[a.name], serviceType, 0, model.High))
+			}
+		case pySettingKeyRe.MatchString(a.name):
+			for _, n := range a.value.strings() {
+				port := 0
+				// EMAIL_HOST = "smtp.example.com" pairs with EMAIL_PORT = 587.
+				if base, ok := strings.CutSuffix(a.name, "_HOST"); ok && values[base+"_PORT"] != nil {
+					port, _ = strconv.Atoi(values[base+"_PORT"].str)
+				}
+				add(settingDep(n, n.str, a.name, serviceTypeFromEnvKey(a.name), port, model.Medium))
+			}
+		}
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
+
+// djangoDatabaseDep reads one DATABASES entry: HOST plus PORT, or the
+// engine's default port. Entries without a HOST (or with a socket path)
+// and file-backed engines yield nothing.
+func djangoDatabaseDep(db *pyNode, role string) (model.NetworkDependency, bool) {
+	if db == nil || db.dict == nil {
+		return model.NetworkDependency{}, false
+	}
+	engine := ""
+	if e := db.dict["ENGINE"]; e.isString() {
+		engine = e.str[strings.LastIndex(e.str, ".")+1:]
+	}
+	port, desc := 0, "database"
+	for _, e := range djangoEngines {
+		if e.engine == engine {
+			port, desc = e.port, e.desc
+			break
+		}
+	}
+	if p := db.dict["PORT"]; p != nil {
+		if n, err := strconv.Atoi(p.str); err == nil && n > 0 {
+			port = n
+		}
+	}
+	host := db.dict["HOST"]
+	if !host.isString() || port == 0 {
+		return model.NetworkDependency{}, false
+	}
+	return settingDep(host, host.str, fmt.Sprintf("%s (%s)", role, desc), "database", port, model.High)
+}
+
+// djangoCacheDeps reads one CACHES entry's LOCATION, a string or a list of
+// servers, using the BACKEND's default port for bare hostnames.
+func djangoCacheDeps(cache *pyNode, role string) []model.NetworkDependency {
+	if cache == nil || cache.dict == nil {
+		return nil
+	}
+	backend := ""
+	if b := cache.dict["BACKEND"]; b.isString() {
+		backend = strings.ToLower(b.str)
+	}
+	port := 0
+	switch {
+	case strings.Contains(backend, "redis"):
+		port = 6379
+	case strings.Contains(backend, "memcache"), strings.Contains(backend, "pylibmc"):
+		port = 11211
+	}
+	var deps []model.NetworkDependency
+	for _, n := range cache.dict["LOCATION"].strings() {
+		if d, ok := settingDep(n, n.str, role, "cache", port, model.High); ok {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// settingDep builds a dep from a setting value: a URL, host:port, or a bare
+// host when defaultPort is known.
+func settingDep(n *pyNode, val, role, serviceType string, defaultPort int, confidence model.Confidence) (model.NetworkDependency, bool) {
+	host, port, scheme, ok := endpointFromValue(val, defaultPort)
+	if !ok {
+		return model.NetworkDependency{}, false
+	}
+	if serviceType == "" {
+		serviceType = schemeServiceTypes[scheme]
+	}
+	desc := fmt.Sprintf("%s: %s:%d", role, host, port)
+	if n.env != "" {
+		// The environment variable wins at runtime; this is its default.
+		confidence = model.Medium
+		desc += fmt.Sprintf(" (default of $%s)", n.env)
+	}
+	return model.NetworkDependency{
+		Target:      host,
+		Port:        port,
+		Protocol:    inferProtocol(scheme, port),
+		Description: desc,
+		Confidence:  confidence,
+		Line:        n.line,
+		Column:      n.col,
+		ServiceType: serviceType,
+	}, true
+}
+
+// pyNode is a Python literal: a string or number (str), a dict with string
+// keys, or a list/tuple. env is set when the value is the default of an
+// environment lookup.
+type pyNode struct {
+	str       string
+	quoted    bool
+	dict      map[string]*pyNode
+	keys      []string
+	list      []*pyNode
+	env       string
+	line, col int
+}
+
+func (n *pyNode) isString() bool {
+	return n != nil && n.quoted
+}
+
+// strings returns n if it is a string, or the strings of a list.
+func (n *pyNode) strings() []*pyNode {
+	if n.isString() {
+		return []*pyNode{n}
+	}
+	var out []*pyNode
+	if n != nil {
+		for _, e := range n.list {
+			if e.isString() {
+				out = append(out, e)
+			}
+		}
+	}
+	return out
+}
+
+// pyAssign is a module-level `NAME = value`. For dotted targets
+// (app.conf.broker_url) name is the last component.
+type pyAssign struct {
+	name  string
+	value *pyNode
+}
+
+// pyEnvCalls are the environment lookups whose default stands in for the
+// value: os.environ.get("X", default), os.getenv, django-environ's
+// env("X", default=...) / env.str / env.db, python-decouple's config and
+// dj_database_url.config(default=...).
+var pyEnvCalls = map[string]bool{
+	"os.environ.get":         true,
+	"environ.get":            true,
+	"os.getenv":              true,
+	"getenv":                 true,
+	"env":                    true,
+	"env.str":                true,
+	"env.url":                true,
+	"env.db":                 true,
+	"env.db_url":             true,
+	"env.cache":              true,
+	"env.cache_url":          true,
+	"config":                 true,
+	"dj_database_url.config": true,
+}
+
+// readPyAssignments returns the module-level assignments whose value is a
+// literal. Indented statements (inside if/def/class) are skipped, as are
+// `app.conf.update(key=value, ...)` calls, which are read as assignments.
+func readPyAssignments(src []byte) []pyAssign {
+	p := &pyParser{toks: tokenizePython(src)}
+	var out []pyAssign
+	for p.peek().kind != pyEOF {
+		start := p.peek()
+		if start.kind == pyName && start.col == 1 {
+			target := p.dottedName()
+			last := target[strings.LastIndex(target, ".")+1:]
+			switch {
+			case p.isOp("="):
+				p.next()
+				if v := p.expr(); v != nil {
+					out = append(out, pyAssign{name: last, value: v})
+				}
+			case p.isOp("(") && strings.HasSuffix(target, "conf.update"):
+				p.next()
+				_, kwargs := p.args()
+				for _, kw := range kwargs {
+					if kw.value != nil {
+						out = append(out, pyAssign{name: kw.name, value: kw.value})
+					}
+				}
+			}
+		}
+		p.skipStatement()
+	}
+	return out
+}
+
+type pyTokenKind int
+
+const (
+	pyEOF pyTokenKind = iota
+	pyNewline
+	pyName
+	pyString
+	pyFString // f-strings are not literals
+	pyNumber
+	pyOp
+)
+
+type pyToken struct {
+	kind      pyTokenKind
+	text      string
+	line, col int
+}
+
+// tokenizePython splits Python source into tokens. Newlines inside
+// brackets and after a backslash are joined, as in Python, so a newline
+// token always ends a logical line.
+func tokenizePython(src []byte) []pyToken {
+	var toks []pyToken
+	line, lineStart, depth := 1, 0, 0
+	emit := func(kind pyTokenKind, text string, at int) {
+		toks = append(toks, pyToken{kind: kind, text: text, line: line, col: at - lineStart + 1})
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			if depth == 0 && len(toks) > 0 && toks[len(toks)-1].kind != pyNewline {
+				emit(pyNewline, "", i)
+			}
+			i++
+			line, lineStart = line+1, i
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' || isPyIdentStart(c):
+			j := i
+			for j < len(src) && isPyIdentChar(src[j]) {
+				j++
+			}
+			prefix := strings.ToLower(string(src[i:j]))
+			if j < len(src) && (src[j] == '"' || src[j] == '\'') && len(prefix) <= 2 && strings.Trim(prefix, "rbuf") == "" {
+				val, end := readPyString(src, j, strings.Contains(prefix, "r"))
+				kind := pyString
+				if strings.Contains(prefix, "f") {
+					kind = pyFString
+				}
+				emit(kind, val, i)
+				for k := i; k < end; k++ {
+					if src[k] == '\n' {
+						line, lineStart = line+1, k+1
+					}
+				}
+				i = end
+				continue
+			}
+			emit(pyName, string(src[i:j]), i)
+			i = j
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(src) && (isPyIdentChar(src[j]) || src[j] == '.') {
+				j++
+			}
+			emit(pyNumber, strings.ReplaceAll(string(src[i:j]), "_", ""), i)
+			i = j
+		default:
+			op := string(c)
+			if i+1 < len(src) {
+				switch two := string(src[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "**", "//", "->", ":=":
+					op = two
+				}
+			}
+			switch op {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+			emit(pyOp, op, i)
+			i += len(op)
+		}
+	}
+	return append(toks, pyToken{kind: pyEOF, line: line})
+}
+
+// readPyString reads the string literal starting at the quote at src[i],
+// single or triple quoted, and returns its value and the index after it.
+// Escapes other than quotes and backslashes are kept as written.
+func readPyString(src []byte, i int, raw bool) (string, int) {
+	quote := src[i]
+	delim := string(quote)
+	if i+2 < len(src) && src[i+1] == quote && src[i+2] == quote {
+		delim = strings.Repeat(string(quote), 3)
+	}
+	var b strings.Builder
+	j := i + len(delim)
+	for j < len(src) {
+		if bytes.HasPrefix(src[j:], []byte(delim)) {
+			return b.String(), j + len(delim)
+		}
+		c := src[j]
+		if c == '\n' && len(delim) == 1 {
+			break
+		}
+		if c == '\\' && j+1 < len(src) {
+			if next := src[j+1]; !raw && (next == '\\' || next == '\'' || next == '"') {
+				b.WriteByte(next)
+			} else {
+				b.WriteByte(c)
+				b.WriteByte(next)
+			}
+			j += 2
+			continue
+		}
+		b.WriteByte(c)
+		j++
+	}
+	return b.String(), j
+}
+
+func isPyIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isPyIdentChar(c byte) bool {
+	return isPyIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// pyParser is a recursive-descent reader for Python literal expressions.
+// Expressions it can't evaluate are skipped and read as nil.
+type pyParser struct {
+	toks []pyToken
+	pos  int
+}
+
+type pyKwarg struct {
+	name  string
+	value *pyNode
+}
+
+func (p *pyParser) peek() pyToken {
+	return p.toks[p.pos]
+}
+
+func (p *pyParser) next() pyToken {
+	t := p.toks[p.pos]
+	if t.kind != pyEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *pyParser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == pyOp && t.text == op
+}
+
+// skipStatement advances past the end of the current logical line.
+func (p *pyParser) skipStatement() {
+	for {
+		switch p.next().kind {
+		case pyNewline, pyEOF:
+			return
+		}
+	}
+}
+
+// skipTo advances past the bracket that closes the current one.
+func (p *pyParser) skipTo(closer string) {
+	depth := 0
+	for {
+		t := p.next()
+		if t.kind == pyEOF {
+			return
+		}
+		if t.kind != pyOp {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+	}
+}
+
+// atExprEnd reports whether the next token ends an expression.
+func (p *pyParser) atExprEnd() bool {
+	t := p.peek()
+	switch t.kind {
+	case pyNewline, pyEOF:
+		return true
+	case pyOp:
+		return strings.Contains(",:)]}", t.text) || t.text == "="
+	}
+	return false
+}
+
+// expr reads a literal expression. Anything beyond a single literal
+// (operators, conditionals, comprehensions) is skipped and yields nil.
+func (p *pyParser) expr() *pyNode {
+	n := p.primary()
+	if p.atExprEnd() {
+		return n
+	}
+	depth := 0
+	for {
+		t := p.peek()
+		if t.kind == pyNewline || t.kind == pyEOF {
+			return nil
+		}
+		if t.kind == pyOp {
+			switch t.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth == 0 {
+					return nil
+				}
+				depth--
+			case ",", ":":
+				if depth == 0 {
+					return nil
+				}
+			}
+		}
+		p.next()
+	}
+}
+
+func (p *pyParser) primary() *pyNode {
+	t := p.peek()
+	switch {
+	case t.kind == pyString || t.kind == pyFString:
+		n := &pyNode{quoted: true, line: t.line, col: t.col}
+		literal := true
+		// Adjacent literals concatenate: "redis://" "cache:6379".
+		for p.peek().kind == pyString || p.peek().kind == pyFString {
+			s := p.next()
+			literal = literal && s.kind == pyString
+			n.str += s.text
+		}
+		if !literal {
+			return nil
+		}
+		return n
+	case t.kind == pyNumber:
+		p.next()
+		return &pyNode{str: t.text, line: t.line, col: t.col}
+	case t.kind == pyOp && t.text == "{":
+		return p.dict()
+	case t.kind == pyOp && (t.text == "[" || t.text == "("):
+		return p.list()
+	case t.kind == pyName:
+		name := p.dottedName()
+		if !p.isOp("(") {
+			return nil
+		}
+		p.next()
+		pos, kwargs := p.args()
+		if !pyEnvCalls[name] {
+			return nil
+		}
+		var def *pyNode
+		if len(pos) > 1 {
+			def = pos[1]
+		}
+		for _, kw := range kwargs {
+			if kw.name == "default" {
+				def = kw.value
+			}
+		}
+		if def == nil {
+			return nil
+		}
+		out := *def
+		if len(pos) > 0 && pos[0].isString() {
+			out.env = pos[0].str
+		}
+		return &out
+	}
+	return nil
+}
+
+func (p *pyParser) dottedName() string {
+	name := p.next().text
+	for p.isOp(".") && p.toks[p.pos+1].kind == pyName {
+		p.next()
+		name += "." + p.next().text
+	}
+	return name
+}
+
+func (p *pyParser) dict() *pyNode {
+	open := p.next()
+	n := &pyNode{dict: make(map[string]*pyNode), line: open.line, col: open.col}
+	for {
+		if p.isOp("}") {
+			p.next()
+			return n
+		}
+		if p.isOp("**") {
+			// {**BASE, ...}: the spread dict isn't known here.
+			p.next()
+			p.expr()
+		} else {
+			k := p.expr()
+			if !p.isOp(":") {
+				p.skipTo("}")
+				return nil // a set, not a dict
+			}
+			p.next()
+			v := p.expr()
+			if k.isString() {
+				if _, dup := n.dict[k.str]; !dup {
+					n.keys = append(n.keys, k.str)
+				}
+				n.dict[k.str] = v
+			}
+		}
+		if p.isOp(",") {
+			p.next()
+		} else if !p.isOp("}") {
+			p.skipTo("}")
+			return n
+		}
+	}
+}
+
+func (p *pyParser) list() *pyNode {
+	open := p.next()
+	closer := "]"
+	if open.text == "(" {
+		closer = ")"
+	}
+	n := &pyNode{line: open.line, col: open.col}
+	tuple := false
+	for {
+		if p.isOp(closer) {
+			p.next()
+			break
+		}
+		n.list = append(n.list, p.expr())
+		if p.isOp(",") {
+			p.next()
+			tuple = true
+		} else if !p.isOp(closer) {
+			p.skipTo(closer)
+			return nil
+		}
+	}
+	if closer == ")" && !tuple && len(n.list) == 1 {
+		return n.list[0] // parenthesized expression
+	}
+	return n
+}
+
+// args reads call arguments up to and including the closing parenthesis.
+func (p *pyParser) args() ([]*pyNode, []pyKwarg) {
+	var pos []*pyNode
+	var kwargs []pyKwarg
+	for {
+		if p.isOp(")") {
+			p.next()
+			return pos, kwargs
+		}
+		switch {
+		case p.peek().kind == pyName && p.toks[p.pos+1].kind == pyOp && p.toks[p.pos+1].text == "=":
+			name := p.next().text
+			p.next()
+			kwargs = append(kwargs, pyKwarg{name: name, value: p.expr()})
+		case p.isOp("*") || p.isOp("**"):
+			p.next()
+			p.expr()
+		default:
+			pos = append(pos, p.expr())
+		}
+		if p.isOp(",") {
+			p.next()
+		} else if !p.isOp(")") {
+			p.skipTo(")")
+			return pos, kwargs
+		}
+	}
+}
@@ -0,0 +1,681 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	defaultRegistry.RegisterResults("rails", "config/database.yml", parseRailsDatabase)
+	defaultRegistry.RegisterResults("rails", "config/cable.yml", parseRailsCable)
+	defaultRegistry.RegisterResults("rails", "config/storage.yml", parseRailsStorage)
+	defaultRegistry.RegisterResults("rails", "config/sidekiq.yml", parseSidekiqConfig)
+	defaultRegistry.RegisterFormat("rails", "Gemfile.lock", parseGemfileLock)
+}
+
+// Rails config files are YAML run through ERB. Only the ERB a config
+// needs to name its endpoints is evaluated: ENV["X"], ENV["X"] || "d",
+// ENV.fetch("X"), ENV.fetch("X", "d") and ENV.fetch("X") { "d" }, looked
+// up in the .env files dotenv-rails loads for the environment. Any other
+// Ruby is dropped, and a value that needed it is skipped. Most files are
+// keyed by Rails environment; findings carry the environment they were
+// read for.
+
+// railsAdapters maps ActiveRecord adapters (and URL schemes) to their
+// database and default port. sqlite3 has no network peer.
+var railsAdapters = map[string]struct {
+	name string
+	port int
+}{
+	"postgresql":      {"PostgreSQL", 5432},
+	"postgres":        {"PostgreSQL", 5432},
+	"postgis":         {"PostgreSQL", 5432},
+	"mysql2":          {"MySQL", 3306},
+	"mysql":           {"MySQL", 3306},
+	"trilogy":         {"MySQL", 3306},
+	"sqlserver":       {"SQL Server", 1433},
+	"oracle_enhanced": {"Oracle", 1521},
+}
+
+// knownGems maps client gems to the infrastructure they talk to, like
+// knownLibs does for Maven/Gradle artifacts.
+var knownGems = []infraLib{
+	{"pg", "postgresql", 5432, "PostgreSQL (pg)", "database"},
+	{"mysql2", "mysql", 3306, "MySQL (mysql2)", "database"},
+	{"trilogy", "mysql", 3306, "MySQL (trilogy)", "database"},
+	{"activerecord-sqlserver-adapter", "sqlserver", 1433, "SQL Server (activerecord-sqlserver-adapter)", "database"},
+	{"redis", "redis", 6379, "Redis (redis-rb)", "cache"},
+	{"redis-client", "redis", 6379, "Redis (redis-client)", "cache"},
+	{"sidekiq", "redis", 6379, "Redis (Sidekiq)", "cache"},
+	{"resque", "redis", 6379, "Redis (Resque)", "cache"},
+	{"ruby-kafka", "kafka", 9092, "Kafka (ruby-kafka)", "broker"},
+	{"rdkafka", "kafka", 9092, "Kafka (rdkafka)", "broker"},
+	{"karafka", "kafka", 9092, "Kafka (Karafka)", "broker"},
+	{"bunny", "rabbitmq", 5672, "RabbitMQ (Bunny)", "broker"},
+	{"sneakers", "rabbitmq", 5672, "RabbitMQ (Sneakers)", "broker"},
+	{"mongoid", "mongodb", 27017, "MongoDB (Mongoid)", "database"},
+	{"mongo", "mongodb", 27017, "MongoDB (driver)", "database"},
+	{"elasticsearch", "elasticsearch", 9200, "Elasticsearch", "search"},
+	{"dalli", "memcached", 11211, "Memcached (Dalli)", "cache"},
+	{"nats-pure", "nats", 4222, "NATS", "broker"},
+}
+
+// railsFile is a Rails YAML config read for one environment: ERB
+// expanded with that environment's variables, and where each line's
+// values came from.
+type railsFile struct {
+	path        string
+	environment string
+	lines       []string // as written, for evidence
+	erb         map[int]*erbLine
+	root        *yaml.Node
+}
+
+// erbLine is what the ERB tags on one line resolved through.
+type erbLine struct {
+	defaulted  bool // a fetch default or || fallback supplied a value
+	unresolved bool // an output tag couldn't be evaluated
+	sources    []model.Provenance
+}
+
+// railsRoot returns the app root of a file in config/.
+func railsRoot(path string) string {
+	return filepath.Dir(filepath.Dir(filepath.Clean(path)))
+}
+
+// railsEnvVars returns the variables dotenv-rails loads for environment,
+// lowest precedence first: .env, .env.<env>, .env.local (not in test)
+// and .env.<env>.local. An empty environment reads .env alone.
+func railsEnvVars(root, environment string) map[string]envSetting {
+	files := []string{".env"}
+	if environment != "" {
+		files = append(files, ".env."+environment)
+		if environment != "test" {
+			files = append(files, ".env.local")
+		}
+		files = append(files, ".env."+environment+".local")
+	}
+	vars := make(map[string]envSetting)
+	for _, f := range files {
+		for k, v := range readDotenv(filepath.Join(root, f)) {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+var (
+	erbTagRe   = regexp.MustCompile(`(?s)<%([=#-]?)(.*?)-?%>`)
+	erbFetchRe = regexp.MustCompile(`^ENV\.fetch\(\s*["']([A-Za-z_][A-Za-z0-9_]*)["']\s*(?:,\s*(.+?)\s*)?\)(?:\s*\{\s*(.*?)\s*\})?$`)
+	erbIndexRe = regexp.MustCompile(`^ENV\[\s*["']([A-Za-z_][A-Za-z0-9_]*)["']\s*\](?:\s*\|\|\s*(.+))?$`)
+	rubyIntRe  = regexp.MustCompile(`^-?\d+$`)
+)
+
+// expandERB evaluates the ERB tags of a config against vars. Tags that
+// print nothing (<% %>, <%# %>) are dropped; an output tag it can't
+// evaluate prints nothing and marks its line unresolved. Newlines inside
+// tags are kept so lines still match the file.
+func expandERB(data string, vars map[string]envSetting) (string, map[int]*erbLine) {
+	info := make(map[int]*erbLine)
+	var b strings.Builder
+	last := 0
+	for _, m := range erbTagRe.FindAllStringSubmatchIndex(data, -1) {
+		b.WriteString(data[last:m[0]])
+		last = m[1]
+		tag := data[m[0]:m[1]]
+		line := strings.Count(data[:m[0]], "\n") + 1
+		if data[m[2]:m[3]] == "=" {
+			l := info[line]
+			if l == nil {
+				l = &erbLine{}
+				info[line] = l
+			}
+			value, ok := evalERB(strings.TrimSpace(data[m[4]:m[5]]), vars, l)
+			if !ok {
+				l.unresolved = true
+			}
+			b.WriteString(value)
+		}
+		b.WriteString(strings.Repeat("\n", strings.Count(tag, "\n")))
+	}
+	b.WriteString(data[last:])
+	return b.String(), info
+}
+
+// evalERB evaluates an ENV lookup, recording its source or default in l.
+func evalERB(expr string, vars map[string]envSetting, l *erbLine) (string, bool) {
+	expr = strings.TrimSuffix(strings.TrimSuffix(expr, ".to_i"), ".to_s")
+	var name, def string
+	if m := erbFetchRe.FindStringSubmatch(expr); m != nil {
+		name, def = m[1], m[2]
+		if m[3] != "" {
+			def = m[3]
+		}
+	} else if m := erbIndexRe.FindStringSubmatch(expr); m != nil {
+		name, def = m[1], m[2]
+	} else if v, ok := rubyLiteral(expr); ok {
+		return v, true
+	} else {
+		return "", false
+	}
+	if e, ok := vars[name]; ok {
+		l.sources = append(l.sources, e.provenance())
+		return e.value, true
+	}
+	v, ok := rubyLiteral(def)
+	if ok {
+		l.defaulted = true
+	}
+	return v, ok
+}
+
+// rubyLiteral reads a string or integer literal.
+func rubyLiteral(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], true
+	}
+	if rubyIntRe.MatchString(s) {
+		return s, true
+	}
+	return "", false
+}
+
+// loadRailsFile reads a config file for one environment. An unparsable
+// file is an error; the environment's own section is up to the caller.
+func loadRailsFile(path, environment string) (*railsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	expanded, info := expandERB(string(data), railsEnvVars(railsRoot(path), environment))
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	f := &railsFile{
+		path:        path,
+		environment: environment,
+		lines:       strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"),
+		erb:         info,
+		root:        &doc,
+	}
+	if len(doc.Content) > 0 {
+		f.root = doc.Content[0]
+	}
+	return f, nil
+}
+
+// railsEnvironments lists the environment sections of a config keyed by
+// environment, skipping the conventional `default` anchor holder.
+func railsEnvironments(path string) ([]string, error) {
+	f, err := loadRailsFile(path, "")
+	if err != nil {
+		return nil, err
+	}
+	var envs []string
+	for _, p := range yamlPairs(f.root) {
+		if p.key != "default" && p.key != "defaults" && p.value.Kind == yaml.MappingNode {
+			envs = append(envs, p.key)
+		}
+	}
+	return envs, nil
+}
+
+// yamlPair is one mapping entry.
+type yamlPair struct {
+	key   string
+	value *yaml.Node
+}
+
+// yamlPairs returns a mapping's entries with aliases followed and merge
+// keys (<<: *default) applied, explicit keys winning over merged ones.
+// Sidekiq's symbol keys (:concurrency:) lose their colon.
+func yamlPairs(n *yaml.Node) []yamlPair {
+	n = yamlDeref(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var merged, own []yamlPair
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], yamlDeref(n.Content[i+1])
+		if k.Value == "<<" {
+			sources := []*yaml.Node{v}
+			if v.Kind == yaml.SequenceNode {
+				sources = v.Content
+			}
+			for _, s := range sources {
+				merged = append(merged, yamlPairs(s)...)
+			}
+			continue
+		}
+		own = append(own, yamlPair{key: strings.TrimPrefix(k.Value, ":"), value: v})
+	}
+	out := own
+	for _, m := range merged {
+		if yamlGet(own, m.key) == nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func yamlDeref(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// yamlGet returns the scalar value node at key, or nil.
+func yamlGet(pairs []yamlPair, key string) *yaml.Node {
+	for _, p := range pairs {
+		if p.key == key {
+			return p.value
+		}
+	}
+	return nil
+}
+
+// scalar returns the value of a scalar node and whether it is usable:
+// present, and not the product of ERB that couldn't be evaluated.
+func (f *railsFile) scalar(n *yaml.Node) (string, bool) {
+	if n == nil || n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return "", false
+	}
+	if l := f.erb[n.Line]; l != nil && l.unresolved {
+		return "", false
+	}
+	return strings.TrimSpace(n.Value), n.Value != ""
+}
+
+// dep fills in where d was declared (the line holding n) and the
+// environment it was read for. Values resolved through a .env chain
+// their provenance; values that fell back to an ERB default are only
+// Medium.
+func (f *railsFile) dep(n *yaml.Node, d model.NetworkDependency) model.NetworkDependency {
+	d.SourceFile = f.path
+	d.Line, d.Column = n.Line, n.Column
+	d.Environment = f.environment
+	d.EvidenceLine = f.evidence(n)
+	l := f.erb[n.Line]
+	if l == nil {
+		return d
+	}
+	if l.defaulted && d.Confidence == model.High {
+		d.Confidence = model.Medium
+	}
+	if len(l.sources) > 0 {
+		d.Provenance = []model.Provenance{{File: d.SourceFile, Line: d.Line, Column: d.Column, Evidence: d.EvidenceLine, Parser: "rails", Confidence: d.Confidence, Environment: d.Environment}}
+		for _, s := range l.sources {
+			s.Confidence = d.Confidence
+			s.Environment = d.Environment
+			d.Provenance = append(d.Provenance, s)
+		}
+	}
+	return d
+}
+
+// evidence renders the line holding n as written, plus the value ERB
+// resolved it to, secrets redacted.
+func (f *railsFile) evidence(n *yaml.Node) string {
+	if n.Line < 1 || n.Line > len(f.lines) {
+		return ""
+	}
+	s := strings.TrimSpace(f.lines[n.Line-1])
+	if f.erb[n.Line] != nil {
+		s += " (resolved: " + n.Value + ")"
+	}
+	return model.RedactSecrets(s)
+}
+
+// perEnvironment runs read on the section of every environment in a
+// config keyed by environment.
+func perEnvironment(path string, read func(f *railsFile, section []yamlPair) []model.NetworkDependency) (Result, error) {
+	envs, err := railsEnvironments(path)
+	if err != nil {
+		return Result{}, err
+	}
+	var res Result
+	for _, env := range envs {
+		f, err := loadRailsFile(path, env)
+		if err != nil {
+			return Result{}, err
+		}
+		section := yamlPairs(yamlGet(yamlPairs(f.root), env))
+		res.Dependencies = append(res.Dependencies, read(f, section)...)
+	}
+	return railsResult(path, res), nil
+}
+
+// railsResult stamps the parser and the file's disable directive.
+func railsResult(path string, res Result) Result {
+	data, _ := os.ReadFile(path)
+	disable := ScanFileDisable(data)
+	for i := range res.Dependencies {
+		res.Dependencies[i].Parser = "rails"
+		res.Dependencies[i].Disabled = disable
+	}
+	return res
+}
+
+// parseRailsDatabase reads config/database.yml: per environment, one
+// database (adapter, host, port, url) or several named ones (Rails 6
+// multi-database: primary, replica, cache...). Hosts starting with / are
+// Unix sockets, and a config without host or url connects over the
+// adapter's local socket; neither is a network peer.
+func parseRailsDatabase(path string) (Result, error) {
+	return perEnvironment(path, func(f *railsFile, section []yamlPair) []model.NetworkDependency {
+		configs := map[string][]yamlPair{"": section}
+		names := []string{""}
+		if yamlGet(section, "adapter") == nil && yamlGet(section, "url") == nil && yamlGet(section, "host") == nil {
+			configs, names = map[string][]yamlPair{}, nil
+			for _, p := range section {
+				if p.value.Kind == yaml.MappingNode {
+					configs[p.key] = yamlPairs(p.value)
+					names = append(names, p.key)
+				}
+			}
+		}
+		var deps []model.NetworkDependency
+		for _, name := range names {
+			deps = append(deps, railsDatabaseDeps(f, name, configs[name])...)
+		}
+		return deps
+	})
+}
+
+func railsDatabaseDeps(f *railsFile, name string, config []yamlPair) []model.NetworkDependency {
+	adapter, _ := f.scalar(yamlGet(config, "adapter"))
+	label := "database.yml " + f.environment
+	if name != "" {
+		label += " " + name
+	}
+	describe := func(db string) string {
+		return fmt.Sprintf("%s (%s)", db, label)
+	}
+
+	// A url that can't be resolved (ENV["DATABASE_URL"] set only in the
+	// deployment) leaves the host/port keys, often merged from the
+	// default section, as the best guess.
+	if n := yamlGet(config, "url"); n != nil {
+		if u, ok := f.scalar(n); ok && strings.Contains(u, "://") {
+			scheme, _, _ := strings.Cut(u, "://")
+			urlAdapter := adapter
+			if urlAdapter == "" {
+				urlAdapter = strings.ToLower(scheme)
+			}
+			a := railsAdapters[urlAdapter]
+			if host, port, _, ok := endpointFromValue(u, a.port); ok {
+				return []model.NetworkDependency{f.dep(n, model.NetworkDependency{
+					Target:      host,
+					Port:        port,
+					Protocol:    "TCP",
+					Description: describe(a.name),
+					Confidence:  model.High,
+					ServiceType: "database",
+				})}
+			}
+		}
+	}
+
+	hostNode := yamlGet(config, "host")
+	hosts, ok := f.scalar(hostNode)
+	a, known := railsAdapters[adapter]
+	if !ok || strings.HasPrefix(hosts, "/") {
+		return nil
+	}
+	port := a.port
+	if p, ok := f.scalar(yamlGet(config, "port")); ok {
+		port, _ = strconv.Atoi(p)
+	}
+	if port <= 0 {
+		return nil
+	}
+	db := a.name
+	if !known {
+		db = "database"
+	}
+	var deps []model.NetworkDependency
+	for _, h := range strings.Split(hosts, ",") {
+		if host, p, _, ok := endpointFromValue(strings.TrimSpace(h), port); ok {
+			deps = append(deps, f.dep(hostNode, model.NetworkDependency{
+				Target:      host,
+				Port:        p,
+				Protocol:    "TCP",
+				Description: describe(db),
+				Confidence:  model.High,
+				ServiceType: "database",
+			}))
+		}
+	}
+	return deps
+}
+
+// parseRailsCable reads config/cable.yml: the Redis URL of each
+// environment using the redis adapter. The async, test and postgresql
+// adapters reach no peer of their own.
+func parseRailsCable(path string) (Result, error) {
+	return perEnvironment(path, func(f *railsFile, section []yamlPair) []model.NetworkDependency {
+		if adapter, _ := f.scalar(yamlGet(section, "adapter")); adapter != "redis" {
+			return nil
+		}
+		n := yamlGet(section, "url")
+		u, ok := f.scalar(n)
+		if !ok {
+			return nil
+		}
+		var deps []model.NetworkDependency
+		for _, ep := range springEndpoints(u, 6379) {
+			deps = append(deps, f.dep(n, model.NetworkDependency{
+				Target:      ep.host,
+				Port:        ep.port,
+				Protocol:    "TCP",
+				Description: fmt.Sprintf("Action Cable Redis (cable.yml %s)", f.environment),
+				Confidence:  model.High,
+				ServiceType: "cache",
+			}))
+		}
+		return deps
+	})
+}
+
+// railsStorageServiceRe matches the Active Storage service an environment
+// file selects.
+var railsStorageServiceRe = regexp.MustCompile(`config\.active_storage\.service\s*=\s*:(\w+)`)
+
+// railsStorageEnvironments maps each Active Storage service to the
+// environments whose config/environments/<env>.rb selects it.
+func railsStorageEnvironments(root string) map[string][]string {
+	out := make(map[string][]string)
+	files, _ := filepath.Glob(filepath.Join(root, "config", "environments", "*.rb"))
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		env := strings.TrimSuffix(filepath.Base(file), ".rb")
+		for _, m := range railsStorageServiceRe.FindAllStringSubmatch(string(data), -1) {
+			out[m[1]] = append(out[m[1]], env)
+		}
+	}
+	return out
+}
+
+// parseRailsStorage reads config/storage.yml. Its sections are Active
+// Storage services, not environments: an S3 service reaches its endpoint
+// or s3.<region>.amazonaws.com, GCS storage.googleapis.com and Azure
+// <account>.blob.core.windows.net. A service only one environment selects
+// (config/environments/<env>.rb) is tagged with that environment. Disk
+// and Mirror services have no peer of their own.
+func parseRailsStorage(path string) (Result, error) {
+	base, err := loadRailsFile(path, "")
+	if err != nil {
+		return Result{}, err
+	}
+	used := railsStorageEnvironments(railsRoot(path))
+	var res Result
+	for _, svc := range yamlPairs(base.root) {
+		f := base
+		if envs := used[svc.key]; len(envs) == 1 {
+			if f, err = loadRailsFile(path, envs[0]); err != nil {
+				return Result{}, err
+			}
+		}
+		config := yamlPairs(yamlGet(yamlPairs(f.root), svc.key))
+		kind, _ := f.scalar(yamlGet(config, "service"))
+		var n *yaml.Node
+		var host string
+		port := 443
+		confidence := model.High
+		switch kind {
+		case "S3":
+			if n = yamlGet(config, "endpoint"); n != nil {
+				u, ok := f.scalar(n)
+				if !ok {
+					continue
+				}
+				h, p, _, ok := endpointFromValue(u, 443)
+				if !ok {
+					continue
+				}
+				host, port = h, p
+			} else if n = yamlGet(config, "region"); n != nil {
+				region, ok := f.scalar(n)
+				if !ok {
+					continue
+				}
+				host, confidence = "s3."+region+".amazonaws.com", model.Medium
+			}
+		case "GCS":
+			n, host, confidence = yamlGet(config, "bucket"), "storage.googleapis.com", model.Medium
+		case "AzureStorage":
+			n = yamlGet(config, "storage_account_name")
+			if account, ok := f.scalar(n); ok {
+				host, confidence = account+".blob.core.windows.net", model.Medium
+			}
+		}
+		if host == "" || n == nil {
+			continue
+		}
+		res.Dependencies = append(res.Dependencies, f.dep(n, model.NetworkDependency{
+			Target:      host,
+			Port:        port,
+			Protocol:    "TCP",
+			Description: fmt.Sprintf("Active Storage %s (storage.yml %s)", kind, svc.key),
+			Confidence:  confidence,
+			ServiceType: "storage",
+		}))
+	}
+	return railsResult(path, res), nil
+}
+
+// parseSidekiqConfig reads config/sidekiq.yml for a Redis URL (:redis:
+// :url:, or redis_url), top-level or in an environment section. Sidekiq
+// usually takes Redis from REDIS_URL instead, which the envfile parser
+// reports.
+func parseSidekiqConfig(path string) (Result, error) {
+	base, err := loadRailsFile(path, "")
+	if err != nil {
+		return Result{}, err
+	}
+	var res Result
+	var visit func(f *railsFile, pairs []yamlPair, depth int)
+	visit = func(f *railsFile, pairs []yamlPair, depth int) {
+		for _, p := range pairs {
+			switch {
+			case p.value.Kind == yaml.MappingNode && depth == 0 && p.key != "redis":
+				// An environment section, read with its own variables.
+				env, err := loadRailsFile(path, p.key)
+				if err != nil {
+					continue
+				}
+				visit(env, yamlPairs(yamlGet(yamlPairs(env.root), p.key)), depth+1)
+			case p.value.Kind == yaml.MappingNode:
+				visit(f, yamlPairs(p.value), depth+1)
+			case p.key == "url" || p.key == "redis_url":
+				u, ok := f.scalar(p.value)
+				if !ok {
+					continue
+				}
+				for _, ep := range springEndpoints(u, 6379) {
+					res.Dependencies = append(res.Dependencies, f.dep(p.value, model.NetworkDependency{
+						Target:      ep.host,
+						Port:        ep.port,
+						Protocol:    "TCP",
+						Description: "Sidekiq Redis",
+						Confidence:  model.High,
+						ServiceType: "cache",
+					}))
+				}
+			}
+		}
+	}
+	visit(base, yamlPairs(base.root), 0)
+	return railsResult(path, res), nil
+}
+
+// parseGemfileLock infers Low-confidence infrastructure deps from the
+// client gems a Rails app depends on directly (the DEPENDENCIES section).
+func parseGemfileLock(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var deps []model.NetworkDependency
+	section := ""
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if line != "" && line[0] != ' ' {
+			section = strings.TrimSpace(line)
+			continue
+		}
+		// Direct dependencies are indented two spaces: "  pg (~> 1.1)".
+		if section != "DEPENDENCIES" || !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") {
+			continue
+		}
+		entry := strings.TrimSpace(line)
+		name, _, _ := strings.Cut(entry, " ")
+		name = strings.TrimSuffix(name, "!")
+		for _, lib := range knownGems {
+			if name != lib.artifactID {
+				continue
+			}
+			deps = append(deps, model.NetworkDependency{
+				Target:       lib.target,
+				Port:         lib.port,
+				Protocol:     inferProtocol("", lib.port),
+				Description:  fmt.Sprintf("gem dependency: %s -> %s", name, lib.description),
+				Confidence:   model.Low,
+				SourceFile:   path,
+				Line:         lineNo,
+				EvidenceLine: entry,
+				ServiceType:  lib.serviceType,
+			})
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", path, err)
+	}
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
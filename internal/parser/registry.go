@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/dormstern/segspec/internal/model"
 )
@@ -9,12 +11,37 @@ import (
 // ParseFunc analyzes a file and returns discovered network dependencies.
 type ParseFunc func(path string) ([]model.NetworkDependency, error)
 
+// Result is what a parser finds in a file: the connections workloads make,
+// the ports they listen on, and the entities (workloads and the other
+// names they are reached by) the file declares, for
+// DependencySet.ResolveIdentities.
+type Result struct {
+	Dependencies []model.NetworkDependency
+	Listeners    []model.Listener
+	Entities     []model.Entity
+}
+
+// ResultFunc is a ParseFunc for formats that declare listeners too
+// (Kubernetes manifests, compose files).
+type ResultFunc func(path string) (Result, error)
+
+// results adapts a ParseFunc to a ResultFunc.
+func (fn ParseFunc) results() ResultFunc {
+	return func(path string) (Result, error) {
+		deps, err := fn(path)
+		return Result{Dependencies: deps}, err
+	}
+}
+
 type entry struct {
 	pattern string
-	fn      ParseFunc
+	format  string
+	fn      ResultFunc
 }
 
-// Registry maps file glob patterns to parser functions.
+// Registry maps file glob patterns to parser functions. A pattern matches
+// the file's basename, or with directory parts ("config/*.json") the same
+// number of trailing path components.
 type Registry struct {
 	entries []entry
 }
@@ -26,21 +53,98 @@ func NewRegistry() *Registry {
 
 // Register adds a parser for files matching the given glob pattern.
 func (r *Registry) Register(pattern string, fn ParseFunc) {
-	r.entries = append(r.entries, entry{pattern: pattern, fn: fn})
+	r.entries = append(r.entries, entry{pattern: pattern, fn: fn.results()})
+}
+
+// RegisterFormat is Register for a named parser format (the keys of
+// Versions()). Dependencies returned through Match are stamped with the
+// format so provenance records say which parser produced them.
+func (r *Registry) RegisterFormat(format, pattern string, fn ParseFunc) {
+	r.RegisterResults(format, pattern, fn.results())
+}
+
+// RegisterResults is RegisterFormat for a parser that also returns
+// listeners.
+func (r *Registry) RegisterResults(format, pattern string, fn ResultFunc) {
+	r.entries = append(r.entries, entry{pattern: pattern, format: format, fn: fn})
 }
 
 // Match returns all parser functions whose pattern matches the given filename.
-func (r *Registry) Match(filename string) []ParseFunc {
-	base := filepath.Base(filename)
-	var matches []ParseFunc
+func (r *Registry) Match(filename string) []ResultFunc {
+	var matches []ResultFunc
 	for _, e := range r.entries {
-		if matched, _ := filepath.Match(e.pattern, base); matched {
-			matches = append(matches, e.fn)
+		if matchPattern(e.pattern, filename) {
+			matches = append(matches, e.stamped())
 		}
 	}
 	return matches
 }
 
+// MatchesFormat reports whether a parser registered under format matches
+// the given filename.
+func (r *Registry) MatchesFormat(filename, format string) bool {
+	for _, e := range r.entries {
+		if e.format == format && matchPattern(e.pattern, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches pattern against as many trailing path components
+// of filename as the pattern has.
+func matchPattern(pattern, filename string) bool {
+	parts := strings.Split(filepath.ToSlash(filename), "/")
+	n := strings.Count(pattern, "/") + 1
+	if len(parts) < n {
+		return false
+	}
+	matched, _ := path.Match(pattern, strings.Join(parts[len(parts)-n:], "/"))
+	return matched
+}
+
+// stamped wraps the entry's parser so every returned dependency and
+// listener (and each of their provenance records) names the format that
+// produced it.
+func (e entry) stamped() ResultFunc {
+	if e.format == "" {
+		return e.fn
+	}
+	return func(path string) (Result, error) {
+		res, err := e.fn(path)
+		stampResult(&res, e.format)
+		return res, err
+	}
+}
+
+// stampResult sets format as the parser of the deps and listeners (and
+// their provenance records) that don't name one.
+func stampResult(res *Result, format string) {
+	for i := range res.Dependencies {
+		d := &res.Dependencies[i]
+		if d.Parser == "" {
+			d.Parser = format
+		}
+		stampProvenance(d.Provenance, format)
+	}
+	for i := range res.Listeners {
+		l := &res.Listeners[i]
+		if l.Parser == "" {
+			l.Parser = format
+		}
+		stampProvenance(l.Provenance, format)
+	}
+}
+
+// stampProvenance sets format on the records that don't name a parser.
+func stampProvenance(records []model.Provenance, format string) {
+	for i := range records {
+		if records[i].Parser == "" {
+			records[i].Parser = format
+		}
+	}
+}
+
 // Patterns returns all registered glob patterns (for diagnostics).
 func (r *Registry) Patterns() []string {
 	patterns := make([]string, len(r.entries))
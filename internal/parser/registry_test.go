@@ -50,6 +50,27 @@ func TestRegistryMatchMultiple(t *testing.T) {
 	}
 }
 
+func TestRegistryMatchDirectoryPattern(t *testing.T) {
+	r := NewRegistry()
+	r.Register("config/*.json", dummyParser())
+
+	tests := []struct {
+		filename string
+		want     int
+	}{
+		{"app/config/default.json", 1},
+		{"config/production.json", 1},
+		{"app/settings/default.json", 0},
+		{"default.json", 0},
+		{"app/config/nested/default.json", 0},
+	}
+	for _, tt := range tests {
+		if got := r.Match(tt.filename); len(got) != tt.want {
+			t.Errorf("Match(%q) returned %d parsers, want %d", tt.filename, len(got), tt.want)
+		}
+	}
+}
+
 func TestRegistryPatterns(t *testing.T) {
 	r := NewRegistry()
 	r.Register("application.yml", dummyParser())
@@ -68,3 +89,17 @@ func TestEmptyRegistryMatch(t *testing.T) {
 		t.Errorf("empty registry matched %d parsers, want 0", len(got))
 	}
 }
+
+func TestRegistryFormatStampsParser(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterFormat("envfile", ".env", dummyParser(model.NetworkDependency{Target: "db", Port: 5432}))
+
+	fns := r.Match(".env")
+	if len(fns) != 1 {
+		t.Fatalf("Match returned %d parsers, want 1", len(fns))
+	}
+	res, _ := fns[0](".env")
+	if deps := res.Dependencies; len(deps) != 1 || deps[0].Parser != "envfile" {
+		t.Errorf("deps = %+v, want Parser envfile", deps)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -15,9 +17,12 @@ import (
 )
 
 func init() {
-	defaultRegistry.Register("application.yml", parseSpringYAML)
-	defaultRegistry.Register("application.yaml", parseSpringYAML)
-	defaultRegistry.Register("application.properties", parseSpringProperties)
+	defaultRegistry.RegisterResults("spring", "application.yml", parseSpringYAML)
+	defaultRegistry.RegisterResults("spring", "application.yaml", parseSpringYAML)
+	defaultRegistry.RegisterResults("spring", "application.properties", parseSpringProperties)
+	defaultRegistry.RegisterResults("spring", "application-*.yml", parseSpringYAML)
+	defaultRegistry.RegisterResults("spring", "application-*.yaml", parseSpringYAML)
+	defaultRegistry.RegisterResults("spring", "application-*.properties", parseSpringProperties)
 }
 
 // jdbcPattern matches JDBC URLs like jdbc:postgresql://host:port/db or jdbc:postgresql://host/db
@@ -40,334 +45,802 @@ var jdbcDescriptions = map[string]string{
 	"h2":         "H2",
 }
 
-// springConfig represents the subset of Spring application.yml we care about.
-type springConfig struct {
-	Spring struct {
-		Datasource struct {
-			URL string `yaml:"url"`
-		} `yaml:"datasource"`
-		Redis struct {
-			Host string `yaml:"host"`
-			Port int    `yaml:"port"`
-		} `yaml:"redis"`
-		Data struct {
-			Redis struct {
-				Host string `yaml:"host"`
-				Port int    `yaml:"port"`
-			} `yaml:"redis"`
-		} `yaml:"data"`
-		Kafka struct {
-			BootstrapServers string `yaml:"bootstrap-servers"`
-		} `yaml:"kafka"`
-		RabbitMQ struct {
-			Host string `yaml:"host"`
-			Port int    `yaml:"port"`
-		} `yaml:"rabbitmq"`
-	} `yaml:"spring"`
-	Server struct {
-		Port int `yaml:"port"`
-	} `yaml:"server"`
-}
-
-func parseSpringYAML(path string) ([]model.NetworkDependency, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
+// Spring config is read as flat properties (spring.datasource.url, ...)
+// whatever the file format, so YAML and .properties files share one
+// extraction path. A file is a list of documents (`---` in YAML, `#---` in
+// .properties); a document may be gated on profiles, and
+// application-{profile}.yml files are documents of that profile.
+
+// springDoc is one config document: its properties in file order and the
+// profiles that activate it (none: always active).
+type springDoc struct {
+	profiles []string
+	props    []springProp
+}
+
+// springProp is one property as written, and where.
+type springProp struct {
+	key       string
+	profile   string // Quarkus profile prefix the key was written with (%prod.), if any
+	raw       string // value as written, placeholders included
+	sep       string // ": " (YAML) or "=" (.properties), for evidence
+	file      string
+	line, col int
+}
+
+// evidence renders the property as written, plus the resolved value when
+// placeholders changed it.
+func (p springProp) evidence(resolved string) string {
+	s := p.profile + p.key + p.sep + p.raw
+	if resolved != p.raw {
+		s += " (resolved: " + resolved + ")"
 	}
+	return s
+}
 
-	// Spring application.yml is a single-workload file: one app per file.
-	// Any `# segspec:disable=...` comment in the file disables the whole
-	// workload. ScanFileDisable tolerates the directive being anywhere in
-	// the file rather than only on line 1.
-	disable := ScanFileDisable(data)
+// SpringOptions selects the Spring profiles to analyze.
+type SpringOptions struct {
+	// Profiles are the active profiles, in order (later ones win). Empty
+	// means every profile: the default configuration and each profile
+	// found are resolved on their own and the union is reported.
+	Profiles []string
+}
+
+// springProfileFile returns the profile of an application-{profile}.*
+// file name, or "".
+func springProfileFile(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	profile, ok := strings.CutPrefix(base, "application-")
+	if !ok {
+		return ""
+	}
+	return profile
+}
 
-	var allDeps []model.NetworkDependency
+// loadSpringFile reads a Spring config file into documents. The second
+// return is the file's disable directive.
+func loadSpringFile(path string) ([]springDoc, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	var docs []springDoc
+	if strings.HasSuffix(path, ".properties") {
+		docs = loadSpringProperties(data, path)
+	} else {
+		docs = loadSpringYAML(data, path)
+	}
+	docs = splitProfilePrefixes(docs)
+	if profile := springProfileFile(path); profile != "" {
+		for i := range docs {
+			if len(docs[i].profiles) == 0 {
+				docs[i].profiles = []string{profile}
+			}
+		}
+	}
+	return docs, ScanFileDisable(data), nil
+}
 
-	// Use yaml.NewDecoder to read ALL documents separated by ---
+// loadSpringYAML flattens every YAML document; parse errors end the file
+// at the last good document.
+func loadSpringYAML(data []byte, path string) []springDoc {
+	var docs []springDoc
 	decoder := yaml.NewDecoder(bytes.NewReader(data))
 	for {
-		var cfg springConfig
-		err := decoder.Decode(&cfg)
-		if err != nil {
-			break // end of documents or parse error
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			break
 		}
+		var doc springDoc
+		flattenSpringYAML(&node, "", path, &doc.props)
+		doc.profiles = springDocProfiles(doc.props)
+		docs = append(docs, doc)
+	}
+	return docs
+}
 
-		deps := extractSpringDeps(cfg, path)
-		allDeps = mergeUnique(allDeps, deps)
+// flattenSpringYAML appends every scalar under n as a dotted property;
+// sequence items are key[i], as Spring binds them.
+func flattenSpringYAML(n *yaml.Node, prefix, path string, out *[]springProp) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			flattenSpringYAML(c, prefix, path, out)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenSpringYAML(n.Content[i+1], key, path, out)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			flattenSpringYAML(c, fmt.Sprintf("%s[%d]", prefix, i), path, out)
+		}
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" || prefix == "" {
+			return
+		}
+		*out = append(*out, springProp{key: prefix, raw: n.Value, sep: ": ", file: path, line: n.Line, col: n.Column})
 	}
+}
 
-	// Also scan all string values in the raw YAML documents for URL patterns not caught above
-	rawDecoder := yaml.NewDecoder(bytes.NewReader(data))
-	for {
-		var raw map[string]interface{}
-		err := rawDecoder.Decode(&raw)
-		if err != nil {
-			break
+// loadSpringProperties reads a .properties file. `#---` (or `!---`) starts
+// a new document, as in Spring Boot 2.4+.
+func loadSpringProperties(data []byte, path string) []springDoc {
+	docs := []springDoc{{}}
+	lineNo := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "#---" || line == "!---" {
+			docs = append(docs, springDoc{})
+			continue
 		}
-		if raw == nil {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
 			continue
 		}
-		found := extractURLsFromMap(raw, path)
-		allDeps = mergeUnique(allDeps, found)
+		// Split on first = or :
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 {
+			continue
+		}
+		cur := &docs[len(docs)-1]
+		cur.props = append(cur.props, springProp{
+			key:  strings.TrimSpace(line[:idx]),
+			raw:  strings.TrimSpace(line[idx+1:]),
+			sep:  "=",
+			file: path,
+			line: lineNo,
+		})
+	}
+	for i := range docs {
+		docs[i].profiles = springDocProfiles(docs[i].props)
 	}
+	return docs
+}
 
-	if disable != "" {
-		for i := range allDeps {
-			allDeps[i].Disabled = disable
+// springDocProfiles reads a document's activation condition: Boot 2.4+'s
+// spring.config.activate.on-profile or the legacy spring.profiles.
+func springDocProfiles(props []springProp) []string {
+	var profiles []string
+	for _, p := range props {
+		key, _, _ := strings.Cut(p.key, "[")
+		if key != "spring.config.activate.on-profile" && key != "spring.profiles" {
+			continue
+		}
+		for _, name := range strings.Split(p.raw, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				profiles = append(profiles, name)
+			}
 		}
 	}
+	return profiles
+}
 
-	return allDeps, nil
+// springActive reports whether a document gated on profiles is active.
+// "!name" matches when name is not active.
+func springActive(profiles, active []string) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if name, neg := strings.CutPrefix(p, "!"); neg {
+			if !containsString(active, name) {
+				return true
+			}
+		} else if containsString(active, p) {
+			return true
+		}
+	}
+	return false
 }
 
-// extractSpringDeps extracts dependencies from a single parsed springConfig document.
-func extractSpringDeps(cfg springConfig, path string) []model.NetworkDependency {
-	var deps []model.NetworkDependency
+// springView is the effective configuration for one set of active
+// profiles: later documents override earlier ones key by key.
+type springView map[string]springProp
 
-	// Datasource URL (JDBC)
-	if cfg.Spring.Datasource.URL != "" {
-		if d, ok := parseJDBC(cfg.Spring.Datasource.URL, path); ok {
-			d.EvidenceLine = "spring.datasource.url: " + cfg.Spring.Datasource.URL
-			d.ServiceType = "database"
-			deps = append(deps, d)
+// springViews builds the views to analyze for one app: the selected
+// profiles, or (none selected) the default configuration plus each
+// profile the files mention. Profile-specific files override the base
+// files, as in Spring.
+func springViews(base, profiled []springDoc, opts SpringOptions) []springView {
+	sets := [][]string{opts.Profiles}
+	if len(opts.Profiles) == 0 {
+		var found []string
+		for _, docs := range [][]springDoc{base, profiled} {
+			for _, d := range docs {
+				for _, p := range d.profiles {
+					p = strings.TrimPrefix(p, "!")
+					if !containsString(found, p) {
+						found = append(found, p)
+					}
+				}
+			}
+		}
+		sort.Strings(found)
+		for _, p := range found {
+			sets = append(sets, []string{p})
 		}
 	}
 
-	// Redis (Spring Boot 2.x: spring.redis.host)
-	if cfg.Spring.Redis.Host != "" {
-		port := cfg.Spring.Redis.Port
-		if port == 0 {
-			port = 6379
+	var views []springView
+	for _, active := range sets {
+		view := make(springView)
+		apply := func(d springDoc) {
+			for _, p := range d.props {
+				view[p.key] = p
+			}
 		}
-		deps = append(deps, model.NetworkDependency{
-			Target:       cfg.Spring.Redis.Host,
-			Port:         port,
-			Protocol:     "TCP",
-			Description:  "Redis",
-			Confidence:   model.High,
-			SourceFile:   path,
-			EvidenceLine: fmt.Sprintf("spring.redis.host: %s", cfg.Spring.Redis.Host),
-			ServiceType:  "cache",
-		})
+		for _, d := range base {
+			if springActive(d.profiles, active) {
+				apply(d)
+			}
+		}
+		for _, name := range active {
+			for _, d := range profiled {
+				if containsString(d.profiles, name) {
+					apply(d)
+				}
+			}
+		}
+		views = append(views, view)
 	}
+	return views
+}
 
-	// Redis (Spring Boot 3.x: spring.data.redis.host)
-	if cfg.Spring.Data.Redis.Host != "" {
-		port := cfg.Spring.Data.Redis.Port
-		if port == 0 {
-			port = 6379
+// springResolution is a property value with its placeholders expanded.
+type springResolution struct {
+	value     string
+	defaulted bool               // some placeholder fell back to its default
+	sources   []model.Provenance // env settings and properties it came from
+}
+
+// springResolver expands ${name:default} placeholders. Like Spring's
+// property-source order, the app's environment (compose/k8s env of its
+// workload, then .env) wins over the config's own properties; the
+// default applies only when neither has the name.
+type springResolver struct {
+	view springView
+	env  map[string]envSetting
+}
+
+const maxPlaceholderDepth = 8
+
+func (r springResolver) resolve(s string, depth int) (springResolution, bool) {
+	res := springResolution{}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			break
 		}
-		deps = append(deps, model.NetworkDependency{
-			Target:       cfg.Spring.Data.Redis.Host,
-			Port:         port,
-			Protocol:     "TCP",
-			Description:  "Redis",
-			Confidence:   model.High,
-			SourceFile:   path,
-			EvidenceLine: fmt.Sprintf("spring.data.redis.host: %s", cfg.Spring.Data.Redis.Host),
-			ServiceType:  "cache",
-		})
+		end := placeholderEnd(s, start)
+		if end < 0 || depth >= maxPlaceholderDepth {
+			return res, false
+		}
+		b.WriteString(s[:start])
+		name, def, hasDef := strings.Cut(s[start+2:end], ":")
+		val, ok := r.lookup(strings.TrimSpace(name), depth, &res)
+		if !ok {
+			if !hasDef {
+				return res, false
+			}
+			d, ok := r.resolve(def, depth+1)
+			if !ok {
+				return res, false
+			}
+			val = d.value
+			res.defaulted = true
+			res.sources = append(res.sources, d.sources...)
+		}
+		b.WriteString(val)
+		s = s[end+1:]
 	}
+	res.value = b.String()
+	return res, true
+}
 
-	// Kafka bootstrap servers
-	if cfg.Spring.Kafka.BootstrapServers != "" {
-		for _, broker := range parseKafkaBrokers(cfg.Spring.Kafka.BootstrapServers) {
-			deps = append(deps, model.NetworkDependency{
-				Target:       broker.host,
-				Port:         broker.port,
-				Protocol:     "TCP",
-				Description:  "Kafka",
-				Confidence:   model.High,
-				SourceFile:   path,
-				EvidenceLine: "spring.kafka.bootstrap-servers: " + cfg.Spring.Kafka.BootstrapServers,
-				ServiceType:  "broker",
-			})
+func (r springResolver) lookup(name string, depth int, res *springResolution) (string, bool) {
+	for _, key := range []string{name, springEnvName(name)} {
+		if e, ok := r.env[key]; ok {
+			res.sources = append(res.sources, e.provenance())
+			return e.value, true
 		}
 	}
+	p, ok := r.view[name]
+	if !ok {
+		return "", false
+	}
+	inner, ok := r.resolve(p.raw, depth+1)
+	if !ok {
+		return "", false
+	}
+	res.defaulted = res.defaulted || inner.defaulted
+	res.sources = append(res.sources, model.Provenance{File: p.file, Line: p.line, Column: p.col, Evidence: p.profile + p.key + p.sep + p.raw, Parser: "spring"})
+	res.sources = append(res.sources, inner.sources...)
+	return inner.value, true
+}
 
-	// RabbitMQ
-	if cfg.Spring.RabbitMQ.Host != "" {
-		port := cfg.Spring.RabbitMQ.Port
-		if port == 0 {
-			port = 5672
+// placeholderEnd returns the index of the } closing the ${ at start,
+// allowing nested placeholders in defaults, or -1.
+func placeholderEnd(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
 		}
-		deps = append(deps, model.NetworkDependency{
-			Target:       cfg.Spring.RabbitMQ.Host,
-			Port:         port,
-			Protocol:     "TCP",
-			Description:  "RabbitMQ",
-			Confidence:   model.High,
-			SourceFile:   path,
-			EvidenceLine: fmt.Sprintf("spring.rabbitmq.host: %s", cfg.Spring.RabbitMQ.Host),
-			ServiceType:  "broker",
-		})
 	}
+	return -1
+}
 
-	// Server port (the app's own listening port)
-	if cfg.Server.Port != 0 {
-		deps = append(deps, model.NetworkDependency{
-			Target:      "self",
-			Port:        cfg.Server.Port,
-			Protocol:    "TCP",
-			Description: "server listening port",
-			Confidence:  model.High,
-			SourceFile:  path,
-		})
-	}
+// springEnvName is the environment variable Spring's relaxed binding reads
+// for a property: spring.data.redis-host -> SPRING_DATA_REDISHOST.
+func springEnvName(name string) string {
+	name = strings.ReplaceAll(strings.ReplaceAll(name, "-", ""), ".", "_")
+	return strings.ToUpper(name)
+}
 
-	return deps
+// get resolves a property of the view. A list-valued property
+// (key[0], key[1], ...) is joined with commas, as Spring binds it to a
+// string; its position is the first item's.
+func (r springResolver) get(key string) (springProp, springResolution, bool) {
+	p, ok := r.view[key]
+	if !ok {
+		var items []string
+		for i := 0; ; i++ {
+			item, ok := r.view[fmt.Sprintf("%s[%d]", key, i)]
+			if !ok {
+				break
+			}
+			if i == 0 {
+				p = item
+				p.key = key
+			}
+			items = append(items, item.raw)
+		}
+		if len(items) == 0 {
+			return p, springResolution{}, false
+		}
+		p.raw = strings.Join(items, ",")
+	}
+	res, ok := r.resolve(p.raw, 0)
+	return p, res, ok
 }
 
-func parseSpringProperties(path string) ([]model.NetworkDependency, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
+// springDep fills in where d was declared. A value that needed a
+// placeholder default is only as good as that default, so it drops to
+// Medium; one resolved through other settings chains them as provenance.
+func springDep(p springProp, res springResolution, d model.NetworkDependency) model.NetworkDependency {
+	d.SourceFile, d.Line, d.Column = p.file, p.line, p.col
+	d.EvidenceLine = p.evidence(res.value)
+	if res.defaulted && d.Confidence == model.High {
+		d.Confidence = model.Medium
+	}
+	if len(res.sources) > 0 {
+		d.Provenance = []model.Provenance{{File: p.file, Line: p.line, Column: p.col, Evidence: d.EvidenceLine, Parser: "spring", Confidence: d.Confidence}}
+		for _, s := range res.sources {
+			s.Confidence = d.Confidence
+			d.Provenance = append(d.Provenance, s)
+		}
 	}
-	defer f.Close()
+	return d
+}
 
-	props := make(map[string]string)
-	disable := ""
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+// springHostPorts are host/port property pairs with a fixed default port.
+var springHostPorts = []struct {
+	host, port  string
+	defaultPort int
+	description string
+	serviceType string
+}{
+	{"spring.redis.host", "spring.redis.port", 6379, "Redis", "cache"},
+	{"spring.data.redis.host", "spring.data.redis.port", 6379, "Redis", "cache"},
+	{"spring.rabbitmq.host", "spring.rabbitmq.port", 5672, "RabbitMQ", "broker"},
+	{"spring.data.mongodb.host", "spring.data.mongodb.port", 27017, "MongoDB", "database"},
+	{"spring.mail.host", "spring.mail.port", 25, "SMTP", "mail"},
+}
+
+// springEndpointLists are properties holding one endpoint or a
+// comma-separated list of them: URLs, or host[:port] entries that take the
+// port from portKey or defaultPort.
+var springEndpointLists = []struct {
+	key, portKey string
+	defaultPort  int
+	description  string
+	serviceType  string
+}{
+	{"spring.data.mongodb.uri", "", 27017, "MongoDB", "database"},
+	{"spring.elasticsearch.uris", "", 9200, "Elasticsearch", "search"},
+	{"spring.elasticsearch.rest.uris", "", 9200, "Elasticsearch", "search"},
+	{"spring.cloud.config.uri", "", 8888, "Spring Cloud Config Server", "config"},
+	{"spring.ldap.urls", "", 389, "LDAP", "ldap"},
+	{"spring.cassandra.contact-points", "spring.cassandra.port", 9042, "Cassandra", "database"},
+	{"spring.data.cassandra.contact-points", "spring.data.cassandra.port", 9042, "Cassandra", "database"},
+}
+
+// springSelfPorts are the app's own listening ports.
+var springSelfPorts = []struct{ key, description string }{
+	{"server.port", "server listening port"},
+	{"management.server.port", "management (actuator) port"},
+}
+
+var (
+	springIndexRe        = regexp.MustCompile(`\[\d+\]`)
+	springGatewayRouteRe = regexp.MustCompile(`^(spring\.cloud\.gateway(?:\.server\.webflux)?\.routes\[\d+\])\.uri$`)
+	springFeignURLRe     = regexp.MustCompile(`^(?:feign|spring\.cloud\.openfeign)\.client\.config\.([^.]+)\.url$`)
+	springEurekaZoneRe   = regexp.MustCompile(`^eureka\.client\.(?:serviceUrl|service-url)\.[^.]+$`)
+)
+
+// springKeyShape strips list indexes from a property key
+// (spring.cloud.gateway.routes[2].uri -> spring.cloud.gateway.routes[].uri)
+// so handled keys can be looked up whatever the index.
+func springKeyShape(key string) string {
+	return strings.TrimSuffix(springIndexRe.ReplaceAllString(key, "[]"), "[]")
+}
+
+// springEndpoints splits a comma-separated list of URLs or host[:port]
+// entries. Hosts after the first in a multi-host URL
+// (mongodb://a:27017,b:27017/db) inherit its scheme.
+func springEndpoints(val string, defaultPort int) []endpoint {
+	var eps []endpoint
+	scheme := ""
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
 			continue
 		}
-		// Comment lines: still scan for a disable directive before skipping.
-		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
-			if disable == "" {
-				disable = ParseDisableDirective(line)
-			}
-			continue
+		if s, _, ok := strings.Cut(item, "://"); ok {
+			scheme = s
+		} else if scheme != "" {
+			item = scheme + "://" + item
 		}
-		// Split on first = or :
-		idx := strings.IndexAny(line, "=:")
-		if idx < 0 {
-			continue
+		if host, port, s, ok := endpointFromValue(item, defaultPort); ok {
+			eps = append(eps, endpoint{host, port, s})
 		}
-		key := strings.TrimSpace(line[:idx])
-		val := strings.TrimSpace(line[idx+1:])
-		props[key] = val
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanning %s: %w", path, err)
+	return eps
+}
+
+// parseR2DBC reads an R2DBC URL (r2dbc:postgresql://host:5432/db, or with
+// a pool: or proxy: wrapper) like the JDBC URL of the same driver.
+func parseR2DBC(raw, sourceFile string) (model.NetworkDependency, bool) {
+	rest, ok := strings.CutPrefix(raw, "r2dbc:")
+	if !ok {
+		return model.NetworkDependency{}, false
+	}
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, "pool:"), "proxy:")
+	if driver, authority, ok := strings.Cut(rest, "://"); ok {
+		if i := strings.Index(authority, "@"); i >= 0 && i < strings.IndexAny(authority+"/", "/") {
+			authority = authority[i+1:]
+		}
+		rest = driver + "://" + authority
 	}
+	d, ok := parseJDBC("jdbc:"+rest, sourceFile)
+	d.Description += " (R2DBC)"
+	return d, ok
+}
 
+// springViewDeps extracts dependencies from one resolved view of an app
+// built on framework (see detectFramework).
+func springViewDeps(r springResolver, framework string) []model.NetworkDependency {
 	var deps []model.NetworkDependency
+	handled := make(map[string]bool)
+	mark := func(keys ...string) {
+		for _, k := range keys {
+			handled[springKeyShape(k)] = true
+		}
+	}
 
-	// Datasource URL
-	if v, ok := props["spring.datasource.url"]; ok {
-		if d, found := parseJDBC(v, path); found {
-			d.EvidenceLine = "spring.datasource.url=" + v
+	// Datasource URL (JDBC) and R2DBC URL
+	mark("spring.datasource.url", "spring.r2dbc.url")
+	if p, res, ok := r.get("spring.datasource.url"); ok {
+		if d, ok := parseJDBC(res.value, p.file); ok {
+			d.ServiceType = "database"
+			deps = append(deps, springDep(p, res, d))
+		}
+	}
+	if p, res, ok := r.get("spring.r2dbc.url"); ok {
+		if d, ok := parseR2DBC(res.value, p.file); ok {
 			d.ServiceType = "database"
-			deps = append(deps, d)
+			deps = append(deps, springDep(p, res, d))
 		}
 	}
 
-	// Redis
-	if host, ok := props["spring.redis.host"]; ok {
-		port := 6379
-		if p, ok := props["spring.redis.port"]; ok {
-			if n, err := strconv.Atoi(p); err == nil {
+	// Redis (Boot 2.x spring.redis, 3.x spring.data.redis), RabbitMQ,
+	// MongoDB and SMTP host/port pairs
+	for _, hp := range springHostPorts {
+		mark(hp.host, hp.port)
+		p, res, ok := r.get(hp.host)
+		if !ok || res.value == "" {
+			continue
+		}
+		port := hp.defaultPort
+		if _, pres, ok := r.get(hp.port); ok {
+			if n, err := strconv.Atoi(pres.value); err == nil {
 				port = n
+				res.defaulted = res.defaulted || pres.defaulted
+				res.sources = append(res.sources, pres.sources...)
 			}
 		}
-		deps = append(deps, model.NetworkDependency{
-			Target:       host,
-			Port:         port,
-			Protocol:     "TCP",
-			Description:  "Redis",
-			Confidence:   model.High,
-			SourceFile:   path,
-			EvidenceLine: "spring.redis.host=" + host,
-			ServiceType:  "cache",
-		})
+		deps = append(deps, springDep(p, res, model.NetworkDependency{
+			Target:      res.value,
+			Port:        port,
+			Protocol:    "TCP",
+			Description: hp.description,
+			Confidence:  model.High,
+			ServiceType: hp.serviceType,
+		}))
 	}
 
-	// Kafka
-	if v, ok := props["spring.kafka.bootstrap-servers"]; ok {
-		for _, broker := range parseKafkaBrokers(v) {
-			deps = append(deps, model.NetworkDependency{
-				Target:       broker.host,
-				Port:         broker.port,
-				Protocol:     "TCP",
-				Description:  "Kafka",
-				Confidence:   model.High,
-				SourceFile:   path,
-				EvidenceLine: "spring.kafka.bootstrap-servers=" + v,
-				ServiceType:  "broker",
-			})
+	// Kafka bootstrap servers
+	mark("spring.kafka.bootstrap-servers")
+	if p, res, ok := r.get("spring.kafka.bootstrap-servers"); ok {
+		for _, broker := range parseKafkaBrokers(res.value) {
+			deps = append(deps, springDep(p, res, model.NetworkDependency{
+				Target:      broker.host,
+				Port:        broker.port,
+				Protocol:    "TCP",
+				Description: "Kafka",
+				Confidence:  model.High,
+				ServiceType: "broker",
+			}))
 		}
 	}
 
-	// RabbitMQ
-	if host, ok := props["spring.rabbitmq.host"]; ok {
-		port := 5672
-		if p, ok := props["spring.rabbitmq.port"]; ok {
-			if n, err := strconv.Atoi(p); err == nil {
-				port = n
+	// MongoDB URI, Elasticsearch, Config Server, LDAP and Cassandra
+	// endpoint lists
+	for _, el := range springEndpointLists {
+		mark(el.key)
+		p, res, ok := r.get(el.key)
+		if !ok {
+			continue
+		}
+		defaultPort := el.defaultPort
+		if el.portKey != "" {
+			mark(el.portKey)
+			if _, pres, ok := r.get(el.portKey); ok {
+				if n, err := strconv.Atoi(pres.value); err == nil {
+					defaultPort = n
+				}
 			}
 		}
-		deps = append(deps, model.NetworkDependency{
-			Target:       host,
-			Port:         port,
-			Protocol:     "TCP",
-			Description:  "RabbitMQ",
-			Confidence:   model.High,
-			SourceFile:   path,
-			EvidenceLine: "spring.rabbitmq.host=" + host,
-			ServiceType:  "broker",
-		})
+		for _, ep := range springEndpoints(res.value, defaultPort) {
+			deps = append(deps, springDep(p, res, model.NetworkDependency{
+				Target:      ep.host,
+				Port:        ep.port,
+				Protocol:    "TCP",
+				Description: el.description,
+				Confidence:  model.High,
+				ServiceType: el.serviceType,
+			}))
+		}
 	}
 
-	// Server port
-	if v, ok := props["server.port"]; ok {
-		if port, err := strconv.Atoi(v); err == nil {
-			deps = append(deps, model.NetworkDependency{
-				Target:      "self",
-				Port:        port,
+	keys := make([]string, 0, len(r.view))
+	for k := range r.view {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Quarkus and Micronaut endpoint keys.
+	deps = append(deps, frameworkViewDeps(r, framework, keys, mark)...)
+
+	// Keyed families: Config Server imports, Eureka zones, Gateway route
+	// URIs and Feign client URLs.
+	addKeyed := func(key, description, serviceType string) {
+		p := r.view[key]
+		res, ok := r.resolve(p.raw, 0)
+		if !ok {
+			return
+		}
+		for _, ep := range springEndpoints(res.value, 0) {
+			deps = append(deps, springDep(p, res, model.NetworkDependency{
+				Target:      ep.host,
+				Port:        ep.port,
 				Protocol:    "TCP",
-				Description: "server listening port",
+				Description: description,
 				Confidence:  model.High,
-				SourceFile:  path,
-			})
+				ServiceType: serviceType,
+			}))
+		}
+	}
+	for _, key := range keys {
+		switch {
+		case springKeyShape(key) == "spring.config.import":
+			mark(key)
+			p := r.view[key]
+			res, ok := r.resolve(p.raw, 0)
+			if !ok {
+				continue
+			}
+			for _, item := range strings.Split(res.value, ",") {
+				item = strings.TrimPrefix(strings.TrimSpace(item), "optional:")
+				if uri, ok := strings.CutPrefix(item, "configserver:"); ok {
+					for _, ep := range springEndpoints(uri, 8888) {
+						deps = append(deps, springDep(p, res, model.NetworkDependency{
+							Target:      ep.host,
+							Port:        ep.port,
+							Protocol:    "TCP",
+							Description: "Spring Cloud Config Server",
+							Confidence:  model.High,
+							ServiceType: "config",
+						}))
+					}
+				}
+			}
+		case springEurekaZoneRe.MatchString(key):
+			mark(key)
+			addKeyed(key, "Eureka", "discovery")
+		case springGatewayRouteRe.MatchString(key):
+			// lb://name targets go through discovery and name no port;
+			// endpointFromValue skips them.
+			mark(key)
+			desc := "gateway route"
+			if _, id, ok := r.get(springGatewayRouteRe.FindStringSubmatch(key)[1] + ".id"); ok && id.value != "" {
+				desc += " " + id.value
+			}
+			addKeyed(key, desc, "http")
+		case springFeignURLRe.MatchString(key):
+			mark(key)
+			addKeyed(key, "Feign client "+springFeignURLRe.FindStringSubmatch(key)[1], "http")
 		}
 	}
 
-	// Scan all property values for URL patterns
-	for key, val := range props {
-		// Skip keys we've already handled explicitly
-		if isHandledSpringKey(key) {
+	// The app's own listening ports are listeners (springViewListeners).
+	for _, sp := range append(springSelfPorts, frameworkSelfPorts[framework]...) {
+		mark(sp.key)
+	}
+
+	// Every other value that holds a URL or host:port.
+	for _, key := range keys {
+		if handled[springKeyShape(key)] {
 			continue
 		}
-		if d, ok := extractFromValue(val, path); ok {
-			deps = mergeUnique(deps, []model.NetworkDependency{d})
+		p := r.view[key]
+		res, ok := r.resolve(p.raw, 0)
+		if !ok {
+			continue
+		}
+		if d, ok := extractFromValue(res.value, p.file); ok {
+			if d.Description == "HTTP service" {
+				d.ServiceType = "http"
+			}
+			deps = mergeUnique(deps, []model.NetworkDependency{springDep(p, res, d)})
 		}
 	}
+	return deps
+}
 
-	if disable != "" {
-		for i := range deps {
-			deps[i].Disabled = disable
+// springViewListeners returns the ports the app of one resolved view
+// listens on. The workload is left empty: like the app's dependencies'
+// Source, it is the service the walker analyzes.
+func springViewListeners(r springResolver, framework string) []model.Listener {
+	var listeners []model.Listener
+	for _, sp := range append(springSelfPorts, frameworkSelfPorts[framework]...) {
+		p, res, ok := r.get(sp.key)
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(res.value)
+		if err != nil || port <= 0 {
+			continue
 		}
+		d := springDep(p, res, model.NetworkDependency{Port: port, Confidence: model.High})
+		listeners = append(listeners, model.Listener{
+			Port:         port,
+			Protocol:     "TCP",
+			Description:  sp.description,
+			Confidence:   d.Confidence,
+			SourceFile:   d.SourceFile,
+			Line:         d.Line,
+			Column:       d.Column,
+			EvidenceLine: d.EvidenceLine,
+			Provenance:   d.Provenance,
+		})
 	}
+	return listeners
+}
 
-	return deps, nil
+// springApp is the config of one Spring app: its base files
+// (application.yml/.properties) and its profile files (application-*.yml).
+// framework is frameworkSpring, or the Quarkus or Micronaut app the same
+// files may belong to.
+type springApp struct {
+	base, profiled []springDoc
+	disable        string
+	framework      string
 }
 
-func isHandledSpringKey(key string) bool {
-	handled := []string{
-		"spring.datasource.url",
-		"spring.redis.host", "spring.redis.port",
-		"spring.kafka.bootstrap-servers",
-		"spring.rabbitmq.host", "spring.rabbitmq.port",
-		"server.port",
+// loadSpringApp reads an app's config files. Of base files in one
+// location, .properties wins over .yml, so paths should list it last.
+func loadSpringApp(paths []string) (springApp, error) {
+	var app springApp
+	for _, path := range paths {
+		docs, disable, err := loadSpringFile(path)
+		if err != nil {
+			return app, err
+		}
+		if app.disable == "" {
+			app.disable = disable
+		}
+		if springProfileFile(path) != "" {
+			app.profiled = append(app.profiled, docs...)
+		} else {
+			app.base = append(app.base, docs...)
+		}
 	}
-	for _, h := range handled {
-		if key == h {
-			return true
+	if len(paths) > 0 {
+		app.framework = detectFramework(filepath.Dir(paths[0]), app)
+	}
+	return app, nil
+}
+
+// name returns the application name (spring.application.name, or
+// Quarkus's and Micronaut's equivalent), as written, or "".
+func (a springApp) name() string {
+	for _, d := range a.base {
+		for _, p := range d.props {
+			if p.key == a.framework+".application.name" && !strings.Contains(p.raw, "${") {
+				return p.raw
+			}
 		}
 	}
-	return false
+	return ""
+}
+
+// result resolves every view of the app against env.
+func (a springApp) result(opts SpringOptions, env map[string]envSetting) Result {
+	var res Result
+	for _, view := range springViews(a.base, a.profiled, opts) {
+		r := springResolver{view: view, env: env}
+		res.Dependencies = mergeUnique(res.Dependencies, springViewDeps(r, a.framework))
+		res.Listeners = mergeUniqueListeners(res.Listeners, springViewListeners(r, a.framework))
+	}
+
+	// A Spring config is a single-workload file: one app per directory.
+	// Any `# segspec:disable=...` comment disables the whole workload.
+	if a.disable != "" {
+		for i := range res.Dependencies {
+			res.Dependencies[i].Disabled = a.disable
+		}
+		for i := range res.Listeners {
+			res.Listeners[i].Disabled = a.disable
+		}
+	}
+	return res
+}
+
+// parseSpringYAML and parseSpringProperties parse one file on its own,
+// resolving placeholders against a .env next to it. The walker resolves
+// Spring config through SpringIndex instead, which also sees profile
+// files, compose/k8s env and .env files further up.
+func parseSpringYAML(path string) (Result, error) {
+	return parseSpringFile(path)
+}
+
+func parseSpringProperties(path string) (Result, error) {
+	return parseSpringFile(path)
+}
+
+func parseSpringFile(path string) (Result, error) {
+	app, err := loadSpringApp([]string{path})
+	if err != nil {
+		return Result{}, err
+	}
+	return app.result(SpringOptions{}, readDotenv(filepath.Join(filepath.Dir(path), ".env"))), nil
 }
 
 type hostPort struct {
@@ -486,7 +959,7 @@ func extractFromValue(val, sourceFile string) (model.NetworkDependency, bool) {
 		return model.NetworkDependency{
 			Target:       matches[1],
 			Port:         port,
-			Protocol:     "TCP",
+			Protocol:     inferProtocol(urlScheme(val), port),
 			Description:  "network service",
 			Confidence:   model.Medium,
 			SourceFile:   sourceFile,
@@ -497,44 +970,122 @@ func extractFromValue(val, sourceFile string) (model.NetworkDependency, bool) {
 	return model.NetworkDependency{}, false
 }
 
-// extractURLsFromMap walks a YAML map and returns deps from URL-like string values.
-func extractURLsFromMap(m map[string]interface{}, sourceFile string) []model.NetworkDependency {
-	var deps []model.NetworkDependency
-	walkYAML(m, func(val string) {
-		if d, ok := extractFromValue(val, sourceFile); ok {
-			deps = append(deps, d)
-		}
-	})
-	return deps
+// schemeDefaultPorts gives the default port of connection URL schemes
+// when the URL doesn't state one.
+var schemeDefaultPorts = map[string]int{
+	"http":       80,
+	"https":      443,
+	"redis":      6379,
+	"rediss":     6379,
+	"amqp":       5672,
+	"amqps":      5671,
+	"pyamqp":     5672,
+	"postgres":   5432,
+	"postgresql": 5432,
+	"postgis":    5432,
+	"mysql":      3306,
+	"mongodb":    27017,
+	"nats":       4222,
+	"kafka":      9092,
+	"memcache":   11211,
+	"pymemcache": 11211,
+	"ws":         80,
+	"wss":        443,
+	"ldap":       389,
+	"ldaps":      636,
 }
 
-func walkYAML(v interface{}, fn func(string)) {
-	switch val := v.(type) {
-	case map[string]interface{}:
-		for _, child := range val {
-			walkYAML(child, fn)
+// schemeServiceTypes maps connection URL schemes to ServiceType.
+var schemeServiceTypes = map[string]string{
+	"redis":      "cache",
+	"rediss":     "cache",
+	"memcache":   "cache",
+	"pymemcache": "cache",
+	"amqp":       "broker",
+	"amqps":      "broker",
+	"pyamqp":     "broker",
+	"postgres":   "database",
+	"postgresql": "database",
+	"postgis":    "database",
+	"mysql":      "database",
+	"mongodb":    "database",
+	"nats":       "broker",
+	"kafka":      "broker",
+}
+
+var hostnameRe = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_.]*$`)
+
+// endpointFromValue parses a connection URL, host:port or, when
+// defaultPort is known, a bare hostname. URLs without a port take their
+// scheme's default. Celery's "db+postgresql://" and "sqla+" prefixes are
+// dropped.
+func endpointFromValue(val string, defaultPort int) (host string, port int, scheme string, ok bool) {
+	val = strings.TrimSpace(val)
+	if strings.Contains(val, "://") {
+		val = strings.TrimPrefix(strings.TrimPrefix(val, "db+"), "sqla+")
+		u, err := url.Parse(val)
+		if err != nil || u.Hostname() == "" {
+			return "", 0, "", false
 		}
-	case []interface{}:
-		for _, child := range val {
-			walkYAML(child, fn)
+		scheme = strings.ToLower(u.Scheme)
+		port, _ = strconv.Atoi(u.Port())
+		if port == 0 {
+			port = schemeDefaultPorts[scheme]
 		}
-	case string:
-		fn(val)
+		if port == 0 {
+			port = defaultPort
+		}
+		return u.Hostname(), port, scheme, port > 0
+	}
+	if m := hostPortPattern.FindStringSubmatch(val); m != nil && m[0] == val {
+		port, _ = strconv.Atoi(m[2])
+		return m[1], port, "", port > 0 && port <= 65535
+	}
+	if defaultPort > 0 && hostnameRe.MatchString(val) {
+		return val, defaultPort, "", true
 	}
+	return "", 0, "", false
 }
 
-// mergeUnique appends deps from extra that don't already exist in base (by Target+Port).
+// mergeUnique appends deps from extra that don't already exist in base (by
+// Target+Port). A duplicate declared on a different line is folded into the
+// existing entry's provenance; one on the same line (the generic URL scan
+// re-finding a structured key) is dropped.
 func mergeUnique(base, extra []model.NetworkDependency) []model.NetworkDependency {
-	seen := make(map[string]bool)
-	for _, d := range base {
-		seen[fmt.Sprintf("%s:%d", d.Target, d.Port)] = true
+	seen := make(map[string]int)
+	for i, d := range base {
+		seen[fmt.Sprintf("%s:%d/%s", d.Target, d.Port, d.Protocol)] = i
 	}
 	for _, d := range extra {
-		key := fmt.Sprintf("%s:%d", d.Target, d.Port)
-		if !seen[key] {
-			seen[key] = true
-			base = append(base, d)
+		key := fmt.Sprintf("%s:%d/%s", d.Target, d.Port, d.Protocol)
+		if i, ok := seen[key]; ok {
+			if base[i].Line != d.Line {
+				base[i] = base[i].MergedWith(d)
+			}
+			continue
+		}
+		seen[key] = len(base)
+		base = append(base, d)
+	}
+	return base
+}
+
+// mergeUniqueListeners is mergeUnique for listeners, keyed by port and
+// protocol.
+func mergeUniqueListeners(base, extra []model.Listener) []model.Listener {
+	seen := make(map[string]int)
+	for i, l := range base {
+		seen[l.Key()] = i
+	}
+	for _, l := range extra {
+		if i, ok := seen[l.Key()]; ok {
+			if base[i].Line != l.Line {
+				base[i] = base[i].MergedWith(l)
+			}
+			continue
 		}
+		seen[l.Key()] = len(base)
+		base = append(base, l)
 	}
 	return base
 }
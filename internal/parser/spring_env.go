@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// envSetting is one environment variable an app is given and where it is
+// set, for provenance.
+type envSetting struct {
+	value     string
+	file      string
+	line, col int
+	evidence  string
+	parser    string // format of the file that sets it
+}
+
+func (e envSetting) provenance() model.Provenance {
+	return model.Provenance{File: e.file, Line: e.line, Column: e.col, Evidence: model.RedactSecrets(e.evidence), Parser: e.parser}
+}
+
+// readDotenv reads the variables a dotenv file sets (see
+// readEnvEntries). A missing or unreadable file yields nothing.
+func readDotenv(path string) map[string]envSetting {
+	env := make(map[string]envSetting)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return env
+	}
+	entries, _ := readEnvEntries(data, nil)
+	for _, e := range entries {
+		env[e.key] = envSetting{value: e.value, file: path, line: e.line, evidence: e.evidence, parser: "envfile"}
+	}
+	return env
+}
+
+// SpringIndex collects Spring config files and the environments they may
+// run in, so ${VAR:default} placeholders resolve against the values a
+// deployment actually sets. Like K8sRefIndex, the walker feeds every file
+// into one index and calls Resolve once at the end.
+//
+// A Spring app is the set of application*.yml/.properties files in one
+// directory. It takes variables from the .env file nearest above it, and,
+// overriding those, from every compose service or Kubernetes container
+// that runs it: a compose service whose build context contains the app,
+// or a compose service, container_name, workload or container named like
+// spring.application.name.
+type SpringIndex struct {
+	opts      SpringOptions
+	configs   map[string][]string // app dir -> config files
+	dotenvs   map[string]map[string]envSetting
+	workloads []springWorkload
+}
+
+// springWorkload is a compose service or Kubernetes container and the
+// literal environment it sets.
+type springWorkload struct {
+	names []string
+	dir   string // compose build context, if any
+	env   map[string]envSetting
+}
+
+// NewSpringIndex returns an empty index resolving the given profiles.
+func NewSpringIndex(opts SpringOptions) *SpringIndex {
+	return &SpringIndex{
+		opts:    opts,
+		configs: make(map[string][]string),
+		dotenvs: make(map[string]map[string]envSetting),
+	}
+}
+
+// AddConfig adds a Spring config file.
+func (x *SpringIndex) AddConfig(path string) {
+	dir := filepath.Dir(filepath.Clean(path))
+	x.configs[dir] = append(x.configs[dir], path)
+}
+
+// AddEnvFile adds a .env file; it applies to apps in its directory and
+// below.
+func (x *SpringIndex) AddEnvFile(path string) {
+	x.dotenvs[filepath.Dir(filepath.Clean(path))] = readDotenv(path)
+}
+
+// AddCompose adds the services of a compose stack (see ComposeStacks),
+// with their environment as compose hands it over: interpolated, env_file
+// entries and overrides included.
+func (x *SpringIndex) AddCompose(paths ...string) error {
+	project, err := loadComposeProject(paths...)
+	if err != nil {
+		return err
+	}
+	for _, name := range project.names() {
+		svc := project.services[name]
+		w := springWorkload{names: []string{name}, dir: svc.build, env: svc.environment}
+		if svc.containerName != "" {
+			w.names = append(w.names, svc.containerName)
+		}
+		x.workloads = append(x.workloads, w)
+	}
+	return nil
+}
+
+// AddManifest adds the containers of the workloads in a Kubernetes
+// manifest, with their literal env values. valueFrom references are
+// K8sRefIndex's business.
+func (x *SpringIndex) AddManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			break
+		}
+		var doc map[string]interface{}
+		if err := node.Decode(&doc); err != nil || doc == nil {
+			continue
+		}
+		ix := indexYAML(&node)
+		for _, c := range podContainers(doc) {
+			w := springWorkload{names: []string{metadataName(doc)}, env: make(map[string]envSetting)}
+			if name, _ := c.spec["name"].(string); name != "" {
+				w.names = append(w.names, name)
+			}
+			for i, e := range toSlice(c.spec["env"]) {
+				entry, ok := e.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := entry["name"].(string)
+				value, ok := entry["value"].(string)
+				if name == "" || !ok {
+					continue
+				}
+				line, col := ix.at(append(append([]interface{}{}, c.path...), "env", i, "value")...)
+				w.env[name] = envSetting{value: value, file: path, line: line, col: col, evidence: name + "=" + value, parser: "k8s"}
+			}
+			x.workloads = append(x.workloads, w)
+		}
+	}
+	return nil
+}
+
+// Resolve parses every Spring app in the index against its environment.
+// Errors are keyed by app directory; the apps that could be read still
+// resolve.
+func (x *SpringIndex) Resolve() (Result, map[string]error) {
+	dirs := make([]string, 0, len(x.configs))
+	for dir := range x.configs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var out Result
+	errs := make(map[string]error)
+	for _, dir := range dirs {
+		paths := x.configs[dir]
+		sort.SliceStable(paths, func(i, j int) bool {
+			return springFileRank(paths[i]) < springFileRank(paths[j])
+		})
+		app, err := loadSpringApp(paths)
+		if err != nil {
+			errs[dir] = err
+			continue
+		}
+		res := app.result(x.opts, x.env(dir, app.name()))
+		out.Dependencies = append(out.Dependencies, res.Dependencies...)
+		out.Listeners = append(out.Listeners, res.Listeners...)
+	}
+	for i := range out.Dependencies {
+		out.Dependencies[i].Parser = "spring"
+	}
+	for i := range out.Listeners {
+		out.Listeners[i].Parser = "spring"
+	}
+	return out, errs
+}
+
+// springFileRank orders an app's files as Spring layers them: .yml before
+// .properties (the latter wins), each alphabetically.
+func springFileRank(path string) string {
+	if strings.HasSuffix(path, ".properties") {
+		return "1" + filepath.Base(path)
+	}
+	return "0" + filepath.Base(path)
+}
+
+// env returns the variables an app in dir named name runs with.
+func (x *SpringIndex) env(dir, name string) map[string]envSetting {
+	env := make(map[string]envSetting)
+	for d := dir; ; d = filepath.Dir(d) {
+		if dotenv, ok := x.dotenvs[d]; ok {
+			for k, v := range dotenv {
+				env[k] = v
+			}
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	for _, w := range x.workloads {
+		if !w.runs(dir, name) {
+			continue
+		}
+		for k, v := range w.env {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// runs reports whether the workload runs the app in dir named name.
+func (w springWorkload) runs(dir, name string) bool {
+	if w.dir != "" && (dir == w.dir || strings.HasPrefix(dir, w.dir+string(filepath.Separator))) {
+		return true
+	}
+	return name != "" && containsString(w.names, name)
+}
@@ -0,0 +1,265 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+// Quarkus and Micronaut read the same application.yml/.properties files
+// as Spring Boot (and application-{profile} files), with their own keys.
+// The framework of an app is taken from its build file when one declares
+// it, else from the keys its config uses; only that framework's keys are
+// extracted. Quarkus's %profile.key prefixes become profile documents.
+
+const (
+	frameworkSpring    = "spring"
+	frameworkQuarkus   = "quarkus"
+	frameworkMicronaut = "micronaut"
+)
+
+// frameworkBuildMarkers identify a framework from its group in a
+// pom.xml or build.gradle. Quarkus and Micronaut apps may also pull in
+// Spring compatibility artifacts, so they're checked first.
+var frameworkBuildMarkers = []struct{ marker, framework string }{
+	{"io.quarkus", frameworkQuarkus},
+	{"io.micronaut", frameworkMicronaut},
+	{"org.springframework.boot", frameworkSpring},
+}
+
+var frameworkBuildFiles = []string{"pom.xml", "build.gradle", "build.gradle.kts"}
+
+// detectFramework returns the framework of the app whose config is in
+// dir: the one the nearest build file above it depends on, else the one
+// whose keys the config uses, else Spring.
+func detectFramework(dir string, app springApp) string {
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		found := false
+		for _, name := range frameworkBuildFiles {
+			data, err := os.ReadFile(filepath.Join(d, name))
+			if err != nil {
+				continue
+			}
+			found = true
+			for _, m := range frameworkBuildMarkers {
+				if strings.Contains(string(data), m.marker) {
+					return m.framework
+				}
+			}
+		}
+		if found || filepath.Dir(d) == d {
+			break
+		}
+	}
+	for _, docs := range [][]springDoc{app.base, app.profiled} {
+		for _, doc := range docs {
+			for _, p := range doc.props {
+				switch {
+				case strings.HasPrefix(p.key, "quarkus."):
+					return frameworkQuarkus
+				case strings.HasPrefix(p.key, "micronaut."), strings.HasPrefix(p.key, "datasources."):
+					return frameworkMicronaut
+				}
+			}
+		}
+	}
+	return frameworkSpring
+}
+
+// splitProfilePrefixes moves Quarkus's profile-prefixed properties
+// (%prod.quarkus.datasource.jdbc.url, or %dev,test.key) out of each
+// document into documents of those profiles, appended after the file's
+// own so they override the unprefixed values as Quarkus does.
+func splitProfilePrefixes(docs []springDoc) []springDoc {
+	var extra []springDoc
+	byProfiles := make(map[string]int)
+	for i := range docs {
+		var kept []springProp
+		for _, p := range docs[i].props {
+			prefix, key, ok := strings.Cut(p.key, ".")
+			if !ok || !strings.HasPrefix(prefix, "%") || len(prefix) < 2 {
+				kept = append(kept, p)
+				continue
+			}
+			p.key, p.profile = key, prefix+"."
+			j, seen := byProfiles[prefix]
+			if !seen {
+				var profiles []string
+				for _, name := range strings.Split(prefix[1:], ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						profiles = append(profiles, name)
+					}
+				}
+				j = len(extra)
+				byProfiles[prefix] = j
+				extra = append(extra, springDoc{profiles: profiles})
+			}
+			extra[j].props = append(extra[j].props, p)
+		}
+		docs[i].props = kept
+	}
+	return append(docs, extra...)
+}
+
+// frameworkKey is a config key (a pattern over the key with quotes and
+// list indexes dropped) naming an endpoint, and how to read its value.
+type frameworkKey struct {
+	key         *regexp.Regexp
+	kind        string // "jdbc", "r2dbc", "kafka" or "endpoints"
+	defaultPort int
+	description string
+	serviceType string
+}
+
+func fk(pattern, kind string, defaultPort int, description, serviceType string) frameworkKey {
+	return frameworkKey{regexp.MustCompile(`^` + pattern + `$`), kind, defaultPort, description, serviceType}
+}
+
+// frameworkKeys are the endpoint keys of Quarkus and Micronaut. Named
+// datasources, Redis clients and REST clients match whatever their name
+// (a quoted Quarkus name may contain dots).
+var frameworkKeys = map[string][]frameworkKey{
+	frameworkQuarkus: {
+		fk(`quarkus\.datasource(\.[^.]+)?\.jdbc\.url`, "jdbc", 0, "Quarkus datasource", "database"),
+		fk(`quarkus\.datasource(\.[^.]+)?\.reactive\.url`, "endpoints", 0, "Quarkus reactive datasource", "database"),
+		fk(`quarkus\.redis(\.[^.]+)?\.hosts`, "endpoints", 6379, "Redis", "cache"),
+		fk(`quarkus\.mongodb(\.[^.]+)?\.(connection-string|hosts)`, "endpoints", 27017, "MongoDB", "database"),
+		fk(`quarkus\.elasticsearch\.hosts`, "endpoints", 9200, "Elasticsearch", "search"),
+		fk(`quarkus\.rest-client\..+\.(url|uri)`, "endpoints", 0, "REST client", "http"),
+		fk(`[^/]+/mp-rest/(url|uri)`, "endpoints", 0, "REST client", "http"),
+		fk(`kafka\.bootstrap\.servers`, "kafka", 0, "Kafka", "broker"),
+		fk(`mp\.messaging\.(incoming|outgoing|connector)\.[^.]+\.bootstrap\.servers`, "kafka", 0, "Kafka", "broker"),
+	},
+	frameworkMicronaut: {
+		fk(`datasources\.[^.]+\.url`, "jdbc", 0, "Micronaut datasource", "database"),
+		fk(`r2dbc\.datasources\.[^.]+\.url`, "r2dbc", 0, "Micronaut R2DBC datasource", "database"),
+		fk(`micronaut\.http\.services\.[^.]+\.urls?`, "endpoints", 0, "HTTP client", "http"),
+		fk(`redis(\.servers\.[^.]+)?\.uris?`, "endpoints", 6379, "Redis", "cache"),
+		fk(`mongodb(\.servers\.[^.]+)?\.uri`, "endpoints", 27017, "MongoDB", "database"),
+		fk(`elasticsearch\.(httpHosts|http-hosts)`, "endpoints", 9200, "Elasticsearch", "search"),
+		fk(`kafka\.bootstrap\.servers`, "kafka", 0, "Kafka", "broker"),
+		fk(`rabbitmq\.uris?`, "endpoints", 5672, "RabbitMQ", "broker"),
+	},
+}
+
+// frameworkHostPorts are host/port pairs, like springHostPorts.
+var frameworkHostPorts = map[string][]struct {
+	host, port  string
+	defaultPort int
+	description string
+	serviceType string
+}{
+	frameworkQuarkus: {
+		{"rabbitmq-host", "rabbitmq-port", 5672, "RabbitMQ", "broker"},
+	},
+	frameworkMicronaut: {
+		{"rabbitmq.host", "rabbitmq.port", 5672, "RabbitMQ", "broker"},
+	},
+}
+
+// frameworkSelfPorts are the app's own listening ports, like
+// springSelfPorts.
+var frameworkSelfPorts = map[string][]struct{ key, description string }{
+	frameworkQuarkus: {
+		{"quarkus.http.port", "server listening port"},
+		{"quarkus.http.ssl-port", "server TLS listening port"},
+		{"quarkus.management.port", "management interface port"},
+		{"quarkus.grpc.server.port", "gRPC server port"},
+	},
+	frameworkMicronaut: {
+		{"micronaut.server.port", "server listening port"},
+		{"grpc.server.port", "gRPC server port"},
+	},
+}
+
+// frameworkKeyShape drops the quotes Quarkus puts around names with dots
+// ("my-client") and list indexes from a key.
+func frameworkKeyShape(key string) string {
+	return springKeyShape(strings.ReplaceAll(key, `"`, ""))
+}
+
+// frameworkViewDeps extracts the Quarkus or Micronaut endpoint keys of
+// one view. mark records the keys it handles, so the generic URL scan
+// doesn't report them again.
+func frameworkViewDeps(r springResolver, framework string, keys []string, mark func(...string)) []model.NetworkDependency {
+	var deps []model.NetworkDependency
+	for _, hp := range frameworkHostPorts[framework] {
+		mark(hp.host, hp.port)
+		p, res, ok := r.get(hp.host)
+		if !ok || res.value == "" {
+			continue
+		}
+		port := hp.defaultPort
+		if _, pres, ok := r.get(hp.port); ok {
+			if n, err := strconv.Atoi(pres.value); err == nil {
+				port = n
+				res.defaulted = res.defaulted || pres.defaulted
+				res.sources = append(res.sources, pres.sources...)
+			}
+		}
+		deps = append(deps, springDep(p, res, model.NetworkDependency{
+			Target:      res.value,
+			Port:        port,
+			Protocol:    "TCP",
+			Description: hp.description,
+			Confidence:  model.High,
+			ServiceType: hp.serviceType,
+		}))
+	}
+
+	done := make(map[string]bool)
+	for _, key := range keys {
+		shape := frameworkKeyShape(key)
+		for _, k := range frameworkKeys[framework] {
+			if !k.key.MatchString(shape) {
+				continue
+			}
+			mark(key)
+			base := springKeyShape(key)
+			if done[base] {
+				break // a list already read whole through r.get
+			}
+			done[base] = true
+			p, res, ok := r.get(base)
+			if !ok || res.value == "" {
+				break
+			}
+			var found []model.NetworkDependency
+			switch k.kind {
+			case "jdbc":
+				if d, ok := parseJDBC(res.value, p.file); ok {
+					found = append(found, d)
+				}
+			case "r2dbc":
+				if d, ok := parseR2DBC(res.value, p.file); ok {
+					found = append(found, d)
+				}
+			case "kafka":
+				for _, b := range parseKafkaBrokers(res.value) {
+					found = append(found, model.NetworkDependency{Target: b.host, Port: b.port, Protocol: "TCP"})
+				}
+			default:
+				// Quarkus reactive URLs may carry a vertx-reactive: prefix.
+				for _, ep := range springEndpoints(strings.TrimPrefix(res.value, "vertx-reactive:"), k.defaultPort) {
+					found = append(found, model.NetworkDependency{Target: ep.host, Port: ep.port, Protocol: inferProtocol(ep.scheme, ep.port)})
+				}
+			}
+			for _, d := range found {
+				if d.Description == "" || k.kind != "jdbc" && k.kind != "r2dbc" {
+					d.Description = k.description
+				} else {
+					d.Description = k.description + ": " + d.Description
+				}
+				d.Confidence = model.High
+				d.ServiceType = k.serviceType
+				deps = append(deps, springDep(p, res, d))
+			}
+			break
+		}
+	}
+	return deps
+}
@@ -0,0 +1,356 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func init() {
+	defaultRegistry.RegisterFormat("terraform", "*.tf", parseTerraform)
+}
+
+// maxSGPortRange caps how many individual ports a security-group rule with
+// a from_port..to_port range is expanded into. Wider ranges are recorded
+// once, as a Port-EndPort range.
+const maxSGPortRange = 16
+
+// parseTerraform extracts network dependencies from a Terraform file
+// without running `terraform plan`: managed data stores become endpoints
+// (their port comes from the `port` attribute or the engine default) and
+// security-group rules become explicit allow edges.
+//
+// Endpoint names prefer the cloud-side identifier literal (identifier,
+// cluster_id, cluster_name, name) and fall back to the Terraform resource
+// name when the identifier is computed.
+func parseTerraform(path string) ([]model.NetworkDependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var deps []model.NetworkDependency
+	for _, b := range parseHCL(data) {
+		if b.Type != "resource" || len(b.Labels) != 2 {
+			continue
+		}
+		switch b.Labels[0] {
+		case "aws_db_instance", "aws_rds_cluster":
+			deps = append(deps, tfDatabase(b, path, "engine", tfAWSEnginePort)...)
+		case "google_sql_database_instance":
+			deps = append(deps, tfDatabase(b, path, "database_version", tfCloudSQLPort)...)
+		case "aws_elasticache_cluster", "aws_elasticache_replication_group":
+			deps = append(deps, tfElastiCache(b, path)...)
+		case "aws_msk_cluster":
+			deps = append(deps, tfMSK(b, path)...)
+		case "aws_security_group_rule", "aws_vpc_security_group_ingress_rule", "aws_vpc_security_group_egress_rule":
+			deps = append(deps, tfSGRule(b, b, path)...)
+		case "aws_security_group":
+			for _, nb := range b.Blocks {
+				if nb.Type == "ingress" || nb.Type == "egress" {
+					deps = append(deps, tfSGRule(b, nb, path)...)
+				}
+			}
+		}
+	}
+
+	if disable := ScanFileDisable(data); disable != "" {
+		for i := range deps {
+			deps[i].Disabled = disable
+		}
+	}
+	return deps, nil
+}
+
+// tfName returns the first literal among the given attributes, or the
+// resource's Terraform name.
+func tfName(b *hclBlock, attrs ...string) string {
+	for _, a := range attrs {
+		if at, ok := b.Attrs[a]; ok {
+			if s, ok := at.str(); ok && s != "" && !strings.Contains(s, "${") {
+				return s
+			}
+		}
+	}
+	return b.Labels[1]
+}
+
+// tfEvidence returns the position and text to cite for a dependency: the
+// attribute that decided the port when present, else the resource header.
+func tfEvidence(b *hclBlock, attr string) (int, string) {
+	if at, ok := b.Attrs[attr]; ok {
+		return at.Line, at.Text
+	}
+	return b.Line, b.Header
+}
+
+// tfAWSEnginePort maps an RDS engine to its default port and description.
+func tfAWSEnginePort(engine string) (int, string) {
+	e := strings.ToLower(engine)
+	switch {
+	case strings.Contains(e, "postgres"):
+		return 5432, "PostgreSQL"
+	case strings.Contains(e, "mysql"):
+		return 3306, "MySQL"
+	case strings.Contains(e, "mariadb"):
+		return 3306, "MariaDB"
+	case strings.HasPrefix(e, "oracle"):
+		return 1521, "Oracle"
+	case strings.HasPrefix(e, "sqlserver"):
+		return 1433, "SQL Server"
+	}
+	return 0, ""
+}
+
+// tfCloudSQLPort maps a Cloud SQL database_version to its default port.
+func tfCloudSQLPort(version string) (int, string) {
+	v := strings.ToUpper(version)
+	switch {
+	case strings.HasPrefix(v, "POSTGRES"):
+		return 5432, "PostgreSQL"
+	case strings.HasPrefix(v, "MYSQL"):
+		return 3306, "MySQL"
+	case strings.HasPrefix(v, "SQLSERVER"):
+		return 1433, "SQL Server"
+	}
+	return 0, ""
+}
+
+func tfDatabase(b *hclBlock, path, engineAttr string, portFor func(string) (int, string)) []model.NetworkDependency {
+	engine, _ := b.Attrs[engineAttr].str()
+	port, desc := portFor(engine)
+	portAttr := engineAttr
+	if p, ok := b.Attrs["port"].num(); ok {
+		port, portAttr = p, "port"
+	}
+	if port == 0 {
+		return nil
+	}
+	if desc == "" {
+		desc = "database"
+	}
+	line, evidence := tfEvidence(b, portAttr)
+	return []model.NetworkDependency{{
+		Target:       tfName(b, "identifier", "cluster_identifier", "name"),
+		Port:         port,
+		Protocol:     "TCP",
+		Description:  fmt.Sprintf("%s (%s)", desc, b.Labels[0]),
+		Confidence:   model.High,
+		SourceFile:   path,
+		Line:         line,
+		EvidenceLine: evidence,
+		ServiceType:  "database",
+	}}
+}
+
+func tfElastiCache(b *hclBlock, path string) []model.NetworkDependency {
+	engine, ok := b.Attrs["engine"].str()
+	if !ok {
+		engine = "redis" // replication groups are Redis-only unless stated
+	}
+	port, desc, portAttr := 6379, "Redis", "engine"
+	if strings.EqualFold(engine, "memcached") {
+		port, desc = 11211, "Memcached"
+	}
+	if p, ok := b.Attrs["port"].num(); ok {
+		port, portAttr = p, "port"
+	}
+	line, evidence := tfEvidence(b, portAttr)
+	return []model.NetworkDependency{{
+		Target:       tfName(b, "cluster_id", "replication_group_id"),
+		Port:         port,
+		Protocol:     "TCP",
+		Description:  fmt.Sprintf("%s (%s)", desc, b.Labels[0]),
+		Confidence:   model.High,
+		SourceFile:   path,
+		Line:         line,
+		EvidenceLine: evidence,
+		ServiceType:  "cache",
+	}}
+}
+
+// tfMSK derives broker ports from encryption_in_transit.client_broker:
+// TLS (the AWS default) listens on 9094, PLAINTEXT on 9092, and
+// TLS_PLAINTEXT on both.
+func tfMSK(b *hclBlock, path string) []model.NetworkDependency {
+	mode := "TLS"
+	line, evidence := b.Line, b.Header
+	if enc := b.block("encryption_info"); enc != nil {
+		if transit := enc.block("encryption_in_transit"); transit != nil {
+			if at, ok := transit.Attrs["client_broker"]; ok {
+				if s, ok := at.str(); ok {
+					mode = strings.ToUpper(s)
+					line, evidence = at.Line, at.Text
+				}
+			}
+		}
+	}
+	var ports []int
+	switch mode {
+	case "PLAINTEXT":
+		ports = []int{9092}
+	case "TLS_PLAINTEXT":
+		ports = []int{9094, 9092}
+	default:
+		ports = []int{9094}
+	}
+	target := tfName(b, "cluster_name")
+	var deps []model.NetworkDependency
+	for _, p := range ports {
+		deps = append(deps, model.NetworkDependency{
+			Target:       target,
+			Port:         p,
+			Protocol:     "TCP",
+			Description:  fmt.Sprintf("Kafka (aws_msk_cluster, %s)", mode),
+			Confidence:   model.High,
+			SourceFile:   path,
+			Line:         line,
+			EvidenceLine: evidence,
+			ServiceType:  "broker",
+		})
+	}
+	return deps
+}
+
+// tfSGRule turns one security-group rule into allow edges. sg is the
+// owning aws_security_group for inline ingress/egress blocks, or the rule
+// resource itself for standalone rules (whose owner comes from
+// security_group_id). Ingress edges run peer -> group; egress edges run
+// group -> peer. All-protocol rules (-1) carry no port and are skipped.
+// CIDR peers (0.0.0.0/0, 10.0.0.0/16, ::/0) are kept as the edge's
+// Source or Target, which renderers turn into ipBlock peers.
+func tfSGRule(sg, rule *hclBlock, path string) []model.NetworkDependency {
+	direction := rule.Type
+	group := sg.Labels[1]
+	if rule == sg {
+		switch sg.Labels[0] {
+		case "aws_vpc_security_group_ingress_rule":
+			direction = "ingress"
+		case "aws_vpc_security_group_egress_rule":
+			direction = "egress"
+		default:
+			direction, _ = rule.Attrs["type"].str()
+		}
+		group = tfRefName(rule.Attrs["security_group_id"].Expr, sg.Labels[1])
+	}
+	if direction != "ingress" && direction != "egress" {
+		return nil
+	}
+
+	proto := "TCP"
+	protoAttr := rule.Attrs["protocol"]
+	if rule == sg && sg.Labels[0] != "aws_security_group_rule" {
+		protoAttr = rule.Attrs["ip_protocol"]
+	}
+	if s, ok := protoAttr.str(); ok {
+		switch strings.ToLower(s) {
+		case "tcp", "6":
+		case "udp", "17":
+			proto = "UDP"
+		case "sctp", "132":
+			proto = "SCTP"
+		default:
+			return nil
+		}
+	}
+
+	from, ok := rule.Attrs["from_port"].num()
+	if !ok {
+		return nil
+	}
+	to, ok := rule.Attrs["to_port"].num()
+	if !ok || to < from {
+		to = from
+	}
+	if from == 0 && to == 0 {
+		return nil
+	}
+	// A narrow range becomes one edge per port; a wider one is carried
+	// whole, as Port-EndPort. Port 0 isn't a port: 0-65535 is every port.
+	ports := []int{from}
+	end := 0
+	if to > from {
+		if to-from < maxSGPortRange {
+			ports = ports[:0]
+			for p := from; p <= to; p++ {
+				ports = append(ports, p)
+			}
+		} else {
+			ports[0], end = max(from, 1), to
+		}
+	}
+	desc := fmt.Sprintf("security group %s %s", group, direction)
+
+	// CIDR peers stay as written and render as ipBlock peers. Prefix
+	// lists name AWS-managed address sets no policy can express, so they
+	// are left out.
+	var peers []string
+	for _, a := range []string{"cidr_blocks", "ipv6_cidr_blocks"} {
+		peers = append(peers, rule.Attrs[a].list()...)
+	}
+	for _, a := range []string{"cidr_ipv4", "cidr_ipv6"} {
+		if s, ok := rule.Attrs[a].str(); ok {
+			peers = append(peers, s)
+		}
+	}
+	for _, a := range []string{"source_security_group_id", "referenced_security_group_id"} {
+		if at, ok := rule.Attrs[a]; ok {
+			peers = append(peers, tfRefName(at.Expr, ""))
+		}
+	}
+	for _, v := range rule.Attrs["security_groups"].list() {
+		peers = append(peers, tfRefName(v, ""))
+	}
+	if rule.Attrs["self"].Expr == "true" {
+		peers = append(peers, group)
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+
+	// Cite the from_port line: inline rules share the group's header, so
+	// the port line is what tells one rule from the next.
+	line, evidence := tfEvidence(rule, "from_port")
+	var deps []model.NetworkDependency
+	for _, peer := range peers {
+		src, dst := peer, group
+		if direction == "egress" {
+			src, dst = group, peer
+		}
+		for _, p := range ports {
+			deps = append(deps, model.NetworkDependency{
+				Source:       src,
+				Target:       dst,
+				Port:         p,
+				EndPort:      end,
+				Protocol:     proto,
+				Description:  desc,
+				Confidence:   model.High,
+				SourceFile:   path,
+				Line:         line,
+				EvidenceLine: evidence,
+			})
+		}
+	}
+	return deps
+}
+
+// tfRefName resolves `aws_security_group.web.id` to "web". Anything that
+// is not a resource reference returns fallback, or the expression itself
+// when fallback is empty.
+func tfRefName(expr, fallback string) string {
+	expr = strings.TrimSpace(expr)
+	if s, ok := (hclAttr{Expr: expr}).str(); ok {
+		return s
+	}
+	parts := strings.Split(expr, ".")
+	if len(parts) >= 2 && strings.HasPrefix(parts[0], "aws_") {
+		return parts[1]
+	}
+	if fallback == "" {
+		return expr
+	}
+	return fallback
+}
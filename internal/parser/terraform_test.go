@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+)
+
+func TestTerraformDataStores(t *testing.T) {
+	tf := `# primary database
+resource "aws_db_instance" "orders" {
+  identifier = "orders-db"
+  engine     = "postgres"
+  /* multi-line
+     comment */
+  tags = {
+    team = "payments"
+  }
+}
+
+resource "aws_rds_cluster" "reports" {
+  engine = "aurora-mysql"
+  port   = 3307
+}
+
+resource "aws_elasticache_cluster" "sessions" {
+  cluster_id = "sessions"
+  engine     = "memcached"
+}
+
+resource "aws_msk_cluster" "events" {
+  cluster_name = "events"
+  encryption_info {
+    encryption_in_transit {
+      client_broker = "TLS_PLAINTEXT"
+    }
+  }
+}
+
+resource "google_sql_database_instance" "analytics" {
+  name             = "analytics-${var.env}"
+  database_version = "POSTGRES_15"
+}
+`
+	path := writeTempFile(t, "main.tf", tf)
+	deps, err := parseTerraform(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertDepCount(t, deps, 6)
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "orders-db" && d.Port == 5432 && d.Confidence == model.High && d.ServiceType == "database"
+	}, "RDS postgres default port")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "reports" && d.Port == 3307 && d.EvidenceLine == "port   = 3307"
+	}, "Aurora explicit port")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "sessions" && d.Port == 11211
+	}, "ElastiCache memcached")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "events" && d.Port == 9092
+	}, "MSK plaintext")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "events" && d.Port == 9094
+	}, "MSK TLS")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Target == "analytics" && d.Port == 5432
+	}, "Cloud SQL with interpolated name falls back to resource name")
+
+	assertLine(t, deps, "orders-db", 5432, 4)
+	assertLine(t, deps, "reports", 3307, 14)
+	assertLine(t, deps, "events", 9094, 26)
+}
+
+func TestTerraformSecurityGroupRules(t *testing.T) {
+	tf := `resource "aws_security_group" "db" {
+  name = "db-sg"
+
+  ingress {
+    from_port       = 5432
+    to_port         = 5432
+    protocol        = "tcp"
+    security_groups = [aws_security_group.app.id]
+  }
+
+  egress {
+    from_port   = 0
+    to_port     = 0
+    protocol    = "-1"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+}
+
+resource "aws_security_group_rule" "app_dns" {
+  type              = "egress"
+  security_group_id = aws_security_group.app.id
+  from_port         = 53
+  to_port           = 53
+  protocol          = "udp"
+  cidr_blocks       = [
+    "10.0.0.2/32",
+  ]
+}
+`
+	path := writeTempFile(t, "sg.tf", tf)
+	deps, err := parseTerraform(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertDepCount(t, deps, 2)
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "app" && d.Target == "db" && d.Port == 5432 && d.Protocol == "TCP" && d.Line == 5
+	}, "inline ingress from app SG")
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "app" && d.Target == "10.0.0.2/32" && d.Port == 53 && d.Protocol == "UDP"
+	}, "standalone egress rule")
+}
+
+func TestTerraformPortRangeAndDisable(t *testing.T) {
+	tf := `# segspec:disable=egress
+resource "aws_security_group_rule" "web" {
+  type              = "ingress"
+  security_group_id = aws_security_group.web.id
+  from_port         = 8080
+  to_port           = 8082
+  protocol          = "tcp"
+  cidr_blocks       = ["10.0.0.0/16"]
+}
+
+resource "aws_security_group_rule" "ephemeral" {
+  type              = "ingress"
+  security_group_id = aws_security_group.web.id
+  from_port         = 1024
+  to_port           = 65535
+  protocol          = "tcp"
+  cidr_blocks       = ["10.0.0.0/16"]
+}
+`
+	deps, err := parseTerraform(writeTempFile(t, "rules.tf", tf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertDepCount(t, deps, 4) // 8080, 8081, 8082 + one entry for the wide range
+	for _, d := range deps {
+		if d.Disabled != "egress" {
+			t.Errorf("%s:%d Disabled = %q, want egress", d.Target, d.Port, d.Disabled)
+		}
+	}
+	assertHasDep(t, deps, func(d model.NetworkDependency) bool {
+		return d.Source == "10.0.0.0/16" && d.Port == 1024 && d.EndPort == 65535
+	}, "wide range recorded once, as a range")
+}
+
+func TestTerraformAllPortsAndPrefixLists(t *testing.T) {
+	tf := `resource "aws_security_group" "web" {
+  egress {
+    from_port       = 0
+    to_port         = 65535
+    protocol        = "tcp"
+    cidr_blocks     = ["0.0.0.0/0"]
+    ipv6_cidr_blocks = ["::/0"]
+    prefix_list_ids = ["pl-63a5400a"]
+  }
+}
+`
+	deps, err := parseTerraform(writeTempFile(t, "web.tf", tf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertDepCount(t, deps, 2) // the prefix list has no addresses to allow
+	for _, d := range deps {
+		if d.Source != "web" || d.Port != 1 || d.EndPort != 65535 || d.PortRange() != "1-65535" {
+			t.Errorf("%s -> %s:%s, want web to every port", d.Source, d.Target, d.PortRange())
+		}
+	}
+}
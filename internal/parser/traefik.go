@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	// Traefik's static (traefik.yml) and file-provider dynamic configs
+	// have arbitrary names in YAML or TOML; files without Traefik's keys
+	// are ignored.
+	for _, pattern := range []string{"*.yaml", "*.yml", "*.toml"} {
+		defaultRegistry.RegisterResults("traefik", pattern, parseTraefik)
+	}
+}
+
+// traefikEntry is one scalar of a Traefik config: its key path
+// (lowercased, since Traefik's keys are case-insensitive, and without
+// list indexes), value and line.
+type traefikEntry struct {
+	path  []string
+	value string
+	line  int
+}
+
+// parseTraefik reads Traefik config: entryPoints addresses (`:80`,
+// `:53/udp`) become listeners, and the load-balancer servers of http,
+// tcp and udp services dependencies.
+func parseTraefik(path string) (Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+	var entries []traefikEntry
+	if strings.HasSuffix(strings.ToLower(path), ".toml") {
+		entries = flattenTraefikTOML(data)
+	} else {
+		var doc yaml.Node
+		if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+			return Result{}, nil
+		}
+		flattenTraefikYAML(doc.Content[0], nil, &entries)
+	}
+
+	lines := sourceLines(data)
+	var res Result
+	for _, e := range entries {
+		p := e.path
+		switch {
+		case len(p) == 3 && p[0] == "entrypoints" && p[2] == "address":
+			address, proto, _ := strings.Cut(e.value, "/")
+			host, port, ok := proxyAddress(address, 0)
+			if !ok || loopbackHost(host) {
+				continue
+			}
+			res.Listeners = append(res.Listeners, proxyListener(path, e.line, lineAt(lines, e.line), port, specProtocol(proto), "Traefik entry point "+p[1]))
+		case len(p) == 6 && p[1] == "services" && p[3] == "loadbalancer" && p[4] == "servers":
+			var host string
+			var port int
+			var ok bool
+			protocol, serviceType := "TCP", ""
+			switch {
+			case p[0] == "http" && p[5] == "url":
+				host, port, _, ok = endpointFromValue(e.value, 80)
+				serviceType = "http"
+			case (p[0] == "tcp" || p[0] == "udp") && p[5] == "address":
+				host, port, ok = proxyAddress(e.value, 0)
+				if p[0] == "udp" {
+					protocol = "UDP"
+				}
+			}
+			if !ok || host == "" || loopbackHost(host) {
+				continue
+			}
+			res.Dependencies = append(res.Dependencies, proxyDep(path, e.line, lineAt(lines, e.line),
+				host, port, protocol, "Traefik service "+p[2], serviceType))
+		}
+	}
+	return withDisable(res, data), nil
+}
+
+// flattenTraefikYAML lists the scalars under n.
+func flattenTraefikYAML(n *yaml.Node, prefix []string, out *[]traefikEntry) {
+	n = yamlDeref(n)
+	if n == nil {
+		return
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		for _, p := range yamlPairs(n) {
+			flattenTraefikYAML(p.value, append(append([]string(nil), prefix...), strings.ToLower(p.key)), out)
+		}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			flattenTraefikYAML(item, prefix, out)
+		}
+	case yaml.ScalarNode:
+		*out = append(*out, traefikEntry{path: prefix, value: n.Value, line: n.Line})
+	}
+}
+
+var (
+	tomlTableRe      = regexp.MustCompile(`^\[\[?\s*([^\]]+?)\s*\]\]?$`)
+	tomlKeyValueRe   = regexp.MustCompile(`^([A-Za-z0-9_."-]+)\s*=\s*(.+)$`)
+	tomlInlineDataRe = regexp.MustCompile(`([A-Za-z0-9_]+)\s*=\s*"([^"]*)"`)
+)
+
+// flattenTraefikTOML lists the string values of a TOML file, the way
+// Traefik's docs write it: [tables], [[arrays of tables]], dotted keys
+// and inline tables of strings (servers = [{ url = "..." }]).
+func flattenTraefikTOML(data []byte) []traefikEntry {
+	var out []traefikEntry
+	var table []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := tomlTableRe.FindStringSubmatch(line); m != nil {
+			table = tomlKeyPath(m[1])
+			continue
+		}
+		m := tomlKeyValueRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		key := append(append([]string(nil), table...), tomlKeyPath(m[1])...)
+		value := strings.TrimSpace(m[2])
+		if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+			for _, kv := range tomlInlineDataRe.FindAllStringSubmatch(value, -1) {
+				out = append(out, traefikEntry{path: append(append([]string(nil), key...), strings.ToLower(kv[1])), value: kv[2], line: lineNo})
+			}
+			continue
+		}
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		out = append(out, traefikEntry{path: key, value: stripQuotes(value), line: lineNo})
+	}
+	return out
+}
+
+// tomlKeyPath splits a dotted TOML key, unquoting and lowercasing its
+// parts.
+func tomlKeyPath(key string) []string {
+	var parts []string
+	for _, part := range strings.Split(key, ".") {
+		parts = append(parts, strings.ToLower(stripQuotes(strings.TrimSpace(part))))
+	}
+	return parts
+}
@@ -0,0 +1,94 @@
+package parser
+
+import "testing"
+
+func TestParseTraefikYAML(t *testing.T) {
+	path := writeTempFile(t, "dynamic.yml", `entryPoints:
+  web:
+    address: ":80"
+  dns:
+    address: ":53/udp"
+http:
+  services:
+    app:
+      loadBalancer:
+        servers:
+          - url: "https://app.internal"
+          - url: "http://127.0.0.1:3000"
+tcp:
+  services:
+    pg:
+      loadBalancer:
+        servers:
+          - address: "postgres:5432"
+udp:
+  services:
+    syslog:
+      loadBalancer:
+        servers:
+          - address: "logs:514"
+`)
+	res, err := parseTraefik(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		target, protocol, serviceType string
+		port, line                    int
+	}{
+		{"app.internal", "TCP", "http", 443, 11},
+		{"postgres", "TCP", "", 5432, 18},
+		{"logs", "UDP", "", 514, 24},
+	} {
+		d := findDep(res.Dependencies, tt.target, tt.port)
+		if d == nil {
+			t.Errorf("missing %s:%d in %v", tt.target, tt.port, res.Dependencies)
+			continue
+		}
+		if d.Protocol != tt.protocol || d.ServiceType != tt.serviceType || d.Line != tt.line {
+			t.Errorf("%s:%d = %s/%q line %d, want %s/%q line %d", tt.target, tt.port, d.Protocol, d.ServiceType, d.Line, tt.protocol, tt.serviceType, tt.line)
+		}
+	}
+	if len(res.Dependencies) != 3 {
+		t.Errorf("got %d deps, want 3: %v", len(res.Dependencies), res.Dependencies)
+	}
+	if l := findListener(res.Listeners, "", 53); l == nil || l.Protocol != "UDP" || l.Description != "Traefik entry point dns" {
+		t.Errorf("dns entry point = %+v", l)
+	}
+	if findListener(res.Listeners, "", 80) == nil {
+		t.Errorf("missing web entry point in %v", res.Listeners)
+	}
+}
+
+func TestParseTraefikTOML(t *testing.T) {
+	path := writeTempFile(t, "traefik.toml", `[entryPoints]
+  [entryPoints.websecure]
+    address = ":443"
+
+[http.services.whoami.loadBalancer]
+  [[http.services.whoami.loadBalancer.servers]]
+    url = "http://whoami:8000"  # v1
+
+[tcp.services.pg.loadBalancer]
+  servers = [{ address = "postgres:5432" }]
+`)
+	res, err := parseTraefik(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := findDep(res.Dependencies, "whoami", 8000); d == nil || d.Line != 7 {
+		t.Errorf("whoami = %+v in %v", d, res.Dependencies)
+	}
+	if findDep(res.Dependencies, "postgres", 5432) == nil {
+		t.Errorf("missing inline-table server in %v", res.Dependencies)
+	}
+	if findListener(res.Listeners, "", 443) == nil {
+		t.Errorf("missing websecure entry point in %v", res.Listeners)
+	}
+
+	// pyproject.toml and other TOML have none of Traefik's keys.
+	other, err := parseTraefik(writeTempFile(t, "pyproject.toml", "[tool.poetry]\nname = \"x\"\n[server]\naddress = \"db:5432\"\n"))
+	if err != nil || len(other.Dependencies)+len(other.Listeners) != 0 {
+		t.Errorf("pyproject.toml = %+v, %v; want nothing", other, err)
+	}
+}
@@ -9,11 +9,22 @@ package parser
 // PATCH for bug fixes that don't change accepted-input shape, MAJOR for
 // breaking changes to evidence-line format.
 const (
-	VersionSpring    = "0.6.0"
-	VersionCompose   = "0.6.0"
-	VersionK8s       = "0.6.0"
-	VersionEnvfile   = "0.6.0"
-	VersionBuildfile = "0.6.0"
+	VersionSpring     = "0.6.0"
+	VersionCompose    = "0.6.0"
+	VersionK8s        = "0.6.0"
+	VersionEnvfile    = "0.6.0"
+	VersionBuildfile  = "0.6.0"
+	VersionTerraform  = "0.1.0"
+	VersionNode       = "0.1.0"
+	VersionPython     = "0.1.0"
+	VersionGo         = "0.1.0"
+	VersionDockerfile = "0.1.0"
+	VersionDotnet     = "0.1.0"
+	VersionRails      = "0.1.0"
+	VersionNginx      = "0.1.0"
+	VersionHAProxy    = "0.1.0"
+	VersionEnvoy      = "0.1.0"
+	VersionTraefik    = "0.1.0"
 )
 
 // Versions returns a map of parser format-name → version string for every
@@ -24,10 +35,21 @@ const (
 // stamp reproducibility metadata.
 func Versions() map[string]string {
 	return map[string]string{
-		"spring":    VersionSpring,
-		"compose":   VersionCompose,
-		"k8s":       VersionK8s,
-		"envfile":   VersionEnvfile,
-		"buildfile": VersionBuildfile,
+		"spring":     VersionSpring,
+		"compose":    VersionCompose,
+		"k8s":        VersionK8s,
+		"envfile":    VersionEnvfile,
+		"buildfile":  VersionBuildfile,
+		"terraform":  VersionTerraform,
+		"node":       VersionNode,
+		"python":     VersionPython,
+		"go":         VersionGo,
+		"dockerfile": VersionDockerfile,
+		"dotnet":     VersionDotnet,
+		"rails":      VersionRails,
+		"nginx":      VersionNginx,
+		"haproxy":    VersionHAProxy,
+		"envoy":      VersionEnvoy,
+		"traefik":    VersionTraefik,
 	}
 }
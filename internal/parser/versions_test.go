@@ -20,11 +20,22 @@ func TestVersionsNonEmpty(t *testing.T) {
 // parser format name we recognize — no orphan entries.
 func TestVersionsKeysMatchKnownFormats(t *testing.T) {
 	known := map[string]bool{
-		"spring":    true,
-		"compose":   true,
-		"k8s":       true,
-		"envfile":   true,
-		"buildfile": true,
+		"spring":     true,
+		"compose":    true,
+		"k8s":        true,
+		"envfile":    true,
+		"buildfile":  true,
+		"terraform":  true,
+		"node":       true,
+		"python":     true,
+		"go":         true,
+		"dockerfile": true,
+		"dotnet":     true,
+		"rails":      true,
+		"nginx":      true,
+		"haproxy":    true,
+		"envoy":      true,
+		"traefik":    true,
 	}
 	v := Versions()
 	for name := range v {
@@ -26,7 +26,8 @@ import (
 // explicit and signoff-ready.
 func Audit(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
-	if len(deps) == 0 {
+	listeners := ds.Listeners()
+	if len(deps) == 0 && len(listeners) == 0 {
 		return "No dependencies found.\n"
 	}
 
@@ -41,7 +42,7 @@ func Audit(ds *model.DependencySet) string {
 
 	// --- Counters ---------------------------------------------------------
 	highCount, medCount, lowCount := tallyConfidence(deps)
-	workloads := uniqueWorkloads(deps)
+	workloads := uniqueWorkloads(deps, listeners)
 	noEvidence := countMissingEvidence(deps)
 
 	fmt.Fprintf(&b, "## Summary\n\n")
@@ -49,6 +50,7 @@ func Audit(ds *model.DependencySet) string {
 	fmt.Fprintf(&b, "|---|---:|\n")
 	fmt.Fprintf(&b, "| Workloads with declared traffic | %d |\n", len(workloads))
 	fmt.Fprintf(&b, "| Total dependencies | %d |\n", len(deps))
+	fmt.Fprintf(&b, "| Listening ports | %d |\n", len(listeners))
 	fmt.Fprintf(&b, "| High confidence (auto-approve candidates) | %d |\n", highCount)
 	fmt.Fprintf(&b, "| Medium confidence (review) | %d |\n", medCount)
 	fmt.Fprintf(&b, "| Low confidence (investigate) | %d |\n", lowCount)
@@ -83,21 +85,34 @@ func Audit(ds *model.DependencySet) string {
 }
 
 // writeWorkloadSection emits one Markdown section for the named workload,
-// with two tables: ingress (others -> workload) and egress (workload -> others).
+// with three tables: the ports it listens on, ingress (others -> workload)
+// and egress (workload -> others).
 func writeWorkloadSection(b *strings.Builder, ds *model.DependencySet, workload string) {
 	ingress := ds.IngressFor(workload)
 	egress := ds.EgressFor(workload)
-	// Filter ingress to exclude self-loops; those are captured as "exposes" rows
-	// in the egress table to avoid double-counting.
-	ingressExt := make([]model.NetworkDependency, 0, len(ingress))
-	for _, d := range ingress {
-		if d.Source != workload {
-			ingressExt = append(ingressExt, d)
-		}
-	}
+	listeners := ds.ListenersFor(workload)
 
 	fmt.Fprintf(b, "### `%s`\n\n", workload)
-	fmt.Fprintf(b, "Status: %s\n\n", workloadStatus(ingressExt, egress))
+	fmt.Fprintf(b, "Status: %s\n\n", workloadStatus(ingress, egress))
+
+	// --- Listener table ---------------------------------------------------
+	if len(listeners) > 0 {
+		fmt.Fprintf(b, "**Listens on**:\n\n")
+		fmt.Fprintf(b, "| Port/Proto | Name | Exposure | Evidence |\n")
+		fmt.Fprintf(b, "|---|---|---|---|\n")
+		for _, l := range listeners {
+			name, exposure := l.Name, string(l.Exposure)
+			if name == "" {
+				name = "-"
+			}
+			if exposure == "" {
+				exposure = "-"
+			}
+			evidence := formatAuditEvidence(model.NetworkDependency{SourceFile: l.SourceFile, Line: l.Line, EvidenceLine: l.EvidenceLine, Provenance: l.Provenance})
+			fmt.Fprintf(b, "| `%d/%s` | %s | %s | %s |\n", l.Port, l.Protocol, name, exposure, evidence)
+		}
+		fmt.Fprintf(b, "\n")
+	}
 
 	// --- Egress table -----------------------------------------------------
 	fmt.Fprintf(b, "**Egress** (this workload connects out to):\n\n")
@@ -109,10 +124,10 @@ func writeWorkloadSection(b *strings.Builder, ds *model.DependencySet, workload
 
 	// --- Ingress table ----------------------------------------------------
 	fmt.Fprintf(b, "**Ingress** (other workloads connecting in):\n\n")
-	if len(ingressExt) == 0 {
+	if len(ingress) == 0 {
 		fmt.Fprintf(b, "_No external ingress declared._\n\n")
 	} else {
-		writeAuditTable(b, ingressExt, "Source")
+		writeAuditTable(b, ingress, "Source")
 	}
 }
 
@@ -137,8 +152,8 @@ func writeAuditTable(b *strings.Builder, deps []model.NetworkDependency, peerLab
 			conf = conf + " (approve)"
 		}
 		evidence := formatAuditEvidence(d)
-		fmt.Fprintf(b, "| `%s` | `%d/%s` | %s | %s |\n",
-			peer, d.Port, d.Protocol, conf, evidence)
+		fmt.Fprintf(b, "| `%s` | `%s/%s` | %s | %s |\n",
+			peer, d.PortRange(), d.Protocol, conf, evidence)
 	}
 	fmt.Fprintf(b, "\n")
 }
@@ -151,7 +166,7 @@ func formatAuditEvidence(d model.NetworkDependency) string {
 		return "_no direct config line_"
 	}
 	if d.EvidenceLine == "" {
-		return fmt.Sprintf("`%s`", d.SourceFile)
+		return fmt.Sprintf("`%s`", d.Location())
 	}
 	line := model.RedactSecrets(d.EvidenceLine)
 	line = strings.ReplaceAll(line, "|", `\|`)
@@ -160,7 +175,23 @@ func formatAuditEvidence(d model.NetworkDependency) string {
 	if len(line) > maxLen {
 		line = line[:maxLen-3] + "..."
 	}
-	return fmt.Sprintf("`%s` &mdash; `%s`", d.SourceFile, line)
+	cell := fmt.Sprintf("`%s` &mdash; `%s`", d.Location(), line)
+	// Further declarations of the same edge are cited by anchor only so
+	// the table stays one row per dependency.
+	var also []string
+	for _, p := range d.Provenance {
+		if p.File == d.SourceFile && p.Line == d.Line && p.Evidence == d.EvidenceLine {
+			continue
+		}
+		also = append(also, fmt.Sprintf("`%s`", p.Location()))
+	}
+	if len(also) > 0 {
+		cell += " (also: " + strings.Join(also, ", ") + ")"
+	}
+	if len(d.Aliases) > 0 {
+		cell += " (aka `" + strings.Join(d.Aliases, "`, `") + "`)"
+	}
+	return cell
 }
 
 // workloadStatus returns a one-line state for the workload section, used to
@@ -198,8 +229,8 @@ func workloadStatus(ingress, egress []model.NetworkDependency) string {
 }
 
 // uniqueWorkloads returns the deterministic union of every Source and Target
-// across the dependency set.
-func uniqueWorkloads(deps []model.NetworkDependency) []string {
+// across the dependency set, and every workload with a listener.
+func uniqueWorkloads(deps []model.NetworkDependency, listeners []model.Listener) []string {
 	seen := make(map[string]bool, len(deps)*2)
 	for _, d := range deps {
 		if d.Source != "" {
@@ -209,6 +240,11 @@ func uniqueWorkloads(deps []model.NetworkDependency) []string {
 			seen[d.Target] = true
 		}
 	}
+	for _, l := range listeners {
+		if l.Workload != "" {
+			seen[l.Workload] = true
+		}
+	}
 	out := make([]string, 0, len(seen))
 	for w := range seen {
 		out = append(out, w)
@@ -243,8 +279,8 @@ func countMissingEvidence(deps []model.NetworkDependency) int {
 	return n
 }
 
-// auditFingerprint is a short stable hash over the dependency keys of the
-// set. It lets reviewers compare two audit ledger documents at a glance
+// auditFingerprint is a short stable hash over the dependency and listener
+// keys of the set. It lets reviewers compare two audit ledger documents at a glance
 // without diffing the full body. It excludes timestamps so identical inputs
 // produce identical fingerprints.
 func auditFingerprint(ds *model.DependencySet) string {
@@ -254,6 +290,9 @@ func auditFingerprint(ds *model.DependencySet) string {
 	for _, d := range deps {
 		fmt.Fprintf(h, "%s|%s|%s\n", d.Key(), d.Confidence, d.SourceFile)
 	}
+	for _, l := range ds.Listeners() {
+		fmt.Fprintf(h, "listener %s|%s|%s\n", l.Key(), l.Confidence, l.SourceFile)
+	}
 	sum := h.Sum(nil)
 	return hex.EncodeToString(sum[:6])
 }
@@ -188,3 +188,16 @@ func TestAuditNoEvidenceCellHandled(t *testing.T) {
 		t.Errorf("expected checklist line for missing-evidence count:\n%s", out)
 	}
 }
+
+func TestAuditEvidenceCarriesLine(t *testing.T) {
+	ds := model.NewDependencySet("svc")
+	ds.Add(model.NetworkDependency{
+		Source: "svc", Target: "db", Port: 5432, Protocol: "TCP",
+		Confidence: model.High, SourceFile: "application.yml", Line: 7,
+		EvidenceLine: "spring.datasource.url: jdbc:postgresql://db:5432/app",
+	})
+	out := Audit(ds)
+	if !strings.Contains(out, "`application.yml:7`") {
+		t.Errorf("audit evidence should anchor to file:line, got:\n%s", out)
+	}
+}
@@ -2,7 +2,6 @@ package renderer
 
 import (
 	"fmt"
-	"net"
 	"sort"
 	"strings"
 
@@ -18,7 +17,7 @@ import (
 //     concrete DNS names, `matchPattern` for wildcards. In-cluster service
 //     names route through `egress[].toEndpoints[]` instead, mirroring the
 //     vanilla podSelector shape.
-//  3. IP-shape destinations use `egress[].toCIDR[]`.
+//  3. IP and CIDR destinations use `egress[].toCIDR[]`.
 //
 // Self-consistency contract: when ANY toFQDNs entry is emitted, this
 // renderer also emits a paired DNS egress to kube-system port 53 with a
@@ -28,7 +27,8 @@ import (
 // own linter.
 //
 // Per-dep Disabled semantics inherit from the vanilla path: deps with
-// Disabled="egress" or Disabled="full" are skipped entirely.
+// Disabled="egress" or Disabled="full" are skipped entirely, as are
+// build-time deps.
 //
 // Free-tier feature. Out of scope for v0.6.0: L7 rules, toServices,
 // per-service-fan-out (one CNP per workload).
@@ -42,19 +42,22 @@ func Cilium(ds *model.DependencySet) string {
 	// disabled-egress deps. Sorting inside each bucket keeps the output
 	// deterministic regardless of input order.
 	type endpointDest struct {
-		name string // simple service name → app label
-		port int
-		prot string
+		name    string // simple service name → app label
+		port    int
+		endPort int
+		prot    string
 	}
 	type fqdnDest struct {
-		host string
-		port int
-		prot string
+		host    string
+		port    int
+		endPort int
+		prot    string
 	}
 	type cidrDest struct {
-		cidr string
-		port int
-		prot string
+		cidr    string
+		port    int
+		endPort int
+		prot    string
 	}
 
 	var (
@@ -66,29 +69,26 @@ func Cilium(ds *model.DependencySet) string {
 
 	dedup := make(map[string]bool)
 	for _, dep := range deps {
-		if dep.Disabled == "egress" || dep.Disabled == "full" {
+		if dep.Disabled == "egress" || dep.Disabled == "full" || dep.BuildTime {
 			continue
 		}
 		if dep.Port <= 0 {
 			skipped = append(skipped, dep.Target)
 			continue
 		}
-		key := fmt.Sprintf("%s|%d|%s", dep.Target, dep.Port, dep.Protocol)
+		prot := policyProtocol(dep.Protocol)
+		key := fmt.Sprintf("%s|%s|%s", dep.Target, dep.PortRange(), prot)
 		if dedup[key] {
 			continue
 		}
 		dedup[key] = true
 
-		prot := strings.ToUpper(dep.Protocol)
-		if prot == "" {
-			prot = "TCP"
-		}
-
 		switch ciliumShapeOf(dep.Target) {
 		case shapeCIDR:
-			cidrs = append(cidrs, cidrDest{cidr: dep.Target + "/32", port: dep.Port, prot: prot})
+			cidr, _ := policyCIDR(dep.Target)
+			cidrs = append(cidrs, cidrDest{cidr: cidr, port: dep.Port, endPort: dep.EndPort, prot: prot})
 		case shapeFQDN:
-			fqdns = append(fqdns, fqdnDest{host: dep.Target, port: dep.Port, prot: prot})
+			fqdns = append(fqdns, fqdnDest{host: dep.Target, port: dep.Port, endPort: dep.EndPort, prot: prot})
 		default: // shapeEndpoint
 			// In-cluster FQDNs like "postgres.production.svc.cluster.local"
 			// keep just the leading service name as the app label, mirroring
@@ -97,7 +97,7 @@ func Cilium(ds *model.DependencySet) string {
 			if i := strings.Index(name, "."); i >= 0 {
 				name = name[:i]
 			}
-			endpoints = append(endpoints, endpointDest{name: name, port: dep.Port, prot: prot})
+			endpoints = append(endpoints, endpointDest{name: name, port: dep.Port, endPort: dep.EndPort, prot: prot})
 		}
 	}
 
@@ -153,7 +153,7 @@ func Cilium(ds *model.DependencySet) string {
 			b.WriteString("    - toEndpoints:\n")
 			b.WriteString("        - matchLabels:\n")
 			fmt.Fprintf(&b, "            app: %s\n", e.name)
-			writeCiliumPort(&b, e.port, e.prot)
+			writeCiliumPort(&b, e.port, e.endPort, e.prot)
 		}
 		for _, f := range fqdns {
 			b.WriteString("    - toFQDNs:\n")
@@ -162,12 +162,12 @@ func Cilium(ds *model.DependencySet) string {
 			} else {
 				fmt.Fprintf(&b, "        - matchName: %q\n", f.host)
 			}
-			writeCiliumPort(&b, f.port, f.prot)
+			writeCiliumPort(&b, f.port, f.endPort, f.prot)
 		}
 		for _, c := range cidrs {
 			b.WriteString("    - toCIDR:\n")
 			fmt.Fprintf(&b, "        - %s\n", c.cidr)
-			writeCiliumPort(&b, c.port, c.prot)
+			writeCiliumPort(&b, c.port, c.endPort, c.prot)
 		}
 
 		// Paired DNS egress: emitted once whenever any toFQDNs rule is
@@ -201,10 +201,13 @@ func Cilium(ds *model.DependencySet) string {
 // writeCiliumPort emits the toPorts block shared by every egress rule.
 // Ports are stringified to keep YAML stable and tolerate non-numeric
 // future port-name extensions; the validator parses both forms.
-func writeCiliumPort(b *strings.Builder, port int, prot string) {
+func writeCiliumPort(b *strings.Builder, port, endPort int, prot string) {
 	b.WriteString("      toPorts:\n")
 	b.WriteString("        - ports:\n")
 	fmt.Fprintf(b, "            - port: %q\n", fmt.Sprintf("%d", port))
+	if endPort > port {
+		fmt.Fprintf(b, "              endPort: %d\n", endPort)
+	}
 	fmt.Fprintf(b, "              protocol: %s\n", prot)
 }
 
@@ -215,7 +218,7 @@ type ciliumShape int
 const (
 	shapeEndpoint ciliumShape = iota // in-cluster service / simple name
 	shapeFQDN                        // external hostname (api.stripe.com, *.amazonaws.com)
-	shapeCIDR                        // literal IP
+	shapeCIDR                        // literal IP or CIDR block
 )
 
 // ciliumShapeOf chooses the destination shape using the same heuristic
@@ -227,7 +230,7 @@ func ciliumShapeOf(target string) ciliumShape {
 	if target == "" {
 		return shapeEndpoint
 	}
-	if ip := net.ParseIP(target); ip != nil {
+	if _, ok := policyCIDR(target); ok {
 		return shapeCIDR
 	}
 	if !strings.Contains(target, ".") {
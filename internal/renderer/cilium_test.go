@@ -184,3 +184,21 @@ func TestCilium_DisabledEgressSkipsRules(t *testing.T) {
 		t.Errorf("Disabled=egress dep must not emit port rule:\n%s", out)
 	}
 }
+
+func TestCilium_Protocols(t *testing.T) {
+	ds := model.NewDependencySet("gateway")
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "statsd", Port: 8125, Protocol: "UDP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "amf", Port: 38412, Protocol: "SCTP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "api", Port: 80, Protocol: ""})
+
+	out := Cilium(ds)
+	for _, want := range []string{
+		"port: \"8125\"\n              protocol: UDP",
+		"port: \"38412\"\n              protocol: SCTP",
+		"port: \"80\"\n              protocol: TCP",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+}
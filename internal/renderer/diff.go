@@ -7,9 +7,10 @@ import (
 	"github.com/dormstern/segspec/internal/model"
 )
 
-// Diff renders a human-readable diff report showing added, removed, and unchanged dependencies.
+// Diff renders a human-readable diff report showing added, removed, and unchanged dependencies,
+// and the listeners that were added or removed.
 func Diff(d model.DependencyDiff) string {
-	if len(d.Added) == 0 && len(d.Removed) == 0 {
+	if !d.HasChanges() {
 		return "No changes detected.\n"
 	}
 
@@ -25,10 +26,17 @@ func Diff(d model.DependencyDiff) string {
 			if source == "" {
 				source = "unknown"
 			}
-			fmt.Fprintf(&b, "  + %s -> %s:%d/%s [%s]\n", source, dep.Target, dep.Port, dep.Protocol, dep.Confidence)
+			fmt.Fprintf(&b, "  + %s -> %s:%s/%s [%s]\n", source, dep.Target, dep.PortRange(), dep.Protocol, dep.Confidence)
+			if len(dep.Provenance) > 1 {
+				writeDiffProvenance(&b, dep.Provenance)
+				continue
+			}
 			if dep.EvidenceLine != "" {
 				fmt.Fprintf(&b, "    Evidence: %s\n", model.RedactSecrets(dep.EvidenceLine))
 			}
+			if dep.SourceFile != "" {
+				fmt.Fprintf(&b, "    At: %s\n", dep.Location())
+			}
 		}
 		fmt.Fprintln(&b)
 	}
@@ -40,9 +48,42 @@ func Diff(d model.DependencyDiff) string {
 			if source == "" {
 				source = "unknown"
 			}
-			fmt.Fprintf(&b, "  - %s -> %s:%d/%s [%s]\n", source, dep.Target, dep.Port, dep.Protocol, dep.Confidence)
-			if dep.SourceFile != "" {
-				fmt.Fprintf(&b, "    Was in: %s\n", dep.SourceFile)
+			fmt.Fprintf(&b, "  - %s -> %s:%s/%s [%s]\n", source, dep.Target, dep.PortRange(), dep.Protocol, dep.Confidence)
+			if len(dep.Provenance) > 1 {
+				writeDiffProvenance(&b, dep.Provenance)
+			} else if dep.SourceFile != "" {
+				fmt.Fprintf(&b, "    Was in: %s\n", dep.Location())
+			}
+		}
+		fmt.Fprintln(&b)
+	}
+
+	if len(d.AddedListeners) > 0 {
+		fmt.Fprintf(&b, "ADDED LISTENERS (%d):\n", len(d.AddedListeners))
+		for _, l := range d.AddedListeners {
+			fmt.Fprintf(&b, "  + %s listens on %s\n", listenerWorkload(l), listenerPort(l))
+			if len(l.Provenance) > 1 {
+				writeDiffProvenance(&b, l.Provenance)
+				continue
+			}
+			if l.EvidenceLine != "" {
+				fmt.Fprintf(&b, "    Evidence: %s\n", model.RedactSecrets(l.EvidenceLine))
+			}
+			if l.SourceFile != "" {
+				fmt.Fprintf(&b, "    At: %s\n", l.Location())
+			}
+		}
+		fmt.Fprintln(&b)
+	}
+
+	if len(d.RemovedListeners) > 0 {
+		fmt.Fprintf(&b, "REMOVED LISTENERS (%d):\n", len(d.RemovedListeners))
+		for _, l := range d.RemovedListeners {
+			fmt.Fprintf(&b, "  - %s listens on %s\n", listenerWorkload(l), listenerPort(l))
+			if len(l.Provenance) > 1 {
+				writeDiffProvenance(&b, l.Provenance)
+			} else if l.SourceFile != "" {
+				fmt.Fprintf(&b, "    Was in: %s\n", l.Location())
 			}
 		}
 		fmt.Fprintln(&b)
@@ -52,3 +93,16 @@ func Diff(d model.DependencyDiff) string {
 
 	return b.String()
 }
+
+// writeDiffProvenance lists every place a merged dependency or listener is
+// declared, with the evidence found there.
+func writeDiffProvenance(b *strings.Builder, records []model.Provenance) {
+	fmt.Fprintf(b, "    Declared in %d places:\n", len(records))
+	for _, p := range records {
+		fmt.Fprintf(b, "      - %s", p.Location())
+		if p.Evidence != "" {
+			fmt.Fprintf(b, ": %s", model.RedactSecrets(p.Evidence))
+		}
+		fmt.Fprintln(b)
+	}
+}
@@ -94,3 +94,70 @@ func TestDiffRenderEvidence(t *testing.T) {
 		t.Errorf("missing evidence line, got:\n%s", out)
 	}
 }
+
+func TestDiffRenderLocation(t *testing.T) {
+	d := model.DependencyDiff{
+		Added: []model.NetworkDependency{
+			{Source: "web", Target: "redis", Port: 6379, Protocol: "TCP", Confidence: model.Medium,
+				SourceFile: ".env", Line: 3, EvidenceLine: "REDIS_URL=redis://redis:6379"},
+		},
+		Removed: []model.NetworkDependency{
+			{Source: "web", Target: "memcached", Port: 11211, Protocol: "TCP", Confidence: model.High,
+				SourceFile: "docker-compose.yml", Line: 14},
+		},
+	}
+	out := Diff(d)
+	if !strings.Contains(out, "At: .env:3") {
+		t.Errorf("missing added location, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Was in: docker-compose.yml:14") {
+		t.Errorf("missing removed location, got:\n%s", out)
+	}
+}
+
+func TestDiffRenderListeners(t *testing.T) {
+	d := model.DependencyDiff{
+		AddedListeners: []model.Listener{
+			{Workload: "api", Port: 443, Protocol: "TCP", Name: "https", Exposure: model.LoadBalancer, SourceFile: "svc.yaml", Line: 9},
+		},
+		RemovedListeners: []model.Listener{
+			{Workload: "api", Port: 8080, Protocol: "TCP"},
+		},
+	}
+	out := Diff(d)
+	if !strings.Contains(out, "ADDED LISTENERS (1):\n  + api listens on 443/TCP (https, LoadBalancer)\n    At: svc.yaml:9\n") {
+		t.Errorf("missing added listener, got:\n%s", out)
+	}
+	if !strings.Contains(out, "REMOVED LISTENERS (1):\n  - api listens on 8080/TCP\n") {
+		t.Errorf("missing removed listener, got:\n%s", out)
+	}
+}
+
+func TestDiffRenderProvenance(t *testing.T) {
+	records := []model.Provenance{
+		{File: "docker-compose.yml", Line: 12, Evidence: "REDIS_URL=redis://cache:6379"},
+		{File: "k8s/api.yaml", Line: 30, Evidence: "value: redis://cache:6379"},
+	}
+	d := model.DependencyDiff{
+		Added: []model.NetworkDependency{
+			{Source: "api", Target: "cache", Port: 6379, Protocol: "TCP", Confidence: model.High,
+				SourceFile: "docker-compose.yml", Line: 12, EvidenceLine: "REDIS_URL=redis://cache:6379", Provenance: records},
+		},
+		Removed: []model.NetworkDependency{
+			{Source: "api", Target: "db", Port: 5432, Protocol: "TCP", Confidence: model.High,
+				SourceFile: ".env", Line: 2, Provenance: []model.Provenance{
+					{File: ".env", Line: 2, Evidence: "DB_HOST=db"},
+					{File: "config/database.yml", Line: 5, Evidence: "host: db"},
+				}},
+		},
+	}
+	out := Diff(d)
+	for _, want := range []string{
+		"  + api -> cache:6379/TCP [high]\n    Declared in 2 places:\n      - docker-compose.yml:12: REDIS_URL=redis://cache:6379\n      - k8s/api.yaml:30: value: redis://cache:6379\n",
+		"  - api -> db:5432/TCP [high]\n    Declared in 2 places:\n      - .env:2: DB_HOST=db\n      - config/database.yml:5: host: db\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -11,7 +11,8 @@ import (
 // Evidence renders a Markdown evidence report explaining why each dependency exists.
 func Evidence(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
-	if len(deps) == 0 {
+	listeners := ds.Listeners()
+	if len(deps) == 0 && len(listeners) == 0 {
 		return "No dependencies found.\n"
 	}
 
@@ -46,16 +47,52 @@ func Evidence(ds *model.DependencySet) string {
 			marker = " \u26a0"
 		}
 
-		fmt.Fprintf(&b, "### %s \u2192 %s:%d/%s [%s]%s\n", source, dep.Target, dep.Port, dep.Protocol, confLabel, marker)
+		fmt.Fprintf(&b, "### %s \u2192 %s:%s/%s [%s]%s\n", source, dep.Target, dep.PortRange(), dep.Protocol, confLabel, marker)
 		fmt.Fprintf(&b, "Justification: %s\n", dep.Description)
-		fmt.Fprintf(&b, "Source: %s\n", dep.SourceFile)
+		fmt.Fprintf(&b, "Source: %s\n", dep.Location())
+		if dep.Environment != "" {
+			fmt.Fprintf(&b, "Environment: %s\n", dep.Environment)
+		}
+		if len(dep.Aliases) > 0 {
+			fmt.Fprintf(&b, "Also known as: `%s`\n", strings.Join(dep.Aliases, "`, `"))
+		}
 		if dep.EvidenceLine != "" {
 			fmt.Fprintf(&b, "Evidence: `%s`\n", model.RedactSecrets(dep.EvidenceLine))
 		} else {
 			fmt.Fprintf(&b, "Evidence: (no direct config line)\n")
 		}
+		if len(dep.Provenance) > 1 {
+			fmt.Fprintf(&b, "Declared in %d places:\n", len(dep.Provenance))
+			for _, p := range dep.Provenance {
+				fmt.Fprintf(&b, "- %s", p.Location())
+				if p.Evidence != "" {
+					fmt.Fprintf(&b, " `%s`", model.RedactSecrets(p.Evidence))
+				}
+				if p.Environment != "" {
+					fmt.Fprintf(&b, " [%s]", p.Environment)
+				}
+				if p.Parser != "" {
+					fmt.Fprintf(&b, " (%s, %s)\n", p.Parser, p.Confidence)
+				} else {
+					fmt.Fprintf(&b, " (%s)\n", p.Confidence)
+				}
+			}
+		}
 		fmt.Fprintf(&b, "\n")
 	}
 
+	if len(listeners) > 0 {
+		fmt.Fprintf(&b, "## Listeners\n\n")
+		for _, l := range listeners {
+			fmt.Fprintf(&b, "### %s listens on %s [%s]\n", listenerWorkload(l), listenerPort(l), strings.ToUpper(string(l.Confidence)))
+			fmt.Fprintf(&b, "Justification: %s\n", l.Description)
+			fmt.Fprintf(&b, "Source: %s\n", l.Location())
+			if l.EvidenceLine != "" {
+				fmt.Fprintf(&b, "Evidence: `%s`\n", model.RedactSecrets(l.EvidenceLine))
+			}
+			fmt.Fprintf(&b, "\n")
+		}
+	}
+
 	return b.String()
 }
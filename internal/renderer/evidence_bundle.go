@@ -41,6 +41,7 @@ type evidenceBundle struct {
 	InputTreeSHA256 string                 `json:"input_tree_sha256"`
 	GeneratedUTC    string                 `json:"generated_utc"`
 	Dependencies    []evidenceBundleDep    `json:"dependencies"`
+	Listeners       []evidenceBundleListener `json:"listeners,omitempty"`
 	Summary         evidenceBundleSummary  `json:"summary"`
 }
 
@@ -51,12 +52,32 @@ type evidenceBundleDep struct {
 	Protocol   string                  `json:"protocol"`
 	Confidence string                  `json:"confidence"`
 	Evidence   evidenceBundleEvidence  `json:"evidence"`
+	// Aliases are the other spellings of Target the configs used.
+	Aliases []string `json:"aliases,omitempty"`
+	// Provenance lists every declaration of this edge, strongest first.
+	// Evidence above is always the first entry.
+	Provenance []evidenceBundleEvidence `json:"provenance,omitempty"`
+}
+
+// evidenceBundleListener is a port a workload listens on, with the
+// declaration that says so.
+type evidenceBundleListener struct {
+	Workload   string                 `json:"workload"`
+	Port       int                    `json:"port"`
+	Protocol   string                 `json:"protocol"`
+	Name       string                 `json:"name,omitempty"`
+	Exposure   string                 `json:"exposure,omitempty"`
+	Confidence string                 `json:"confidence"`
+	Evidence   evidenceBundleEvidence `json:"evidence"`
 }
 
 type evidenceBundleEvidence struct {
 	File        string `json:"file"`
 	Line        int    `json:"line"`
+	Column      int    `json:"column,omitempty"`
 	Declaration string `json:"declaration"`
+	Parser      string `json:"parser,omitempty"`
+	Confidence  string `json:"confidence,omitempty"`
 }
 
 type evidenceBundleSummary struct {
@@ -104,6 +125,13 @@ func EvidenceBundleSARIF(ds *model.DependencySet, segspecVersion string, inputFi
 			// placeholder so consumers don't choke on nil locations.
 			artifactURI = "unknown"
 		}
+		region := map[string]any{
+			"startLine": d.Evidence.Line,
+			"snippet":   map[string]any{"text": d.Evidence.Declaration},
+		}
+		if d.Evidence.Column > 0 {
+			region["startColumn"] = d.Evidence.Column
+		}
 		result := map[string]any{
 			"ruleId":  "segspec.network-dependency",
 			"level":   "note",
@@ -112,14 +140,38 @@ func EvidenceBundleSARIF(ds *model.DependencySet, segspecVersion string, inputFi
 				{
 					"physicalLocation": map[string]any{
 						"artifactLocation": map[string]any{"uri": artifactURI},
-						"region": map[string]any{
-							"startLine": d.Evidence.Line,
-							"snippet":   map[string]any{"text": d.Evidence.Declaration},
-						},
+						"region":           region,
 					},
 				},
 			},
 		}
+		// Additional declarations of the same edge become relatedLocations
+		// so code-scanning UIs can link to each one.
+		if len(d.Provenance) > 1 {
+			related := make([]map[string]any, 0, len(d.Provenance)-1)
+			for i, p := range d.Provenance[1:] {
+				uri := p.File
+				if uri == "" {
+					uri = "unknown"
+				}
+				r := map[string]any{
+					"startLine": p.Line,
+					"snippet":   map[string]any{"text": p.Declaration},
+				}
+				if p.Column > 0 {
+					r["startColumn"] = p.Column
+				}
+				related = append(related, map[string]any{
+					"id": i + 1,
+					"physicalLocation": map[string]any{
+						"artifactLocation": map[string]any{"uri": uri},
+						"region":           r,
+					},
+					"message": map[string]any{"text": fmt.Sprintf("also declared (%s, confidence: %s)", p.Parser, p.Confidence)},
+				})
+			}
+			result["relatedLocations"] = related
+		}
 		results = append(results, result)
 	}
 
@@ -197,15 +249,33 @@ func buildBundle(ds *model.DependencySet, segspecVersion string, inputFiles []Ev
 		case model.Low:
 			low++
 		}
-		// Line numbers are not tracked in the model today; once
-		// parser-line-numbers lands as its own feature we'll plumb the real
-		// integer through. Until then we synthesize a non-zero placeholder
-		// (1) whenever the dependency has a SourceFile so SARIF tooling that
-		// requires a positive startLine doesn't reject the document.
-		line := 0
-		if d.SourceFile != "" {
+		// Parsers record the real line of the declaring config entry. Deps
+		// that can't be pinned to one line (Helm-rendered output, AI
+		// discoveries) fall back to a non-zero placeholder (1) whenever
+		// they have a SourceFile, so SARIF tooling that requires a positive
+		// startLine doesn't reject the document.
+		line := d.Line
+		if line == 0 && d.SourceFile != "" {
 			line = 1
 		}
+		var prov []evidenceBundleEvidence
+		if len(d.Provenance) > 1 {
+			prov = make([]evidenceBundleEvidence, 0, len(d.Provenance))
+			for _, p := range d.Provenance {
+				pline := p.Line
+				if pline == 0 && p.File != "" {
+					pline = 1
+				}
+				prov = append(prov, evidenceBundleEvidence{
+					File:        p.File,
+					Line:        pline,
+					Column:      p.Column,
+					Declaration: model.RedactSecrets(p.Evidence),
+					Parser:      p.Parser,
+					Confidence:  string(p.Confidence),
+				})
+			}
+		}
 		bundleDeps = append(bundleDeps, evidenceBundleDep{
 			Source:     d.Source,
 			Target:     d.Target,
@@ -215,7 +285,34 @@ func buildBundle(ds *model.DependencySet, segspecVersion string, inputFiles []Ev
 			Evidence: evidenceBundleEvidence{
 				File:        d.SourceFile,
 				Line:        line,
+				Column:      d.Column,
 				Declaration: model.RedactSecrets(d.EvidenceLine),
+				Parser:      d.Parser,
+			},
+			Aliases:    d.Aliases,
+			Provenance: prov,
+		})
+	}
+
+	var bundleListeners []evidenceBundleListener
+	for _, l := range ds.Listeners() {
+		line := l.Line
+		if line == 0 && l.SourceFile != "" {
+			line = 1
+		}
+		bundleListeners = append(bundleListeners, evidenceBundleListener{
+			Workload:   l.Workload,
+			Port:       l.Port,
+			Protocol:   l.Protocol,
+			Name:       l.Name,
+			Exposure:   string(l.Exposure),
+			Confidence: string(l.Confidence),
+			Evidence: evidenceBundleEvidence{
+				File:        l.SourceFile,
+				Line:        line,
+				Column:      l.Column,
+				Declaration: model.RedactSecrets(l.EvidenceLine),
+				Parser:      l.Parser,
 			},
 		})
 	}
@@ -230,6 +327,7 @@ func buildBundle(ds *model.DependencySet, segspecVersion string, inputFiles []Ev
 		InputTreeSHA256: hashInputTree(inputFiles),
 		GeneratedUTC:    time.Now().UTC().Format(time.RFC3339),
 		Dependencies:    bundleDeps,
+		Listeners:       bundleListeners,
 		Summary: evidenceBundleSummary{
 			Total:  len(deps),
 			High:   high,
@@ -285,6 +285,83 @@ func TestEvidenceBundleSARIFResults(t *testing.T) {
 	}
 }
 
+// TestEvidenceBundleSARIFUsesRealLine verifies that a dependency carrying a
+// parser-recorded line/column surfaces it as the SARIF region instead of
+// the placeholder line 1.
+func TestEvidenceBundleSARIFUsesRealLine(t *testing.T) {
+	ds := model.NewDependencySet("orders")
+	ds.Add(model.NetworkDependency{
+		Source: "orders", Target: "postgres", Port: 5432, Protocol: "TCP",
+		Confidence:   model.High,
+		SourceFile:   "application.yml",
+		Line:         12,
+		Column:       5,
+		EvidenceLine: "spring.datasource.url: jdbc:postgresql://postgres:5432/db",
+	})
+	out := EvidenceBundleSARIF(ds, "0.6.0-dev", nil)
+
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(out), &obj); err != nil {
+		t.Fatalf("not valid JSON: %v\n%s", err, out)
+	}
+	run := obj["runs"].([]any)[0].(map[string]any)
+	result := run["results"].([]any)[0].(map[string]any)
+	loc := result["locations"].([]any)[0].(map[string]any)
+	region := loc["physicalLocation"].(map[string]any)["region"].(map[string]any)
+	if ln, _ := region["startLine"].(float64); ln != 12 {
+		t.Errorf("startLine = %v, want 12", region["startLine"])
+	}
+	if col, _ := region["startColumn"].(float64); col != 5 {
+		t.Errorf("startColumn = %v, want 5", region["startColumn"])
+	}
+
+	bundle := EvidenceBundleJSON(ds, "0.6.0-dev", nil)
+	if !strings.Contains(bundle, `"line": 12`) {
+		t.Errorf("bundle evidence should carry line 12:\n%s", bundle)
+	}
+}
+
+func TestEvidenceBundleListsEveryProvenance(t *testing.T) {
+	ds := model.NewDependencySet("orders")
+	ds.Add(model.NetworkDependency{
+		Source: "orders", Target: "postgres", Port: 5432, Protocol: "TCP",
+		Confidence: model.Medium, SourceFile: ".env", Line: 2,
+		EvidenceLine: "DATABASE_URL=postgresql://postgres:5432/db", Parser: "envfile",
+	})
+	ds.Add(model.NetworkDependency{
+		Source: "orders", Target: "postgres", Port: 5432, Protocol: "TCP",
+		Confidence: model.High, SourceFile: "application.yml", Line: 12,
+		EvidenceLine: "spring.datasource.url: jdbc:postgresql://postgres:5432/db", Parser: "spring",
+	})
+
+	bundle := EvidenceBundleJSON(ds, "0.6.0-dev", nil)
+	for _, want := range []string{`"provenance"`, `"file": ".env"`, `"parser": "envfile"`} {
+		if !strings.Contains(bundle, want) {
+			t.Errorf("bundle missing %s:\n%s", want, bundle)
+		}
+	}
+
+	out := EvidenceBundleSARIF(ds, "0.6.0-dev", nil)
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(out), &obj); err != nil {
+		t.Fatalf("not valid JSON: %v\n%s", err, out)
+	}
+	result := obj["runs"].([]any)[0].(map[string]any)["results"].([]any)[0].(map[string]any)
+	related, _ := result["relatedLocations"].([]any)
+	if len(related) != 1 {
+		t.Fatalf("relatedLocations = %v, want the .env declaration", result["relatedLocations"])
+	}
+	uri := related[0].(map[string]any)["physicalLocation"].(map[string]any)["artifactLocation"].(map[string]any)["uri"]
+	if uri != ".env" {
+		t.Errorf("related uri = %v, want .env", uri)
+	}
+
+	md := Evidence(ds)
+	if !strings.Contains(md, "Declared in 2 places") || !strings.Contains(md, "- .env:2") {
+		t.Errorf("markdown evidence should cite both sources:\n%s", md)
+	}
+}
+
 // stripGeneratedUTC removes the generated_utc line from a JSON evidence
 // bundle, so determinism tests can compare bodies without false negatives
 // from timestamp drift.
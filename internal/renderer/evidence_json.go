@@ -16,6 +16,7 @@ type evidenceReport struct {
 	ParserVersions map[string]string         `json:"parser_versions"`
 	Summary        evidenceSummary           `json:"summary"`
 	Dependencies   []model.NetworkDependency `json:"dependencies"`
+	Listeners      []model.Listener          `json:"listeners,omitempty"`
 }
 
 type evidenceSummary struct {
@@ -28,7 +29,8 @@ type evidenceSummary struct {
 // EvidenceJSON renders a JSON evidence report.
 func EvidenceJSON(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
-	if len(deps) == 0 {
+	listeners := ds.Listeners()
+	if len(deps) == 0 && len(listeners) == 0 {
 		// Even with zero deps we stamp parser_versions so downstream
 		// tooling (baselines, evidence bundles) can verify which parser
 		// versions ran and confirm the empty result is reproducible.
@@ -48,6 +50,13 @@ func EvidenceJSON(ds *model.DependencySet) string {
 	copy(redacted, deps)
 	for i := range redacted {
 		redacted[i].EvidenceLine = model.RedactSecrets(redacted[i].EvidenceLine)
+		redacted[i].Provenance = redactProvenance(redacted[i].Provenance)
+	}
+	redactedListeners := make([]model.Listener, len(listeners))
+	copy(redactedListeners, listeners)
+	for i := range redactedListeners {
+		redactedListeners[i].EvidenceLine = model.RedactSecrets(redactedListeners[i].EvidenceLine)
+		redactedListeners[i].Provenance = redactProvenance(redactedListeners[i].Provenance)
 	}
 
 	var highCount, medCount, lowCount int
@@ -74,6 +83,7 @@ func EvidenceJSON(ds *model.DependencySet) string {
 			Low:    lowCount,
 		},
 		Dependencies: redacted,
+		Listeners:    redactedListeners,
 	}
 
 	data, err := json.MarshalIndent(report, "", "  ")
@@ -82,3 +92,17 @@ func EvidenceJSON(ds *model.DependencySet) string {
 	}
 	return string(data) + "\n"
 }
+
+// redactProvenance returns a copy of records with secrets redacted from
+// their evidence.
+func redactProvenance(records []model.Provenance) []model.Provenance {
+	if len(records) == 0 {
+		return records
+	}
+	prov := make([]model.Provenance, len(records))
+	copy(prov, records)
+	for j := range prov {
+		prov[j].Evidence = model.RedactSecrets(prov[j].Evidence)
+	}
+	return prov
+}
@@ -17,7 +17,9 @@ import (
 // Deps whose Disabled field is "egress" or "full" are skipped entirely —
 // the whole purpose of the source-level directive is to make the rule
 // disappear (k8s #112560). The summary/audit/evidence-bundle formats still
-// surface the directive so the suppression remains auditable.
+// surface the directive so the suppression remains auditable. Build-time
+// deps (package registries) are made by the build, not the pod, and are
+// skipped too.
 func NetworkPolicy(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
 	if len(deps) == 0 {
@@ -28,23 +30,25 @@ func NetworkPolicy(ds *model.DependencySet) string {
 	type egressRule struct {
 		target   string
 		port     int
+		endPort  int
 		protocol string
 	}
 	rules := make([]egressRule, 0, len(deps))
 	seen := make(map[string]bool)
 	var skipped []string
 	for _, dep := range deps {
-		if dep.Disabled == "egress" || dep.Disabled == "full" {
+		if dep.Disabled == "egress" || dep.Disabled == "full" || dep.BuildTime {
 			continue
 		}
 		if dep.Port <= 0 {
 			skipped = append(skipped, dep.Target)
 			continue
 		}
-		key := fmt.Sprintf("%s:%d:%s", dep.Target, dep.Port, dep.Protocol)
+		proto := policyProtocol(dep.Protocol)
+		key := fmt.Sprintf("%s:%s:%s", dep.Target, dep.PortRange(), proto)
 		if !seen[key] {
 			seen[key] = true
-			rules = append(rules, egressRule{dep.Target, dep.Port, dep.Protocol})
+			rules = append(rules, egressRule{dep.Target, dep.Port, dep.EndPort, proto})
 		}
 	}
 
@@ -91,14 +95,9 @@ func NetworkPolicy(ds *model.DependencySet) string {
 
 	// Render each egress rule with proper destination selectors (Fix 1)
 	for _, rule := range rules {
-		proto := strings.ToUpper(rule.protocol)
-		if proto == "" {
-			proto = "TCP"
-		}
 		renderEgressTo(&b, rule.target)
 		fmt.Fprintf(&b, "      ports:\n")
-		fmt.Fprintf(&b, "        - port: %d\n", rule.port)
-		fmt.Fprintf(&b, "          protocol: %s\n", proto)
+		writePolicyPort(&b, rule.port, rule.endPort, rule.protocol)
 	}
 
 	// DNS egress (port 53) restricted to kube-system namespace (Fix 1)
@@ -124,24 +123,36 @@ func NetworkPolicy(ds *model.DependencySet) string {
 // ingress and egress rules. Each policy includes:
 // - Default-deny for both directions (via policyTypes)
 // - Ingress rules for what talks to this service
+// - Ingress from anywhere on published (NodePort/LoadBalancer/host) ports
 // - Egress rules for what this service talks to
 // - DNS egress to kube-system for services that have egress
 func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
-	if len(deps) == 0 {
+	listeners := ds.Listeners()
+	if len(deps) == 0 && len(listeners) == 0 {
 		return ""
 	}
 
-	// Discover all services (both sources and targets)
+	// Discover all services (sources, targets and listening workloads)
 	allServices := make(map[string]bool)
+	// Address peers (an IP, a CIDR block) are ipBlock rules on the
+	// workloads they talk to, not workloads of their own.
 	for _, dep := range deps {
-		if dep.Source != "" {
+		if dep.BuildTime {
+			continue
+		}
+		if _, ok := policyCIDR(dep.Source); dep.Source != "" && !ok {
 			allServices[dep.Source] = true
 		}
-		if dep.Target != "" {
+		if _, ok := policyCIDR(dep.Target); dep.Target != "" && !ok {
 			allServices[dep.Target] = true
 		}
 	}
+	for _, l := range listeners {
+		if l.Workload != "" {
+			allServices[l.Workload] = true
+		}
+	}
 	serviceList := make([]string, 0, len(allServices))
 	for s := range allServices {
 		serviceList = append(serviceList, s)
@@ -153,7 +164,7 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 	// every dep where it is the Target (ingress dirs); we re-derive it
 	// here so the renderer doesn't depend on parser-side bookkeeping
 	// beyond the per-dep flag.
-	disableBySvc := computeDisableMap(deps)
+	disableBySvc := computeDisableMap(deps, listeners)
 
 	var b strings.Builder
 	first := true
@@ -169,8 +180,9 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 		}
 		first = false
 		svcName := sanitizeName(svc)
-		egress := ds.EgressFor(svc)
-		ingress := ds.IngressFor(svc)
+		egress := runtimeDeps(ds.EgressFor(svc))
+		ingress := runtimeDeps(ds.IngressFor(svc))
+		published := publishedPorts(ds.ListenersFor(svc))
 
 		// Honor partial directives on this workload by zeroing the rule
 		// list for the suppressed direction. We keep the policyTypes
@@ -179,6 +191,7 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 		switch disableBySvc[svc] {
 		case "ingress":
 			ingress = nil
+			published = nil
 		case "egress":
 			egress = nil
 		}
@@ -198,7 +211,7 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 		fmt.Fprintf(&b, "    - Egress\n")
 
 		// Ingress rules
-		if len(ingress) > 0 {
+		if len(ingress) > 0 || len(published) > 0 {
 			fmt.Fprintf(&b, "  ingress:\n")
 			for _, dep := range ingress {
 				if dep.Source == "" {
@@ -206,15 +219,17 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 				}
 				renderIngressFrom(&b, dep.Source)
 				if dep.Port > 0 {
-					proto := strings.ToUpper(dep.Protocol)
-					if proto == "" {
-						proto = "TCP"
-					}
 					fmt.Fprintf(&b, "      ports:\n")
-					fmt.Fprintf(&b, "        - port: %d\n", dep.Port)
-					fmt.Fprintf(&b, "          protocol: %s\n", proto)
+					writePolicyPort(&b, dep.Port, dep.EndPort, policyProtocol(dep.Protocol))
 				}
 			}
+			// A published port takes traffic from outside the cluster,
+			// so its rule has no `from:`.
+			for _, l := range published {
+				fmt.Fprintf(&b, "    - ports:\n")
+				fmt.Fprintf(&b, "        - port: %d\n", l.Port)
+				fmt.Fprintf(&b, "          protocol: %s\n", policyProtocol(l.Protocol))
+			}
 		}
 
 		// Egress rules
@@ -224,14 +239,9 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 				if dep.Port <= 0 {
 					continue
 				}
-				proto := strings.ToUpper(dep.Protocol)
-				if proto == "" {
-					proto = "TCP"
-				}
 				renderEgressTo(&b, dep.Target)
 				fmt.Fprintf(&b, "      ports:\n")
-				fmt.Fprintf(&b, "        - port: %d\n", dep.Port)
-				fmt.Fprintf(&b, "          protocol: %s\n", proto)
+				writePolicyPort(&b, dep.Port, dep.EndPort, policyProtocol(dep.Protocol))
 			}
 			// DNS egress
 			fmt.Fprintf(&b, "    - to:\n")
@@ -252,10 +262,10 @@ func PerServiceNetworkPolicy(ds *model.DependencySet) string {
 // renderIngressFrom writes the `from:` block for an ingress rule.
 // Mirrors renderEgressTo but uses `from:` instead of `to:`.
 func renderIngressFrom(b *strings.Builder, source string) {
-	if ip := net.ParseIP(source); ip != nil {
+	if cidr, ok := policyCIDR(source); ok {
 		fmt.Fprintf(b, "    - from:\n")
 		fmt.Fprintf(b, "        - ipBlock:\n")
-		fmt.Fprintf(b, "            cidr: %s/32\n", source)
+		fmt.Fprintf(b, "            cidr: %s\n", cidr)
 	} else if strings.Contains(source, ".") {
 		parts := strings.SplitN(source, ".", 3)
 		svcName := parts[0]
@@ -283,13 +293,13 @@ func renderIngressFrom(b *strings.Builder, source string) {
 // renderEgressTo writes the appropriate `to:` block for the given target.
 // - Simple service name (no dots, no IP) -> podSelector with app label
 // - FQDN (contains dots, not an IP) -> podSelector + namespaceSelector
-// - IP address -> ipBlock with /32 CIDR
+// - IP address or CIDR block -> ipBlock
 func renderEgressTo(b *strings.Builder, target string) {
-	if ip := net.ParseIP(target); ip != nil {
-		// IP address target
+	if cidr, ok := policyCIDR(target); ok {
+		// IP address or CIDR target
 		fmt.Fprintf(b, "    - to:\n")
 		fmt.Fprintf(b, "        - ipBlock:\n")
-		fmt.Fprintf(b, "            cidr: %s/32\n", target)
+		fmt.Fprintf(b, "            cidr: %s\n", cidr)
 	} else if strings.Contains(target, ".") {
 		// FQDN target — extract service name and namespace
 		parts := strings.SplitN(target, ".", 3)
@@ -316,6 +326,71 @@ func renderEgressTo(b *strings.Builder, target string) {
 	}
 }
 
+// runtimeDeps drops build-time deps, which the running pod never makes.
+func runtimeDeps(deps []model.NetworkDependency) []model.NetworkDependency {
+	var out []model.NetworkDependency
+	for _, d := range deps {
+		if !d.BuildTime {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// policyCIDR returns the ipBlock CIDR for a peer that is an IP address
+// (a single-host block) or a CIDR block, or false for a name.
+func policyCIDR(peer string) (string, bool) {
+	if ip := net.ParseIP(peer); ip != nil {
+		if ip.To4() != nil {
+			return peer + "/32", true
+		}
+		return peer + "/128", true
+	}
+	if _, _, err := net.ParseCIDR(peer); err == nil {
+		return peer, true
+	}
+	return "", false
+}
+
+// writePolicyPort writes one NetworkPolicyPort entry, with endPort when
+// the rule covers a range.
+func writePolicyPort(b *strings.Builder, port, endPort int, protocol string) {
+	fmt.Fprintf(b, "        - port: %d\n", port)
+	if endPort > port {
+		fmt.Fprintf(b, "          endPort: %d\n", endPort)
+	}
+	fmt.Fprintf(b, "          protocol: %s\n", protocol)
+}
+
+// publishedPorts returns the listeners exposed outside the cluster or
+// compose network, one per port and protocol.
+func publishedPorts(listeners []model.Listener) []model.Listener {
+	var out []model.Listener
+	seen := make(map[string]bool)
+	for _, l := range listeners {
+		if !l.Exposure.External() || l.Port <= 0 {
+			continue
+		}
+		key := fmt.Sprintf("%d:%s", l.Port, policyProtocol(l.Protocol))
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// policyProtocol returns the NetworkPolicy protocol for a dependency's
+// transport: UDP or SCTP when the parser found one, TCP otherwise (the
+// default, and the transport of anything that isn't a valid protocol).
+func policyProtocol(p string) string {
+	switch p = strings.ToUpper(p); p {
+	case "UDP", "SCTP":
+		return p
+	}
+	return "TCP"
+}
+
 // sanitizeName converts a string to a valid K8s resource name.
 func sanitizeName(s string) string {
 	s = strings.ToLower(s)
@@ -353,8 +428,9 @@ func sanitizeName(s string) string {
 //
 // In practice the parsers stamp every dep emitted from one workload with
 // the same directive, so this aggregation is a no-op consistency check
-// plus the cross-workload promotion.
-func computeDisableMap(deps []model.NetworkDependency) map[string]string {
+// plus the cross-workload promotion. A listener's directive applies to
+// its workload.
+func computeDisableMap(deps []model.NetworkDependency, listeners []model.Listener) map[string]string {
 	// The parser-side contract: when a workload W carries a disable
 	// directive, every dep it emits is stamped with that directive AND
 	// has W as its Source (or, if Source is empty because the parser
@@ -364,16 +440,9 @@ func computeDisableMap(deps []model.NetworkDependency) map[string]string {
 	// trap of disabling a downstream service just because an upstream
 	// disabled-egress dep happens to point at it.
 	out := make(map[string]string)
-	for _, d := range deps {
-		if d.Disabled == "" {
-			continue
-		}
-		owner := d.Source
-		if owner == "" {
-			owner = d.Target
-		}
-		if owner == "" {
-			continue
+	add := func(owner, directive string) {
+		if directive == "" || owner == "" {
+			return
 		}
 		// "full" wins over partial directives; partial directives merge
 		// (ingress+egress => full). This makes the renderer's
@@ -382,13 +451,23 @@ func computeDisableMap(deps []model.NetworkDependency) map[string]string {
 		case "full":
 			// already strongest
 		case "":
-			out[owner] = d.Disabled
+			out[owner] = directive
 		default:
-			if out[owner] != d.Disabled {
+			if out[owner] != directive {
 				out[owner] = "full"
 			}
 		}
 	}
+	for _, d := range deps {
+		owner := d.Source
+		if owner == "" {
+			owner = d.Target
+		}
+		add(owner, d.Disabled)
+	}
+	for _, l := range listeners {
+		add(l.Workload, l.Disabled)
+	}
 	return out
 }
 
@@ -37,6 +37,38 @@ func TestNetworkPolicyBasic(t *testing.T) {
 	}
 }
 
+func TestNetworkPolicyProtocols(t *testing.T) {
+	ds := model.NewDependencySet("gateway")
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "statsd", Port: 8125, Protocol: "UDP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "edge", Port: 443, Protocol: "UDP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "edge", Port: 443, Protocol: "TCP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "amf", Port: 38412, Protocol: "SCTP"})
+	ds.Add(model.NetworkDependency{Source: "gateway", Target: "api", Port: 80, Protocol: "http"})
+
+	if ds.Len() != 5 {
+		t.Fatalf("Len() = %d, want 5: edge:443 over UDP and TCP are distinct", ds.Len())
+	}
+	for name, out := range map[string]string{
+		"netpol":      NetworkPolicy(ds),
+		"per-service": PerServiceNetworkPolicy(ds),
+	} {
+		for _, want := range []string{
+			"- port: 8125\n          protocol: UDP",
+			"- port: 443\n          protocol: UDP",
+			"- port: 443\n          protocol: TCP",
+			"- port: 38412\n          protocol: SCTP",
+			"- port: 80\n          protocol: TCP",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: missing %q in\n%s", name, want, out)
+			}
+		}
+		if strings.Contains(out, "protocol: http") {
+			t.Errorf("%s: invalid protocol passed through:\n%s", name, out)
+		}
+	}
+}
+
 func TestNetworkPolicyEmpty(t *testing.T) {
 	ds := model.NewDependencySet("empty")
 	out := NetworkPolicy(ds)
@@ -306,6 +338,46 @@ func TestPerServiceNetworkPolicy(t *testing.T) {
 	}
 }
 
+func TestPerServiceNetworkPolicyCIDRPeers(t *testing.T) {
+	ds := model.NewDependencySet("infra")
+	ds.Add(model.NetworkDependency{Source: "10.0.0.0/16", Target: "web", Port: 1024, EndPort: 65535, Protocol: "TCP"})
+	ds.Add(model.NetworkDependency{Source: "web", Target: "::/0", Port: 443, Protocol: "TCP"})
+
+	output := PerServiceNetworkPolicy(ds)
+
+	if strings.Count(output, "kind: NetworkPolicy") != 1 {
+		t.Errorf("CIDR peers should not get policies of their own:\n%s", output)
+	}
+	for _, want := range []string{
+		"cidr: 10.0.0.0/16",
+		"cidr: ::/0",
+		"- port: 1024\n          endPort: 65535\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestNetworkPolicySkipsBuildTime(t *testing.T) {
+	ds := model.NewDependencySet("web")
+	ds.Add(model.NetworkDependency{Source: "web", Target: "registry.npmjs.org", Port: 443, Protocol: "TCP", BuildTime: true})
+	ds.Add(model.NetworkDependency{Source: "web", Target: "db", Port: 5432, Protocol: "TCP"})
+
+	for name, out := range map[string]string{
+		"NetworkPolicy":           NetworkPolicy(ds),
+		"PerServiceNetworkPolicy": PerServiceNetworkPolicy(ds),
+		"Cilium":                  Cilium(ds),
+	} {
+		if strings.Contains(out, "registry") || strings.Contains(out, "port: 443") || strings.Contains(out, `port: "443"`) {
+			t.Errorf("%s allows the build-time registry:\n%s", name, out)
+		}
+		if !strings.Contains(out, "app: db") {
+			t.Errorf("%s lost the runtime dep:\n%s", name, out)
+		}
+	}
+}
+
 func TestPerServiceNetworkPolicyEmpty(t *testing.T) {
 	ds := model.NewDependencySet("empty")
 	output := PerServiceNetworkPolicy(ds)
@@ -340,3 +412,31 @@ func TestPerServiceNetworkPolicySkipsInvalidPort(t *testing.T) {
 		t.Error("valid port 8080 should be present")
 	}
 }
+
+func TestPerServiceNetworkPolicyListeners(t *testing.T) {
+	ds := model.NewDependencySet("myapp")
+	ds.Add(model.NetworkDependency{Source: "web", Target: "api", Port: 8080, Protocol: "TCP"})
+	ds.AddListener(model.Listener{Workload: "web", Port: 443, Protocol: "TCP", Exposure: model.LoadBalancer})
+	ds.AddListener(model.Listener{Workload: "api", Port: 8080, Protocol: "TCP", Exposure: model.ClusterIP})
+	ds.AddListener(model.Listener{Workload: "db", Port: 5432, Protocol: "TCP"})
+
+	output := PerServiceNetworkPolicy(ds)
+
+	// A workload known only by its listener still gets a policy.
+	if !strings.Contains(output, "name: db-netpol") {
+		t.Error("missing policy for listener-only workload db")
+	}
+	// The published port is open to any source...
+	web := output[strings.Index(output, "name: web-netpol"):]
+	if !strings.Contains(web, "  ingress:\n    - ports:\n        - port: 443\n          protocol: TCP\n") {
+		t.Errorf("missing open ingress on web's LoadBalancer port:\n%s", web)
+	}
+	// ...a ClusterIP port only to the workloads that call it.
+	if strings.Count(output, "    - ports:\n") != 1 {
+		t.Errorf("only published ports get a rule without from:\n%s", output)
+	}
+	// Listeners are not edges: nothing allows a workload to reach itself.
+	if strings.Contains(output, "port: 5432") {
+		t.Errorf("db's own port must not become an egress rule:\n%s", output)
+	}
+}
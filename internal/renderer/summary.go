@@ -10,7 +10,8 @@ import (
 // Summary renders a human-readable dependency report.
 func Summary(ds *model.DependencySet) string {
 	deps := ds.Dependencies()
-	if len(deps) == 0 {
+	listeners := ds.Listeners()
+	if len(deps) == 0 && len(listeners) == 0 {
 		return "No dependencies found.\n"
 	}
 
@@ -32,7 +33,7 @@ func Summary(ds *model.DependencySet) string {
 		conf := string(dep.Confidence)
 		desc := dep.Description
 		if desc == "" {
-			desc = fmt.Sprintf("%s:%d/%s", dep.Target, dep.Port, dep.Protocol)
+			desc = fmt.Sprintf("%s:%s/%s", dep.Target, dep.PortRange(), dep.Protocol)
 		}
 		// Surface the source-level disable directive so operators can SEE
 		// that a workload's policies are intentionally suppressed (k8s
@@ -44,9 +45,39 @@ func Summary(ds *model.DependencySet) string {
 		if dep.Disabled != "" {
 			disabledTag = fmt.Sprintf("  [disabled: %s]", dep.Disabled)
 		}
-		fmt.Fprintf(&b, "  → %s:%d/%s  [%s]  %s%s\n", dep.Target, dep.Port, dep.Protocol, conf, desc, disabledTag)
+		envTag := ""
+		if dep.Environment != "" {
+			envTag = fmt.Sprintf("  [env: %s]", dep.Environment)
+		}
+		if dep.BuildTime {
+			envTag += "  [build time]"
+		}
+		fmt.Fprintf(&b, "  → %s:%s/%s  [%s]  %s%s%s\n", dep.Target, dep.PortRange(), dep.Protocol, conf, desc, envTag, disabledTag)
 		if dep.SourceFile != "" {
-			fmt.Fprintf(&b, "    source: %s\n", dep.SourceFile)
+			fmt.Fprintf(&b, "    source: %s\n", dep.Location())
+		}
+		if len(dep.Aliases) > 0 {
+			fmt.Fprintf(&b, "    aka:    %s\n", strings.Join(dep.Aliases, ", "))
+		}
+		for _, p := range dep.Provenance {
+			if p.File == dep.SourceFile && p.Line == dep.Line && p.Evidence == dep.EvidenceLine {
+				continue
+			}
+			fmt.Fprintf(&b, "    also:   %s\n", p.Location())
+		}
+	}
+
+	if len(listeners) > 0 {
+		fmt.Fprintf(&b, "\nListeners: %d\n\n", len(listeners))
+		for _, l := range listeners {
+			disabledTag := ""
+			if l.Disabled != "" {
+				disabledTag = fmt.Sprintf("  [disabled: %s]", l.Disabled)
+			}
+			fmt.Fprintf(&b, "  ← %s listens on %s  [%s]  %s%s\n", listenerWorkload(l), listenerPort(l), l.Confidence, l.Description, disabledTag)
+			if l.SourceFile != "" {
+				fmt.Fprintf(&b, "    source: %s\n", l.Location())
+			}
 		}
 	}
 
@@ -57,3 +88,29 @@ func Summary(ds *model.DependencySet) string {
 
 	return b.String()
 }
+
+// listenerWorkload returns the listener's workload, or "unknown" for a
+// port whose owner the config doesn't name.
+func listenerWorkload(l model.Listener) string {
+	if l.Workload == "" {
+		return "unknown"
+	}
+	return l.Workload
+}
+
+// listenerPort formats a listener's port as "8080/TCP", followed by the
+// port name and exposure when known: "8080/TCP (http, NodePort)".
+func listenerPort(l model.Listener) string {
+	port := fmt.Sprintf("%d/%s", l.Port, l.Protocol)
+	var extra []string
+	if l.Name != "" {
+		extra = append(extra, l.Name)
+	}
+	if l.Exposure != "" {
+		extra = append(extra, string(l.Exposure))
+	}
+	if len(extra) > 0 {
+		port += " (" + strings.Join(extra, ", ") + ")"
+	}
+	return port
+}
@@ -73,3 +73,37 @@ func TestSummaryShowsSourceFile(t *testing.T) {
 		t.Error("missing source file reference")
 	}
 }
+
+func TestSummaryListeners(t *testing.T) {
+	ds := model.NewDependencySet("shop")
+	ds.AddListener(model.Listener{
+		Workload: "api", Port: 8080, Protocol: "TCP", Name: "http", Exposure: model.NodePort,
+		Description: "container port 8080", Confidence: model.High, SourceFile: "deploy.yaml", Line: 12,
+	})
+
+	out := Summary(ds)
+
+	if strings.Contains(out, "No dependencies") {
+		t.Fatal("a set with only listeners is not empty")
+	}
+	if !strings.Contains(out, "Listeners: 1") {
+		t.Errorf("missing listener count:\n%s", out)
+	}
+	if !strings.Contains(out, "api listens on 8080/TCP (http, NodePort)") {
+		t.Errorf("missing listener line:\n%s", out)
+	}
+	if strings.Contains(out, "→ api:8080") {
+		t.Errorf("listener rendered as a dependency:\n%s", out)
+	}
+}
+
+func TestSummaryAliases(t *testing.T) {
+	ds := model.NewDependencySet("shop")
+	ds.Add(model.NetworkDependency{Source: "app", Target: "redis", Port: 6379, Protocol: "TCP",
+		Confidence: model.High, SourceFile: "app.yaml", Aliases: []string{"10.0.0.4", "redis.default"}})
+
+	out := Summary(ds)
+	if !strings.Contains(out, "    aka:    10.0.0.4, redis.default\n") {
+		t.Errorf("missing alias line:\n%s", out)
+	}
+}
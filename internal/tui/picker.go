@@ -130,9 +130,9 @@ func (p *Picker) View() string {
 			style = selectedStyle
 		}
 
-		line := fmt.Sprintf("%s %s -> %s:%d/%s  [%s]  %s",
+		line := fmt.Sprintf("%s %s -> %s:%s/%s  [%s]  %s",
 			checkbox,
-			it.dep.Source, it.dep.Target, it.dep.Port, it.dep.Protocol,
+			it.dep.Source, it.dep.Target, it.dep.PortRange(), it.dep.Protocol,
 			it.dep.Confidence, it.dep.Description)
 
 		if i == p.cursor {
@@ -9,8 +9,9 @@ import (
 
 // renderHelmTemplate shells out to `helm template` to render a chart.
 // valuesFile is optional — if empty, uses the chart's default values.yaml.
+// Each of sets is passed as a --set override.
 // Returns the rendered YAML as a string.
-func renderHelmTemplate(chartDir string, valuesFile string) (string, error) {
+func renderHelmTemplate(chartDir string, valuesFile string, sets ...string) (string, error) {
 	if _, err := exec.LookPath("helm"); err != nil {
 		return "", fmt.Errorf("helm not installed: %w", err)
 	}
@@ -22,6 +23,9 @@ func renderHelmTemplate(chartDir string, valuesFile string) (string, error) {
 	if valuesFile != "" {
 		args = append(args, "-f", valuesFile)
 	}
+	for _, s := range sets {
+		args = append(args, "--set", s)
+	}
 
 	cmd := exec.CommandContext(ctx, "helm", args...)
 	out, err := cmd.Output()
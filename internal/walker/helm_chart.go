@@ -0,0 +1,361 @@
+package walker
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmChart is a chart loaded into memory: its metadata, default values,
+// templates and vendored subcharts. Paths are slash-separated and relative
+// to the chart root.
+type helmChart struct {
+	name      string
+	meta      map[string]interface{} // Chart.yaml
+	values    map[string]interface{} // values.yaml
+	files     map[string][]byte      // every file in the chart, for .Files
+	templates []string               // templates/* paths, sorted
+	subcharts []*helmChart
+	// prefix is the chart's location relative to the top-level chart
+	// root ("" for the root, "charts/redis/" for a subchart) and is
+	// prepended to template paths in evidence.
+	prefix string
+}
+
+// helmDependency is one entry of Chart.yaml `dependencies` (or the v1
+// requirements.yaml).
+type helmDependency struct {
+	Name      string `yaml:"name"`
+	Alias     string `yaml:"alias"`
+	Condition string `yaml:"condition"`
+}
+
+// loadHelmChartDir reads a chart directory into memory.
+func loadHelmChartDir(dir string) (*helmChart, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(dir, p)
+		if relErr != nil {
+			return relErr
+		}
+		data, readErr := os.ReadFile(p)
+		if readErr != nil {
+			return readErr
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading chart %s: %w", dir, err)
+	}
+	return newHelmChart(files, "")
+}
+
+// loadHelmChartArchive unpacks a packaged (.tgz) chart. Archives hold a
+// single top-level directory named after the chart, which is stripped.
+func loadHelmChartArchive(data []byte, prefix string) (*helmChart, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = body
+	}
+	return newHelmChart(files, prefix)
+}
+
+// newHelmChart builds a chart from its file map, recursing into charts/.
+func newHelmChart(files map[string][]byte, prefix string) (*helmChart, error) {
+	c := &helmChart{files: make(map[string][]byte), prefix: prefix}
+	sub := make(map[string]map[string][]byte)
+	var archives []string
+	for p, data := range files {
+		if rest, ok := strings.CutPrefix(p, "charts/"); ok {
+			if name, inner, ok := strings.Cut(rest, "/"); ok {
+				if sub[name] == nil {
+					sub[name] = make(map[string][]byte)
+				}
+				sub[name][inner] = data
+			} else if strings.HasSuffix(rest, ".tgz") {
+				archives = append(archives, p)
+			}
+			continue
+		}
+		c.files[p] = data
+		if strings.HasPrefix(p, "templates/") {
+			c.templates = append(c.templates, p)
+		}
+	}
+	sort.Strings(c.templates)
+
+	chartYAML, ok := c.files["Chart.yaml"]
+	if !ok {
+		return nil, fmt.Errorf("%sChart.yaml not found", prefix)
+	}
+	if err := yaml.Unmarshal(chartYAML, &c.meta); err != nil {
+		return nil, fmt.Errorf("parsing %sChart.yaml: %w", prefix, err)
+	}
+	if c.meta == nil {
+		c.meta = map[string]interface{}{}
+	}
+	c.name, _ = c.meta["name"].(string)
+
+	if v, ok := c.files["values.yaml"]; ok {
+		if err := yaml.Unmarshal(v, &c.values); err != nil {
+			return nil, fmt.Errorf("parsing %svalues.yaml: %w", prefix, err)
+		}
+	}
+	if c.values == nil {
+		c.values = map[string]interface{}{}
+	}
+
+	names := make([]string, 0, len(sub))
+	for n := range sub {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		sc, err := newHelmChart(sub[n], prefix+"charts/"+n+"/")
+		if err != nil {
+			return nil, err
+		}
+		c.subcharts = append(c.subcharts, sc)
+	}
+	sort.Strings(archives)
+	for _, a := range archives {
+		sc, err := loadHelmChartArchive(files[a], prefix+a+"/")
+		if err != nil {
+			return nil, fmt.Errorf("unpacking %s%s: %w", prefix, a, err)
+		}
+		c.subcharts = append(c.subcharts, sc)
+	}
+	return c, nil
+}
+
+// dependencies returns the chart's declared dependencies from Chart.yaml
+// or, for apiVersion v1 charts, requirements.yaml.
+func (c *helmChart) dependencies() []helmDependency {
+	var holder struct {
+		Dependencies []helmDependency `yaml:"dependencies"`
+	}
+	if data, ok := c.files["Chart.yaml"]; ok {
+		yaml.Unmarshal(data, &holder)
+	}
+	if len(holder.Dependencies) == 0 {
+		if data, ok := c.files["requirements.yaml"]; ok {
+			yaml.Unmarshal(data, &holder)
+		}
+	}
+	return holder.Dependencies
+}
+
+// chartObject exposes Chart.yaml under Helm's capitalized field names
+// (.Chart.Name, .Chart.AppVersion, ...).
+func (c *helmChart) chartObject() map[string]interface{} {
+	out := make(map[string]interface{}, len(c.meta))
+	for k, v := range c.meta {
+		switch k {
+		case "apiVersion":
+			out["APIVersion"] = v
+		case "appVersion":
+			out["AppVersion"] = v
+		default:
+			if k != "" {
+				out[strings.ToUpper(k[:1])+k[1:]] = v
+			}
+		}
+	}
+	out["Name"] = c.name
+	return out
+}
+
+// mergeValues deep-merges src over dst and returns dst. Maps merge
+// recursively; any other value in src replaces dst's. A nil in src
+// deletes the key, matching Helm's "set to null to remove a default".
+func mergeValues(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+		sm, sok := v.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+		if sok && dok {
+			dst[k] = mergeValues(dm, sm)
+			continue
+		}
+		dst[k] = deepCopyValue(v)
+	}
+	return dst
+}
+
+// parseHelmSet applies one `--set` expression (a=b,c.d=e,list[0]=f) to
+// vals. Values are typed the way Helm types them: true/false become
+// bools, integers become int64, null removes the key, {a,b} is a list, and
+// everything else is a string. Commas and dots can be escaped with `\`.
+func parseHelmSet(vals map[string]interface{}, expr string) error {
+	for _, assign := range splitHelmSet(expr, ',') {
+		if assign == "" {
+			continue
+		}
+		key, raw, ok := strings.Cut(assign, "=")
+		if !ok {
+			return fmt.Errorf("--set %q: missing '='", assign)
+		}
+		var segs []string
+		for _, s := range splitHelmSet(key, '.') {
+			segs = append(segs, strings.ReplaceAll(s, `\.`, "."))
+		}
+		if err := setHelmPath(vals, segs, typeHelmSetValue(raw)); err != nil {
+			return fmt.Errorf("--set %q: %w", assign, err)
+		}
+	}
+	return nil
+}
+
+// splitHelmSet splits on sep outside {} lists and not preceded by `\`.
+func splitHelmSet(s string, sep byte) []string {
+	var out []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	out = append(out, s[start:])
+	if sep == ',' {
+		for i := range out {
+			out[i] = strings.ReplaceAll(out[i], `\,`, ",")
+		}
+	}
+	return out
+}
+
+func typeHelmSetValue(raw string) interface{} {
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+		var list []interface{}
+		for _, item := range splitHelmSet(raw[1:len(raw)-1], ',') {
+			list = append(list, typeHelmSetValue(item))
+		}
+		return list
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n
+	}
+	return raw
+}
+
+// setHelmPath assigns value at the key path, creating maps and growing
+// lists (`name[2]`) as needed.
+func setHelmPath(vals map[string]interface{}, segs []string, value interface{}) error {
+	var cur interface{} = vals
+	for i, seg := range segs {
+		last := i == len(segs)-1
+		name, idx := seg, -1
+		if open := strings.Index(seg, "["); open >= 0 && strings.HasSuffix(seg, "]") {
+			n, err := strconv.Atoi(seg[open+1 : len(seg)-1])
+			if err != nil || n < 0 {
+				return fmt.Errorf("bad list index in %q", seg)
+			}
+			name, idx = seg[:open], n
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%q is not a map", strings.Join(segs[:i], "."))
+		}
+		if idx < 0 {
+			if last {
+				if value == nil {
+					delete(m, name)
+				} else {
+					m[name] = value
+				}
+				return nil
+			}
+			next, ok := m[name].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				m[name] = next
+			}
+			cur = next
+			continue
+		}
+		list, _ := m[name].([]interface{})
+		for len(list) <= idx {
+			list = append(list, nil)
+		}
+		m[name] = list
+		if last {
+			list[idx] = value
+			return nil
+		}
+		next, ok := list[idx].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			list[idx] = next
+		}
+		cur = next
+	}
+	return nil
+}
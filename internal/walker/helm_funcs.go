@@ -0,0 +1,545 @@
+package walker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth bounds recursive include/tpl calls, as Helm does, so a
+// self-including define fails instead of overflowing the stack.
+const maxIncludeDepth = 1000
+
+// readValuesFile loads a user-supplied values file.
+func readValuesFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading values file: %w", err)
+	}
+	var vals map[string]interface{}
+	if err := yaml.Unmarshal(data, &vals); err != nil {
+		return nil, fmt.Errorf("parsing values file %s: %w", path, err)
+	}
+	return vals, nil
+}
+
+// funcMap returns the Helm template functions segspec supports: Helm's own
+// (include, tpl, required, toYaml, ...) and the Sprig functions charts use
+// for string, list, dict and arithmetic work. Functions that talk to a
+// cluster (lookup) return empty results.
+func (r *helmRenderer) funcMap() template.FuncMap {
+	return template.FuncMap{
+		// Helm
+		"include":  r.include,
+		"tpl":      r.tpl,
+		"required": helmRequired,
+		"fail":     func(msg string) (string, error) { return "", errors.New(msg) },
+		"toYaml":   func(v interface{}) string { s, _ := helmToYAML(v); return s },
+		"fromYaml": func(s string) map[string]interface{} {
+			var m map[string]interface{}
+			yaml.Unmarshal([]byte(s), &m)
+			return m
+		},
+		"toJson": func(v interface{}) string { b, _ := json.Marshal(v); return string(b) },
+		"fromJson": func(s string) map[string]interface{} {
+			var m map[string]interface{}
+			json.Unmarshal([]byte(s), &m)
+			return m
+		},
+		"lookup": func(...interface{}) map[string]interface{} { return map[string]interface{}{} },
+
+		// defaults and flow
+		"default":  helmDefault,
+		"empty":    helmEmpty,
+		"coalesce": helmCoalesce,
+		"ternary": func(a, b interface{}, cond bool) interface{} {
+			if cond {
+				return a
+			}
+			return b
+		},
+
+		// strings
+		"quote": func(v ...interface{}) string { return strings.Join(mapStrings(v, strconv.Quote), " ") },
+		"squote": func(v ...interface{}) string {
+			return strings.Join(mapStrings(v, func(s string) string { return "'" + s + "'" }), " ")
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      helmTitle,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cut, s string) string { return strings.Trim(s, cut) },
+		"trimPrefix": func(p, s string) string { return strings.TrimPrefix(s, p) },
+		"trimSuffix": func(p, s string) string { return strings.TrimSuffix(s, p) },
+		"trunc":      helmTrunc,
+		"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(sub, s string) bool { return strings.Contains(s, sub) },
+		"hasPrefix":  func(p, s string) bool { return strings.HasPrefix(s, p) },
+		"hasSuffix":  func(p, s string) bool { return strings.HasSuffix(s, p) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"indent":     helmIndent,
+		"nindent":    func(n int, s string) string { return "\n" + helmIndent(n, s) },
+		"cat": func(v ...interface{}) string {
+			return strings.Join(strings.Fields(strings.TrimSpace(fmt.Sprint(v...))), " ")
+		},
+		"join":         func(sep string, v interface{}) string { return strings.Join(helmStrings(v), sep) },
+		"splitList":    func(sep, s string) []interface{} { return toIfaceList(strings.Split(s, sep)) },
+		"toString":     helmToString,
+		"toStrings":    helmStrings,
+		"b64enc":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":       func(s string) string { b, _ := base64.StdEncoding.DecodeString(s); return string(b) },
+		"sha256sum":    func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) },
+		"randAlphaNum": func(n int) string { return strings.Repeat("x", n) }, // deterministic output
+		"regexMatch":   func(re, s string) bool { ok, _ := regexp.MatchString(re, s); return ok },
+		"regexReplaceAll": func(re, s, repl string) string {
+			return regexp.MustCompile(re).ReplaceAllString(s, repl)
+		},
+		"semverCompare": func(string, string) bool { return true },
+
+		// conversion and types
+		"int":     func(v interface{}) int { return int(helmToInt64(v)) },
+		"int64":   helmToInt64,
+		"float64": helmToFloat64,
+		"atoi":    func(s string) int { n, _ := strconv.Atoi(s); return n },
+		"kindOf":  func(v interface{}) string { return helmKind(v) },
+		"kindIs":  func(k string, v interface{}) bool { return helmKind(v) == k },
+		"typeOf":  func(v interface{}) string { return fmt.Sprintf("%T", v) },
+
+		// arithmetic
+		"add": func(v ...interface{}) int64 {
+			var sum int64
+			for _, x := range v {
+				sum += helmToInt64(x)
+			}
+			return sum
+		},
+		"add1": func(v interface{}) int64 { return helmToInt64(v) + 1 },
+		"sub":  func(a, b interface{}) int64 { return helmToInt64(a) - helmToInt64(b) },
+		"mul": func(v ...interface{}) int64 {
+			p := int64(1)
+			for _, x := range v {
+				p *= helmToInt64(x)
+			}
+			return p
+		},
+		"div": func(a, b interface{}) int64 {
+			if d := helmToInt64(b); d != 0 {
+				return helmToInt64(a) / d
+			}
+			return 0
+		},
+		"mod": func(a, b interface{}) int64 {
+			if d := helmToInt64(b); d != 0 {
+				return helmToInt64(a) % d
+			}
+			return 0
+		},
+		"max": func(a interface{}, v ...interface{}) int64 {
+			m := helmToInt64(a)
+			for _, x := range v {
+				if n := helmToInt64(x); n > m {
+					m = n
+				}
+			}
+			return m
+		},
+		"min": func(a interface{}, v ...interface{}) int64 {
+			m := helmToInt64(a)
+			for _, x := range v {
+				if n := helmToInt64(x); n < m {
+					m = n
+				}
+			}
+			return m
+		},
+		"until": func(n int) []int {
+			out := make([]int, 0, n)
+			for i := 0; i < n; i++ {
+				out = append(out, i)
+			}
+			return out
+		},
+
+		// lists
+		"list": func(v ...interface{}) []interface{} { return v },
+		"first": func(v interface{}) interface{} {
+			l := helmList(v)
+			if len(l) == 0 {
+				return nil
+			}
+			return l[0]
+		},
+		"last": func(v interface{}) interface{} {
+			l := helmList(v)
+			if len(l) == 0 {
+				return nil
+			}
+			return l[len(l)-1]
+		},
+		"rest": func(v interface{}) []interface{} {
+			l := helmList(v)
+			if len(l) == 0 {
+				return l
+			}
+			return l[1:]
+		},
+		"append": func(v interface{}, x interface{}) []interface{} {
+			return append(append([]interface{}{}, helmList(v)...), x)
+		},
+		"prepend": func(v interface{}, x interface{}) []interface{} { return append([]interface{}{x}, helmList(v)...) },
+		"concat": func(v ...interface{}) []interface{} {
+			var out []interface{}
+			for _, l := range v {
+				out = append(out, helmList(l)...)
+			}
+			return out
+		},
+		"has": func(x interface{}, v interface{}) bool {
+			for _, e := range helmList(v) {
+				if reflect.DeepEqual(e, x) {
+					return true
+				}
+			}
+			return false
+		},
+		"uniq": func(v interface{}) []interface{} {
+			var out []interface{}
+			for _, e := range helmList(v) {
+				dup := false
+				for _, o := range out {
+					if reflect.DeepEqual(o, e) {
+						dup = true
+						break
+					}
+				}
+				if !dup {
+					out = append(out, e)
+				}
+			}
+			return out
+		},
+		"compact": func(v interface{}) []interface{} {
+			var out []interface{}
+			for _, e := range helmList(v) {
+				if !helmEmpty(e) {
+					out = append(out, e)
+				}
+			}
+			return out
+		},
+		"sortAlpha": func(v interface{}) []string { s := helmStrings(v); sort.Strings(s); return s },
+
+		// dicts
+		"dict": func(kv ...interface{}) map[string]interface{} {
+			m := make(map[string]interface{}, len(kv)/2)
+			for i := 0; i+1 < len(kv); i += 2 {
+				m[helmToString(kv[i])] = kv[i+1]
+			}
+			return m
+		},
+		"get":    func(m map[string]interface{}, k string) interface{} { return m[k] },
+		"set":    func(m map[string]interface{}, k string, v interface{}) map[string]interface{} { m[k] = v; return m },
+		"unset":  func(m map[string]interface{}, k string) map[string]interface{} { delete(m, k); return m },
+		"hasKey": func(m map[string]interface{}, k string) bool { _, ok := m[k]; return ok },
+		"keys": func(ms ...map[string]interface{}) []string {
+			var out []string
+			for _, m := range ms {
+				for k := range m {
+					out = append(out, k)
+				}
+			}
+			sort.Strings(out)
+			return out
+		},
+		"values": func(m map[string]interface{}) []interface{} {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]interface{}, 0, len(m))
+			for _, k := range keys {
+				out = append(out, m[k])
+			}
+			return out
+		},
+		"pluck": func(k string, ms ...map[string]interface{}) []interface{} {
+			var out []interface{}
+			for _, m := range ms {
+				if v, ok := m[k]; ok {
+					out = append(out, v)
+				}
+			}
+			return out
+		},
+		"merge": func(dst map[string]interface{}, srcs ...map[string]interface{}) map[string]interface{} {
+			for _, src := range srcs {
+				for k, v := range src {
+					if _, ok := dst[k]; !ok {
+						dst[k] = v
+					} else if dm, ok := dst[k].(map[string]interface{}); ok {
+						if sm, ok := v.(map[string]interface{}); ok {
+							for sk, sv := range sm {
+								if _, ok := dm[sk]; !ok {
+									dm[sk] = sv
+								}
+							}
+						}
+					}
+				}
+			}
+			return dst
+		},
+		"mergeOverwrite": func(dst map[string]interface{}, srcs ...map[string]interface{}) map[string]interface{} {
+			for _, src := range srcs {
+				dst = mergeValues(dst, src)
+			}
+			return dst
+		},
+		"deepCopy": deepCopyValue,
+	}
+}
+
+// include executes a named template and returns its output, so it can be
+// piped (`include "x" . | nindent 4`), unlike the `template` action.
+func (r *helmRenderer) include(name string, data interface{}) (string, error) {
+	if r.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("include %q: nesting too deep", name)
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	var buf bytes.Buffer
+	if err := r.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// tpl renders a string as a template against data, with access to every
+// define in the chart.
+func (r *helmRenderer) tpl(text string, data interface{}) (string, error) {
+	if r.depth >= maxIncludeDepth {
+		return "", fmt.Errorf("tpl: nesting too deep")
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	t, err := r.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	t, err = t.New("tpl").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+func helmRequired(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+// helmToYAML marshals v with two-space indentation (matching Helm's
+// output) and without the trailing newline.
+func helmToYAML(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	enc.Close()
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func helmDefault(def interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || helmEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+func helmCoalesce(v ...interface{}) interface{} {
+	for _, x := range v {
+		if !helmEmpty(x) {
+			return x
+		}
+	}
+	return nil
+}
+
+// helmEmpty follows Sprig: nil, zero numbers, false, and empty strings,
+// lists and maps are empty.
+func helmEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func helmTitle(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func helmTrunc(n int, s string) string {
+	if n >= 0 && len(s) > n {
+		return s[:n]
+	}
+	if n < 0 && len(s) > -n {
+		return s[len(s)+n:]
+	}
+	return s
+}
+
+func helmIndent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func helmToString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func helmStrings(v interface{}) []string {
+	l := helmList(v)
+	out := make([]string, 0, len(l))
+	for _, e := range l {
+		out = append(out, helmToString(e))
+	}
+	return out
+}
+
+func mapStrings(v []interface{}, fn func(string) string) []string {
+	out := make([]string, 0, len(v))
+	for _, x := range v {
+		if x == nil {
+			continue
+		}
+		out = append(out, fn(helmToString(x)))
+	}
+	return out
+}
+
+func toIfaceList(s []string) []interface{} {
+	out := make([]interface{}, len(s))
+	for i, x := range s {
+		out[i] = x
+	}
+	return out
+}
+
+// helmList converts any slice or array to []interface{}.
+func helmList(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	out := make([]interface{}, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out
+}
+
+func helmToInt64(v interface{}) int64 {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int64:
+		return x
+	case int32:
+		return int64(x)
+	case uint64:
+		return int64(x)
+	case float64:
+		return int64(x)
+	case float32:
+		return int64(x)
+	case string:
+		n, _ := strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		return n
+	case bool:
+		if x {
+			return 1
+		}
+	}
+	return 0
+}
+
+func helmToFloat64(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f
+	}
+	return float64(helmToInt64(v))
+}
+
+// helmKind reports Sprig's kindOf names (map, slice, string, int, ...).
+func helmKind(v interface{}) string {
+	if v == nil {
+		return "invalid"
+	}
+	return reflect.ValueOf(v).Kind().String()
+}
@@ -0,0 +1,389 @@
+package walker
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// helmRendered is one rendered template: its path relative to the chart
+// directory, the manifest text, and for each rendered line (index 0 is
+// line 1) the template line it came from, 0 when unknown.
+type helmRendered struct {
+	Path    string
+	Content string
+	Lines   []int
+}
+
+// helmRelease is the .Release object. segspec renders a single fixed
+// release so output is reproducible.
+var helmRelease = map[string]interface{}{
+	"Name":      "segspec-render",
+	"Namespace": "default",
+	"Service":   "Helm",
+	"IsInstall": true,
+	"IsUpgrade": false,
+	"Revision":  1,
+}
+
+// helmAPIVersions backs .Capabilities.APIVersions.Has.
+type helmAPIVersions []string
+
+// Has reports whether the group/version (or group/version/kind) is known.
+// segspec has no cluster to ask, so every stable API is reported present.
+func (v helmAPIVersions) Has(apiVersion string) bool {
+	for _, a := range v {
+		if a == apiVersion || strings.HasPrefix(apiVersion, a+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+type helmKubeVersion struct {
+	Version, Major, Minor, GitVersion string
+}
+
+type helmCapabilities struct {
+	KubeVersion helmKubeVersion
+	APIVersions helmAPIVersions
+}
+
+var defaultHelmCapabilities = helmCapabilities{
+	KubeVersion: helmKubeVersion{Version: "v1.29.0", Major: "1", Minor: "29", GitVersion: "v1.29.0"},
+	APIVersions: helmAPIVersions{
+		"v1", "apps/v1", "batch/v1", "networking.k8s.io/v1", "policy/v1",
+		"autoscaling/v2", "rbac.authorization.k8s.io/v1", "apiextensions.k8s.io/v1",
+	},
+}
+
+// renderHelmChart renders a chart directory in-process: values.yaml is
+// merged with valuesFile and then the --set expressions, enabled subcharts
+// get their scoped values, and every template under templates/ (and each
+// subchart's) is executed against one shared template set so defines from
+// any _helpers.tpl are visible everywhere, as with `helm template`.
+//
+// Per-template failures are returned as errors alongside whatever did
+// render; a chart that can't be loaded at all returns only an error.
+func renderHelmChart(chartDir, valuesFile string, sets []string) ([]helmRendered, []error) {
+	chart, err := loadHelmChartDir(chartDir)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	vals := mergeValues(map[string]interface{}{}, chart.values)
+	if valuesFile != "" {
+		user, err := readValuesFile(valuesFile)
+		if err != nil {
+			return nil, []error{err}
+		}
+		vals = mergeValues(vals, user)
+	}
+	for _, s := range sets {
+		if err := parseHelmSet(vals, s); err != nil {
+			return nil, []error{err}
+		}
+	}
+
+	r := &helmRenderer{}
+	r.tmpl = template.New("segspec").Option("missingkey=zero").Funcs(r.funcMap())
+	var errs []error
+	r.collect(chart, vals, &errs)
+
+	// Parse every chart's templates (partials included) before executing
+	// any, so includes resolve regardless of file order.
+	for _, u := range r.units {
+		for _, p := range u.chart.templates {
+			name := u.chart.prefix + p
+			if _, err := r.tmpl.New(name).Parse(string(u.chart.files[p])); err != nil {
+				errs = append(errs, fmt.Errorf("parsing %s: %w", name, err))
+			}
+		}
+	}
+
+	var out []helmRendered
+	for _, u := range r.units {
+		for _, p := range u.chart.templates {
+			if !isManifestTemplate(path.Base(p)) {
+				continue
+			}
+			name := u.chart.prefix + p
+			t := r.tmpl.Lookup(name)
+			if t == nil {
+				continue // failed to parse; already reported
+			}
+			data := map[string]interface{}{
+				"Values":       u.values,
+				"Release":      helmRelease,
+				"Chart":        u.chart.chartObject(),
+				"Capabilities": defaultHelmCapabilities,
+				"Files":        helmFiles(u.chart.files),
+				"Template":     map[string]interface{}{"Name": name, "BasePath": u.chart.prefix + "templates"},
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				errs = append(errs, fmt.Errorf("rendering %s: %w", name, err))
+				continue
+			}
+			text := strings.ReplaceAll(buf.String(), "<no value>", "")
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			out = append(out, helmRendered{
+				Path:    name,
+				Content: text,
+				Lines:   alignTemplateLines(string(u.chart.files[p]), text),
+			})
+		}
+	}
+	return out, errs
+}
+
+// isManifestTemplate reports whether a template file renders a manifest.
+// Partials (_*.tpl) and NOTES.txt are skipped, as Helm does.
+func isManifestTemplate(base string) bool {
+	if strings.HasPrefix(base, "_") {
+		return false
+	}
+	switch path.Ext(base) {
+	case ".yaml", ".yml", ".tpl", ".json":
+		return true
+	}
+	return false
+}
+
+// helmUnit is one chart to execute with its effective values.
+type helmUnit struct {
+	chart  *helmChart
+	values map[string]interface{}
+}
+
+type helmRenderer struct {
+	tmpl  *template.Template
+	units []helmUnit
+	depth int
+}
+
+// collect records chart and its enabled subcharts with their scoped values.
+// A subchart sees its own values.yaml overlaid with the parent's
+// values[<alias or name>], plus the parent's `global` block.
+func (r *helmRenderer) collect(c *helmChart, vals map[string]interface{}, errs *[]error) {
+	r.units = append(r.units, helmUnit{chart: c, values: vals})
+
+	deps := c.dependencies()
+	present := make(map[string]*helmChart, len(c.subcharts))
+	for _, sc := range c.subcharts {
+		present[sc.name] = sc
+	}
+	seen := make(map[string]bool)
+	for _, d := range deps {
+		sc, ok := present[d.Name]
+		if !ok {
+			*errs = append(*errs, fmt.Errorf("%sChart.yaml: dependency %q is not vendored under charts/ (run `helm dependency build`)", c.prefix, d.Name))
+			continue
+		}
+		seen[d.Name] = true
+		if d.Condition != "" && !helmConditionTrue(vals, d.Condition) {
+			continue
+		}
+		key := d.Name
+		if d.Alias != "" {
+			key = d.Alias
+		}
+		r.collect(subchartView(sc, key), subchartValues(sc, vals, key), errs)
+	}
+	// Vendored charts not listed as dependencies are still rendered.
+	for _, sc := range c.subcharts {
+		if !seen[sc.name] {
+			r.collect(sc, subchartValues(sc, vals, sc.name), errs)
+		}
+	}
+}
+
+// subchartView returns sc renamed to alias (Helm exposes the alias as
+// .Chart.Name); the original is returned when no alias is set.
+func subchartView(sc *helmChart, alias string) *helmChart {
+	if alias == sc.name {
+		return sc
+	}
+	cp := *sc
+	cp.name = alias
+	return &cp
+}
+
+func subchartValues(sc *helmChart, parent map[string]interface{}, key string) map[string]interface{} {
+	vals := mergeValues(map[string]interface{}{}, sc.values)
+	if scoped, ok := parent[key].(map[string]interface{}); ok {
+		vals = mergeValues(vals, scoped)
+	}
+	if global, ok := parent["global"].(map[string]interface{}); ok {
+		g, _ := vals["global"].(map[string]interface{})
+		vals["global"] = mergeValues(mergeValues(map[string]interface{}{}, g), global)
+	}
+	return vals
+}
+
+// helmConditionTrue evaluates a dependency condition: a comma-separated
+// list of value paths, the first one that resolves to a bool wins. An
+// unresolvable condition leaves the subchart enabled.
+func helmConditionTrue(vals map[string]interface{}, cond string) bool {
+	for _, p := range strings.Split(cond, ",") {
+		var cur interface{} = vals
+		for _, seg := range strings.Split(strings.TrimSpace(p), ".") {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				cur = nil
+				break
+			}
+			cur = m[seg]
+		}
+		if b, ok := cur.(bool); ok {
+			return b
+		}
+	}
+	return true
+}
+
+// helmFiles backs .Files.
+type helmFiles map[string][]byte
+
+// Get returns a chart file's contents, or "" when absent.
+func (f helmFiles) Get(name string) string { return string(f[name]) }
+
+// GetBytes returns a chart file's contents as bytes.
+func (f helmFiles) GetBytes(name string) []byte { return f[name] }
+
+// Lines returns a chart file split into lines.
+func (f helmFiles) Lines(name string) []string {
+	return strings.Split(strings.TrimRight(string(f[name]), "\n"), "\n")
+}
+
+// Glob returns the files matching a shell pattern.
+func (f helmFiles) Glob(pattern string) helmFiles {
+	out := helmFiles{}
+	for name, data := range f {
+		if ok, _ := path.Match(pattern, name); ok {
+			out[name] = data
+		}
+	}
+	return out
+}
+
+// AsConfig renders the files as a ConfigMap data block.
+func (f helmFiles) AsConfig() string {
+	names := make([]string, 0, len(f))
+	for n := range f {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	m := make(map[string]interface{}, len(f))
+	for _, n := range names {
+		m[path.Base(n)] = string(f[n])
+	}
+	s, _ := helmToYAML(m)
+	return s
+}
+
+// --- template/line alignment ---
+
+var (
+	helmActionRe  = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	helmControlRe = regexp.MustCompile(`^\{\{-?\s*(?:if|else|end|range|with|define|/\*|\$[A-Za-z0-9_]*\s*:?=)`)
+)
+
+// templateLine describes one template source line for alignment.
+type templateLine struct {
+	fragments []string // literal text between actions, trimmed, non-empty
+	output    bool     // the line is a lone action that emits text (toYaml, include, ...)
+	lead      bool     // the line starts with literal text rather than an action
+}
+
+// alignTemplateLines maps each line of rendered output back to the
+// template line that produced it. text/template keeps no positions in its
+// output, so this is a best-effort alignment: a rendered line matches a
+// template line when the template's literal fragments appear in it in
+// order, searching forward from the previous match and wrapping around for
+// range loops. Lines produced by a lone output action (`{{ toYaml .x |
+// nindent 4 }}`) are attributed to that action's line.
+func alignTemplateLines(tmpl, rendered string) []int {
+	// Replace actions with a NUL but keep their newlines so line numbers
+	// stay aligned with the source.
+	masked := helmActionRe.ReplaceAllStringFunc(tmpl, func(a string) string {
+		return "\x00" + strings.Repeat("\n", strings.Count(a, "\n"))
+	})
+	srcLines := strings.Split(tmpl, "\n")
+	maskedLines := strings.Split(masked, "\n")
+	lines := make([]templateLine, len(maskedLines))
+	for i, ml := range maskedLines {
+		var tl templateLine
+		trimmed := strings.TrimSpace(ml)
+		tl.lead = trimmed != "" && trimmed[0] != 0
+		for _, f := range strings.Split(ml, "\x00") {
+			if f = strings.TrimSpace(f); f != "" {
+				tl.fragments = append(tl.fragments, f)
+			}
+		}
+		if len(tl.fragments) == 0 && strings.Contains(ml, "\x00") && i < len(srcLines) {
+			tl.output = !helmControlRe.MatchString(strings.TrimSpace(srcLines[i]))
+		}
+		lines[i] = tl
+	}
+
+	out := strings.Split(rendered, "\n")
+	result := make([]int, len(out))
+	ptr := 0
+	for ri, rl := range out {
+		rt := strings.TrimSpace(rl)
+		if rt == "" || rt == "---" {
+			continue
+		}
+		match := -1
+		for pass := 0; pass < 2 && match < 0; pass++ {
+			from, to := ptr, len(lines)
+			if pass == 1 {
+				from, to = 0, ptr
+			}
+			for j := from; j < to; j++ {
+				if lines[j].matches(rt) {
+					match = j
+					break
+				}
+			}
+		}
+		if match < 0 {
+			for j := ptr; j < len(lines); j++ {
+				if lines[j].output {
+					match = j
+					break
+				}
+			}
+		}
+		if match >= 0 {
+			result[ri] = match + 1
+			ptr = match
+		}
+	}
+	return result
+}
+
+func (tl templateLine) matches(line string) bool {
+	if len(tl.fragments) == 0 {
+		return false
+	}
+	if tl.lead && !strings.HasPrefix(line, tl.fragments[0]) {
+		return false
+	}
+	rest := line
+	for _, f := range tl.fragments {
+		i := strings.Index(rest, f)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(f):]
+	}
+	return true
+}
@@ -4,6 +4,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+	"github.com/dormstern/segspec/internal/parser"
 )
 
 func TestRenderHelmTemplate(t *testing.T) {
@@ -31,3 +34,155 @@ func TestRenderHelmTemplate_NotInstalled(t *testing.T) {
 		t.Errorf("error should mention helm: %v", err)
 	}
 }
+
+func findDep(ds *model.DependencySet, source, target string, port int) (model.NetworkDependency, bool) {
+	for _, d := range ds.Dependencies() {
+		if d.Source == source && d.Target == target && d.Port == port {
+			return d, true
+		}
+	}
+	return model.NetworkDependency{}, false
+}
+
+func findListener(ds *model.DependencySet, workload string, port int) (model.Listener, bool) {
+	for _, l := range ds.ListenersFor(workload) {
+		if l.Port == port {
+			return l, true
+		}
+	}
+	return model.Listener{}, false
+}
+
+func TestWalkHelmRendersInProcess(t *testing.T) {
+	// No helm on PATH: the built-in renderer must not need it.
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", "")
+	defer os.Setenv("PATH", origPath)
+
+	ds, warnings, err := Walk("testdata/helm-app", parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	l, ok := findListener(ds, "myapp", 8080)
+	if !ok {
+		t.Fatalf("missing rendered container port; got %v", ds.Listeners())
+	}
+	if l.SourceFile != "templates/deployment.yaml" || l.Line != 12 {
+		t.Errorf("evidence = %s, want templates/deployment.yaml:12", l.Location())
+	}
+}
+
+func TestWalkHelmSubchartsAndHelpers(t *testing.T) {
+	ds, warnings, err := Walk("testdata/helm-umbrella", parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	// Listeners: a name from a _helpers.tpl define, and a subchart with
+	// parent-scoped values.
+	for _, c := range []struct {
+		workload string
+		port     int
+		file     string
+		line     int
+	}{
+		{"shop-api", 8080, "templates/deployment.yaml", 15},
+		{"shop-redis", 6379, "charts/redis/templates/service.yaml", 7},
+	} {
+		l, ok := findListener(ds, c.workload, c.port)
+		if !ok {
+			t.Errorf("missing listener %s:%d", c.workload, c.port)
+			continue
+		}
+		if l.SourceFile != c.file || l.Line != c.line {
+			t.Errorf("%s:%d evidence = %s, want %s:%d", c.workload, c.port, l.Location(), c.file, c.line)
+		}
+	}
+
+	checks := []struct {
+		source, target string
+		port           int
+		file           string
+		line           int
+	}{
+		{"shop-api", "orders-db", 5432, "templates/deployment.yaml", 18}, // printf over values
+		{"shop-api", "payments", 9000, "templates/deployment.yaml", 21},  // inside a range loop
+	}
+	for _, c := range checks {
+		d, ok := findDep(ds, c.source, c.target, c.port)
+		if !ok {
+			t.Errorf("missing %s -> %s:%d", c.source, c.target, c.port)
+			continue
+		}
+		if d.SourceFile != c.file || d.Line != c.line {
+			t.Errorf("%s -> %s:%d evidence = %s, want %s:%d", c.source, c.target, c.port, d.Location(), c.file, c.line)
+		}
+	}
+	if _, ok := findListener(ds, "segspec-render-metrics", 9102); ok {
+		t.Error("metrics subchart rendered although its condition is false")
+	}
+
+	// configMapKeyRef resolved across two rendered templates, with both
+	// positions mapped back to template lines.
+	search, ok := findDep(ds, "shop-api", "search", 9200)
+	if !ok {
+		t.Fatal("configMapKeyRef into the chart's ConfigMap not resolved")
+	}
+	var locs []string
+	for _, p := range search.Provenance {
+		locs = append(locs, p.Location())
+	}
+	if got := strings.Join(locs, " "); got != "templates/configmap.yaml:6 templates/deployment.yaml:25" {
+		t.Errorf("provenance = %s", got)
+	}
+}
+
+func TestWalkHelmSetOverrides(t *testing.T) {
+	opts := WalkOptions{HelmSet: []string{"service.port=9090,metrics.enabled=true"}}
+	ds, _, err := Walk("testdata/helm-umbrella", parser.DefaultRegistry(), opts)
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if _, ok := findListener(ds, "shop-api", 9090); !ok {
+		t.Error("--set service.port=9090 not applied")
+	}
+	if _, ok := findListener(ds, "shop-api", 8080); ok {
+		t.Error("default port still present after --set override")
+	}
+	if _, ok := findListener(ds, "segspec-render-metrics", 9102); !ok {
+		t.Error("--set metrics.enabled=true did not enable the subchart")
+	}
+}
+
+func TestParseHelmSet(t *testing.T) {
+	vals := map[string]interface{}{"image": map[string]interface{}{"tag": "latest"}, "drop": "me"}
+	if err := parseHelmSet(vals, `image.repository=app,replicas=3,debug=true,hosts={a,b},servers[1].port=80,drop=null,note=a\,b`); err != nil {
+		t.Fatalf("parseHelmSet: %v", err)
+	}
+	image := vals["image"].(map[string]interface{})
+	if image["repository"] != "app" || image["tag"] != "latest" {
+		t.Errorf("image = %v", image)
+	}
+	if vals["replicas"] != int64(3) || vals["debug"] != true || vals["note"] != "a,b" {
+		t.Errorf("scalars = %v %v %v", vals["replicas"], vals["debug"], vals["note"])
+	}
+	if hosts := vals["hosts"].([]interface{}); len(hosts) != 2 || hosts[1] != "b" {
+		t.Errorf("hosts = %v", hosts)
+	}
+	servers := vals["servers"].([]interface{})
+	if len(servers) != 2 || servers[1].(map[string]interface{})["port"] != int64(80) {
+		t.Errorf("servers = %v", servers)
+	}
+	if _, ok := vals["drop"]; ok {
+		t.Error("null did not remove the key")
+	}
+	if err := parseHelmSet(vals, "novalue"); err == nil {
+		t.Error("expected an error for an assignment without '='")
+	}
+}
@@ -0,0 +1,859 @@
+package walker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFiles are the file names kustomize accepts, in lookup order.
+var kustomizationFiles = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomization mirrors the subset of kustomization.yaml that changes which
+// workloads exist and how they are wired: resources, patches, generators and
+// the name/namespace transformers. Image, label and replica transformers
+// don't affect network edges and are ignored.
+type kustomization struct {
+	Resources             []string             `yaml:"resources"`
+	Bases                 []string             `yaml:"bases"`
+	Components            []string             `yaml:"components"`
+	Namespace             string               `yaml:"namespace"`
+	NamePrefix            string               `yaml:"namePrefix"`
+	NameSuffix            string               `yaml:"nameSuffix"`
+	PatchesStrategicMerge []string             `yaml:"patchesStrategicMerge"`
+	PatchesJSON6902       []kustomizePatch     `yaml:"patchesJson6902"`
+	Patches               []kustomizePatch     `yaml:"patches"`
+	ConfigMapGenerator    []kustomizeGenerator `yaml:"configMapGenerator"`
+	SecretGenerator       []kustomizeGenerator `yaml:"secretGenerator"`
+}
+
+type kustomizePatch struct {
+	Path   string           `yaml:"path"`
+	Patch  string           `yaml:"patch"`
+	Target *kustomizeTarget `yaml:"target"`
+}
+
+type kustomizeTarget struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type kustomizeGenerator struct {
+	Name      string   `yaml:"name"`
+	Namespace string   `yaml:"namespace"`
+	Behavior  string   `yaml:"behavior"`
+	Literals  []string `yaml:"literals"`
+	Files     []string `yaml:"files"`
+	Envs      []string `yaml:"envs"`
+	Env       string   `yaml:"env"`
+}
+
+// kresource is one object in a kustomize build. origName is the name the
+// object was first declared with, so overlay patches written against the
+// base name still match after a base-level namePrefix.
+type kresource struct {
+	obj      map[string]interface{}
+	origName string
+}
+
+func (r *kresource) kind() string {
+	k, _ := r.obj["kind"].(string)
+	return k
+}
+
+func (r *kresource) meta() map[string]interface{} {
+	m, ok := r.obj["metadata"].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		r.obj["metadata"] = m
+	}
+	return m
+}
+
+func (r *kresource) name() string {
+	n, _ := r.meta()["name"].(string)
+	return n
+}
+
+// clusterScopedKinds never receive the kustomization namespace.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                true,
+	"ClusterRole":              true,
+	"ClusterRoleBinding":       true,
+	"CustomResourceDefinition": true,
+	"PersistentVolume":         true,
+	"StorageClass":             true,
+	"PriorityClass":            true,
+}
+
+// kustomizeBuilder renders kustomizations in-process. It records every file
+// a kustomization pulls in (consumed) so the loose walk can skip them, and
+// every kustomization directory reached through another (referenced) so
+// only the top-level overlays are rendered by default.
+type kustomizeBuilder struct {
+	consumed   map[string]bool
+	referenced map[string]bool
+	visiting   map[string]bool
+	warnings   []error
+}
+
+func newKustomizeBuilder() *kustomizeBuilder {
+	return &kustomizeBuilder{
+		consumed:   make(map[string]bool),
+		referenced: make(map[string]bool),
+		visiting:   make(map[string]bool),
+	}
+}
+
+// findKustomization returns the kustomization file in dir, or "".
+func findKustomization(dir string) string {
+	for _, name := range kustomizationFiles {
+		p := filepath.Join(dir, name)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// detectKustomizations finds directories containing a kustomization file.
+func detectKustomizations(root string) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, name := range kustomizationFiles {
+			if d.Name() == name {
+				dirs = append(dirs, filepath.Dir(path))
+				break
+			}
+		}
+		return nil
+	})
+	return dirs
+}
+
+// build renders the kustomization in dir into its resulting objects.
+func (kb *kustomizeBuilder) build(dir string) ([]*kresource, error) {
+	dir = filepath.Clean(dir)
+	kfile := findKustomization(dir)
+	if kfile == "" {
+		return nil, fmt.Errorf("%s: no kustomization file", dir)
+	}
+	if kb.visiting[dir] {
+		return nil, fmt.Errorf("%s: kustomization cycle", dir)
+	}
+	kb.visiting[dir] = true
+	defer delete(kb.visiting, dir)
+	kb.consumed[kfile] = true
+
+	data, err := os.ReadFile(kfile)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", kfile, err)
+	}
+	var k kustomization
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", kfile, err)
+	}
+
+	var res []*kresource
+	refs := append(append(append([]string{}, k.Resources...), k.Bases...), k.Components...)
+	for _, ref := range refs {
+		if isRemoteKustomizeRef(ref) {
+			kb.warnings = append(kb.warnings, fmt.Errorf("%s: remote resource %q skipped", kfile, ref))
+			continue
+		}
+		p := filepath.Join(dir, ref)
+		info, statErr := os.Stat(p)
+		if statErr != nil {
+			kb.warnings = append(kb.warnings, fmt.Errorf("%s: resource %q: %w", kfile, ref, statErr))
+			continue
+		}
+		if info.IsDir() {
+			kb.referenced[filepath.Clean(p)] = true
+			sub, subErr := kb.build(p)
+			if subErr != nil {
+				kb.warnings = append(kb.warnings, subErr)
+				continue
+			}
+			res = append(res, sub...)
+			continue
+		}
+		objs, loadErr := kb.loadFile(p)
+		if loadErr != nil {
+			kb.warnings = append(kb.warnings, loadErr)
+			continue
+		}
+		for _, o := range objs {
+			r := &kresource{obj: o}
+			r.origName = r.name()
+			res = append(res, r)
+		}
+	}
+
+	for _, g := range k.ConfigMapGenerator {
+		res = kb.generate(res, dir, "ConfigMap", g)
+	}
+	for _, g := range k.SecretGenerator {
+		res = kb.generate(res, dir, "Secret", g)
+	}
+
+	for _, entry := range k.PatchesStrategicMerge {
+		docs, patchErr := kb.patchDocs(dir, entry, "")
+		if patchErr != nil {
+			kb.warnings = append(kb.warnings, patchErr)
+			continue
+		}
+		for _, d := range docs {
+			if m, ok := d.(map[string]interface{}); ok {
+				applyStrategicPatch(res, m, nil)
+			}
+		}
+	}
+	for _, p := range append(append([]kustomizePatch{}, k.PatchesJSON6902...), k.Patches...) {
+		docs, patchErr := kb.patchDocs(dir, p.Path, p.Patch)
+		if patchErr != nil {
+			kb.warnings = append(kb.warnings, patchErr)
+			continue
+		}
+		for _, d := range docs {
+			switch v := d.(type) {
+			case []interface{}:
+				if p.Target == nil {
+					kb.warnings = append(kb.warnings, fmt.Errorf("%s: JSON6902 patch without target", kfile))
+					continue
+				}
+				for _, r := range res {
+					if matchesTarget(r, p.Target) {
+						if err := applyJSONPatch(r.obj, deepCopyValue(v).([]interface{})); err != nil {
+							kb.warnings = append(kb.warnings, fmt.Errorf("%s: %w", kfile, err))
+						}
+					}
+				}
+			case map[string]interface{}:
+				applyStrategicPatch(res, v, p.Target)
+			}
+		}
+	}
+
+	transformNames(res, k)
+	return res, nil
+}
+
+// isRemoteKustomizeRef reports whether a resource entry points at a git or
+// HTTP location; segspec stays offline and skips those.
+func isRemoteKustomizeRef(ref string) bool {
+	return strings.Contains(ref, "://") || strings.HasPrefix(ref, "github.com/") ||
+		strings.HasPrefix(ref, "git@") || strings.Contains(ref, "?ref=")
+}
+
+// loadFile decodes every object in a multi-document YAML file.
+func (kb *kustomizeBuilder) loadFile(path string) ([]map[string]interface{}, error) {
+	kb.consumed[filepath.Clean(path)] = true
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var out []map[string]interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if doc != nil {
+			out = append(out, doc)
+		}
+	}
+	return out, nil
+}
+
+// patchDocs returns the decoded documents of a patch given either as a file
+// path relative to dir or inline. patchesStrategicMerge also accepts inline
+// YAML in the path slot, which is detected by the absence of such a file.
+func (kb *kustomizeBuilder) patchDocs(dir, path, inline string) ([]interface{}, error) {
+	var data []byte
+	if path != "" {
+		p := filepath.Join(dir, path)
+		if b, err := os.ReadFile(p); err == nil {
+			kb.consumed[filepath.Clean(p)] = true
+			data = b
+		} else if strings.Contains(path, "\n") {
+			data = []byte(path)
+		} else {
+			return nil, fmt.Errorf("reading patch %s: %w", p, err)
+		}
+	} else {
+		data = []byte(inline)
+	}
+	var docs []interface{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// generate applies one configMapGenerator/secretGenerator entry. Generated
+// names get no content-hash suffix: segspec resolves references by name
+// within the build, so the suffix would only add noise to evidence.
+func (kb *kustomizeBuilder) generate(res []*kresource, dir, kind string, g kustomizeGenerator) []*kresource {
+	data := map[string]string{}
+	for _, lit := range g.Literals {
+		if k, v, ok := strings.Cut(lit, "="); ok {
+			data[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	for _, f := range g.Files {
+		key, file := filepath.Base(f), f
+		if k, v, ok := strings.Cut(f, "="); ok {
+			key, file = k, v
+		}
+		b, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			kb.warnings = append(kb.warnings, fmt.Errorf("%s generator %s: %w", kind, g.Name, err))
+			continue
+		}
+		data[key] = string(b)
+	}
+	envs := g.Envs
+	if g.Env != "" {
+		envs = append(envs, g.Env)
+	}
+	for _, f := range envs {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			kb.warnings = append(kb.warnings, fmt.Errorf("%s generator %s: %w", kind, g.Name, err))
+			continue
+		}
+		sc := bufio.NewScanner(bytes.NewReader(b))
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if k, v, ok := strings.Cut(line, "="); ok {
+				data[strings.TrimSpace(k)] = strings.Trim(strings.TrimSpace(v), `"'`)
+			}
+		}
+	}
+
+	values := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if kind == "Secret" {
+			v = base64.StdEncoding.EncodeToString([]byte(v))
+		}
+		values[k] = v
+	}
+
+	if g.Behavior == "merge" || g.Behavior == "replace" {
+		for _, r := range res {
+			if r.kind() == kind && (r.name() == g.Name || r.origName == g.Name) {
+				existing, _ := r.obj["data"].(map[string]interface{})
+				if existing == nil || g.Behavior == "replace" {
+					existing = map[string]interface{}{}
+				}
+				for k, v := range values {
+					existing[k] = v
+				}
+				r.obj["data"] = existing
+				return res
+			}
+		}
+	}
+
+	meta := map[string]interface{}{"name": g.Name}
+	if g.Namespace != "" {
+		meta["namespace"] = g.Namespace
+	}
+	obj := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata":   meta,
+		"data":       values,
+	}
+	if kind == "Secret" {
+		obj["type"] = "Opaque"
+	}
+	return append(res, &kresource{obj: obj, origName: g.Name})
+}
+
+// matchesTarget reports whether r is selected by a patch target. Names are
+// matched against both the current and the originally declared name, and
+// may be regular expressions as in kustomize.
+func matchesTarget(r *kresource, t *kustomizeTarget) bool {
+	if t.Kind != "" && t.Kind != r.kind() {
+		return false
+	}
+	if t.Namespace != "" {
+		if ns, _ := r.meta()["namespace"].(string); ns != t.Namespace {
+			return false
+		}
+	}
+	if t.Name == "" {
+		return true
+	}
+	if t.Name == r.name() || t.Name == r.origName {
+		return true
+	}
+	re, err := regexp.Compile("^(?:" + t.Name + ")$")
+	return err == nil && (re.MatchString(r.name()) || re.MatchString(r.origName))
+}
+
+// applyStrategicPatch merges patch into every resource it selects: the
+// explicit target when given, else the resource with the patch's own kind
+// and name.
+func applyStrategicPatch(res []*kresource, patch map[string]interface{}, target *kustomizeTarget) {
+	if target == nil {
+		kind, _ := patch["kind"].(string)
+		name := ""
+		if m, ok := patch["metadata"].(map[string]interface{}); ok {
+			name, _ = m["name"].(string)
+		}
+		target = &kustomizeTarget{Kind: kind, Name: name}
+		if name == "" {
+			return
+		}
+	}
+	for _, r := range res {
+		if !matchesTarget(r, target) {
+			continue
+		}
+		// The patch's metadata.name identifies the target; it must not
+		// undo a prefix applied by a base.
+		p := deepCopyValue(patch).(map[string]interface{})
+		if m, ok := p["metadata"].(map[string]interface{}); ok {
+			delete(m, "name")
+		}
+		strategicMerge(r.obj, p)
+	}
+}
+
+// strategicMergeKeys are the fields used to match list items, tried in
+// order. Kubernetes declares these per field (containers by name, ports by
+// containerPort, volumeMounts by mountPath); picking the first key present
+// on the patch item covers the fields that matter for network edges.
+var strategicMergeKeys = []string{"name", "containerPort", "port", "mountPath", "ip"}
+
+// strategicMerge merges patch into dst following Kubernetes strategic-merge
+// semantics for the common cases: maps merge recursively, null deletes a
+// key, `$patch: replace|delete` directives are honoured, and lists of
+// objects merge item-by-item on their merge key. Lists of scalars replace.
+func strategicMerge(dst, patch map[string]interface{}) {
+	for k, pv := range patch {
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		if pv == nil {
+			delete(dst, k)
+			continue
+		}
+		switch p := pv.(type) {
+		case map[string]interface{}:
+			dm, ok := dst[k].(map[string]interface{})
+			if !ok || p["$patch"] == "replace" {
+				delete(p, "$patch")
+				dst[k] = p
+				continue
+			}
+			if p["$patch"] == "delete" {
+				delete(dst, k)
+				continue
+			}
+			strategicMerge(dm, p)
+		case []interface{}:
+			dl, ok := dst[k].([]interface{})
+			if !ok {
+				dst[k] = p
+				continue
+			}
+			dst[k] = mergeList(dl, p)
+		default:
+			dst[k] = pv
+		}
+	}
+}
+
+func mergeList(dst, patch []interface{}) []interface{} {
+	key := ""
+	for _, item := range patch {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return patch
+		}
+		for _, k := range strategicMergeKeys {
+			if _, ok := m[k]; ok {
+				key = k
+				break
+			}
+		}
+		if key != "" {
+			break
+		}
+	}
+	if key == "" {
+		return patch
+	}
+	for _, item := range patch {
+		pm := item.(map[string]interface{})
+		idx := -1
+		for i, d := range dst {
+			if dm, ok := d.(map[string]interface{}); ok && fmt.Sprint(dm[key]) == fmt.Sprint(pm[key]) {
+				idx = i
+				break
+			}
+		}
+		switch {
+		case pm["$patch"] == "delete":
+			if idx >= 0 {
+				dst = append(dst[:idx], dst[idx+1:]...)
+			}
+		case idx >= 0:
+			strategicMerge(dst[idx].(map[string]interface{}), pm)
+		default:
+			dst = append(dst, pm)
+		}
+	}
+	return dst
+}
+
+// applyJSONPatch applies RFC 6902 operations (add, remove, replace, move,
+// copy; test is ignored) to obj in place.
+func applyJSONPatch(obj map[string]interface{}, ops []interface{}) error {
+	var root interface{} = obj
+	for _, raw := range ops {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := op["op"].(string)
+		path, _ := op["path"].(string)
+		from, _ := op["from"].(string)
+		value := op["value"]
+		var err error
+		switch kind {
+		case "add", "replace", "remove":
+			root, err = jsonPatchAt(root, jsonPointer(path), kind, value)
+		case "move", "copy":
+			var v interface{}
+			v, err = jsonPointerGet(root, jsonPointer(from))
+			if err == nil && kind == "move" {
+				root, err = jsonPatchAt(root, jsonPointer(from), "remove", nil)
+			}
+			if err == nil {
+				root, err = jsonPatchAt(root, jsonPointer(path), "add", deepCopyValue(v))
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("json patch %s %s: %w", kind, path, err)
+		}
+	}
+	return nil
+}
+
+// jsonPointer splits an RFC 6901 pointer into unescaped tokens.
+func jsonPointer(p string) []string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil
+	}
+	parts := strings.Split(p, "/")
+	for i, s := range parts {
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+	}
+	return parts
+}
+
+func jsonPointerGet(node interface{}, tokens []string) (interface{}, error) {
+	for _, tok := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[tok]
+			if !ok {
+				return nil, fmt.Errorf("path not found")
+			}
+			node = v
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, fmt.Errorf("index %q out of range", tok)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("path not found")
+		}
+	}
+	return node, nil
+}
+
+// jsonPatchAt applies one add/replace/remove at tokens and returns the
+// (possibly reallocated) node.
+func jsonPatchAt(node interface{}, tokens []string, op string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		if op == "remove" {
+			return nil, fmt.Errorf("cannot remove document root")
+		}
+		return value, nil
+	}
+	tok, last := tokens[0], len(tokens) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, exists := n[tok]
+		if last {
+			switch {
+			case op == "add":
+				n[tok] = value
+			case !exists:
+				return nil, fmt.Errorf("path not found")
+			case op == "replace":
+				n[tok] = value
+			default:
+				delete(n, tok)
+			}
+			return n, nil
+		}
+		if !exists {
+			return nil, fmt.Errorf("path not found")
+		}
+		nc, err := jsonPatchAt(child, tokens[1:], op, value)
+		n[tok] = nc
+		return n, err
+	case []interface{}:
+		if tok == "-" {
+			if last && op == "add" {
+				return append(n, value), nil
+			}
+			return nil, fmt.Errorf("'-' is only valid for add")
+		}
+		i, err := strconv.Atoi(tok)
+		if err != nil || i < 0 || i > len(n) || (i == len(n) && !(last && op == "add")) {
+			return nil, fmt.Errorf("index %q out of range", tok)
+		}
+		if last {
+			switch op {
+			case "add":
+				n = append(n, nil)
+				copy(n[i+1:], n[i:])
+				n[i] = value
+			case "replace":
+				n[i] = value
+			default:
+				n = append(n[:i], n[i+1:]...)
+			}
+			return n, nil
+		}
+		nc, err := jsonPatchAt(n[i], tokens[1:], op, value)
+		n[i] = nc
+		return n, err
+	}
+	return nil, fmt.Errorf("path not found")
+}
+
+// transformNames applies namePrefix/nameSuffix and namespace to every
+// resource, then rewrites ConfigMap and Secret references inside the
+// resources so env/envFrom/volume lookups keep resolving after a rename.
+func transformNames(res []*kresource, k kustomization) {
+	renamed := map[string]map[string]string{"ConfigMap": {}, "Secret": {}}
+	for _, r := range res {
+		kind := r.kind()
+		meta := r.meta()
+		if (k.NamePrefix != "" || k.NameSuffix != "") && kind != "Namespace" && kind != "CustomResourceDefinition" {
+			old := r.name()
+			nn := k.NamePrefix + old + k.NameSuffix
+			meta["name"] = nn
+			if m, ok := renamed[kind]; ok {
+				m[old] = nn
+			}
+		}
+		if k.Namespace != "" && !clusterScopedKinds[kind] {
+			meta["namespace"] = k.Namespace
+		}
+	}
+	if len(renamed["ConfigMap"]) == 0 && len(renamed["Secret"]) == 0 {
+		return
+	}
+	for _, r := range res {
+		rewriteConfigRefs(r.obj, renamed["ConfigMap"], renamed["Secret"])
+	}
+}
+
+// rewriteConfigRefs walks an object and renames ConfigMap/Secret references
+// (configMapKeyRef, configMapRef, secretKeyRef, secretRef and configMap /
+// secret volumes).
+func rewriteConfigRefs(v interface{}, cms, secrets map[string]string) {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		for k, child := range n {
+			ref, isMap := child.(map[string]interface{})
+			if isMap {
+				var names map[string]string
+				field := "name"
+				switch k {
+				case "configMapKeyRef", "configMapRef", "configMap":
+					names = cms
+				case "secretKeyRef", "secretRef":
+					names = secrets
+				case "secret":
+					names, field = secrets, "secretName"
+				}
+				if names != nil {
+					if old, ok := ref[field].(string); ok {
+						if nn, ok := names[old]; ok {
+							ref[field] = nn
+						}
+					}
+				}
+			}
+			rewriteConfigRefs(child, cms, secrets)
+		}
+	case []interface{}:
+		for _, child := range n {
+			rewriteConfigRefs(child, cms, secrets)
+		}
+	}
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for k, c := range n {
+			out[k] = deepCopyValue(c)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, c := range n {
+			out[i] = deepCopyValue(c)
+		}
+		return out
+	}
+	return v
+}
+
+// renderKustomizeYAML serializes a build as a multi-document YAML stream,
+// ordered by kind and name so the output is stable across runs.
+func renderKustomizeYAML(res []*kresource) (string, error) {
+	sorted := make([]*kresource, len(res))
+	copy(sorted, res)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].kind() != sorted[j].kind() {
+			return sorted[i].kind() < sorted[j].kind()
+		}
+		return sorted[i].name() < sorted[j].name()
+	})
+	var b strings.Builder
+	for i, r := range sorted {
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		out, err := yaml.Marshal(r.obj)
+		if err != nil {
+			return "", err
+		}
+		b.Write(out)
+	}
+	return b.String(), nil
+}
+
+// kustomizePlan is the result of building every kustomization under a root.
+type kustomizePlan struct {
+	consumed map[string]bool    // files the loose walk must skip
+	renders  map[string]string  // kustomization dir -> rendered YAML
+	order    []string           // dirs to render, in walk order
+	warnings map[string][]error // kustomization dir -> build warnings
+}
+
+// planKustomize builds the kustomizations under root. With overlay empty,
+// every kustomization not referenced by another one (the top-level
+// overlays) is rendered; otherwise only the directories whose path relative
+// to root, or whose base name, equals overlay. Files pulled in by any
+// kustomization, selected or not, are reported as consumed so the loose
+// walk does not double-count a base or parse an unselected environment.
+func planKustomize(root, overlay string) (*kustomizePlan, error) {
+	plan := &kustomizePlan{
+		consumed: make(map[string]bool),
+		renders:  make(map[string]string),
+		warnings: make(map[string][]error),
+	}
+	dirs := detectKustomizations(root)
+	if len(dirs) == 0 {
+		if overlay != "" {
+			return plan, fmt.Errorf("no kustomization matches overlay %q", overlay)
+		}
+		return plan, nil
+	}
+
+	built := make(map[string][]*kresource)
+	referenced := make(map[string]bool)
+	for _, dir := range dirs {
+		kb := newKustomizeBuilder()
+		res, err := kb.build(dir)
+		for p := range kb.consumed {
+			plan.consumed[p] = true
+		}
+		for p := range kb.referenced {
+			referenced[p] = true
+		}
+		if err != nil {
+			plan.warnings[dir] = append(plan.warnings[dir], err)
+			continue
+		}
+		plan.warnings[dir] = append(plan.warnings[dir], kb.warnings...)
+		built[dir] = res
+	}
+
+	overlay = filepath.ToSlash(filepath.Clean(overlay))
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			rel = dir
+		}
+		rel = filepath.ToSlash(rel)
+		if overlay != "." {
+			if rel != overlay && filepath.Base(dir) != overlay {
+				continue
+			}
+		} else if referenced[filepath.Clean(dir)] {
+			continue
+		}
+		plan.order = append(plan.order, dir)
+		res, ok := built[dir]
+		if !ok {
+			continue
+		}
+		out, err := renderKustomizeYAML(res)
+		if err != nil {
+			plan.warnings[dir] = append(plan.warnings[dir], err)
+			continue
+		}
+		plan.renders[dir] = out
+	}
+	if overlay != "." && len(plan.order) == 0 {
+		return plan, fmt.Errorf("no kustomization matches overlay %q", overlay)
+	}
+	return plan, nil
+}
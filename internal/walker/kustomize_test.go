@@ -0,0 +1,151 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dormstern/segspec/internal/model"
+	"github.com/dormstern/segspec/internal/parser"
+)
+
+func hasDep(ds *model.DependencySet, source, target string, port int) bool {
+	for _, d := range ds.Dependencies() {
+		if d.Source == source && d.Target == target && d.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWalkKustomizeBuildsTopLevelOverlays(t *testing.T) {
+	ds, warnings, err := Walk("testdata/kustomize-app", parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	// Both overlays are rendered with their own names...
+	if _, ok := findListener(ds, "prod-api", 8080); !ok {
+		t.Error("missing prod overlay container port")
+	}
+	if _, ok := findListener(ds, "api-dev", 8080); !ok {
+		t.Error("missing dev overlay container port")
+	}
+	// ...and the base is neither rendered on its own nor parsed as loose YAML.
+	if len(ds.ListenersFor("api")) > 0 {
+		t.Error("base resources leaked into the result under their un-prefixed names")
+	}
+	for _, d := range ds.Dependencies() {
+		if strings.Contains(d.SourceFile, "base/") {
+			t.Errorf("dep %s cites base file %s; bases should only appear via an overlay build", d.Key(), d.SourceFile)
+		}
+	}
+	for _, l := range ds.Listeners() {
+		if strings.Contains(l.SourceFile, "base/") {
+			t.Errorf("listener %s cites base file %s; bases should only appear via an overlay build", l.Key(), l.SourceFile)
+		}
+	}
+}
+
+func TestWalkKustomizeOverlaySelection(t *testing.T) {
+	ds, warnings, err := Walk("testdata/kustomize-app", parser.DefaultRegistry(), WalkOptions{KustomizeOverlay: "prod"})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	checks := []struct {
+		source, target string
+		port           int
+		want           bool
+	}{
+		{"prod-api", "db-prod", 5432, true},       // strategic-merge patch replaced env value
+		{"prod-api", "db-dev", 5432, false},       // base value is gone
+		{"prod-api", "cache", 6379, true},         // base generator literal, via envFrom
+		{"prod-api", "kafka", 9092, true},         // overlay generator merge, via envFrom
+		{"prod-api-config", "cache", 6379, false}, // resolved ConfigMap entries move to the consumer
+		{"prod-api-config", "kafka", 9092, false},
+	}
+	for _, c := range checks {
+		if got := hasDep(ds, c.source, c.target, c.port); got != c.want {
+			t.Errorf("%s -> %s:%d present = %v, want %v", c.source, c.target, c.port, got, c.want)
+		}
+	}
+	for _, c := range []struct {
+		workload string
+		port     int
+		want     bool
+	}{
+		{"prod-api", 9090, true}, // patch appended a container port
+		{"prod-api", 443, true},  // JSON6902 patch on the Service
+		{"api-dev", 8080, false}, // dev overlay not selected
+	} {
+		if _, got := findListener(ds, c.workload, c.port); got != c.want {
+			t.Errorf("listener %s:%d present = %v, want %v", c.workload, c.port, got, c.want)
+		}
+	}
+	for _, d := range ds.Dependencies() {
+		if d.SourceFile != "overlays/prod/kustomization.yaml (kustomize build)" {
+			t.Errorf("dep %s SourceFile = %q", d.Key(), d.SourceFile)
+		}
+		if d.Parser != "k8s" {
+			t.Errorf("dep %s Parser = %q, want k8s", d.Key(), d.Parser)
+		}
+	}
+	for _, l := range ds.Listeners() {
+		if l.Parser != "k8s" {
+			t.Errorf("listener %s Parser = %q, want k8s", l.Key(), l.Parser)
+		}
+	}
+}
+
+func TestWalkKustomizeUnknownOverlayWarns(t *testing.T) {
+	_, warnings, err := Walk("testdata/kustomize-app", parser.DefaultRegistry(), WalkOptions{KustomizeOverlay: "staging"})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	found := false
+	for _, w := range warnings {
+		if strings.Contains(w.Err.Error(), `overlay "staging"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a warning for the unknown overlay, got %v", warnings)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	obj := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"ports": []interface{}{
+				map[string]interface{}{"port": 80},
+			},
+		},
+	}
+	ops := []interface{}{
+		map[string]interface{}{"op": "add", "path": "/spec/ports/-", "value": map[string]interface{}{"port": 9090}},
+		map[string]interface{}{"op": "replace", "path": "/spec/ports/0/port", "value": 443},
+		map[string]interface{}{"op": "add", "path": "/metadata", "value": map[string]interface{}{"name": "x"}},
+		map[string]interface{}{"op": "copy", "from": "/metadata/name", "path": "/metadata/alias"},
+	}
+	if err := applyJSONPatch(obj, ops); err != nil {
+		t.Fatalf("applyJSONPatch: %v", err)
+	}
+	ports := obj["spec"].(map[string]interface{})["ports"].([]interface{})
+	if len(ports) != 2 || ports[0].(map[string]interface{})["port"] != 443 || ports[1].(map[string]interface{})["port"] != 9090 {
+		t.Errorf("ports = %v, want [443 9090]", ports)
+	}
+	if obj["metadata"].(map[string]interface{})["alias"] != "x" {
+		t.Errorf("copy op did not apply: %v", obj["metadata"])
+	}
+
+	bad := []interface{}{map[string]interface{}{"op": "remove", "path": "/spec/missing"}}
+	if err := applyJSONPatch(obj, bad); err == nil {
+		t.Error("expected an error removing a missing path")
+	}
+}
@@ -0,0 +1,7 @@
+{{- define "shop.fullname" -}}
+{{- printf "%s-api" .Chart.Name | trunc 63 | trimSuffix "-" -}}
+{{- end -}}
+
+{{- define "shop.labels" -}}
+app: {{ include "shop.fullname" . }}
+{{- end -}}
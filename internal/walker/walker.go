@@ -1,8 +1,10 @@
 package walker
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/dormstern/segspec/internal/model"
 	"github.com/dormstern/segspec/internal/parser"
@@ -16,6 +18,7 @@ var skippedDirs = map[string]bool{
 	".git":         true,
 	".svn":         true,
 	"__pycache__":  true,
+	".terraform":   true, // downloaded provider/module cache
 }
 
 // WalkWarning represents a non-fatal error encountered while walking.
@@ -26,6 +29,8 @@ type WalkWarning struct {
 }
 
 // detectHelmCharts finds directories containing Chart.yaml under root.
+// Subcharts vendored under a chart's charts/ directory are rendered with
+// their parent, so they are not reported as charts of their own.
 func detectHelmCharts(root string) []string {
 	var charts []string
 	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
@@ -33,7 +38,7 @@ func detectHelmCharts(root string) []string {
 			return nil
 		}
 		if d.IsDir() {
-			if skippedDirs[d.Name()] {
+			if skippedDirs[d.Name()] || (d.Name() == "charts" && isHelmChartDir(filepath.Dir(path))) {
 				return filepath.SkipDir
 			}
 			return nil
@@ -46,9 +51,22 @@ func detectHelmCharts(root string) []string {
 	return charts
 }
 
+// isHelmChartDir reports whether dir holds a Chart.yaml.
+func isHelmChartDir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "Chart.yaml"))
+	return err == nil && !info.IsDir()
+}
+
 // WalkOptions configures optional behavior for Walk.
 type WalkOptions struct {
-	HelmValuesFile string // Helm values file to use when rendering charts (optional)
+	HelmValuesFile    string   // Helm values file to use when rendering charts (optional)
+	HelmSet           []string // Helm --set overrides, applied after the values file (optional)
+	HelmBinary        bool     // render charts with the external helm binary instead of in-process
+	KustomizeOverlay  string   // Kustomize overlay to render, by path or directory name (optional; default: every top-level overlay)
+	SpringProfiles    []string // active Spring profiles (optional; default: the default config plus each profile found)
+	ComposeProfiles   []string // active Compose profiles (optional; default: every service)
+	ComposeOverrides  []string // Compose override files to layer on each base file, by environment or file name (optional; default: the override file)
+	DotnetEnvironment string   // active ASP.NET Core environment (optional; default: appsettings.json plus each appsettings.{Environment}.json found)
 }
 
 // Walk recursively scans root for files matching registered parsers,
@@ -64,6 +82,63 @@ func Walk(root string, registry *parser.Registry, opts ...WalkOptions) (*model.D
 	ds := model.NewDependencySet(serviceName)
 	var warnings []WalkWarning
 
+	// Build Kustomize overlays first: the files they consume are skipped
+	// by the loose walk below, so bases and patches aren't parsed as
+	// standalone manifests.
+	kplan, kerr := planKustomize(root, options.KustomizeOverlay)
+	if kerr != nil {
+		warnings = append(warnings, WalkWarning{File: options.KustomizeOverlay, Err: kerr})
+	}
+
+	// Chart templates aren't valid YAML until rendered, so they're left to
+	// the Helm pass below rather than parsed as loose manifests.
+	charts := detectHelmCharts(root)
+	chartDirs := make(map[string]bool, len(charts))
+	for _, c := range charts {
+		chartDirs[filepath.Clean(c)] = true
+	}
+
+	// ConfigMap/Secret references usually point into another file, so
+	// they're collected from every manifest and resolved after the walk.
+	refs := newK8sRefPass()
+
+	// Spring placeholders resolve against .env files and the compose/k8s
+	// env of the workload running the app, so Spring config is parsed
+	// after the walk too.
+	spring := parser.NewSpringIndex(parser.SpringOptions{Profiles: options.SpringProfiles})
+
+	// Compose files are merged with their overrides, so they're grouped
+	// during the walk and parsed per stack afterwards.
+	var composeFiles []string
+
+	// appsettings.{Environment}.json overlays the appsettings.json beside
+	// it, so .NET config is grouped by directory and parsed afterwards.
+	dotnetApps := make(map[string][]string)
+
+	// Entities parsers declare, for identity resolution at the end.
+	var entities []model.Entity
+
+	parseFile := func(path string) {
+		for _, fn := range registry.Match(path) {
+			res, parseErr := fn(path)
+			if parseErr != nil {
+				relPath, relErr := filepath.Rel(root, path)
+				if relErr != nil {
+					relPath = path
+				}
+				warnings = append(warnings, WalkWarning{File: relPath, Err: parseErr})
+				continue
+			}
+			addResult(ds, res, serviceName)
+			entities = append(entities, res.Entities...)
+		}
+	}
+
+	// Env files a compose service loads with env_file are that service's
+	// environment; read on their own they'd be attributed to the root
+	// service too. They're held back until the compose stacks are known.
+	var envFiles []string
+
 	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // skip inaccessible paths
@@ -72,59 +147,353 @@ func Walk(root string, registry *parser.Registry, opts ...WalkOptions) (*model.D
 			if skippedDirs[d.Name()] {
 				return filepath.SkipDir
 			}
+			if (d.Name() == "templates" || d.Name() == "charts") && chartDirs[filepath.Dir(filepath.Clean(path))] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if kplan.consumed[filepath.Clean(path)] {
+			return nil
+		}
+		if registry.MatchesFormat(path, "spring") {
+			spring.AddConfig(path)
 			return nil
 		}
 
-		parsers := registry.Match(path)
-		for _, fn := range parsers {
-			deps, parseErr := fn(path)
-			if parseErr != nil {
-				relPath, relErr := filepath.Rel(root, path)
-				if relErr != nil {
-					relPath = path
-				}
-				warnings = append(warnings, WalkWarning{File: relPath, Err: parseErr})
-				continue
-			}
-			for i := range deps {
-				if deps[i].Source == "" {
-					deps[i].Source = serviceName
-				}
-				ds.Add(deps[i])
-			}
+		if registry.MatchesFormat(path, "compose") {
+			composeFiles = append(composeFiles, path)
+			return nil
+		}
+
+		if registry.MatchesFormat(path, "dotnet") {
+			dir := filepath.Dir(filepath.Clean(path))
+			dotnetApps[dir] = append(dotnetApps[dir], path)
+			return nil
+		}
+
+		if registry.MatchesFormat(path, "envfile") {
+			envFiles = append(envFiles, path)
+		} else {
+			parseFile(path)
+		}
+		if registry.MatchesFormat(path, "k8s") {
+			refs.index.AddFile(path)
+			spring.AddManifest(path)
+		}
+		// Spring apps take their variables from a plain .env, as compose
+		// does; variants like .env.production are per-environment.
+		if filepath.Base(path) == ".env" {
+			spring.AddEnvFile(path)
 		}
 		return nil
 	})
 
-	// After normal file walk, detect and process Helm charts
-	charts := detectHelmCharts(root)
-	for _, chartDir := range charts {
-		rendered, renderErr := renderHelmTemplate(chartDir, options.HelmValuesFile)
-		if renderErr != nil {
-			relPath, relErr := filepath.Rel(root, chartDir)
-			if relErr != nil {
-				relPath = chartDir
-			}
-			warnings = append(warnings, WalkWarning{File: relPath + "/Chart.yaml", Err: renderErr})
+	for _, dir := range kplan.order {
+		relPath, relErr := filepath.Rel(root, dir)
+		if relErr != nil {
+			relPath = dir
+		}
+		kfile := filepath.Base(findKustomization(dir))
+		for _, w := range kplan.warnings[dir] {
+			warnings = append(warnings, WalkWarning{File: relPath + "/" + kfile, Err: w})
+		}
+		rendered, ok := kplan.renders[dir]
+		if !ok {
 			continue
 		}
+		sourceLabel := relPath + "/" + kfile + " (kustomize build)"
+		refs.addRendered(rendered, sourceLabel, nil)
+		res, parseErr := parser.ParseK8sContent(rendered, sourceLabel)
+		if parseErr != nil {
+			warnings = append(warnings, WalkWarning{File: relPath, Err: parseErr})
+			continue
+		}
+		// Positions refer to the rendered stream, not to any file a
+		// reviewer can open; drop them rather than point at the
+		// kustomization.
+		dropPositions(res)
+		addResult(ds, res, serviceName)
+	}
+
+	// After normal file walk, render Helm charts. Each template is parsed
+	// on its own so evidence cites templates/<file>:<line>.
+	for _, chartDir := range charts {
 		relPath, relErr := filepath.Rel(root, chartDir)
 		if relErr != nil {
 			relPath = chartDir
 		}
-		sourceLabel := relPath + "/Chart.yaml (helm template)"
-		deps, parseErr := parser.ParseK8sContent(rendered, sourceLabel)
+		if options.HelmBinary {
+			warnings = append(warnings, walkHelmBinary(ds, refs, chartDir, relPath, serviceName, options)...)
+			continue
+		}
+		rendered, renderErrs := renderHelmChart(chartDir, options.HelmValuesFile, options.HelmSet)
+		for _, rerr := range renderErrs {
+			warnings = append(warnings, WalkWarning{File: relPath + "/Chart.yaml", Err: rerr})
+		}
+		for _, f := range rendered {
+			sourceLabel := filepath.ToSlash(filepath.Join(relPath, f.Path))
+			refs.addRendered(f.Content, sourceLabel, f.Lines)
+			res, parseErr := parser.ParseK8sContent(f.Content, sourceLabel)
+			if parseErr != nil {
+				warnings = append(warnings, WalkWarning{File: sourceLabel, Err: parseErr})
+				continue
+			}
+			remapHelmLines(res, f.Lines)
+			addResult(ds, res, serviceName)
+		}
+	}
+
+	refs.dropConsumed(ds)
+	resolved, unresolved := refs.resolve()
+	for _, d := range resolved {
+		if d.Source == "" {
+			d.Source = serviceName
+		}
+		ds.Add(d)
+	}
+	for _, u := range unresolved {
+		file := u.File
+		if rel, relErr := filepath.Rel(root, file); relErr == nil && filepath.IsAbs(file) {
+			file = rel
+		}
+		warnings = append(warnings, WalkWarning{File: file, Err: u})
+	}
+
+	composeEnvFiles := make(map[string]bool)
+	stacks, unmatched, skipped := parser.ComposeStacks(composeFiles, options.ComposeOverrides)
+	for _, name := range unmatched {
+		warnings = append(warnings, WalkWarning{File: name, Err: fmt.Errorf("no compose file matches override %q", name)})
+	}
+	for _, f := range skipped {
+		relPath, relErr := filepath.Rel(root, f)
+		if relErr != nil {
+			relPath = f
+		}
+		warnings = append(warnings, WalkWarning{File: relPath, Err: fmt.Errorf("compose override not applied; pass --compose-override %s to layer it", filepath.Base(f))})
+	}
+	for _, stack := range stacks {
+		relPath, relErr := filepath.Rel(root, stack[0])
+		if relErr != nil {
+			relPath = stack[0]
+		}
+		res, parseErr := parser.ParseCompose(stack, parser.ComposeOptions{Profiles: options.ComposeProfiles})
 		if parseErr != nil {
 			warnings = append(warnings, WalkWarning{File: relPath, Err: parseErr})
 			continue
 		}
-		for i := range deps {
-			if deps[i].Source == "" {
-				deps[i].Source = serviceName
+		addResult(ds, res, serviceName)
+		entities = append(entities, res.Entities...)
+		spring.AddCompose(stack...)
+		consumed, _ := parser.ComposeEnvFiles(stack)
+		for _, f := range consumed {
+			composeEnvFiles[f] = true
+		}
+	}
+	for _, path := range envFiles {
+		if !composeEnvFiles[filepath.Clean(path)] {
+			parseFile(path)
+		}
+	}
+
+	dotnetDirs := make([]string, 0, len(dotnetApps))
+	for dir := range dotnetApps {
+		dotnetDirs = append(dotnetDirs, dir)
+	}
+	sort.Strings(dotnetDirs)
+	for _, dir := range dotnetDirs {
+		res, parseErr := parser.ParseAppSettings(dotnetApps[dir], parser.DotnetOptions{Environment: options.DotnetEnvironment})
+		if parseErr != nil {
+			relPath, relErr := filepath.Rel(root, dir)
+			if relErr != nil {
+				relPath = dir
 			}
-			ds.Add(deps[i])
+			warnings = append(warnings, WalkWarning{File: relPath, Err: parseErr})
+			continue
 		}
+		addResult(ds, res, serviceName)
 	}
 
+	springRes, springErrs := spring.Resolve()
+	addResult(ds, springRes, serviceName)
+	springDirs := make([]string, 0, len(springErrs))
+	for dir := range springErrs {
+		springDirs = append(springDirs, dir)
+	}
+	sort.Strings(springDirs)
+	for _, dir := range springDirs {
+		serr := springErrs[dir]
+		relPath, relErr := filepath.Rel(root, dir)
+		if relErr != nil {
+			relPath = dir
+		}
+		warnings = append(warnings, WalkWarning{File: relPath, Err: serr})
+	}
+
+	// With every dependency in, those on a Service move to the workloads
+	// it selects, and every other spelling of a workload (namespaced DNS
+	// names, Service names, addresses) folds into its canonical name.
+	refs.index.LinkServices(ds)
+	ds.ResolveIdentities(append(refs.index.Entities(), entities...))
+
 	return ds, warnings, err
 }
+
+// walkHelmBinary renders a chart with `helm template` and adds its
+// dependencies to ds. helm emits one stream, so deps cite Chart.yaml and
+// carry no line numbers.
+func walkHelmBinary(ds *model.DependencySet, refs *k8sRefPass, chartDir, relPath, serviceName string, options WalkOptions) []WalkWarning {
+	rendered, renderErr := renderHelmTemplate(chartDir, options.HelmValuesFile, options.HelmSet...)
+	if renderErr != nil {
+		return []WalkWarning{{File: relPath + "/Chart.yaml", Err: renderErr}}
+	}
+	sourceLabel := relPath + "/Chart.yaml (helm template)"
+	refs.addRendered(rendered, sourceLabel, nil)
+	res, parseErr := parser.ParseK8sContent(rendered, sourceLabel)
+	if parseErr != nil {
+		return []WalkWarning{{File: relPath, Err: parseErr}}
+	}
+	// Positions refer to the rendered stream, not to any file a
+	// reviewer can open; drop them rather than point at Chart.yaml.
+	dropPositions(res)
+	addResult(ds, res, serviceName)
+	return nil
+}
+
+// addResult adds a parser's dependencies and listeners to ds. Those the
+// parser couldn't attribute to a workload belong to the analyzed service.
+func addResult(ds *model.DependencySet, res parser.Result, serviceName string) {
+	for _, d := range res.Dependencies {
+		if d.Source == "" {
+			d.Source = serviceName
+		}
+		ds.Add(d)
+	}
+	for _, l := range res.Listeners {
+		if l.Workload == "" {
+			l.Workload = serviceName
+		}
+		ds.AddListener(l)
+	}
+}
+
+// dropPositions clears the line and column of every dep and listener in
+// res.
+func dropPositions(res parser.Result) {
+	for i := range res.Dependencies {
+		res.Dependencies[i].Line, res.Dependencies[i].Column = 0, 0
+	}
+	for i := range res.Listeners {
+		res.Listeners[i].Line, res.Listeners[i].Column = 0, 0
+	}
+}
+
+// remapHelmLines rewrites the rendered-output lines of res's deps and
+// listeners to the template lines that produced them (0 when alignment
+// failed). Columns are dropped: template expressions change widths, so
+// they'd rarely be right.
+func remapHelmLines(res parser.Result, lines []int) {
+	for i := range res.Dependencies {
+		d := &res.Dependencies[i]
+		d.Line, d.Column = sourceLine(lines, d.Line), 0
+		remapProvenance(d.Provenance, lines)
+	}
+	for i := range res.Listeners {
+		l := &res.Listeners[i]
+		l.Line, l.Column = sourceLine(lines, l.Line), 0
+		remapProvenance(l.Provenance, lines)
+	}
+}
+
+// remapProvenance is remapHelmLines for provenance records.
+func remapProvenance(records []model.Provenance, lines []int) {
+	for j := range records {
+		p := &records[j]
+		p.Line, p.Column = sourceLine(lines, p.Line), 0
+	}
+}
+
+// sourceLine maps a 1-based line of rendered output to its source line
+// through lines (see alignTemplateLines); nil lines map everything to 0.
+func sourceLine(lines []int, l int) int {
+	if l > 0 && l <= len(lines) {
+		return lines[l-1]
+	}
+	return 0
+}
+
+// k8sRefPass gathers manifests for ConfigMap/Secret reference resolution
+// and Service linking.
+// Rendered (Kustomize/Helm) content is indexed under its source label with
+// the alignment that maps its lines back to a file, so resolved evidence
+// gets the same positions as the directly parsed deps.
+type k8sRefPass struct {
+	index *parser.K8sRefIndex
+	lines map[string][]int
+}
+
+func newK8sRefPass() *k8sRefPass {
+	return &k8sRefPass{index: parser.NewK8sRefIndex(), lines: make(map[string][]int)}
+}
+
+func (p *k8sRefPass) addRendered(content, sourceLabel string, lines []int) {
+	p.index.Add([]byte(content), sourceLabel)
+	p.lines[sourceLabel] = lines
+}
+
+// resolve returns the resolved deps, and the references that resolve to
+// nothing, with rendered positions remapped.
+func (p *k8sRefPass) resolve() ([]model.NetworkDependency, []parser.UnresolvedRef) {
+	deps, unresolved := p.index.Resolve()
+	for i := range deps {
+		d := &deps[i]
+		if lines, ok := p.lines[d.SourceFile]; ok {
+			d.Line, d.Column = sourceLine(lines, d.Line), 0
+		}
+		for j := range d.Provenance {
+			pr := &d.Provenance[j]
+			if lines, ok := p.lines[pr.File]; ok {
+				pr.Line, pr.Column = sourceLine(lines, pr.Line), 0
+			}
+		}
+	}
+	for i := range unresolved {
+		u := &unresolved[i]
+		if lines, ok := p.lines[u.File]; ok {
+			u.Line = sourceLine(lines, u.Line)
+		}
+	}
+	return deps, unresolved
+}
+
+// dropConsumed removes the deps read straight from ConfigMap data that a
+// workload reference resolves: they belong to the consuming workload, and
+// left in they'd make the ConfigMap look like a workload of its own. A dep
+// is dropped only when every declaration of it is a consumed entry.
+func (p *k8sRefPass) dropConsumed(ds *model.DependencySet) {
+	consumed := make(map[string]bool)
+	for _, e := range p.index.Consumed() {
+		line := e.Line
+		if lines, ok := p.lines[e.File]; ok {
+			line = sourceLine(lines, line)
+		}
+		consumed[fmt.Sprintf("%s@%s:%d", e.Name, e.File, line)] = true
+	}
+	if len(consumed) == 0 {
+		return
+	}
+	ds.Rewrite(func(d model.NetworkDependency) []model.NetworkDependency {
+		if d.Parser != "k8s" {
+			return []model.NetworkDependency{d}
+		}
+		records := d.Provenance
+		if len(records) == 0 {
+			records = []model.Provenance{{File: d.SourceFile, Line: d.Line}}
+		}
+		for _, r := range records {
+			if !consumed[fmt.Sprintf("%s@%s:%d", d.Source, r.File, r.Line)] {
+				return []model.NetworkDependency{d}
+			}
+		}
+		return nil
+	}, nil)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 
@@ -173,14 +174,15 @@ func TestDetectHelmCharts(t *testing.T) {
 }
 
 func TestWalkHelmChartWarningWhenNoHelm(t *testing.T) {
-	// When helm is not installed, Walk should produce a warning for Helm charts
+	// When the helm binary is requested but not installed, Walk should
+	// produce a warning for Helm charts
 	// Save PATH and set to empty to simulate helm not found
 	origPath := os.Getenv("PATH")
 	os.Setenv("PATH", "")
 	defer os.Setenv("PATH", origPath)
 
 	r := parser.NewRegistry()
-	ds, warnings, err := Walk("testdata/helm-app", r)
+	ds, warnings, err := Walk("testdata/helm-app", r, WalkOptions{HelmBinary: true})
 	if err != nil {
 		t.Fatalf("Walk() error: %v", err)
 	}
@@ -209,7 +211,7 @@ func TestWalkOptionsPassesHelmValues(t *testing.T) {
 	defer os.Setenv("PATH", origPath)
 
 	r := parser.NewRegistry()
-	opts := WalkOptions{HelmValuesFile: "testdata/helm-app/values.yaml"}
+	opts := WalkOptions{HelmValuesFile: "testdata/helm-app/values.yaml", HelmBinary: true}
 	ds, warnings, err := Walk("testdata/helm-app", r, opts)
 	if err != nil {
 		t.Fatalf("Walk() error: %v", err)
@@ -255,3 +257,379 @@ func TestWalkNoWarningsWhenAllFilesParse(t *testing.T) {
 		t.Errorf("expected 0 warnings, got %d", len(warnings))
 	}
 }
+
+func TestWalkResolvesConfigMapRefsAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        envFrom:
+        - configMapRef:
+            name: api-config
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: api-config
+data:
+  BROKER: kafka:9092
+`), 0644)
+
+	ds, warnings, err := Walk(dir, parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	found := false
+	for _, d := range ds.Dependencies() {
+		if d.Port == 0 {
+			t.Errorf("port-0 reference dep %s still emitted", d.Key())
+		}
+		if d.Source == "api" && d.Target == "kafka" && d.Port == 9092 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("envFrom reference not resolved to kafka:9092; got %v", ds.Dependencies())
+	}
+}
+
+func TestWalkConfigMapRefsOwnershipAndWarnings(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      containers:
+      - name: api
+        env:
+        - name: BROKER
+          valueFrom:
+            configMapKeyRef:
+              name: api-config
+              key: BROKER
+        - name: DB_URL
+          valueFrom:
+            secretKeyRef:
+              name: api-db
+              key: url
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: api-config
+data:
+  BROKER: kafka:9092
+`), 0644)
+
+	ds, warnings, err := Walk(dir, parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	for _, d := range ds.Dependencies() {
+		if d.Source == "api-config" {
+			t.Errorf("ConfigMap left as a workload of its own: %s", d.Key())
+		}
+	}
+	if len(ds.Dependencies()) != 1 || ds.Dependencies()[0].Source != "api" {
+		t.Errorf("want only api -> kafka:9092, got %v", ds.Dependencies())
+	}
+	if len(warnings) != 1 || warnings[0].File != "deploy.yaml" || !strings.Contains(warnings[0].Err.Error(), "Secret api-db") {
+		t.Errorf("want a warning for the Secret outside the tree, got %v", warnings)
+	}
+}
+
+func TestWalkLinksServicesToBackingWorkloads(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "deploy.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  template:
+    metadata:
+      labels:
+        app: backend-pods
+    spec:
+      containers:
+      - name: backend
+        ports:
+        - name: http
+          containerPort: 8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: client
+spec:
+  template:
+    spec:
+      containers:
+      - name: client
+        env:
+        - name: API_URL
+          value: http://api:80
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "service.yaml"), []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: api
+spec:
+  type: NodePort
+  selector:
+    app: backend-pods
+  ports:
+  - port: 80
+    targetPort: http
+`), 0644)
+
+	ds, warnings, err := Walk(dir, parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	if _, ok := findDep(ds, "client", "backend", 8080); !ok {
+		t.Errorf("client -> api:80 not linked to backend:8080; got %v", ds.Dependencies())
+	}
+	l, ok := findListener(ds, "backend", 8080)
+	if !ok || l.Exposure != model.NodePort || l.Name != "http" {
+		t.Errorf("backend listener = %+v, want NodePort on http", l)
+	}
+	if _, ok := findListener(ds, "api", 80); ok {
+		t.Error("listener still on the Service port")
+	}
+}
+
+func TestWalkResolvesIdentities(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "redis.yaml"), []byte(`apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: redis-master
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: redis
+    spec:
+      containers:
+      - name: redis
+        ports:
+        - containerPort: 6379
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: redis
+  namespace: default
+spec:
+  clusterIP: 10.0.0.4
+  selector:
+    app: redis
+  ports:
+  - port: 6379
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - name: CACHE_URL
+          value: redis://redis.default.svc.cluster.local:6379
+        - name: SESSION_STORE
+          value: redis-master:6379
+        - name: REDIS_HOST
+          value: 10.0.0.4:6379
+`), 0644)
+
+	ds, _, err := Walk(dir, parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	var toRedis []model.NetworkDependency
+	for _, d := range ds.Dependencies() {
+		if d.Source == "app" {
+			toRedis = append(toRedis, d)
+		}
+	}
+	if len(toRedis) != 1 || toRedis[0].Key() != "app->redis-master:6379/TCP" {
+		t.Fatalf("app deps = %v, want one edge to redis-master:6379", toRedis)
+	}
+	want := []string{"10.0.0.4", "redis.default.svc.cluster.local"}
+	if fmt.Sprint(toRedis[0].Aliases) != fmt.Sprint(want) {
+		t.Errorf("aliases = %v, want %v", toRedis[0].Aliases, want)
+	}
+}
+
+func TestWalkResolvesSpringPlaceholdersFromCompose(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "orders"), 0755)
+	os.WriteFile(filepath.Join(dir, "orders", "application-prod.yml"), []byte(`spring:
+  redis:
+    host: ${REDIS_HOST:localhost}
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "orders", "application.yml"), []byte(`spring:
+  application:
+    name: orders
+  datasource:
+    url: jdbc:postgresql://${DB_HOST:localhost}:5432/orders
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(`services:
+  orders-api:
+    container_name: orders
+    image: example/orders
+    environment:
+      - DB_HOST=orders-db
+`), 0644)
+
+	ds, warnings, err := Walk(dir, parser.DefaultRegistry(), WalkOptions{SpringProfiles: []string{"default"}})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	var db *model.NetworkDependency
+	for _, d := range ds.Dependencies() {
+		if d.Target == "localhost" {
+			t.Errorf("placeholder default used despite compose environment: %s", d.Key())
+		}
+		if d.Target == "orders-db" && d.Port == 5432 {
+			d := d
+			db = &d
+		}
+	}
+	if db == nil {
+		t.Fatalf("datasource not resolved from compose environment; got %v", ds.Dependencies())
+	}
+	if db.Parser != "spring" || !strings.Contains(db.EvidenceLine, "(resolved: jdbc:postgresql://orders-db:5432/orders)") {
+		t.Errorf("parser = %q, evidence = %q", db.Parser, db.EvidenceLine)
+	}
+}
+
+func TestWalkLayersAppSettingsEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "Orders"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "Orders", "appsettings.json"), []byte(`{
+  "ConnectionStrings": { "Orders": "Server=sql-dev,1433;Database=orders" },
+  "Redis": { "Configuration": "redis:6379" }
+}`), 0644)
+	os.WriteFile(filepath.Join(dir, "src", "Orders", "appsettings.Production.json"), []byte(`{
+  "ConnectionStrings": { "Orders": "Server=sql-prod,1433;Database=orders" }
+}`), 0644)
+
+	ds, warnings, err := Walk(dir, parser.DefaultRegistry(), WalkOptions{DotnetEnvironment: "Production"})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	source := filepath.Base(dir)
+	if _, ok := findDep(ds, source, "sql-dev", 1433); ok {
+		t.Error("base connection string kept despite the Production overlay")
+	}
+	prod, ok := findDep(ds, source, "sql-prod", 1433)
+	if !ok || prod.Environment != "production" || prod.Parser != "dotnet" {
+		t.Errorf("sql-prod = %+v (found %v), want a production dotnet dep", prod, ok)
+	}
+	if _, ok := findDep(ds, source, "redis", 6379); !ok {
+		t.Error("base setting not carried into the Production view")
+	}
+}
+
+func TestWalkMergesComposeOverrides(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(`services:
+  api:
+    image: example/api
+    environment:
+      DATABASE_URL: postgres://db-base:5432/app
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "docker-compose.override.yml"), []byte(`services:
+  api:
+    environment:
+      DATABASE_URL: postgres://db-dev:5432/app
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "docker-compose.prod.yml"), []byte(`services:
+  api:
+    environment:
+      DATABASE_URL: postgres://db-prod:5432/app
+`), 0644)
+
+	targets := func(opts WalkOptions) []string {
+		ds, warnings, err := Walk(dir, parser.DefaultRegistry(), opts)
+		if err != nil {
+			t.Fatalf("Walk() error: %v", err)
+		}
+		// By default the prod file is left out, and the walk says so.
+		wantWarnings := 0
+		if len(opts.ComposeOverrides) == 0 {
+			wantWarnings = 1
+		}
+		if len(warnings) != wantWarnings || wantWarnings == 1 && !strings.Contains(warnings[0].Err.Error(), "--compose-override docker-compose.prod.yml") {
+			t.Errorf("warnings = %v, want %d", warnings, wantWarnings)
+		}
+		var got []string
+		for _, d := range ds.Dependencies() {
+			if d.Source == "api" && d.Port == 5432 {
+				got = append(got, d.Target)
+			}
+		}
+		return got
+	}
+
+	if got := targets(WalkOptions{}); fmt.Sprint(got) != "[db-dev]" {
+		t.Errorf("default: want the override file applied, got %v", got)
+	}
+	if got := targets(WalkOptions{ComposeOverrides: []string{"prod"}}); fmt.Sprint(got) != "[db-prod]" {
+		t.Errorf("--compose-override prod: got %v", got)
+	}
+}
+
+func TestWalkAttributesComposeEnvFilesToTheirService(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(`services:
+  web:
+    image: example/web
+    env_file: web.env
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "web.env"), []byte("REDIS_URL=redis://cache:6379\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "worker.env"), []byte("AMQP_URL=amqp://queue:5672\n"), 0644)
+
+	ds, _, err := Walk(dir, parser.DefaultRegistry())
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+	root := filepath.Base(dir)
+	var got []string
+	for _, d := range ds.Dependencies() {
+		got = append(got, d.Source+"->"+d.Target)
+	}
+	sort.Strings(got)
+	// web.env belongs to web alone; worker.env, which no service loads,
+	// is still read for the root service.
+	if want := fmt.Sprint([]string{root + "->queue", "web->cache"}); fmt.Sprint(got) != want {
+		t.Errorf("deps = %v, want %v", got, want)
+	}
+}